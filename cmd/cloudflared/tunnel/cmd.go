@@ -331,7 +331,7 @@ func StartServer(
 		defer wg.Done()
 		readinessServer := metrics.NewReadyServer(log)
 		observer.RegisterSink(readinessServer)
-		errC <- metrics.ServeMetrics(metricsListener, ctx.Done(), readinessServer, log)
+		errC <- metrics.ServeMetrics(metricsListener, ctx.Done(), readinessServer, ingressRules.StatusHandler(), ingressRules.StreamsHandler(), log)
 	}()
 
 	if err := ingressRules.StartOrigins(&wg, log, ctx.Done(), errC); err != nil {
@@ -672,6 +672,12 @@ func configureProxyFlags(shouldHide bool) []cli.Flag {
 			EnvVars: []string{"TUNNEL_URL"},
 			Hidden:  shouldHide,
 		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:    ingress.RouteFlag,
+			Usage:   "Proxy requests matching `hostname=HOSTNAME;path=REGEX;service=URL` to the service, like an ingress rule in the config file. Repeat it for more rules, the last one must have no hostname or path to catch all other requests. TUNNEL_ROUTE separates rules with commas, so paths with commas can only be set with --route.",
+			EnvVars: []string{"TUNNEL_ROUTE"},
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewBoolFlag(&cli.BoolFlag{
 			Name:    "hello-world",
 			Value:   false,
@@ -769,6 +775,13 @@ func configureProxyFlags(shouldHide bool) []cli.Flag {
 			EnvVars: []string{"TUNNEL_NO_CHUNKED_ENCODING"},
 			Hidden:  shouldHide,
 		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:    ingress.StrictRequestFramingFlag,
+			Usage:   "Rejects requests with ambiguous framing, e.g. both Content-Length and Transfer-Encoding headers, to protect your origin from HTTP request smuggling. Set to false to proxy them as-is.",
+			Value:   true,
+			EnvVars: []string{"TUNNEL_STRICT_REQUEST_FRAMING"},
+			Hidden:  shouldHide,
+		}),
 	}
 	return append(flags, sshFlags(shouldHide)...)
 }
@@ -897,6 +910,50 @@ func configureLoggingFlags(shouldHide bool) []cli.Flag {
 			EnvVars: []string{"TUNNEL_LOGDIRECTORY"},
 			Hidden:  shouldHide,
 		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:    "log-routing",
+			Usage:   "Log the ingress rule and origin service each request is routed to, at info level.",
+			EnvVars: []string{"TUNNEL_LOG_ROUTING"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "audit-log",
+			Usage:   "Record every routing decision (host, ingress rule, origin service and reason) to this file as JSON, independently of the other logs.",
+			EnvVars: []string{"TUNNEL_AUDIT_LOG"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    "audit-log-max-size",
+			Usage:   "Rotate the audit log when it reaches this size in megabytes. Rotated files are kept.",
+			Value:   100,
+			EnvVars: []string{"TUNNEL_AUDIT_LOG_MAX_SIZE"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "access-log-format",
+			Usage:   "Format of the lines written to the accessLogFile of ingress rules: json, clf for the Common Log Format, or combined for the Combined Log Format.",
+			Value:   origin.AccessLogFormatJSON,
+			EnvVars: []string{"TUNNEL_ACCESS_LOG_FORMAT"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    "max-total-origin-connections",
+			Usage:   "Maximum number of connections to origins open at once, across all ingress rules. Idle connections are closed to make room for new ones, and requests that still need another connection are answered with 503. 0 means no limit.",
+			EnvVars: []string{"TUNNEL_MAX_TOTAL_ORIGIN_CONNECTIONS"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "rollout-config",
+			Usage:   "Route --rollout-percent of the requests with the ingress rules of this configuration file, and the others with the current ones, to roll out new rules gradually.",
+			EnvVars: []string{"TUNNEL_ROLLOUT_CONFIG"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewFloat64Flag(&cli.Float64Flag{
+			Name:    "rollout-percent",
+			Usage:   "Percentage of the requests, picked at random, that are routed with the ingress rules of --rollout-config.",
+			EnvVars: []string{"TUNNEL_ROLLOUT_PERCENT"},
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:    "trace-output",
 			Usage:   "Name of trace output file, generated when cloudflared stops.",
@@ -1,9 +1,15 @@
 package tunnel
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/ingress"
 )
 
 func TestHostnameFromURI(t *testing.T) {
@@ -15,3 +21,24 @@ func TestHostnameFromURI(t *testing.T) {
 	assert.Equal(t, "", hostnameFromURI("trash"))
 	assert.Equal(t, "", hostnameFromURI("https://awesomesauce.com"))
 }
+
+func TestRouteFlagFromEnv(t *testing.T) {
+	require.NoError(t, os.Setenv("TUNNEL_ROUTE", "hostname=api.example.com;path=^/v[0-9]+/;service=http://localhost:8000,service=http_status:404"))
+	defer os.Unsetenv("TUNNEL_ROUTE")
+
+	var routes []string
+	app := &cli.App{
+		Flags: configureProxyFlags(true),
+		Action: func(c *cli.Context) error {
+			routes = c.StringSlice(ingress.RouteFlag)
+			return nil
+		},
+	}
+	require.NoError(t, app.Run([]string{"cloudflared"}))
+	ing, err := ingress.ParseRouteFlags(routes, &config.Configuration{})
+	require.NoError(t, err)
+	require.Len(t, ing.Rules, 2)
+	assert.Equal(t, "api.example.com", ing.Rules[0].Hostname)
+	assert.Equal(t, "^/v[0-9]+/", ing.Rules[0].Path.String())
+	assert.Equal(t, "HTTP 404", ing.Rules[1].Service.String())
+}
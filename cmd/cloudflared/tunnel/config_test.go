@@ -1,9 +1,14 @@
 package tunnel
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/ingress"
 )
 
 func TestDedup(t *testing.T) {
@@ -11,3 +16,15 @@ func TestDedup(t *testing.T) {
 	actual := dedup([]string{"a", "b", "a"})
 	require.ElementsMatch(t, expected, actual)
 }
+
+func TestLogIngressDiagnostics(t *testing.T) {
+	var out bytes.Buffer
+	log := zerolog.New(&out)
+	logIngressDiagnostics(&log, ingress.Diagnostics{
+		{Severity: ingress.SeverityWarning, RuleIndex: 1, Message: "this rule will never be matched"},
+		{Severity: ingress.SeverityInfo, RuleIndex: -1, Message: "the config has no hostnames"},
+	})
+	assert.Equal(t, `{"level":"warn","message":"Ingress rule #2: this rule will never be matched"}
+{"level":"info","message":"the config has no hostnames"}
+`, out.String())
+}
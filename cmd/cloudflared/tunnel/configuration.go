@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	homedir "github.com/mitchellh/go-homedir"
@@ -209,7 +210,9 @@ func prepareTunnelConfig(
 			Version:  version,
 			Arch:     buildInfo.OSArch(),
 		}
-		ingressRules, err = ingress.ParseIngress(cfg)
+		var diags ingress.Diagnostics
+		ingressRules, diags, err = ingress.ParseIngressWithDiagnostics(ingress.WithOriginRequestFlags(c, cfg))
+		logIngressDiagnostics(log, diags)
 		if err != nil && err != ingress.ErrNoIngressRules {
 			return nil, ingress.Ingress{}, err
 		}
@@ -225,6 +228,16 @@ func prepareTunnelConfig(
 		}
 	}
 
+	if routes := c.StringSlice(ingress.RouteFlag); len(routes) > 0 {
+		if c.IsSet("url") {
+			return nil, ingress.Ingress{}, ingress.ErrURLIncompatibleWithIngress
+		}
+		ingressRules, err = ingress.ParseRouteFlags(routes, ingress.WithOriginRequestFlags(c, cfg))
+		if err != nil {
+			return nil, ingress.Ingress{}, err
+		}
+	}
+
 	// Convert single-origin configuration into multi-origin configuration.
 	if ingressRules.IsEmpty() {
 		ingressRules, err = ingress.NewSingleOrigin(c, !isNamedTunnel)
@@ -233,6 +246,26 @@ func prepareTunnelConfig(
 		}
 	}
 
+	maxOriginConnections := c.Int("max-total-origin-connections")
+	if maxOriginConnections < 0 {
+		return nil, ingress.Ingress{}, errors.New("--max-total-origin-connections can't be negative")
+	}
+	ingressRules.LimitOriginConnections(maxOriginConnections)
+
+	if rolloutConfig := c.String("rollout-config"); rolloutConfig != "" {
+		next, err := readRolloutIngress(c, rolloutConfig)
+		if err != nil {
+			return nil, ingress.Ingress{}, err
+		}
+		percent := c.Float64("rollout-percent")
+		if err := ingressRules.RollOut(next, percent, time.Now().UnixNano()); err != nil {
+			return nil, ingress.Ingress{}, err
+		}
+		log.Info().Msgf("Routing %v%% of the requests with the ingress rules of %s", percent, rolloutConfig)
+	} else if c.IsSet("rollout-percent") {
+		return nil, ingress.Ingress{}, errors.New("--rollout-percent needs --rollout-config with the ingress rules to roll out")
+	}
+
 	var warpRoutingService *ingress.WarpRoutingService
 	warpRoutingEnabled := isWarpRoutingEnabled(cfg.WarpRouting, isNamedTunnel)
 	if warpRoutingEnabled {
@@ -255,7 +288,17 @@ func prepareTunnelConfig(
 		edgeTLSConfigs[p] = edgeTLSConfig
 	}
 
-	originProxy := origin.NewOriginProxy(ingressRules, warpRoutingService, tags, log)
+	var auditLog *origin.AuditLog
+	if auditLogPath := c.String("audit-log"); auditLogPath != "" {
+		if auditLog, err = origin.NewAuditLog(auditLogPath, c.Int("audit-log-max-size")); err != nil {
+			return nil, ingress.Ingress{}, err
+		}
+	}
+	accessLogFormat := c.String("access-log-format")
+	if err := origin.ValidateAccessLogFormat(accessLogFormat); err != nil {
+		return nil, ingress.Ingress{}, err
+	}
+	originProxy := origin.NewOriginProxy(ingressRules, warpRoutingService, tags, c.Bool("log-routing"), auditLog, accessLogFormat, log)
 	connectionConfig := &connection.Config{
 		OriginProxy:     originProxy,
 		GracePeriod:     c.Duration("grace-period"),
@@ -322,3 +365,19 @@ func dedup(slice []string) []string {
 	}
 	return keys
 }
+
+// logIngressDiagnostics logs the non-fatal problems found in the ingress rules, e.g. rules that
+// can never match, which would otherwise only show up in `cloudflared tunnel ingress validate`.
+func logIngressDiagnostics(log *zerolog.Logger, diags ingress.Diagnostics) {
+	for _, diag := range diags {
+		event := log.Info()
+		if diag.Severity != ingress.SeverityInfo {
+			event = log.Warn()
+		}
+		if diag.RuleIndex < 0 {
+			event.Msg(diag.Message)
+		} else {
+			event.Msgf("Ingress rule #%d: %s", diag.RuleIndex+1, diag.Message)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package tunnel
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/cloudflare/cloudflared/cmd/cloudflared/cliutil"
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/ingress"
+	"github.com/cloudflare/cloudflared/tlsconfig"
+	"github.com/cloudflare/cloudflared/validation"
+)
+
+// legacyOriginRequestKeys maps the single-origin settings of a config file to the
+// originRequest settings of an ingress rule.
+var legacyOriginRequestKeys = map[string]string{
+	ingress.ProxyConnectTimeoutFlag:       "connectTimeout",
+	ingress.ProxyTLSTimeoutFlag:           "tlsTimeout",
+	ingress.ProxyTCPKeepAliveFlag:         "tcpKeepAlive",
+	ingress.ProxyNoHappyEyeballsFlag:      "noHappyEyeballs",
+	ingress.ProxyKeepAliveConnectionsFlag: "keepAliveConnections",
+	ingress.ProxyKeepAliveTimeoutFlag:     "keepAliveTimeout",
+	ingress.HTTPHostHeaderFlag:            "httpHostHeader",
+	ingress.OriginServerNameFlag:          "originServerName",
+	tlsconfig.OriginCAPoolFlag:            "caPool",
+	ingress.NoTLSVerifyFlag:               "noTLSVerify",
+	ingress.NoChunkedEncodingFlag:         "disableChunkedEncoding",
+	ingress.ProxyAddressFlag:              "proxyAddress",
+	ingress.ProxyPortFlag:                 "proxyPort",
+	ingress.StrictRequestFramingFlag:      "strictRequestFraming",
+}
+
+var (
+	migrateInFlag = &cli.StringFlag{
+		Name:  "in",
+		Usage: "Read the legacy configuration from `FILEPATH`",
+	}
+	migrateOutFlag = &cli.StringFlag{
+		Name:  "out",
+		Usage: "Write the migrated configuration to `FILEPATH` instead of printing it",
+	}
+)
+
+func buildMigrateIngressCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "migrate",
+		Action:    cliutil.Action(migrateIngressCommand),
+		Usage:     "Convert a single-origin configuration to ingress rules",
+		UsageText: "cloudflared tunnel ingress migrate --in FILEPATH [--out FILEPATH]",
+		Description: "Reads a configuration file that uses url, hello-world, unix-socket or bastion with " +
+			"hostname, and writes an equivalent one with ingress rules: one for the hostname, and a " +
+			"catch-all rule that answers 404. The origin settings, like no-tls-verify, move to the " +
+			"rule's originRequest. Every other setting is kept as it is.",
+		Flags: []cli.Flag{migrateInFlag, migrateOutFlag},
+	}
+}
+
+// migrateIngressCommand converts the legacy configuration file given by --in.
+func migrateIngressCommand(c *cli.Context) error {
+	inPath := c.String(migrateInFlag.Name)
+	if inPath == "" {
+		return cliutil.UsageError("cloudflared tunnel ingress migrate needs --in with the configuration to convert")
+	}
+	legacy, err := ioutil.ReadFile(inPath)
+	if err != nil {
+		return errors.Wrap(err, "unable to read the legacy configuration")
+	}
+	migrated, err := migrateLegacyConfig(legacy)
+	if err != nil {
+		return err
+	}
+	if outPath := c.String(migrateOutFlag.Name); outPath != "" {
+		return ioutil.WriteFile(outPath, migrated, 0600)
+	}
+	_, err = os.Stdout.Write(migrated)
+	return err
+}
+
+// migrateLegacyConfig returns the YAML configuration with its single origin replaced by ingress
+// rules, and checks that the rules are valid.
+func migrateLegacyConfig(legacy []byte) ([]byte, error) {
+	var settings yaml.MapSlice
+	if err := yaml.Unmarshal(legacy, &settings); err != nil {
+		return nil, errors.Wrap(err, "unable to parse the legacy configuration")
+	}
+	var (
+		migrated      yaml.MapSlice
+		hostname      string
+		service       string
+		originRequest yaml.MapSlice
+	)
+	for _, setting := range settings {
+		key, _ := setting.Key.(string)
+		switch key {
+		case "ingress":
+			return nil, errors.New("the configuration already has ingress rules")
+		case "hostname":
+			hostname = fmt.Sprint(setting.Value)
+		case "url":
+			originURL, err := validation.ValidateUrl(fmt.Sprint(setting.Value))
+			if err != nil {
+				return nil, errors.Wrap(err, "Error validating origin URL")
+			}
+			service = originURL.String()
+		case "hello-world":
+			if setting.Value == true {
+				service = "hello_world"
+			}
+		case "unix-socket":
+			service = "unix:" + fmt.Sprint(setting.Value)
+		case config.BastionFlag:
+			if setting.Value == true {
+				service = ingress.ServiceBastion
+			}
+		case ingress.Socks5Flag:
+			if setting.Value == true {
+				originRequest = append(originRequest, yaml.MapItem{Key: "proxyType", Value: "socks"})
+			}
+		default:
+			if originKey, ok := legacyOriginRequestKeys[key]; ok {
+				originRequest = append(originRequest, yaml.MapItem{Key: originKey, Value: setting.Value})
+			} else {
+				migrated = append(migrated, setting)
+			}
+		}
+	}
+	if service == "" {
+		// What cloudflared proxies to when no origin is configured.
+		service = "http://localhost:8080"
+	}
+
+	rule := yaml.MapSlice{{Key: "service", Value: service}}
+	if len(originRequest) > 0 {
+		rule = append(rule, yaml.MapItem{Key: "originRequest", Value: originRequest})
+	}
+	rules := []yaml.MapSlice{rule}
+	if hostname != "" {
+		rule = append(yaml.MapSlice{{Key: "hostname", Value: hostname}}, rule...)
+		rules = []yaml.MapSlice{rule, {{Key: "service", Value: "http_status:404"}}}
+	}
+	migrated = append(migrated, yaml.MapItem{Key: "ingress", Value: rules})
+
+	out, err := yaml.Marshal(migrated)
+	if err != nil {
+		return nil, err
+	}
+	var conf config.Configuration
+	if err := yaml.Unmarshal(out, &conf); err != nil {
+		return nil, errors.Wrap(err, "unable to parse the migrated configuration")
+	}
+	if _, err := ingress.ParseIngress(&conf); err != nil {
+		return nil, errors.Wrap(err, "the migrated ingress rules are invalid")
+	}
+	return out, nil
+}
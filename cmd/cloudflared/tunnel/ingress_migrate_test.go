@@ -0,0 +1,84 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	legacy := `
+tunnel: 9a8b7c6d-1234-5678-9abc-def012345678
+credentials-file: /etc/cloudflared/creds.json
+hostname: app.example.com
+url: localhost:8000
+no-tls-verify: true
+proxy-connect-timeout: 10s
+loglevel: debug
+`
+	migrated, err := migrateLegacyConfig([]byte(legacy))
+	require.NoError(t, err)
+
+	expected := `tunnel: 9a8b7c6d-1234-5678-9abc-def012345678
+credentials-file: /etc/cloudflared/creds.json
+loglevel: debug
+ingress:
+- hostname: app.example.com
+  service: http://localhost:8000
+  originRequest:
+    noTLSVerify: true
+    connectTimeout: 10s
+- service: http_status:404
+`
+	assert.Equal(t, expected, string(migrated))
+
+	var conf config.Configuration
+	require.NoError(t, yaml.Unmarshal(migrated, &conf))
+	ing, err := ingress.ParseIngress(&conf)
+	require.NoError(t, err)
+	require.Len(t, ing.Rules, 2)
+	assert.Equal(t, "app.example.com", ing.Rules[0].Hostname)
+	assert.Equal(t, "http://localhost:8000", ing.Rules[0].Service.String())
+	assert.True(t, ing.Rules[0].Config.NoTLSVerify)
+	assert.Equal(t, 10*time.Second, ing.Rules[0].Config.ConnectTimeout)
+	assert.Equal(t, "HTTP 404", ing.Rules[1].Service.String())
+}
+
+func TestMigrateLegacyConfigServices(t *testing.T) {
+	tests := []struct {
+		legacy        string
+		expectService string
+	}{
+		{legacy: "hello-world: true", expectService: "Hello World test origin"},
+		{legacy: "unix-socket: /run/app.sock", expectService: "unix socket: /run/app.sock"},
+		{legacy: "url: tcp://localhost:2222", expectService: "localhost:2222"},
+		{legacy: "loglevel: info", expectService: "http://localhost:8080"},
+	}
+	for _, test := range tests {
+		migrated, err := migrateLegacyConfig([]byte(test.legacy))
+		require.NoError(t, err, test.legacy)
+		var conf config.Configuration
+		require.NoError(t, yaml.Unmarshal(migrated, &conf))
+		ing, err := ingress.ParseIngress(&conf)
+		require.NoError(t, err)
+		// Without a hostname, the only rule is the catch-all.
+		require.Len(t, ing.Rules, 1, test.legacy)
+		assert.Equal(t, test.expectService, ing.Rules[0].Service.String(), test.legacy)
+	}
+}
+
+func TestMigrateLegacyConfigWithIngress(t *testing.T) {
+	legacy := `
+url: localhost:8000
+ingress:
+  - service: http://localhost:8001
+`
+	_, err := migrateLegacyConfig([]byte(legacy))
+	assert.Error(t, err)
+}
@@ -2,7 +2,11 @@ package tunnel
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"strings"
 
 	"github.com/cloudflare/cloudflared/cmd/cloudflared/cliutil"
 	"github.com/cloudflare/cloudflared/config"
@@ -35,23 +39,59 @@ func buildIngressSubcommand() *cli.Command {
 
 		To ensure cloudflared can route all incoming requests, the last rule must be a catch-all
 		rule that matches all traffic. You can validate these rules with the 'ingress validate'
-		command, and test which rule matches a particular URL with 'ingress rule <URL>'.
+		command, check them for common mistakes with 'ingress lint', and test which rule matches
+		a particular URL with 'ingress rule <URL>'.
 
-		Multiple-origin routing is incompatible with the --url flag.`,
-		Subcommands: []*cli.Command{buildValidateIngressCommand(), buildTestURLCommand()},
+		Multiple-origin routing is incompatible with the --url flag. To convert a configuration
+		that uses it, run 'ingress migrate'.`,
+		Subcommands: []*cli.Command{buildValidateIngressCommand(), buildLintIngressCommand(), buildTestURLCommand(), buildRoutesCommand(), buildMigrateIngressCommand()},
 	}
 }
 
 func buildValidateIngressCommand() *cli.Command {
 	return &cli.Command{
-		Name:        "validate",
-		Action:      cliutil.ConfiguredActionWithWarnings(validateIngressCommand),
-		Usage:       "Validate the ingress configuration ",
-		UsageText:   "cloudflared tunnel [--config FILEPATH] ingress validate",
-		Description: "Validates the configuration file, ensuring your ingress rules are OK.",
+		Name:      "validate",
+		Action:    cliutil.ConfiguredActionWithWarnings(validateIngressCommand),
+		Usage:     "Validate the ingress configuration ",
+		UsageText: "cloudflared tunnel [--config FILEPATH] ingress validate [--test-paths path=PATH] [--check-origins] [--quiet]",
+		Description: "Validates the configuration file, ensuring your ingress rules are OK. " +
+			"Use --test-paths to also report which rule would match sample requests, e.g. " +
+			"--test-paths path=/api/users or --test-paths host=www.example.com,path=/index.html. " +
+			"Use --check-origins to also connect to every origin, and --quiet to only report the " +
+			"result with the exit code, e.g. for a container's HEALTHCHECK.",
+		Flags: []cli.Flag{testPathsFlag, checkOriginsFlag, quietFlag},
 	}
 }
 
+func buildLintIngressCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "lint",
+		Action:    cliutil.ConfiguredAction(lintIngressCommand),
+		Usage:     "Check the ingress configuration for common mistakes",
+		UsageText: "cloudflared tunnel [--config FILEPATH] ingress lint",
+		Description: "Reports settings in the ingress rules that are valid, but usually mistakes, like path " +
+			"regexes that aren't anchored with ^, disabled TLS verification or wildcards for a whole " +
+			"top-level domain. Fails if any rule is invalid, like 'ingress validate'.",
+	}
+}
+
+var testPathsFlag = &cli.StringSliceFlag{
+	Name:  "test-paths",
+	Usage: "Report which rule matches a sample request, given as `path=PATH` with an optional host=HOSTNAME",
+}
+
+var checkOriginsFlag = &cli.BoolFlag{
+	Name:  "check-origins",
+	Usage: "Fail unless cloudflared can connect to the origin of every rule",
+}
+
+var quietFlag = &cli.BoolFlag{
+	Name:  "quiet",
+	Usage: "Print nothing, and only exit with a non-zero code if validation fails",
+}
+
+var errNoConfigFile = errors.New("No configuration file was found. Please create one, or use the --config flag to specify its filepath. You can use the help command to learn more about configuration files")
+
 func buildTestURLCommand() *cli.Command {
 	return &cli.Command{
 		Name:      "rule",
@@ -67,29 +107,136 @@ func buildTestURLCommand() *cli.Command {
 	}
 }
 
+func buildRoutesCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "routes",
+		Action:    cliutil.ConfiguredAction(routesCommand),
+		Usage:     "List the HTTP routes served by the ingress rules",
+		UsageText: "cloudflared tunnel [--config FILEPATH] ingress routes [--output FORMAT]",
+		Description: "Lists the hostname, path, methods and service of every ingress rule that proxies HTTP " +
+			"requests, in the order they are matched. Use --output json or --output yaml to produce a route " +
+			"table for documentation or for importing into an API gateway.",
+		Flags: []cli.Flag{outputFormatFlag},
+	}
+}
+
 // validateIngressCommand check the syntax of the ingress rules in the cloudflared config file
 func validateIngressCommand(c *cli.Context, warnings string) error {
-	conf := config.GetConfiguration()
+	return validateIngressQuietly(c, config.GetConfiguration(), warnings)
+}
+
+// validateIngressQuietly is validateIngress, which prints nothing with --quiet.
+func validateIngressQuietly(c *cli.Context, conf *config.Configuration, warnings string) error {
+	if !c.Bool(quietFlag.Name) {
+		return validateIngress(c, conf, warnings, os.Stdout)
+	}
+	if err := validateIngress(c, conf, warnings, ioutil.Discard); err != nil {
+		// Exit with an error code, but without printing the error.
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+func validateIngress(c *cli.Context, conf *config.Configuration, warnings string, out io.Writer) error {
 	if conf.Source() == "" {
-		fmt.Println("No configuration file was found. Please create one, or use the --config flag to specify its filepath. You can use the help command to learn more about configuration files")
-		return nil
+		// There are no rules to validate, which a script checking them mustn't take for valid.
+		return errNoConfigFile
 	}
-	fmt.Println("Validating rules from", conf.Source())
-	if _, err := ingress.ParseIngress(conf); err != nil {
+	fmt.Fprintln(out, "Validating rules from", conf.Source())
+	ing, diags, err := ingress.ParseIngressWithDiagnostics(conf)
+	if len(diags) > 0 {
+		fmt.Fprintln(out, diags)
+	}
+	if err != nil {
 		return errors.Wrap(err, "Validation failed")
 	}
 	if c.IsSet("url") {
 		return ingress.ErrURLIncompatibleWithIngress
 	}
+	if samples := c.StringSlice(testPathsFlag.Name); len(samples) > 0 {
+		report, err := testPaths(ing, samples)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(out, report)
+	}
+	if c.Bool(checkOriginsFlag.Name) {
+		unreachable := 0
+		for _, check := range ing.CheckOrigins(c.Context) {
+			if check.Err != nil {
+				fmt.Fprintf(out, "Rule #%d: cannot connect to %s: %s\n", check.Rule+1, check.Address, check.Err)
+				unreachable++
+			}
+		}
+		if unreachable > 0 {
+			return fmt.Errorf("Validation failed: %d origins are unreachable", unreachable)
+		}
+	}
 	if warnings != "" {
-		fmt.Println("Warning: unused keys detected in your config file. Here is a list of unused keys:")
-		fmt.Println(warnings)
+		fmt.Fprintln(out, "Warning: unused keys detected in your config file. Here is a list of unused keys:")
+		fmt.Fprintln(out, warnings)
 		return nil
 	}
-	fmt.Println("OK")
+	fmt.Fprintln(out, "OK")
+	return nil
+}
+
+// lintIngressCommand prints the diagnostics for the ingress rules in the cloudflared config file.
+func lintIngressCommand(c *cli.Context) error {
+	return lintIngress(config.GetConfiguration(), os.Stdout)
+}
+
+func lintIngress(conf *config.Configuration, out io.Writer) error {
+	if conf.Source() == "" {
+		return errNoConfigFile
+	}
+	fmt.Fprintln(out, "Linting rules from", conf.Source())
+	diags := ingress.Lint(conf)
+	if len(diags) > 0 {
+		fmt.Fprintln(out, diags)
+	}
+	if diags.HasErrors() {
+		return errors.New("Linting failed")
+	}
+	if len(diags) == 0 {
+		fmt.Fprintln(out, "OK")
+	}
 	return nil
 }
 
+// testPaths reports which rule matches each sample, formatted like "host=HOSTNAME,path=PATH".
+func testPaths(ing ingress.Ingress, samples []string) (string, error) {
+	var report strings.Builder
+	for _, sample := range samples {
+		var hostname, path string
+		for _, field := range strings.Split(sample, ",") {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) != 2 {
+				return "", fmt.Errorf("--test-paths %q should look like path=PATH", sample)
+			}
+			switch keyValue[0] {
+			case "host":
+				hostname = keyValue[1]
+			case "path":
+				path = keyValue[1]
+			default:
+				return "", fmt.Errorf("--test-paths %q has unknown key %s, only host and path are supported", sample, keyValue[0])
+			}
+		}
+		if !strings.HasPrefix(path, "/") {
+			return "", fmt.Errorf("--test-paths %q needs a path starting with /", sample)
+		}
+
+		rule, i := ing.FindMatchingRule(hostname, path)
+		fmt.Fprintf(&report, "%s matches rule #%d", sample, rule.Index+1)
+		if i == len(ing.Rules)-1 {
+			report.WriteString(" (catch-all)")
+		}
+		report.WriteRune('\n')
+	}
+	return report.String(), nil
+}
+
 // testURLCommand checks which ingress rule matches the given URL.
 func testURLCommand(c *cli.Context) error {
 	requestArg := c.Args().First()
@@ -112,8 +259,36 @@ func testURLCommand(c *cli.Context) error {
 		return errors.Wrap(err, "Validation failed")
 	}
 
-	_, i := ing.FindMatchingRule(requestURL.Hostname(), requestURL.Path)
-	fmt.Printf("Matched rule #%d\n", i+1)
-	fmt.Println(ing.Rules[i].MultiLineString())
+	rule, _ := ing.FindMatchingRule(requestURL.Hostname(), requestURL.Path)
+	fmt.Printf("Matched rule #%d\n", rule.Index+1)
+	fmt.Println(rule.MultiLineString())
+	return nil
+}
+
+// routesCommand prints the HTTP routes of the ingress rules.
+func routesCommand(c *cli.Context) error {
+	conf := config.GetConfiguration()
+	ing, err := ingress.ParseIngress(conf)
+	if err != nil {
+		return errors.Wrap(err, "Validation failed")
+	}
+
+	routes := ing.Routes()
+	if outputFormat := c.String(outputFormatFlag.Name); outputFormat != "" {
+		return renderOutput(outputFormat, routes)
+	}
+
+	writer := tabWriter()
+	defer writer.Flush()
+	_, _ = fmt.Fprintln(writer, "HOSTNAME\tPATH\tMETHODS\tSERVICE\t")
+	for _, route := range routes {
+		path := route.Path
+		if route.PathTemplate != "" {
+			path = route.PathTemplate
+		} else if route.PathSuffix != "" {
+			path = route.PathSuffix
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t\n", route.Hostname, path, strings.Join(route.Methods, ","), route.Service)
+	}
 	return nil
 }
@@ -0,0 +1,181 @@
+package tunnel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+
+	"github.com/cloudflare/cloudflared/cmd/cloudflared/cliutil"
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestTestPaths(t *testing.T) {
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Path: "^/api/v[0-9]+/", Service: "http_status:200"},
+			{Hostname: "www.example.com", Path: `\.html$`, Service: "http_status:201"},
+			{Service: "http_status:404"},
+		},
+	})
+	require.NoError(t, err)
+
+	report, err := testPaths(ing, []string{
+		"path=/api/v2/users",
+		"path=/internal/api/v2/users",
+		"host=www.example.com,path=/index.html",
+		"path=/index.html",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `path=/api/v2/users matches rule #1
+path=/internal/api/v2/users matches rule #3 (catch-all)
+host=www.example.com,path=/index.html matches rule #2
+path=/index.html matches rule #3 (catch-all)
+`, report)
+
+	for _, invalid := range []string{"/api", "path=api", "method=GET,path=/", "host=www.example.com"} {
+		_, err := testPaths(ing, []string{invalid})
+		assert.Error(t, err, invalid)
+	}
+
+	// Rules are reported by their position in the file, even if a priority moved them.
+	ing, err = ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "www.example.com", Service: "http_status:200"},
+			{Hostname: "api.example.com", Service: "http_status:201", Priority: 10},
+			{Service: "http_status:404"},
+		},
+	})
+	require.NoError(t, err)
+	report, err = testPaths(ing, []string{"host=api.example.com,path=/", "host=www.example.com,path=/"})
+	require.NoError(t, err)
+	assert.Equal(t, `host=api.example.com,path=/ matches rule #2
+host=www.example.com,path=/ matches rule #1
+`, report)
+}
+
+func TestValidateIngressQuiet(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedListener.Close()
+
+	tests := []struct {
+		name         string
+		config       string
+		args         []string
+		expectFailed bool
+	}{
+		{
+			name:   "valid",
+			config: "ingress:\n - service: http_status:404\n",
+		},
+		{
+			name:         "invalid",
+			config:       "ingress:\n - hostname: www.example.com\n   service: http_status:404\n",
+			expectFailed: true,
+		},
+		{
+			name:   "reachable origin",
+			config: "ingress:\n - service: http://" + listener.Addr().String() + "\n",
+			args:   []string{"--check-origins"},
+		},
+		{
+			name:         "unreachable origin",
+			config:       "ingress:\n - service: http://" + closedListener.Addr().String() + "\n",
+			args:         []string{"--check-origins"},
+			expectFailed: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.yml")
+			require.NoError(t, ioutil.WriteFile(configFile, []byte(test.config), 0600))
+
+			var exitErr error
+			app := &cli.App{
+				Flags:          []cli.Flag{&cli.StringFlag{Name: "config"}},
+				Commands:       []*cli.Command{buildIngressSubcommand()},
+				ExitErrHandler: func(c *cli.Context, err error) { exitErr = err },
+			}
+			args := append([]string{"cloudflared", "--config", configFile, "ingress", "validate", "--quiet"}, test.args...)
+			stdout := captureStdout(t, func() { exitErr = app.Run(args) })
+
+			assert.Empty(t, stdout)
+			if !test.expectFailed {
+				assert.NoError(t, exitErr)
+				return
+			}
+			require.Error(t, exitErr)
+			exitCoder, ok := exitErr.(cli.ExitCoder)
+			require.True(t, ok)
+			assert.Equal(t, 1, exitCoder.ExitCode())
+			assert.Empty(t, exitErr.Error(), "quiet mode must not print the error")
+		})
+	}
+}
+
+func TestValidateIngressWithoutConfigFile(t *testing.T) {
+	for _, quiet := range []bool{false, true} {
+		var exitErr error
+		app := &cli.App{
+			Commands: []*cli.Command{{
+				Name:  "validate",
+				Flags: []cli.Flag{quietFlag},
+				Action: cliutil.WithErrorHandler(func(c *cli.Context) error {
+					return validateIngressQuietly(c, &config.Configuration{}, "")
+				}),
+			}},
+			ExitErrHandler: func(c *cli.Context, err error) { exitErr = err },
+		}
+		args := []string{"cloudflared", "validate"}
+		if quiet {
+			args = append(args, "--quiet")
+		}
+		stdout := captureStdout(t, func() { exitErr = app.Run(args) })
+
+		assert.Empty(t, stdout)
+		require.Error(t, exitErr)
+		exitCoder, ok := exitErr.(cli.ExitCoder)
+		require.True(t, ok)
+		assert.Equal(t, 1, exitCoder.ExitCode())
+		if quiet {
+			assert.Empty(t, exitErr.Error(), "quiet mode must not print the error")
+		} else {
+			assert.Contains(t, exitErr.Error(), "No configuration file was found")
+		}
+	}
+}
+
+func TestLintIngressWithoutConfigFile(t *testing.T) {
+	var out bytes.Buffer
+	assert.Equal(t, errNoConfigFile, lintIngress(&config.Configuration{}, &out))
+	assert.Empty(t, out.String())
+}
+
+// captureStdout returns what f writes to os.Stdout.
+func captureStdout(t *testing.T, f func()) string {
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(reader)
+		output <- data
+	}()
+	f()
+	writer.Close()
+	return string(<-output)
+}
@@ -0,0 +1,30 @@
+package tunnel
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// readRolloutIngress parses the ingress rules of the configuration file given by
+// --rollout-config, with the same origin request flags as the current rules.
+func readRolloutIngress(c *cli.Context, path string) (ingress.Ingress, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ingress.Ingress{}, errors.Wrap(err, "unable to read the configuration to roll out")
+	}
+	var conf config.Configuration
+	if err := yaml.Unmarshal(content, &conf); err != nil {
+		return ingress.Ingress{}, errors.Wrap(err, "unable to parse the configuration to roll out")
+	}
+	rules, err := ingress.ParseIngress(ingress.WithOriginRequestFlags(c, &conf))
+	if err != nil {
+		return ingress.Ingress{}, errors.Wrap(err, "the ingress rules to roll out are invalid")
+	}
+	return rules, nil
+}
@@ -0,0 +1,29 @@
+package tunnel
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestReadRolloutIngressAppliesOriginRequestFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "next.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+ingress:
+ - service: http://localhost:9000
+`), 0600))
+	set := flag.NewFlagSet("contrive", 0)
+	set.Bool(ingress.StrictRequestFramingFlag, true, "")
+	require.NoError(t, set.Parse([]string{"--" + ingress.StrictRequestFramingFlag + "=false"}))
+
+	next, err := readRolloutIngress(cli.NewContext(nil, set, nil), path)
+	require.NoError(t, err)
+	assert.False(t, next.Rules[0].Config.StrictRequestFraming)
+}
@@ -175,12 +175,129 @@ func ValidateUrl(c *cli.Context, allowURLFromArgs bool) (*url.URL, error) {
 }
 
 type UnvalidatedIngressRule struct {
-	Hostname      string
-	Path          string
-	Service       string
+	Hostname string
+	Path     string
+	// PathTemplate matches the requests whose path fits it, like /users/{id}/posts, as an
+	// alternative to a Path regex. Each {name} matches one path segment.
+	PathTemplate string `yaml:"pathTemplate"`
+	Service      string
+	// Schedule restricts the rule to a daily time window, e.g. for maintenance.
+	Schedule *IngressSchedule `yaml:"schedule"`
+	// BodyMatch restricts the rule to requests whose JSON body has a field with a given value.
+	BodyMatch *IngressBodyMatch `yaml:"bodyMatch"`
+	// Shard restricts the rule to the requests whose key hashes to its bucket.
+	Shard *IngressShard `yaml:"shard"`
+	// PathSuffix matches the requests whose path ends with it, e.g. .json.
+	PathSuffix string `yaml:"pathSuffix"`
+	// Priority moves the rule ahead of the rules with lower priorities, which default to 0.
+	// Rules with the same priority keep their order. The catch-all rule always stays last.
+	Priority int `yaml:"priority"`
+	// RequireClientCert rejects requests that didn't present a client certificate to
+	// Cloudflare with 403 Forbidden. It relies on the Cf-Cert-Presented header, which
+	// Cloudflare adds with the "Add TLS client auth headers" managed transform, so that
+	// transform is required: without it, every request is rejected.
+	RequireClientCert bool `yaml:"requireClientCert"`
+	// RequireTLS rejects requests that didn't come over a TLS connection to Cloudflare, going by
+	// the edge's Cf-Visitor and X-Forwarded-Proto headers, with 426 Upgrade Required, pointing
+	// them to the same URL over https.
+	RequireTLS bool `yaml:"requireTLS"`
+	// Accepts restricts the rule to requests whose Accept header prefers one of these media
+	// types, e.g. application/json, for content negotiation.
+	Accepts []string `yaml:"accepts"`
+	// PathSegments restricts the rule to requests whose path has a number of segments, e.g. to
+	// route /users and /users/42 to different rules.
+	PathSegments *IngressPathSegments `yaml:"pathSegments"`
+	// Referer rejects requests with 403 Forbidden, unless they were referred by one of the
+	// allowed origins, e.g. to stop other sites from hotlinking.
+	Referer *IngressReferer `yaml:"referer"`
+	// Group sends the rule's requests to the services of a group in Configuration.Groups,
+	// instead of Service. Rules naming the same group don't share its response times, draining
+	// or health.
+	Group string `yaml:"group"`
+	// GroupStrategy picks the group's service for each request: weighted, the default, picks
+	// at random in proportion to the weights, least-time picks the service that has recently
+	// been responding the fastest.
+	GroupStrategy string `yaml:"groupStrategy"`
+	// LoadBalancer configures how the rule spreads requests over the group's services.
+	LoadBalancer *IngressLoadBalancer `yaml:"loadBalancer"`
+	// ServiceRef uses the service of that name in Configuration.Services, instead of Service.
+	ServiceRef    string              `yaml:"serviceRef"`
 	OriginRequest OriginRequestConfig `yaml:"originRequest"`
 }
 
+// UnmarshalYAML rejects clientALPN and requireSNI: cloudflared only sees its own TLS connection
+// to the edge, never the protocol the eyeball negotiated or the server name it sent.
+func (r *UnvalidatedIngressRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	keys, err := yamlKeys(unmarshal)
+	if err != nil {
+		return err
+	}
+	if _, ok := keys["clientALPN"]; ok {
+		return errors.New("clientALPN isn't supported, because cloudflared can't tell which protocol the eyeball negotiated with Cloudflare")
+	}
+	if _, ok := keys["requireSNI"]; ok {
+		return errors.New("requireSNI isn't supported, because cloudflared can't tell which server name the eyeball sent to Cloudflare")
+	}
+	type plain UnvalidatedIngressRule
+	return unmarshal((*plain)(r))
+}
+
+// IngressLoadBalancer configures the load balancing of an ingress rule over its group.
+type IngressLoadBalancer struct {
+	// MinHealthy answers 503 while fewer of the group's services are healthy, rather than
+	// overloading the remaining ones. Services are unhealthy while they fail requests or drain.
+	MinHealthy int `yaml:"minHealthy"`
+	// SelectTimeout makes requests wait this long for one of the group's services to be healthy
+	// while none is, and then answers 503. By default a service that failed is tried anyway.
+	SelectTimeout time.Duration `yaml:"selectTimeout"`
+}
+
+// IngressReferer lists the origins, like https://www.example.com, whose pages may refer
+// requests to an ingress rule.
+type IngressReferer struct {
+	Allow []string `yaml:"allow"`
+	// AllowMissing also accepts requests without a Referer header, e.g. when users open a
+	// link directly.
+	AllowMissing bool `yaml:"allowMissing"`
+}
+
+// IngressBodyMatch restricts an ingress rule to requests whose JSON body has a field with the
+// given value, e.g. to route webhooks by event type.
+type IngressBodyMatch struct {
+	// JSONPath to the field, e.g. $.event or $.commits[0].author.
+	JSONPath string `yaml:"jsonPath"`
+	Equals   string `yaml:"equals"`
+	// MaxBytes bounds how much of the body is buffered to find the field. Requests with larger
+	// bodies don't match.
+	MaxBytes int `yaml:"maxBytes"`
+}
+
+// IngressShard restricts an ingress rule to the requests whose key hashes to its bucket, to
+// spread requests deterministically over sharded origins. Requests without the key don't match.
+type IngressShard struct {
+	// By selects the key, either header:<name> or query:<name>.
+	By      string `yaml:"by"`
+	Buckets int    `yaml:"buckets"`
+	// Bucket is the index of this rule's bucket, from 0 to buckets-1.
+	Bucket int `yaml:"bucket"`
+}
+
+// IngressPathSegments bounds the number of segments of the request path, like 2 for /a/b. Max
+// defaults to no bound.
+type IngressPathSegments struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
+// IngressSchedule restricts an ingress rule to a daily time window, e.g. for maintenance.
+// Times are formatted as "15:04". The window may wrap around midnight.
+type IngressSchedule struct {
+	After  string `yaml:"after"`
+	Before string `yaml:"before"`
+	// IANA time zone name, defaults to UTC.
+	TZ string `yaml:"tz"`
+}
+
 // OriginRequestConfig is a set of optional fields that users may set to
 // customize how cloudflared sends requests to origin services. It is used to set
 // up general config that apply to all rules, and also, specific per-rule
@@ -223,6 +340,313 @@ type OriginRequestConfig struct {
 	ProxyType *string `yaml:"proxyType"`
 	// IP rules for the proxy service
 	IPRules []IngressIPRule `yaml:"ipRules"`
+	// Maximum number of redirects from the origin that cloudflared follows itself, instead of
+	// passing them to the client. 0 passes every redirect through.
+	FollowRedirects *int `yaml:"followRedirects"`
+	// Maximum bytes per second proxied for this rule, in each direction. 0 means unlimited.
+	MaxBytesPerSecond *int `yaml:"maxBytesPerSecond"`
+	// Routes TCP connections to a different origin address (host:port) based on the SNI
+	// of the eyeball's TLS ClientHello, e.g. for a tcp:// service fronting several TLS backends.
+	SNIRoutes map[string]string `yaml:"sniRoutes"`
+	// How to handle an origin certificate that fails verification: strict (the default) aborts the
+	// request, warn logs a warning but still proxies, off skips verification like noTLSVerify.
+	TLSVerifyMode *string `yaml:"tlsVerifyMode"`
+	// Reject requests whose framing is ambiguous, e.g. with both Content-Length and Transfer-Encoding,
+	// with 400 instead of proxying them, to protect the origin from request smuggling. On by default.
+	StrictRequestFraming *bool `yaml:"strictRequestFraming"`
+	// Requests repeating the value of this header, e.g. Idempotency-Key, within idempotencyWindow
+	// get the first request's response instead of being proxied to the origin again. 5xx and
+	// incomplete responses aren't replayed.
+	IdempotencyHeader *string `yaml:"idempotencyHeader"`
+	// How long the response to a request with an idempotencyHeader is replayed for.
+	IdempotencyWindow *time.Duration `yaml:"idempotencyWindow"`
+	// Maximum number of simultaneous websocket sessions for this rule. Further upgrade requests
+	// are rejected with 503 until a session closes. No limit if unset.
+	MaxWebsockets *int `yaml:"maxWebsockets"`
+	// Regex replacements applied to text response bodies, e.g. to rewrite internal URLs in HTML
+	// or JSON. Binary, compressed and large responses are proxied unchanged.
+	ResponseRewrite []ResponseRewriteRule `yaml:"responseRewrite"`
+	// ReadBufferBytes sets the size of the buffer used to copy data read from the origin of
+	// a websocket or TCP stream. The default is 32KiB.
+	ReadBufferBytes *int `yaml:"readBufferBytes"`
+	// WriteBufferBytes sets the size of the buffer used to copy data written to the origin
+	// of a websocket or TCP stream. The default is 32KiB.
+	WriteBufferBytes *int `yaml:"writeBufferBytes"`
+	// SignRequests adds a header with an HMAC signature of each request, so the origin can
+	// verify the request came through cloudflared.
+	SignRequests *SignRequestsConfig `yaml:"signRequests"`
+	// KeepConnectionHeader forwards the eyeball's Connection header, and the headers it names,
+	// instead of replacing them with Connection: keep-alive, or close with assumeHTTP10. Other
+	// hop-by-hop headers are still removed.
+	KeepConnectionHeader *bool `yaml:"keepConnectionHeader"`
+	// ForceLocalTLS connects to http://localhost origins with TLS, if they accept it. Origins
+	// that don't are still reached over plain HTTP, with a warning.
+	ForceLocalTLS *bool `yaml:"forceLocalTLS"`
+	// ALPN lists the application protocols offered in the TLS handshake with the origin, in
+	// order of preference: h2 and/or http/1.1. Offering h2 enables HTTP/2 to the origin.
+	ALPN []string `yaml:"alpn"`
+	// MaxConcurrentDials limits the connection attempts to the origin that may be in flight at
+	// once. Further attempts wait until one of them finishes. 0 means no limit.
+	MaxConcurrentDials *int `yaml:"maxConcurrentDials"`
+	// WellKnown serves robots.txt and security.txt from local files, without asking the origin.
+	WellKnown *WellKnownConfig `yaml:"wellKnown"`
+	// TraceContext sends W3C trace context to the origin: a traceparent header from the
+	// eyeball is passed on, and one is generated for requests without it.
+	TraceContext *bool `yaml:"traceContext"`
+	// TCPTLS wraps the connection to a TCP origin in TLS, for TCP services that are
+	// actually TLS.
+	TCPTLS *TCPTLSConfig `yaml:"tcpTLS"`
+	// ResponseTimeoutByMethod bounds how long the origin may take to respond to requests,
+	// depending on their method, e.g. to give up on slow reads sooner than on slow writes.
+	ResponseTimeoutByMethod map[string]time.Duration `yaml:"responseTimeoutByMethod"`
+	// ACMEChallenge is a directory of ACME HTTP-01 challenge responses, served by cloudflared
+	// for /.well-known/acme-challenge/<token> instead of the origin.
+	ACMEChallenge *string `yaml:"acmeChallenge"`
+	// RewritePath replaces the path of every request with a fixed path, e.g. /fixed/endpoint.
+	// The query is kept.
+	RewritePath *string `yaml:"rewritePath"`
+	// RewriteCookieDomain rewrites the Domain of cookies set by the origin, e.g. from its
+	// internal domain to the public hostname.
+	RewriteCookieDomain *CookieDomainRewrite `yaml:"rewriteCookieDomain"`
+	// ForceSecureCookies adds the Secure attribute to cookies set by the origin, e.g. for
+	// origins that are reached over HTTP and don't know the eyeball uses HTTPS.
+	ForceSecureCookies *bool `yaml:"forceSecureCookies"`
+	// CookieSameSite adds a SameSite attribute, Strict, Lax or None, to cookies set by the
+	// origin without one.
+	CookieSameSite *string `yaml:"cookieSameSite"`
+	// DNSNegativeTTL caches failed DNS lookups of the origin's hostname for this long, so
+	// requests fail fast instead of querying the resolver again. 0 disables caching.
+	DNSNegativeTTL *time.Duration `yaml:"dnsNegativeTTL"`
+	// ClientRequestTimeout bounds how long the eyeball may take to send the whole request body.
+	// Slower requests are answered with 408 Request Timeout. 0 means no limit.
+	ClientRequestTimeout *time.Duration `yaml:"clientRequestTimeout"`
+	// StartupGrace answers requests with 503 and Retry-After, instead of 502, while the origin
+	// can't be reached during this long after startup, until it has responded once.
+	StartupGrace *time.Duration `yaml:"startupGrace"`
+	// CanonicalizeHeaders sends request headers to the origin in Go's canonical casing. Turn it off
+	// for origins that expect the exact casing the eyeball sent.
+	CanonicalizeHeaders *bool `yaml:"canonicalizeHeaders"`
+	// ClientWriteTimeout aborts a response if writing to the eyeball stays blocked for this long,
+	// e.g. because the client stopped reading, to release the origin connection.
+	ClientWriteTimeout *time.Duration `yaml:"clientWriteTimeout"`
+	// OriginScheme, http or https, replaces the scheme of an HTTP service's URL to connect to the
+	// same host and port, e.g. to switch a templated service to TLS.
+	OriginScheme *string `yaml:"originScheme"`
+	// AssumeHTTP10 treats the origin like an HTTP/1.0 server: every request asks the origin to
+	// close the connection and connections aren't reused, so a response without a Content-Length
+	// ends when the origin closes the connection.
+	AssumeHTTP10 *bool `yaml:"assumeHTTP10"`
+	// Deadline bounds connecting to the origin, waiting for its response and reading the response
+	// body, together. Requests that exceed it are answered with 504 Gateway Timeout, if the
+	// response hasn't started yet.
+	Deadline *time.Duration `yaml:"deadline"`
+	// IPPreference restricts or orders the IP families the origin's addresses are dialed with:
+	// ipv4-only, ipv6-only, ipv4-first or ipv6-first. The addresses of the other family are only
+	// tried if dialing the preferred ones fails. By default, Go's dialer picks the order.
+	IPPreference *string `yaml:"ipPreference"`
+	// SynthesizeHead sends HEAD requests to the origin as GET requests, and drops the response
+	// body, for origins that don't implement HEAD.
+	SynthesizeHead *bool `yaml:"synthesizeHead"`
+	// DialQueueTimeout bounds how long a request waits for one of the maxConcurrentDials slots
+	// to connect to the origin. Requests that wait longer are answered with 503 Service
+	// Unavailable. By default, they wait as long as the request lasts.
+	DialQueueTimeout *time.Duration `yaml:"dialQueueTimeout"`
+	// RedirectHost redirects requests for one host to the same path and query on another, e.g.
+	// from example.com to its canonical www.example.com, instead of proxying them.
+	RedirectHost *HostRedirect `yaml:"redirectHost"`
+	// ForwardAuthorization passes the eyeball's Authorization header to the origin. Turn it off
+	// if the origin shouldn't see the eyeball's credentials, e.g. because Access authenticated
+	// the request already.
+	ForwardAuthorization *bool `yaml:"forwardAuthorization"`
+	// AdaptiveConcurrency caps the requests in flight to the origin, lowering the cap when the
+	// origin's response times rise above their usual level, and answers 503 beyond it.
+	AdaptiveConcurrency *bool `yaml:"adaptiveConcurrency"`
+	// AllowExtendedConnect forwards extended CONNECT requests, which carry a :protocol pseudo-header,
+	// e.g. for WebTransport. Turn it off to reject them with 400 instead.
+	AllowExtendedConnect *bool `yaml:"allowExtendedConnect"`
+	// IdempotencyCoalesceOnly shares the response to a request with an idempotencyHeader only with
+	// the requests repeating its key while it's in flight, instead of replaying it afterwards.
+	// idempotencyWindow isn't needed then, and is ignored.
+	IdempotencyCoalesceOnly *bool `yaml:"idempotencyCoalesceOnly"`
+	// TrailingSlash normalizes the trailing slash of request paths before they're proxied: add
+	// appends one, strip removes it, and preserve, the default, leaves paths as they are.
+	TrailingSlash *string `yaml:"trailingSlash"`
+	// ServerTiming adds a Server-Timing header to responses, with how long cloudflared took to
+	// connect to the origin (dial), to get the first response byte (ttfb) and in total (total).
+	ServerTiming *bool `yaml:"serverTiming"`
+	// MaxResponseHeaderBytes bounds the size of the origin's response headers. Larger ones are
+	// answered with 502 instead of being passed to the eyeball. 0 keeps the default of 10 MB.
+	MaxResponseHeaderBytes *int64 `yaml:"maxResponseHeaderBytes"`
+	// RewriteLocation rewrites the Location header of redirects from the origin, e.g. from its
+	// internal URL to the public one.
+	RewriteLocation *LocationRewrite `yaml:"rewriteLocation"`
+	// JSONSchema is a JSON Schema file that request bodies must match, or they're rejected with 400
+	// before reaching the origin. Bodies are buffered up to 1 MiB to be validated.
+	JSONSchema *string `yaml:"jsonSchema"`
+	// AllowMethods lists the request methods that the rule proxies, like [GET, POST]. Other
+	// methods are answered 405 with an Allow header, instead of reaching the origin.
+	AllowMethods []string `yaml:"allowMethods"`
+	// ClientCertEnv names the environment variable with the PEM client certificate that
+	// cloudflared presents to the origin for mTLS, along with the key in clientKeyEnv.
+	ClientCertEnv *string `yaml:"clientCertEnv"`
+	// ClientKeyEnv names the environment variable with the PEM private key of clientCertEnv.
+	ClientKeyEnv *string `yaml:"clientKeyEnv"`
+	// SlowRequestThreshold logs a warning, with how long dialing the origin, its first response
+	// byte and the whole request took, for every request that takes longer than this.
+	SlowRequestThreshold *time.Duration `yaml:"slowRequestThreshold"`
+	// TLSRenegotiation lets legacy origins renegotiate TLS: never (the default), once per
+	// connection, or freely.
+	TLSRenegotiation *string `yaml:"tlsRenegotiation"`
+	// AccessLogFile appends a JSON line for every request to a file of the request's hostname,
+	// e.g. /var/log/cloudflared/{hostname}.log.
+	AccessLogFile *string `yaml:"accessLogFile"`
+	// AllowUnrequestedUpgrade proxies 101 Switching Protocols responses to requests that didn't
+	// ask to upgrade. By default they're answered with 502, instead of tunneling whatever follows.
+	AllowUnrequestedUpgrade *bool `yaml:"allowUnrequestedUpgrade"`
+	// DNSCacheTTL caches the addresses that the origin's hostname resolves to for this long,
+	// whatever the TTL of its DNS records.
+	DNSCacheTTL *time.Duration `yaml:"dnsCacheTTL"`
+	// Group services answering with this header set to true, e.g. X-CF-Drain: true, are tried
+	// again only after 30 seconds, in case they stopped draining.
+	DrainHeader *string `yaml:"drainHeader"`
+	// ReportTo adds a Report-To header for this endpoint group to the responses that don't
+	// have one, so browsers know where to send security reports.
+	ReportTo *ReportTo `yaml:"reportTo"`
+	// AdaptiveTimeout answers 504 when the origin takes longer to respond than a percentile of
+	// its recent response times, times a multiplier, e.g. twice the p99.
+	AdaptiveTimeout *AdaptiveTimeout `yaml:"adaptiveTimeout"`
+	// SortQueryParams sorts the query parameters of requests by name before they're proxied, for
+	// origins or caches that are sensitive to their order.
+	SortQueryParams *bool `yaml:"sortQueryParams"`
+	// DropQueryParams removes these query parameters from requests before they're proxied, e.g.
+	// [utm_source, utm_medium].
+	DropQueryParams []string `yaml:"dropQueryParams"`
+	// ErrorPages replaces the body of responses with these statuses, e.g. 404 or 503, with the
+	// HTML template in the file, which may use {{.Host}}, {{.RequestID}}, {{.Status}} and
+	// {{.StatusText}}.
+	ErrorPages map[int]string `yaml:"errorPages"`
+	// PoolKey decides which rules share connections to the same origin: byOrigin shares them
+	// between the byOrigin rules of the origin that have the same TLS settings and timeouts, and
+	// they're opened with the SNI of the first one. bySNI, the default, keeps each rule's
+	// connections to itself.
+	PoolKey *string `yaml:"poolKey"`
+	// SLOGoodStatuses counts the responses with these statuses, or ranges of statuses like
+	// 200-399, as good in the SLO metrics of the rule.
+	SLOGoodStatuses []string `yaml:"sloGoodStatuses"`
+	// HealthResponsePath answers GET and HEAD requests to exactly this path, e.g. /cf-health, with
+	// 200 from cloudflared itself, for load balancer health checks that shouldn't reach the origin.
+	HealthResponsePath *string `yaml:"healthResponsePath"`
+	// StrictChunked reads the whole chunked response of the origin before sending any of it, so
+	// that the eyeball gets 502 instead of a truncated body if the chunked encoding is malformed.
+	// Server-sent events are still streamed.
+	StrictChunked *bool `yaml:"strictChunked"`
+	// WarmupPath is the path, e.g. /warmup, that warmupOnStart requests from the origin.
+	WarmupPath *string `yaml:"warmupPath"`
+	// WarmupOnStart sends a GET request for warmupPath to the origin when cloudflared starts
+	// serving the rule, e.g. so that the origin fills its caches before eyeballs arrive.
+	WarmupOnStart *bool `yaml:"warmupOnStart"`
+	// DeadLetterLog appends a JSON line with the method, path, status and error of every request
+	// that failed, with an error or a 5xx status, to this file. Bodies and queries aren't logged.
+	DeadLetterLog *string `yaml:"deadLetterLog"`
+	// SetCookieSameSite sets the SameSite attribute of every cookie set by the origin to Strict,
+	// Lax or None, replacing the origin's. Cookies with None also get Secure. It can't be set
+	// along with cookieSameSite, which only adds the attribute to cookies that don't have one.
+	SetCookieSameSite *string `yaml:"setCookieSameSite"`
+	// ForwardedHeader adds a Forwarded header (RFC 7239) with the eyeball's proto, host and for to
+	// requests, next to the X-Forwarded-* headers that Cloudflare sets.
+	ForwardedHeader *bool `yaml:"forwardedHeader"`
+	// RequestIDHeader, e.g. X-Request-Id, is the header that identifies each request. A request
+	// without it gets a new random ID, and the response echoes the ID to the eyeball.
+	RequestIDHeader *string `yaml:"requestIdHeader"`
+}
+
+// UnmarshalYAML rejects forwardEarlyHints: neither the http2 nor the h2mux connection to the
+// edge can send 103 Early Hints before the response.
+func (c *OriginRequestConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	keys, err := yamlKeys(unmarshal)
+	if err != nil {
+		return err
+	}
+	if _, ok := keys["forwardEarlyHints"]; ok {
+		return errors.New("forwardEarlyHints isn't supported, because the connection to the edge can't send 103 Early Hints")
+	}
+	type plain OriginRequestConfig
+	return unmarshal((*plain)(c))
+}
+
+// yamlKeys returns the keys of the YAML mapping that unmarshal decodes.
+func yamlKeys(unmarshal func(interface{}) error) (map[string]interface{}, error) {
+	var keys map[string]interface{}
+	if err := unmarshal(&keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ResponseRewriteRule replaces every match of a regex in a response body. Replace may refer
+// to capture groups like $1.
+type ResponseRewriteRule struct {
+	Match   string `yaml:"match"`
+	Replace string `yaml:"replace"`
+}
+
+// SignRequestsConfig makes cloudflared sign the requests it proxies to the origin with an HMAC
+// of Key, so the origin can verify they came through the tunnel.
+type SignRequestsConfig struct {
+	Key string `yaml:"key"`
+	// Header the signature is sent in, X-CF-Signature by default.
+	Header string `yaml:"header"`
+}
+
+// WellKnownConfig names local files that cloudflared serves itself, instead of the origin.
+type WellKnownConfig struct {
+	// Robots is served for /robots.txt.
+	Robots string `yaml:"robots"`
+	// SecurityTxt is served for /.well-known/security.txt.
+	SecurityTxt string `yaml:"securityTxt"`
+}
+
+// TCPTLSConfig makes cloudflared speak TLS to a TCP origin, e.g. a database that only accepts
+// TLS connections.
+type TCPTLSConfig struct {
+	// ServerName is sent as SNI and checked against the origin's certificate.
+	ServerName string `yaml:"serverName"`
+}
+
+// CookieDomainRewrite replaces the Domain attribute From of Set-Cookie headers with To.
+type CookieDomainRewrite struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// LocationRewrite replaces the URL prefix From of Location headers in redirects with To.
+type LocationRewrite struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// AdaptiveTimeout derives the response timeout from the origin's recent response times: it's
+// the Percentile of them, times Multiplier.
+type AdaptiveTimeout struct {
+	Percentile float64 `yaml:"percentile"`
+	Multiplier float64 `yaml:"multiplier"`
+}
+
+// ReportTo is a Reporting API endpoint group that browsers send security reports, like CSP
+// violations, to.
+type ReportTo struct {
+	// Endpoint is the https URL that reports are sent to.
+	Endpoint string `yaml:"endpoint"`
+	// MaxAge is how many seconds browsers remember the endpoint.
+	MaxAge int `yaml:"maxAge"`
+}
+
+// HostRedirect redirects requests for the host From to the same URL on the host To, with the
+// redirect status Status, 308 by default.
+type HostRedirect struct {
+	From   string `yaml:"from"`
+	To     string `yaml:"to"`
+	Status int    `yaml:"status"`
 }
 
 type IngressIPRule struct {
@@ -231,11 +655,35 @@ type IngressIPRule struct {
 	Allow  bool    `yaml:"allow"`
 }
 
-type Configuration struct {
-	TunnelID      string `yaml:"tunnel"`
-	Ingress       []UnvalidatedIngressRule
-	WarpRouting   WarpRoutingConfig   `yaml:"warp-routing"`
+// WeightedService is a member of an ingress group. Each one receives a share of the group's
+// requests in proportion to its weight.
+type WeightedService struct {
+	Service string `yaml:"service"`
+	Weight  int    `yaml:"weight"`
+}
+
+// NamedService is a service that ingress rules can share with serviceRef, together with its
+// originRequest settings.
+type NamedService struct {
+	Service       string              `yaml:"service"`
 	OriginRequest OriginRequestConfig `yaml:"originRequest"`
+}
+
+type Configuration struct {
+	TunnelID string `yaml:"tunnel"`
+	Ingress  []UnvalidatedIngressRule
+	// DefaultService is the service of the catch-all rule that is added to Ingress, if it
+	// doesn't end with one.
+	DefaultService string `yaml:"defaultService"`
+	// RejectAbsoluteForm answers requests with an absolute-form target, e.g.
+	// GET http://example.com/path, with 400 instead of proxying them.
+	RejectAbsoluteForm bool `yaml:"rejectAbsoluteForm"`
+	// Groups are pools of services, by name, that ingress rules can share with group.
+	Groups map[string][]WeightedService `yaml:"groups"`
+	// Services are service definitions, by name, that ingress rules can share with serviceRef.
+	Services      map[string]NamedService `yaml:"services"`
+	WarpRouting   WarpRoutingConfig       `yaml:"warp-routing"`
+	OriginRequest OriginRequestConfig     `yaml:"originRequest"`
 	sourceFile    string
 }
 
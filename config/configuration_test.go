@@ -81,3 +81,59 @@ counters:
 	assert.Equal(t, 456, counters[1])
 
 }
+
+func TestUnsupportedKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawYAML string
+		wantErr string
+	}{
+		{
+			name: "forwardEarlyHints in the defaults",
+			rawYAML: `
+originRequest:
+  forwardEarlyHints: true
+`,
+			wantErr: "forwardEarlyHints isn't supported, because the connection to the edge can't send 103 Early Hints",
+		},
+		{
+			name: "forwardEarlyHints in a rule",
+			rawYAML: `
+ingress:
+  - service: http://localhost:8080
+    originRequest:
+      forwardEarlyHints: false
+`,
+			wantErr: "forwardEarlyHints isn't supported, because the connection to the edge can't send 103 Early Hints",
+		},
+		{
+			name: "clientALPN",
+			rawYAML: `
+ingress:
+  - hostname: grpc.example.com
+    service: https://localhost:50051
+    clientALPN: [h2]
+  - service: http_status:404
+`,
+			wantErr: "clientALPN isn't supported, because cloudflared can't tell which protocol the eyeball negotiated with Cloudflare",
+		},
+		{
+			name: "requireSNI",
+			rawYAML: `
+ingress:
+  - hostname: strict.example.com
+    service: http_status:200
+    requireSNI: true
+  - service: http_status:404
+`,
+			wantErr: "requireSNI isn't supported, because cloudflared can't tell which server name the eyeball sent to Cloudflare",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config configFileSettings
+			err := yaml.Unmarshal([]byte(tt.rawYAML), &config)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
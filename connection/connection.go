@@ -1,6 +1,8 @@
 package connection
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,6 +17,11 @@ import (
 
 const LogFieldConnIndex = "connIndex"
 
+// ErrResponseAborted is returned by OriginProxy.Proxy when it gave up on writing a response that
+// already started, e.g. because the eyeball stopped reading it. A write may still be blocked on
+// the stream, so the stream is reset instead of being answered with an error.
+var ErrResponseAborted = errors.New("the response to the eyeball was aborted")
+
 type Config struct {
 	OriginProxy     OriginProxy
 	GracePeriod     time.Duration
@@ -102,6 +109,21 @@ type ConnectedFuse interface {
 	IsConnected() bool
 }
 
+type connIndexKey struct{}
+
+// WithConnIndex returns a copy of ctx that remembers which tunnel connection a request came
+// through.
+func WithConnIndex(ctx context.Context, connIndex uint8) context.Context {
+	return context.WithValue(ctx, connIndexKey{}, connIndex)
+}
+
+// ConnIndex returns the index of the tunnel connection that a request with this context came
+// through, if it's known.
+func ConnIndex(ctx context.Context) (uint8, bool) {
+	connIndex, ok := ctx.Value(connIndexKey{}).(uint8)
+	return connIndex, ok
+}
+
 func IsServerSentEvent(headers http.Header) bool {
 	if contentType := headers.Get("content-type"); contentType != "" {
 		return strings.HasPrefix(strings.ToLower(contentType), "text/event-stream")
@@ -222,6 +222,10 @@ func (h *h2muxConnection) ServeStream(stream *h2mux.MuxedStream) error {
 	}
 
 	err := h.config.OriginProxy.Proxy(respWriter, req, sourceConnectionType)
+	if errors.Is(err, ErrResponseAborted) {
+		// The stream is closed once this returns, which unblocks the write.
+		return err
+	}
 	if err != nil {
 		respWriter.WriteErrorResponse()
 		return err
@@ -234,11 +238,15 @@ func (h *h2muxConnection) newRequest(stream *h2mux.MuxedStream) (*http.Request,
 	if err != nil {
 		return nil, errors.Wrap(err, "Unexpected error from http.NewRequest")
 	}
-	err = H2RequestHeadersToH1Request(stream.Headers, req)
+	headerNames, err := h2RequestHeadersToH1Request(stream.Headers, req)
 	if err != nil {
 		return nil, errors.Wrap(err, "invalid request received")
 	}
-	return req, nil
+	ctx := WithConnIndex(req.Context(), h.connIndex)
+	if len(headerNames) > 0 {
+		ctx = WithHeaderNames(ctx, headerNames)
+	}
+	return req.WithContext(ctx), nil
 }
 
 type h2muxRespWriter struct {
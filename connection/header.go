@@ -1,6 +1,7 @@
 package connection
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net/http"
@@ -52,6 +53,14 @@ const ()
 // This operation includes conversion of the pseudo-headers into their closest
 // HTTP/1 equivalents. See https://tools.ietf.org/html/rfc7540#section-8.1.2.3
 func H2RequestHeadersToH1Request(h2 []h2mux.Header, h1 *http.Request) error {
+	_, err := h2RequestHeadersToH1Request(h2, h1)
+	return err
+}
+
+// h2RequestHeadersToH1Request is H2RequestHeadersToH1Request, but also returns how the eyeball
+// spelled the names of the user headers that aren't in canonical form.
+func h2RequestHeadersToH1Request(h2 []h2mux.Header, h1 *http.Request) (map[string]string, error) {
+	var names map[string]string
 	for _, header := range h2 {
 		name := strings.ToLower(header.Name)
 		if !IsControlHeader(name) {
@@ -86,6 +95,15 @@ func H2RequestHeadersToH1Request(h2 []h2mux.Header, h1 *http.Request) error {
 				h1.URL.Path = "*"
 				continue
 			}
+			if !strings.HasPrefix(header.Value, "/") {
+				// An absolute-form target, like clients send to proxies: see RFC7230 section 5.3.2.
+				// It's normalized by the ingress rules, which need the original target.
+				if requestURL, err := url.ParseRequestURI(header.Value); err == nil && requestURL.IsAbs() {
+					h1.URL = requestURL
+					h1.RequestURI = header.Value
+					continue
+				}
+			}
 			// Due to the behavior of validation.ValidateUrl, h1.URL may
 			// already have a partial value, with or without a trailing slash.
 			base := h1.URL.String()
@@ -93,13 +111,13 @@ func H2RequestHeadersToH1Request(h2 []h2mux.Header, h1 *http.Request) error {
 			// But we know :path begins with '/', because we handled '*' above - see RFC7540
 			requestURL, err := url.Parse(base + header.Value)
 			if err != nil {
-				return errors.Wrap(err, fmt.Sprintf("invalid path '%v'", header.Value))
+				return nil, errors.Wrap(err, fmt.Sprintf("invalid path '%v'", header.Value))
 			}
 			h1.URL = requestURL
 		case "content-length":
 			contentLength, err := strconv.ParseInt(header.Value, 10, 64)
 			if err != nil {
-				return fmt.Errorf("unparseable content length")
+				return nil, fmt.Errorf("unparseable content length")
 			}
 			h1.ContentLength = contentLength
 		case RequestUserHeaders:
@@ -107,10 +125,16 @@ func H2RequestHeadersToH1Request(h2 []h2mux.Header, h1 *http.Request) error {
 			// Find and parse user headers serialized into a single one
 			userHeaders, err := DeserializeHeaders(header.Value)
 			if err != nil {
-				return errors.Wrap(err, "Unable to parse user headers")
+				return nil, errors.Wrap(err, "Unable to parse user headers")
 			}
 			for _, userHeader := range userHeaders {
 				h1.Header.Add(userHeader.Name, userHeader.Value)
+				if canonical := http.CanonicalHeaderKey(userHeader.Name); canonical != userHeader.Name {
+					if names == nil {
+						names = make(map[string]string)
+					}
+					names[canonical] = userHeader.Name
+				}
 			}
 		default:
 			// All other control headers shall just be proxied transparently
@@ -118,7 +142,21 @@ func H2RequestHeadersToH1Request(h2 []h2mux.Header, h1 *http.Request) error {
 		}
 	}
 
-	return nil
+	return names, nil
+}
+
+type headerNamesKey struct{}
+
+// WithHeaderNames returns a copy of ctx that remembers how the eyeball spelled request header
+// names, keyed by their canonical form.
+func WithHeaderNames(ctx context.Context, names map[string]string) context.Context {
+	return context.WithValue(ctx, headerNamesKey{}, names)
+}
+
+// HeaderNames returns the header names saved by WithHeaderNames, if any.
+func HeaderNames(ctx context.Context) map[string]string {
+	names, _ := ctx.Value(headerNamesKey{}).(map[string]string)
+	return names
 }
 
 func IsControlHeader(headerName string) bool {
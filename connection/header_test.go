@@ -69,6 +69,38 @@ func TestH2RequestHeadersToH1Request_NoHeaders(t *testing.T) {
 	assert.NoError(t, headersConversionErr)
 }
 
+func TestH2RequestHeadersToH1Request_HeaderNames(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	assert.NoError(t, err)
+
+	mockHeaders := http.Header{
+		"x-custom-HEADER": {"Mock value 1"},
+		"Content-Type":    {"text/plain"},
+	}
+	names, err := h2RequestHeadersToH1Request(createSerializedHeaders(RequestUserHeaders, mockHeaders), request)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Mock value 1", request.Header.Get("X-Custom-Header"))
+	assert.Equal(t, map[string]string{"X-Custom-Header": "x-custom-HEADER"}, names)
+	assert.Equal(t, names, HeaderNames(WithHeaderNames(request.Context(), names)))
+}
+
+func TestH2RequestHeadersToH1Request_AbsoluteFormPath(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, "http://localhost:8080", nil)
+	assert.NoError(t, err)
+
+	headersConversionErr := H2RequestHeadersToH1Request(
+		[]h2mux.Header{
+			{Name: ":path", Value: "http://example.com/a?b=c"},
+		},
+		request,
+	)
+
+	assert.NoError(t, headersConversionErr)
+	assert.Equal(t, "http://example.com/a?b=c", request.URL.String())
+	assert.Equal(t, "http://example.com/a?b=c", request.RequestURI)
+}
+
 func TestH2RequestHeadersToH1Request_InvalidHostPath(t *testing.T) {
 	request, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
 	assert.NoError(t, err)
@@ -2,6 +2,7 @@ package connection
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -97,6 +98,7 @@ func (c *http2Connection) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	c.activeRequestsWG.Add(1)
 	defer c.activeRequestsWG.Done()
 
+	r = r.WithContext(WithConnIndex(r.Context(), c.connIndex))
 	connType := determineHTTP2Type(r)
 	respWriter, err := newHTTP2RespWriter(r, w, connType)
 	if err != nil {
@@ -115,6 +117,10 @@ func (c *http2Connection) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	default:
 		proxyErr = c.config.OriginProxy.Proxy(respWriter, r, connType)
 	}
+	if errors.Is(proxyErr, ErrResponseAborted) {
+		// Resetting the stream unblocks the write, which still uses w.
+		panic(http.ErrAbortHandler)
+	}
 	if proxyErr != nil {
 		respWriter.WriteErrorResponse()
 	}
@@ -0,0 +1,81 @@
+package ingress
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// newAccepts checks the media types of accepts, and returns them in lower case.
+func newAccepts(types []string) ([]string, error) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+	accepts := make([]string, 0, len(types))
+	seen := make(map[string]bool, len(types))
+	for _, t := range types {
+		mediaType, params, err := mime.ParseMediaType(t)
+		if err != nil || len(params) > 0 || strings.Contains(mediaType, "*") || !strings.Contains(mediaType, "/") {
+			return nil, fmt.Errorf("%q isn't a media type like application/json", t)
+		}
+		if seen[mediaType] {
+			return nil, fmt.Errorf("%s is listed more than once", mediaType)
+		}
+		seen[mediaType] = true
+		accepts = append(accepts, mediaType)
+	}
+	return accepts, nil
+}
+
+// matchesAccept checks if one of the media types that the request's Accept header prefers,
+// those with the highest q-value, is one of types. Ranges like application/* or */* prefer
+// every type they cover. Requests without an Accept header accept any type.
+func matchesAccept(types []string, req *http.Request) bool {
+	header := strings.Join(req.Header.Values("Accept"), ",")
+	if strings.TrimSpace(header) == "" {
+		return true
+	}
+	var (
+		preferred []string
+		bestQ     float64
+	)
+	for _, accepted := range strings.Split(header, ",") {
+		mediaRange, params, err := mime.ParseMediaType(accepted)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qValue, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qValue, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 || q < bestQ {
+			continue
+		}
+		if q > bestQ {
+			bestQ, preferred = q, nil
+		}
+		preferred = append(preferred, mediaRange)
+	}
+	for _, mediaRange := range preferred {
+		for _, t := range types {
+			if mediaRangeCovers(mediaRange, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func mediaRangeCovers(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
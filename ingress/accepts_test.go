@@ -0,0 +1,72 @@
+package ingress
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccepts(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: api.example.com
+   service: http://localhost:8000
+   accepts: [application/json, Application/Problem+JSON]
+ - service: http://localhost:8001
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"application/json", "application/problem+json"}, ing.Rules[0].Accepts)
+
+	tests := []struct {
+		accept     string
+		expectRule int
+	}{
+		{accept: "application/json", expectRule: 0},
+		{accept: "text/html", expectRule: 1},
+		{accept: "application/json; charset=utf-8", expectRule: 0},
+		{accept: "application/problem+json", expectRule: 0},
+		{accept: "text/html;q=0.5, application/json", expectRule: 0},
+		{accept: "application/json;q=0.5, text/html", expectRule: 1},
+		{accept: "text/html, application/xhtml+xml, application/xml;q=0.9, */*;q=0.8", expectRule: 1},
+		{accept: "text/html, application/json", expectRule: 0},
+		{accept: "application/json;q=0, text/html;q=0.1", expectRule: 1},
+		{accept: "application/*", expectRule: 0},
+		{accept: "*/*", expectRule: 0},
+		{accept: "", expectRule: 0},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+		require.NoError(t, err)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		_, i := ing.FindMatchingRuleForRequest(req)
+		assert.Equal(t, test.expectRule, i, test.accept)
+	}
+}
+
+func TestParseAccepts(t *testing.T) {
+	for _, types := range []string{"[json]", "[application/*]", "[text/html; charset=utf-8]", "[application/json, application/JSON]"} {
+		rawYAML := `
+ingress:
+ - hostname: api.example.com
+   service: http://localhost:8000
+   accepts: ` + types + `
+ - service: http_status:404
+`
+		_, err := ParseIngress(MustReadIngress(rawYAML))
+		assert.Error(t, err, types)
+	}
+
+	// A rule restricted to some media types doesn't catch all requests.
+	_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   accepts: [application/json]
+ - service: http_status:404
+`))
+	assert.NoError(t, err)
+}
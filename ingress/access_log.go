@@ -0,0 +1,135 @@
+package ingress
+
+import (
+	"container/list"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	accessLogHostnamePlaceholder = "{hostname}"
+	// maxOpenAccessLogs bounds the access log files kept open, since a wildcard rule can see
+	// any number of hostnames. The least recently written file is closed first.
+	maxOpenAccessLogs = 64
+
+	accessLogDirPermMode  = 0744
+	accessLogFilePermMode = 0600
+)
+
+// accessLogHostname matches the hostnames that may be used in the name of an access log file,
+// so the Host header can't point it at another directory.
+var accessLogHostname = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// AccessLogs appends access log lines to the file that the accessLogFile template names for
+// each hostname. Its methods are safe to call on a nil AccessLogs, which logs nothing.
+type AccessLogs struct {
+	template string
+
+	lock sync.Mutex
+	// files indexes the elements of recent, which are *accessLogFile ordered from the most
+	// recently written.
+	files  map[string]*list.Element
+	recent *list.List
+}
+
+type accessLogFile struct {
+	filename string
+	file     *os.File
+}
+
+func newAccessLogs(template string) *AccessLogs {
+	if template == "" {
+		return nil
+	}
+	return &AccessLogs{template: template, files: make(map[string]*list.Element), recent: list.New()}
+}
+
+// validateAccessLogFile checks that template has no other placeholder than {hostname}.
+func validateAccessLogFile(template string) error {
+	if strings.ContainsAny(strings.ReplaceAll(template, accessLogHostnamePlaceholder, ""), "{}") {
+		return fmt.Errorf("accessLogFile %q may only contain the placeholder %s", template, accessLogHostnamePlaceholder)
+	}
+	if strings.HasSuffix(template, "/") {
+		return fmt.Errorf("accessLogFile %q must name a file, not a directory", template)
+	}
+	return nil
+}
+
+// Filename returns the access log file for requests to host, which may include a port.
+// Hostnames that can't be part of a file name are replaced with _.
+func (a *AccessLogs) Filename(host string) string {
+	hostname := strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = h
+	}
+	if !accessLogHostname.MatchString(hostname) {
+		hostname = "_"
+	}
+	return strings.ReplaceAll(a.template, accessLogHostnamePlaceholder, hostname)
+}
+
+// Write appends line to the access log file for requests to host.
+func (a *AccessLogs) Write(host string, line []byte) error {
+	if a == nil {
+		return nil
+	}
+	filename := a.Filename(host)
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	element, ok := a.files[filename]
+	if ok {
+		a.recent.MoveToFront(element)
+	} else {
+		file, err := openLogFile(filename, "access log")
+		if err != nil {
+			return err
+		}
+		if a.recent.Len() >= maxOpenAccessLogs {
+			oldest := a.recent.Remove(a.recent.Back()).(*accessLogFile)
+			delete(a.files, oldest.filename)
+			_ = oldest.file.Close()
+		}
+		element = a.recent.PushFront(&accessLogFile{filename: filename, file: file})
+		a.files[filename] = element
+	}
+	_, err := element.Value.(*accessLogFile).file.Write(line)
+	return err
+}
+
+// openLogFile opens filename to append to it, creating it and its directory if needed. kind
+// names the log in errors.
+func openLogFile(filename, kind string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), accessLogDirPermMode); err != nil {
+		return nil, errors.Wrapf(err, "unable to create the %s's directory", kind)
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, accessLogFilePermMode)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open the %s", kind)
+	}
+	return file, nil
+}
+
+// Close closes the open access log files. Later writes open them again.
+func (a *AccessLogs) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	var firstErr error
+	for element := a.recent.Front(); element != nil; element = element.Next() {
+		if err := element.Value.(*accessLogFile).file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	a.files = make(map[string]*list.Element)
+	a.recent.Init()
+	return firstErr
+}
@@ -0,0 +1,53 @@
+package ingress
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogFilename(t *testing.T) {
+	logs := newAccessLogs("/var/log/cloudflared/{hostname}.log")
+	for host, expected := range map[string]string{
+		"www.example.com":      "/var/log/cloudflared/www.example.com.log",
+		"WWW.Example.com:8443": "/var/log/cloudflared/www.example.com.log",
+		"../../etc/passwd":     "/var/log/cloudflared/_.log",
+		"a/b.example.com":      "/var/log/cloudflared/_.log",
+		"":                     "/var/log/cloudflared/_.log",
+	} {
+		assert.Equal(t, expected, logs.Filename(host), host)
+	}
+}
+
+func TestAccessLogsBoundOpenFiles(t *testing.T) {
+	dir := t.TempDir()
+	logs := newAccessLogs(filepath.Join(dir, "{hostname}.log"))
+	defer logs.Close()
+	for i := 0; i <= maxOpenAccessLogs; i++ {
+		require.NoError(t, logs.Write(fmt.Sprintf("host%d.example.com", i), []byte("line\n")))
+	}
+	assert.Equal(t, maxOpenAccessLogs, logs.recent.Len())
+	assert.NotContains(t, logs.files, filepath.Join(dir, "host0.example.com.log"))
+
+	// A closed file is opened again, and appended to.
+	require.NoError(t, logs.Write("host0.example.com", []byte("again\n")))
+	content, err := ioutil.ReadFile(filepath.Join(dir, "host0.example.com.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "line\nagain\n", string(content))
+}
+
+func TestParseAccessLogFile(t *testing.T) {
+	for _, invalid := range []string{"/var/log/{host}.log", "/var/log/{hostname}/"} {
+		_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     accessLogFile: "` + invalid + `"
+`))
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,53 @@
+package ingress
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const acmeChallengePrefix = "/.well-known/acme-challenge/"
+
+// acmeToken matches ACME challenge tokens, which are base64url encoded. It also keeps paths
+// like ../ from escaping the challenge directory.
+var acmeToken = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ACMEChallenges serves ACME HTTP-01 challenge responses from a directory with a file per
+// token, e.g. the webroot of an ACME client. Its methods are safe to call on a nil
+// ACMEChallenges, which serves nothing.
+type ACMEChallenges struct {
+	dir string
+}
+
+func newACMEChallenges(dir string) (*ACMEChallenges, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading the acmeChallenge directory")
+	}
+	if !info.IsDir() {
+		return nil, errors.Errorf("acmeChallenge %s is not a directory", dir)
+	}
+	return &ACMEChallenges{dir: dir}, nil
+}
+
+// File returns the challenge response for the request path, if it's an ACME challenge path.
+// Tokens without a file in the directory return an error that os.IsNotExist accepts.
+// Challenge files are read for every request, because ACME clients add them while running.
+func (a *ACMEChallenges) File(path string) ([]byte, bool, error) {
+	if a == nil || !strings.HasPrefix(path, acmeChallengePrefix) {
+		return nil, false, nil
+	}
+	token := strings.TrimPrefix(path, acmeChallengePrefix)
+	if !acmeToken.MatchString(token) {
+		return nil, true, os.ErrNotExist
+	}
+	content, err := ioutil.ReadFile(filepath.Join(a.dir, token))
+	return content, true, err
+}
@@ -0,0 +1,118 @@
+package ingress
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+// StreamsStatus reports how many requests and streams, e.g. websockets, are being proxied.
+type StreamsStatus struct {
+	Connections []ConnectionStreams `json:"connections"`
+	Rules       []RuleStreams       `json:"rules"`
+}
+
+// ConnectionStreams counts the open streams of a tunnel connection.
+type ConnectionStreams struct {
+	Connection    uint8 `json:"connection"`
+	ActiveStreams int   `json:"activeStreams"`
+}
+
+// RuleStreams counts the open streams proxied by an ingress rule.
+type RuleStreams struct {
+	Rule int `json:"rule"`
+	// Config is the configuration the rule is from, ConfigCurrent or ConfigRollout.
+	Config        string `json:"config"`
+	Hostname      string `json:"hostname"`
+	Service       string `json:"service"`
+	ActiveStreams int    `json:"activeStreams"`
+}
+
+// activeStreams counts the open streams by rule and by tunnel connection. Its methods are safe
+// to call on a nil activeStreams, which counts nothing.
+type activeStreams struct {
+	lock   sync.Mutex
+	byRule []int
+	byConn map[uint8]int
+}
+
+func newActiveStreams(numRules int) *activeStreams {
+	return &activeStreams{byRule: make([]int, numRules), byConn: make(map[uint8]int)}
+}
+
+func (s *activeStreams) add(ruleIndex int, connIndex uint8, hasConn bool, delta int) {
+	if s == nil || ruleIndex < 0 || ruleIndex >= len(s.byRule) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.byRule[ruleIndex] += delta
+	if hasConn {
+		s.byConn[connIndex] += delta
+	}
+}
+
+// OpenStream counts req as an open stream of the rule with the given index, and of the tunnel
+// connection it came through, until the returned function is called.
+func (ing Ingress) OpenStream(req *http.Request, ruleIndex int) (closeStream func()) {
+	connIndex, hasConn := connection.ConnIndex(req.Context())
+	ing.streams.add(ruleIndex, connIndex, hasConn, 1)
+	return func() {
+		ing.streams.add(ruleIndex, connIndex, hasConn, -1)
+	}
+}
+
+// Streams returns the open streams of every tunnel connection that has been used, in order
+// of their index, and of every rule, in the order they are matched, followed by the rules being
+// rolled out, if any.
+func (ing Ingress) Streams() StreamsStatus {
+	byConn := make(map[uint8]int)
+	status := StreamsStatus{
+		Connections: []ConnectionStreams{},
+		Rules:       ing.ruleStreams(byConn),
+	}
+	if ing.rollout != nil {
+		status.Rules = append(status.Rules, ing.rollout.next.ruleStreams(byConn)...)
+	}
+	for connIndex, count := range byConn {
+		status.Connections = append(status.Connections, ConnectionStreams{Connection: connIndex, ActiveStreams: count})
+	}
+	sort.Slice(status.Connections, func(i, j int) bool {
+		return status.Connections[i].Connection < status.Connections[j].Connection
+	})
+	return status
+}
+
+// ruleStreams returns the open streams of every rule, and adds those of every tunnel
+// connection to byConn.
+func (ing Ingress) ruleStreams(byConn map[uint8]int) []RuleStreams {
+	rules := make([]RuleStreams, len(ing.Rules))
+	for i, rule := range ing.Rules {
+		rules[i] = RuleStreams{Rule: rule.Index, Config: ing.ConfigName(), Hostname: rule.Hostname, Service: rule.Service.String()}
+	}
+	if ing.streams == nil {
+		return rules
+	}
+	ing.streams.lock.Lock()
+	defer ing.streams.lock.Unlock()
+	for i := range rules {
+		if i < len(ing.streams.byRule) {
+			rules[i].ActiveStreams = ing.streams.byRule[i]
+		}
+	}
+	for connIndex, count := range ing.streams.byConn {
+		byConn[connIndex] += count
+	}
+	return rules
+}
+
+// StreamsHandler serves the open streams as JSON.
+func (ing Ingress) StreamsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ing.Streams())
+	})
+}
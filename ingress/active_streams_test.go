@@ -0,0 +1,66 @@
+package ingress
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+func TestActiveStreams(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: api.example.com
+   service: https://localhost:8000
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+
+	newRequest := func(connIndex uint8) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "http://api.example.com", nil)
+		require.NoError(t, err)
+		return req.WithContext(connection.WithConnIndex(req.Context(), connIndex))
+	}
+	closeFirst := ing.OpenStream(newRequest(0), 0)
+	closeSecond := ing.OpenStream(newRequest(0), 0)
+	closeThird := ing.OpenStream(newRequest(2), 1)
+	// Requests that didn't come through a tunnel connection only count for their rule.
+	closeFourth := ing.OpenStream(httptest.NewRequest(http.MethodGet, "http://www.example.com", nil), 1)
+
+	assert.Equal(t, StreamsStatus{
+		Connections: []ConnectionStreams{
+			{Connection: 0, ActiveStreams: 2},
+			{Connection: 2, ActiveStreams: 1},
+		},
+		Rules: []RuleStreams{
+			{Rule: 0, Config: ConfigCurrent, Hostname: "api.example.com", Service: "https://localhost:8000", ActiveStreams: 2},
+			{Rule: 1, Config: ConfigCurrent, Hostname: "", Service: "HTTP 404", ActiveStreams: 2},
+		},
+	}, ing.Streams())
+
+	closeFirst()
+	closeThird()
+	closeFourth()
+	status := ing.Streams()
+	assert.Equal(t, []ConnectionStreams{
+		{Connection: 0, ActiveStreams: 1},
+		{Connection: 2, ActiveStreams: 0},
+	}, status.Connections)
+	assert.Equal(t, 1, status.Rules[0].ActiveStreams)
+	assert.Equal(t, 0, status.Rules[1].ActiveStreams)
+
+	closeSecond()
+	recorder := httptest.NewRecorder()
+	ing.StreamsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/streams", nil))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var served StreamsStatus
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &served))
+	assert.Equal(t, 0, served.Rules[0].ActiveStreams)
+	assert.Equal(t, 0, served.Connections[0].ActiveStreams)
+}
@@ -0,0 +1,149 @@
+package ingress
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	initialConcurrencyLimit = 20
+	minConcurrencyLimit     = 1
+	maxConcurrencyLimit     = 1000
+	// The limit is adjusted once per window of response times, which lasts at least
+	// windowDuration and windowSamples responses.
+	windowDuration = 100 * time.Millisecond
+	windowSamples  = 10
+	// limitSmoothing is how much of each new limit is blended into the current one.
+	limitSmoothing = 0.2
+	// longRTTWeight is the weight of each window in the long-term average response time that
+	// windows are compared to, which spans about 100 windows.
+	longRTTWeight = 2.0 / 101
+	// minGradient bounds how much a single slow window can lower the limit.
+	minGradient = 0.5
+)
+
+// ConcurrencyLimiter caps how many requests are in flight to an origin, adjusting the cap with
+// the gradient of the origin's response times: while the origin answers as fast as it usually
+// does, the cap grows, and when it slows down, the cap shrinks so that requests beyond it are
+// shed instead of queueing at the origin. Its methods are safe to call on a nil
+// ConcurrencyLimiter, which doesn't limit anything.
+type ConcurrencyLimiter struct {
+	lock     sync.Mutex
+	limit    float64
+	inFlight int
+	// longRTT is the long-term average response time, in seconds.
+	longRTT float64
+	window  rttWindow
+	clock   func() time.Time
+}
+
+// rttWindow sums up the responses since the limit was last adjusted.
+type rttWindow struct {
+	start       time.Time
+	samples     int
+	rttSum      time.Duration
+	maxInFlight int
+}
+
+func newConcurrencyLimiter(adaptive bool) *ConcurrencyLimiter {
+	if !adaptive {
+		return nil
+	}
+	return &ConcurrencyLimiter{limit: initialConcurrencyLimit, clock: time.Now}
+}
+
+// TryAcquire starts a request if fewer than Limit requests are in flight. Each successful call
+// must be followed by a call to Release on the returned request once it's over.
+func (l *ConcurrencyLimiter) TryAcquire() (*LimitedRequest, bool) {
+	if l == nil {
+		return nil, true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.inFlight >= int(l.limit) {
+		return nil, false
+	}
+	l.inFlight++
+	if l.inFlight > l.window.maxInFlight {
+		l.window.maxInFlight = l.inFlight
+	}
+	return &LimitedRequest{limiter: l, start: l.clock()}, true
+}
+
+// Limit returns how many requests may currently be in flight.
+func (l *ConcurrencyLimiter) Limit() int {
+	if l == nil {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return int(l.limit)
+}
+
+// record adds the response time of a request to the window, and adjusts the limit once the
+// window is over.
+func (l *ConcurrencyLimiter) record(start time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.clock()
+	if l.window.start.IsZero() {
+		l.window.start = start
+	}
+	l.window.samples++
+	l.window.rttSum += now.Sub(start)
+	if l.window.samples < windowSamples || now.Sub(l.window.start) < windowDuration {
+		return
+	}
+	window := l.window
+	l.window = rttWindow{start: now, maxInFlight: l.inFlight}
+
+	sample := (window.rttSum / time.Duration(window.samples)).Seconds()
+	if sample <= 0 {
+		return
+	}
+	if l.longRTT == 0 {
+		l.longRTT = sample
+	} else {
+		l.longRTT = l.longRTT*(1-longRTTWeight) + sample*longRTTWeight
+	}
+	gradient := math.Max(minGradient, math.Min(1, l.longRTT/sample))
+	// The square root of the limit leaves room for some requests to queue, so the limit keeps
+	// growing while the origin keeps up.
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+	if newLimit > l.limit && window.maxInFlight*2 < int(l.limit) {
+		// Too few requests were in flight to tell if the origin can handle more.
+		return
+	}
+	l.limit = l.limit*(1-limitSmoothing) + newLimit*limitSmoothing
+	l.limit = math.Max(minConcurrencyLimit, math.Min(maxConcurrencyLimit, l.limit))
+}
+
+// LimitedRequest is a request started by ConcurrencyLimiter.TryAcquire. Its methods are safe
+// to call on a nil LimitedRequest.
+type LimitedRequest struct {
+	limiter   *ConcurrencyLimiter
+	start     time.Time
+	responded bool
+}
+
+// Responded records how long the origin took to respond, once its response headers arrived.
+func (r *LimitedRequest) Responded() {
+	if r == nil || r.responded {
+		return
+	}
+	r.responded = true
+	r.limiter.record(r.start)
+}
+
+// Release frees the capacity of the request, e.g. once its response body was proxied.
+func (r *LimitedRequest) Release() {
+	if r == nil {
+		return
+	}
+	// A request that fails without a response still tells how slow the origin is.
+	r.Responded()
+	r.limiter.lock.Lock()
+	r.limiter.inFlight--
+	r.limiter.lock.Unlock()
+}
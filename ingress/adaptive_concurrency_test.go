@@ -0,0 +1,109 @@
+package ingress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// proxyRound starts as many requests as the limiter allows, and finishes them all after rtt.
+func proxyRound(limiter *ConcurrencyLimiter, now *time.Time, rtt time.Duration) int {
+	var requests []*LimitedRequest
+	for {
+		request, ok := limiter.TryAcquire()
+		if !ok {
+			break
+		}
+		requests = append(requests, request)
+	}
+	*now = now.Add(rtt)
+	for _, request := range requests {
+		request.Responded()
+		request.Release()
+	}
+	return len(requests)
+}
+
+func TestConcurrencyLimiterFollowsLatency(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newConcurrencyLimiter(true)
+	limiter.clock = func() time.Time { return now }
+
+	assert.Equal(t, initialConcurrencyLimit, proxyRound(limiter, &now, 10*time.Millisecond))
+	for i := 0; i < 100; i++ {
+		proxyRound(limiter, &now, 10*time.Millisecond)
+	}
+	steadyLimit := limiter.Limit()
+	assert.Greater(t, steadyLimit, initialConcurrencyLimit, "the limit should grow while the origin keeps up")
+
+	previous := steadyLimit
+	for i := 0; i < 10; i++ {
+		// Each window lasts two rounds.
+		proxyRound(limiter, &now, 50*time.Millisecond)
+		proxyRound(limiter, &now, 50*time.Millisecond)
+		assert.Less(t, limiter.Limit(), previous, "the limit should shrink while the origin is slower than usual")
+		previous = limiter.Limit()
+	}
+	for i := 0; i < 1000; i++ {
+		proxyRound(limiter, &now, time.Second)
+	}
+	assert.GreaterOrEqual(t, limiter.Limit(), minConcurrencyLimit)
+}
+
+func TestConcurrencyLimiterSheds(t *testing.T) {
+	limiter := newConcurrencyLimiter(true)
+	var requests []*LimitedRequest
+	for i := 0; i < initialConcurrencyLimit; i++ {
+		request, ok := limiter.TryAcquire()
+		require.True(t, ok)
+		requests = append(requests, request)
+	}
+	_, ok := limiter.TryAcquire()
+	assert.False(t, ok)
+
+	requests[0].Release()
+	_, ok = limiter.TryAcquire()
+	assert.True(t, ok)
+}
+
+func TestConcurrencyLimiterIdleOrigin(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newConcurrencyLimiter(true)
+	limiter.clock = func() time.Time { return now }
+	// A single request at a time can't tell whether the origin could handle more.
+	for i := 0; i < 100; i++ {
+		request, ok := limiter.TryAcquire()
+		require.True(t, ok)
+		now = now.Add(10 * time.Millisecond)
+		request.Release()
+	}
+	assert.Equal(t, initialConcurrencyLimit, limiter.Limit())
+}
+
+func TestNilConcurrencyLimiter(t *testing.T) {
+	var unlimited *ConcurrencyLimiter
+	assert.Nil(t, newConcurrencyLimiter(false))
+	for i := 0; i < 10; i++ {
+		request, ok := unlimited.TryAcquire()
+		assert.True(t, ok)
+		request.Responded()
+		request.Release()
+	}
+}
+
+func TestParseAdaptiveConcurrency(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: api.example.com
+   service: https://localhost:8000
+   originRequest:
+     adaptiveConcurrency: true
+ - service: https://localhost:8001
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	assert.NotNil(t, ing.Rules[0].Concurrency)
+	assert.Nil(t, ing.Rules[1].Concurrency)
+}
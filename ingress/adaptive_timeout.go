@@ -0,0 +1,126 @@
+package ingress
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+const (
+	// adaptiveTimeoutSamples is how many of the latest response times the percentile is of.
+	adaptiveTimeoutSamples = 1000
+	// adaptiveTimeoutMinSamples is how many response times are needed before requests time
+	// out, so that a few fast responses don't make the timeout too short.
+	adaptiveTimeoutMinSamples = 100
+	// adaptiveTimeoutRecompute is how many responses the percentile is reused for, so that
+	// the samples aren't sorted for every request.
+	adaptiveTimeoutRecompute = 50
+	// minAdaptiveTimeout bounds the timeout of origins that respond very fast, so that a
+	// short hiccup doesn't fail most requests.
+	minAdaptiveTimeout = 100 * time.Millisecond
+)
+
+// AdaptiveTimeout bounds how long the origin may take to send the response headers, by a
+// percentile of its latest response times times a multiplier. Its methods are safe to call on
+// a nil AdaptiveTimeout, which doesn't time out anything.
+type AdaptiveTimeout struct {
+	percentile float64
+	multiplier float64
+	clock      func() time.Time
+	afterFunc  func(d time.Duration, f func()) (stop func() bool)
+
+	lock sync.Mutex
+	// samples is a ring buffer of the latest response times, next is where the next one goes.
+	samples []time.Duration
+	next    int
+	// timeout is 0 until there are adaptiveTimeoutMinSamples.
+	timeout         time.Duration
+	sinceRecomputed int
+}
+
+func newAdaptiveTimeout(c config.AdaptiveTimeout) *AdaptiveTimeout {
+	if c == (config.AdaptiveTimeout{}) {
+		return nil
+	}
+	return &AdaptiveTimeout{
+		percentile: c.Percentile,
+		multiplier: c.Multiplier,
+		clock:      time.Now,
+		afterFunc: func(d time.Duration, f func()) func() bool {
+			return time.AfterFunc(d, f).Stop
+		},
+		samples: make([]time.Duration, 0, adaptiveTimeoutSamples),
+	}
+}
+
+// Timeout returns how long the origin currently has to respond, or 0 if there aren't enough
+// response times to tell yet.
+func (t *AdaptiveTimeout) Timeout() time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.timeout
+}
+
+// Start returns req with a context that is canceled once the current timeout passes, and a
+// ResponseTimer that must be stopped once the response headers arrive. Stopping it records
+// how long the origin took.
+func (t *AdaptiveTimeout) Start(req *http.Request) (*http.Request, *ResponseTimer) {
+	if t == nil {
+		return req, nil
+	}
+	started := t.clock()
+	timer := ResponseTimer{
+		timeout:  t.Timeout(),
+		stop:     func() bool { return false },
+		observed: func() { t.record(t.clock().Sub(started)) },
+	}
+	if timer.timeout == 0 {
+		return req, &timer
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	timer.stop = t.afterFunc(timer.timeout, func() {
+		atomic.StoreInt32(&timer.expired, 1)
+		cancel()
+	})
+	return req.WithContext(ctx), &timer
+}
+
+// record adds a response time, and recomputes the timeout every adaptiveTimeoutRecompute
+// of them. Requests that timed out are recorded too, so the timeout grows if the origin
+// slows down.
+func (t *AdaptiveTimeout) record(elapsed time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(t.samples) < adaptiveTimeoutSamples {
+		t.samples = append(t.samples, elapsed)
+	} else {
+		t.samples[t.next] = elapsed
+		t.next = (t.next + 1) % adaptiveTimeoutSamples
+	}
+	t.sinceRecomputed++
+	if len(t.samples) < adaptiveTimeoutMinSamples || (t.timeout != 0 && t.sinceRecomputed < adaptiveTimeoutRecompute) {
+		return
+	}
+	t.sinceRecomputed = 0
+	sorted := make([]time.Duration, len(t.samples))
+	copy(sorted, t.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	// The nearest-rank percentile.
+	rank := int(math.Ceil(t.percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	t.timeout = time.Duration(float64(sorted[rank-1]) * t.multiplier)
+	if t.timeout < minAdaptiveTimeout {
+		t.timeout = minAdaptiveTimeout
+	}
+}
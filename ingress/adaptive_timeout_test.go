@@ -0,0 +1,111 @@
+package ingress
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+func TestAdaptiveTimeoutTracksPercentile(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     adaptiveTimeout:
+       percentile: 99
+       multiplier: 2
+`))
+	require.NoError(t, err)
+	timeout := ing.Rules[0].AdaptiveTimeout
+	require.NotNil(t, timeout)
+	now := time.Unix(1600000000, 0)
+	timeout.clock = func() time.Time { return now }
+	timers := &fakeTimers{}
+	timeout.afterFunc = timers.afterFunc
+
+	// The fake origin takes between 0 and max to respond, uniformly.
+	random := rand.New(rand.NewSource(1))
+	respond := func(max time.Duration) {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+		_, timer := timeout.Start(req)
+		now = now.Add(time.Duration(random.Int63n(int64(max))))
+		timer.Stop()
+	}
+
+	for i := 0; i < adaptiveTimeoutMinSamples-1; i++ {
+		respond(time.Second)
+	}
+	assert.Zero(t, timeout.Timeout(), "there aren't enough samples yet")
+	assert.Empty(t, timers.durations)
+
+	for i := 0; i < adaptiveTimeoutSamples; i++ {
+		respond(time.Second)
+	}
+	// Twice the p99 of up to 1s is close to 2s.
+	assert.InDelta(t, 1.98, timeout.Timeout().Seconds(), 0.03)
+	assert.Equal(t, timeout.Timeout(), timers.durations[len(timers.durations)-1])
+
+	// Once the origin slows down, the timeout follows, once its latest response times are
+	// all slower.
+	for i := 0; i < adaptiveTimeoutSamples+adaptiveTimeoutRecompute; i++ {
+		respond(5 * time.Second)
+	}
+	assert.InDelta(t, 9.9, timeout.Timeout().Seconds(), 0.15)
+
+	// Very fast origins still get minAdaptiveTimeout.
+	for i := 0; i < adaptiveTimeoutSamples+adaptiveTimeoutRecompute; i++ {
+		respond(time.Millisecond)
+	}
+	assert.Equal(t, minAdaptiveTimeout, timeout.Timeout())
+}
+
+func TestAdaptiveTimeoutExpires(t *testing.T) {
+	timeout := newAdaptiveTimeout(config.AdaptiveTimeout{Percentile: 50, Multiplier: 1})
+	now := time.Unix(1600000000, 0)
+	timeout.clock = func() time.Time { return now }
+	timers := &fakeTimers{}
+	timeout.afterFunc = timers.afterFunc
+	for i := 0; i < adaptiveTimeoutMinSamples; i++ {
+		timeout.record(time.Second)
+	}
+	require.Equal(t, time.Second, timeout.Timeout())
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	req, timer := timeout.Start(req)
+	assert.False(t, timer.Expired())
+	timers.fire[0]()
+	assert.True(t, timer.Expired())
+	assert.Error(t, req.Context().Err())
+	assert.Equal(t, time.Second, timer.Timeout())
+
+	var nilTimeout *AdaptiveTimeout
+	unchanged, nilTimer := nilTimeout.Start(req)
+	assert.Equal(t, req, unchanged)
+	nilTimer.Stop()
+	assert.False(t, nilTimer.Expired())
+}
+
+func TestParseAdaptiveTimeout(t *testing.T) {
+	for _, invalid := range []string{
+		"{percentile: 0, multiplier: 2}",
+		"{percentile: 101, multiplier: 2}",
+		"{percentile: 99}",
+		"{percentile: 99, multiplier: 0.5}",
+	} {
+		_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     adaptiveTimeout: ` + invalid + `
+`))
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,74 @@
+package ingress
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/textproto"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssumeHTTP10(t *testing.T) {
+	// An HTTP/1.0 origin, which ends every response body by closing the connection.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	body := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	headerLines := make(chan []string, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := textproto.NewReader(bufio.NewReader(conn))
+			var lines []string
+			for {
+				line, err := reader.ReadLine()
+				if err != nil || line == "" {
+					break
+				}
+				lines = append(lines, line)
+			}
+			headerLines <- lines
+			_, _ = conn.Write([]byte("HTTP/1.0 200 OK\r\nContent-Type: application/octet-stream\r\n\r\n"))
+			_, _ = conn.Write(body)
+			conn.Close()
+		}
+	}()
+
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://` + listener.Addr().String() + `
+   originRequest:
+     assumeHTTP10: true
+`))
+	require.NoError(t, err)
+	assert.True(t, ing.Rules[0].Config.AssumeHTTP10)
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/download", nil)
+		require.NoError(t, err)
+		resp, err := ing.Rules[0].Service.(HTTPOriginProxy).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int64(-1), resp.ContentLength)
+		received, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, body, received, "the whole body must be relayed")
+		// Every request got a connection of its own, which the origin was asked to close.
+		assert.Contains(t, <-headerLines, "Connection: close")
+	}
+}
@@ -0,0 +1,135 @@
+package ingress
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// BandwidthLimiter throttles the bytes proxied for a rule, independently in each direction.
+// A nil BandwidthLimiter doesn't throttle anything.
+type BandwidthLimiter struct {
+	upstream   *tokenBucket
+	downstream *tokenBucket
+}
+
+func newBandwidthLimiter(bytesPerSecond int) *BandwidthLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &BandwidthLimiter{
+		upstream:   newTokenBucket(bytesPerSecond),
+		downstream: newTokenBucket(bytesPerSecond),
+	}
+}
+
+// LimitUpstream throttles data read from the eyeball, to be sent to the origin.
+func (l *BandwidthLimiter) LimitUpstream(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &throttledReader{r: r, bucket: l.upstream}
+}
+
+// LimitUpstreamBody is like LimitUpstream, but keeps the body closeable.
+func (l *BandwidthLimiter) LimitUpstreamBody(body io.ReadCloser) io.ReadCloser {
+	if l == nil || body == nil {
+		return body
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{l.LimitUpstream(body), body}
+}
+
+// LimitDownstream throttles data written to the eyeball, which was received from the origin.
+func (l *BandwidthLimiter) LimitDownstream(w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+	return &throttledWriter{w: w, bucket: l.downstream}
+}
+
+// tokenBucket allows a sustained rate of bytes per second, with bursts of up to one second's
+// worth of bytes.
+type tokenBucket struct {
+	sync.Mutex
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+	// clock and sleep can be replaced by tests.
+	clock func() time.Time
+	sleep func(time.Duration)
+}
+
+func newTokenBucket(bytesPerSecond int) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(bytesPerSecond),
+		burst:  bytesPerSecond,
+		tokens: float64(bytesPerSecond),
+		clock:  time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// wait takes n tokens from the bucket, blocking until they are available. Tokens may be
+// borrowed from the future, which makes later callers wait longer.
+func (b *tokenBucket) wait(n int) {
+	b.Lock()
+	now := b.clock()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > float64(b.burst) {
+			b.tokens = float64(b.burst)
+		}
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.Unlock()
+
+	if delay > 0 {
+		b.sleep(delay)
+	}
+}
+
+type throttledReader struct {
+	r      io.Reader
+	bucket *tokenBucket
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > tr.bucket.burst {
+		p = p[:tr.bucket.burst]
+	}
+	n, err := tr.r.Read(p)
+	tr.bucket.wait(n)
+	return n, err
+}
+
+type throttledWriter struct {
+	w      io.Writer
+	bucket *tokenBucket
+}
+
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > tw.bucket.burst {
+			chunk = chunk[:tw.bucket.burst]
+		}
+		tw.bucket.wait(len(chunk))
+		n, err := tw.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
@@ -0,0 +1,129 @@
+package ingress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock whose time only moves when something sleeps.
+type fakeClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) elapsed() time.Duration {
+	return c.Now().Sub(time.Unix(0, 0))
+}
+
+func newFakeClockLimiter(bytesPerSecond int, clock *fakeClock) *BandwidthLimiter {
+	limiter := newBandwidthLimiter(bytesPerSecond)
+	for _, bucket := range []*tokenBucket{limiter.upstream, limiter.downstream} {
+		bucket.clock = clock.Now
+		bucket.sleep = clock.Sleep
+	}
+	return limiter
+}
+
+func TestBandwidthLimiterDownstream(t *testing.T) {
+	const (
+		bytesPerSecond = 1000
+		total          = 10 * bytesPerSecond
+	)
+	clock := newFakeClock()
+	limiter := newFakeClockLimiter(bytesPerSecond, clock)
+
+	var out bytes.Buffer
+	n, err := io.Copy(limiter.LimitDownstream(&out), bytes.NewReader(make([]byte, total)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(total), n)
+	assert.Equal(t, total, out.Len())
+
+	// The first second's worth of bytes is allowed as a burst; the rest is throttled.
+	assert.InDelta(t, 9*time.Second, clock.elapsed(), float64(100*time.Millisecond))
+}
+
+func TestBandwidthLimiterUpstream(t *testing.T) {
+	const (
+		bytesPerSecond = 500
+		total          = 5 * bytesPerSecond
+	)
+	clock := newFakeClock()
+	limiter := newFakeClockLimiter(bytesPerSecond, clock)
+
+	body := ioutil.NopCloser(bytes.NewReader(make([]byte, total)))
+	data, err := ioutil.ReadAll(limiter.LimitUpstreamBody(body))
+	require.NoError(t, err)
+	assert.Len(t, data, total)
+	assert.InDelta(t, 4*time.Second, clock.elapsed(), float64(100*time.Millisecond))
+}
+
+func TestBandwidthLimiterDirectionsAreIndependent(t *testing.T) {
+	const bytesPerSecond = 1000
+	clock := newFakeClock()
+	limiter := newFakeClockLimiter(bytesPerSecond, clock)
+
+	_, err := limiter.LimitDownstream(ioutil.Discard).Write(make([]byte, bytesPerSecond))
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(limiter.LimitUpstream(bytes.NewReader(make([]byte, bytesPerSecond))))
+	require.NoError(t, err)
+	// Both directions used only their own burst.
+	assert.Equal(t, time.Duration(0), clock.elapsed())
+}
+
+func TestNilBandwidthLimiter(t *testing.T) {
+	var limiter *BandwidthLimiter
+	assert.Nil(t, newBandwidthLimiter(0))
+
+	var out bytes.Buffer
+	assert.Equal(t, &out, limiter.LimitDownstream(&out))
+	reader := bytes.NewReader(nil)
+	assert.Equal(t, reader, limiter.LimitUpstream(reader))
+}
+
+func TestParseMaxBytesPerSecond(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: example.com
+   service: https://localhost:8000
+   originRequest:
+     maxBytesPerSecond: 1048576
+ - service: https://localhost:8001
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	require.NotNil(t, ing.Rules[0].Bandwidth)
+	assert.Equal(t, float64(1048576), ing.Rules[0].Bandwidth.upstream.rate)
+	assert.Nil(t, ing.Rules[1].Bandwidth)
+
+	rawYAML = `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     maxBytesPerSecond: -1
+`
+	_, err = ParseIngress(MustReadIngress(rawYAML))
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package ingress
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+const (
+	defaultBodyMatchMaxBytes = 64 * 1024
+	maxBodyMatchMaxBytes     = 1 << 20
+)
+
+var jsonPathStep = regexp.MustCompile(`^(?:\.([A-Za-z0-9_-]+)|\[(\d+)\]|\['([^']*)'\]|\["([^"]*)"\])`)
+
+// BodyMatch matches requests whose JSON body has a field with a given value.
+type BodyMatch struct {
+	jsonPath string
+	steps    []jsonPathStepValue
+	equals   string
+	maxBytes int
+}
+
+// jsonPathStepValue selects either an object field or, if isIndex is set, an array element.
+type jsonPathStepValue struct {
+	field   string
+	index   int
+	isIndex bool
+}
+
+func newBodyMatch(c config.IngressBodyMatch) (*BodyMatch, error) {
+	steps, err := parseJSONPath(c.JSONPath)
+	if err != nil {
+		return nil, err
+	}
+	maxBytes := c.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = defaultBodyMatchMaxBytes
+	}
+	if maxBytes < 0 || maxBytes > maxBodyMatchMaxBytes {
+		return nil, fmt.Errorf("maxBytes must be between 1 and %d, got %d", maxBodyMatchMaxBytes, c.MaxBytes)
+	}
+	return &BodyMatch{jsonPath: c.JSONPath, steps: steps, equals: c.Equals, maxBytes: maxBytes}, nil
+}
+
+// parseJSONPath supports the subset of JSONPath that selects a single value, e.g.
+// $.commits[0]['author'].
+func parseJSONPath(path string) ([]jsonPathStepValue, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("jsonPath %q must start with $", path)
+	}
+	var steps []jsonPathStepValue
+	for rest := path[1:]; rest != ""; {
+		match := jsonPathStep.FindStringSubmatch(rest)
+		if match == nil {
+			return nil, fmt.Errorf("jsonPath %q is invalid at %q, only .field, [index] and ['field'] are supported", path, rest)
+		}
+		rest = rest[len(match[0]):]
+		if match[2] != "" {
+			index, err := strconv.Atoi(match[2])
+			if err != nil {
+				return nil, fmt.Errorf("jsonPath %q has an invalid index %s", path, match[2])
+			}
+			steps = append(steps, jsonPathStepValue{index: index, isIndex: true})
+		} else {
+			steps = append(steps, jsonPathStepValue{field: match[1] + match[3] + match[4]})
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("jsonPath %q must select a field", path)
+	}
+	return steps, nil
+}
+
+func (m *BodyMatch) String() string {
+	return fmt.Sprintf("%s == %s", m.jsonPath, m.equals)
+}
+
+func (m *BodyMatch) matches(body *requestBody) bool {
+	data, complete := body.peek(m.maxBytes)
+	if !complete {
+		return false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return false
+	}
+	for _, step := range m.steps {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if step.isIndex {
+				return false
+			}
+			var ok bool
+			if value, ok = v[step.field]; !ok {
+				return false
+			}
+		case []interface{}:
+			if !step.isIndex || step.index >= len(v) {
+				return false
+			}
+			value = v[step.index]
+		default:
+			return false
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v == m.equals
+	case json.Number:
+		return v.String() == m.equals
+	case bool:
+		return strconv.FormatBool(v) == m.equals
+	case nil:
+		return m.equals == "null"
+	default:
+		// Objects and arrays never equal a scalar.
+		return false
+	}
+}
+
+// requestBody lets rules look at the start of a request body before it's proxied. The
+// buffered bytes are put back in front of the rest of the body by restore.
+type requestBody struct {
+	req      *http.Request
+	original io.ReadCloser
+	buffered []byte
+	eof      bool
+	// stream is set for the bodies of stream requests, which are never read.
+	stream bool
+}
+
+func newRequestBody(req *http.Request) *requestBody {
+	body := requestBody{req: req, original: req.Body}
+	if req.Body == nil || req.Body == http.NoBody {
+		body.eof = true
+	}
+	return &body
+}
+
+// peek returns up to n bytes from the start of the body, and whether that is the whole body.
+func (b *requestBody) peek(n int) ([]byte, bool) {
+	if b.stream {
+		return nil, false
+	}
+	// Read one byte more than needed, to find out if anything comes after the first n bytes.
+	if want := n + 1 - len(b.buffered); !b.eof && want > 0 {
+		more := make([]byte, want)
+		read, err := io.ReadFull(b.original, more)
+		b.buffered = append(b.buffered, more[:read]...)
+		if err != nil {
+			// Treat an error like the end of the body, the origin will see the same error.
+			b.eof = true
+		}
+	}
+	if len(b.buffered) > n {
+		return b.buffered[:n], false
+	}
+	return b.buffered, b.eof
+}
+
+func (b *requestBody) restore() {
+	if len(b.buffered) == 0 {
+		return
+	}
+	b.req.Body = &readCloser{
+		Reader: io.MultiReader(bytes.NewReader(b.buffered), b.original),
+		Closer: b.original,
+	}
+}
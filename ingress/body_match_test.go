@@ -0,0 +1,138 @@
+package ingress
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	steps, err := parseJSONPath(`$.commits[0]['author']["name"]`)
+	require.NoError(t, err)
+	assert.Equal(t, []jsonPathStepValue{
+		{field: "commits"},
+		{index: 0, isIndex: true},
+		{field: "author"},
+		{field: "name"},
+	}, steps)
+
+	for _, invalid := range []string{"", "$", "event", "$.", "$..event", "$.commits[*]", "$.commits[-1]", "$[?(@.a)]"} {
+		_, err := parseJSONPath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestBodyMatch(t *testing.T) {
+	const body = `{"event": "push", "count": 2, "forced": false, "commits": [{"id": "abc"}], "sender": null}`
+	tests := []struct {
+		jsonPath string
+		equals   string
+		want     bool
+	}{
+		{jsonPath: "$.event", equals: "push", want: true},
+		{jsonPath: "$.event", equals: "pull_request", want: false},
+		{jsonPath: "$.count", equals: "2", want: true},
+		{jsonPath: "$.forced", equals: "false", want: true},
+		{jsonPath: "$.sender", equals: "null", want: true},
+		{jsonPath: "$.commits[0].id", equals: "abc", want: true},
+		{jsonPath: "$.commits[1].id", equals: "abc", want: false},
+		{jsonPath: "$.commits", equals: "abc", want: false},
+		{jsonPath: "$.missing", equals: "", want: false},
+		{jsonPath: "$.event.name", equals: "push", want: false},
+	}
+	for _, test := range tests {
+		bodyMatch, err := newBodyMatch(config.IngressBodyMatch{JSONPath: test.jsonPath, Equals: test.equals})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "https://example.com", strings.NewReader(body))
+		require.NoError(t, err)
+		assert.Equal(t, test.want, bodyMatch.matches(newRequestBody(req)), "%s == %s", test.jsonPath, test.equals)
+	}
+
+	// Bodies that aren't JSON, or too large to buffer, never match.
+	bodyMatch, err := newBodyMatch(config.IngressBodyMatch{JSONPath: "$.event", Equals: "push", MaxBytes: 20})
+	require.NoError(t, err)
+	for _, body := range []string{`event=push`, `{"event": "push", "padding": "..."}`, ``} {
+		req, err := http.NewRequest(http.MethodPost, "https://example.com", strings.NewReader(body))
+		require.NoError(t, err)
+		assert.False(t, bodyMatch.matches(newRequestBody(req)), body)
+	}
+
+	for _, maxBytes := range []int{-1, maxBodyMatchMaxBytes + 1} {
+		_, err := newBodyMatch(config.IngressBodyMatch{JSONPath: "$.event", MaxBytes: maxBytes})
+		assert.Error(t, err)
+	}
+}
+
+func TestFindMatchingRuleForRequestBodyMatch(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: hooks.example.com
+   bodyMatch:
+     jsonPath: $.event
+     equals: push
+   service: https://localhost:8000
+ - hostname: hooks.example.com
+   service: https://localhost:8001
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+
+	tests := []struct {
+		body          string
+		wantRuleIndex int
+	}{
+		{body: `{"event": "push", "ref": "refs/heads/master"}`, wantRuleIndex: 0},
+		{body: `{"event": "issues"}`, wantRuleIndex: 1},
+		{body: strings.Repeat(" ", defaultBodyMatchMaxBytes) + `{"event": "push"}`, wantRuleIndex: 1},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodPost, "https://hooks.example.com/github", strings.NewReader(test.body))
+		require.NoError(t, err)
+		_, ruleIndex := ing.FindMatchingRuleForRequest(req)
+		assert.Equal(t, test.wantRuleIndex, ruleIndex)
+
+		// The body is forwarded unmodified, whether the rule matched or not.
+		forwarded, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, test.body, string(forwarded))
+	}
+
+	// The body isn't read for requests that can't match the rule anyway.
+	req, err := http.NewRequest(http.MethodPost, "https://other.example.com/", strings.NewReader(`{"event": "push"}`))
+	require.NoError(t, err)
+	originalBody := req.Body
+	_, ruleIndex := ing.FindMatchingRuleForRequest(req)
+	assert.Equal(t, 2, ruleIndex)
+	assert.Equal(t, originalBody, req.Body)
+}
+
+func TestParseBodyMatch(t *testing.T) {
+	rawYAML := `
+ingress:
+ - bodyMatch:
+     jsonPath: event
+     equals: push
+   service: https://localhost:8000
+ - service: http_status:404
+`
+	_, err := ParseIngress(MustReadIngress(rawYAML))
+	assert.Error(t, err)
+
+	// A rule with only a bodyMatch isn't a catch-all rule.
+	rawYAML = `
+ingress:
+ - bodyMatch:
+     jsonPath: $.event
+     equals: push
+   service: https://localhost:8000
+`
+	_, err = ParseIngress(MustReadIngress(rawYAML))
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package ingress
+
+import (
+	"context"
+	"net"
+	"net/url"
+)
+
+// OriginCheck is the outcome of connecting to an origin of a rule.
+type OriginCheck struct {
+	Rule    int
+	Address string
+	Err     error
+}
+
+// CheckOrigins connects to the origin of every rule that cloudflared doesn't serve itself, and
+// closes the connection right away, without sending a request. Each connection uses the rule's
+// dialer and connectTimeout.
+func (ing Ingress) CheckOrigins(ctx context.Context) []OriginCheck {
+	var checks []OriginCheck
+	for _, rule := range ing.Rules {
+		cfg := rule.Config
+		cfg.dialContext = rule.DialContext
+		network, addresses := originAddresses(rule.Service)
+		for _, address := range addresses {
+			check := OriginCheck{Rule: rule.Index, Address: address}
+			conn, err := cfg.dialer()(ctx, network, address)
+			if err == nil {
+				_ = conn.Close()
+			}
+			check.Err = err
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// originAddresses returns where the service connects to, if it's an origin that cloudflared
+// doesn't run itself.
+func originAddresses(service originService) (network string, addresses []string) {
+	switch service := service.(type) {
+	case *httpService:
+		return "tcp", []string{urlAddress(service.url)}
+	case *h2cService:
+		return "tcp", []string{urlAddress(service.url)}
+	case *s3Bucket:
+		return "tcp", []string{urlAddress(service.endpoint)}
+	case *unixSocketPath:
+		return "unix", []string{service.path}
+	case *tcpOverWSService:
+		if service.isBastion {
+			return "", nil
+		}
+		return "tcp", []string{service.dest}
+	case *weightedGroup:
+		for _, member := range service.services {
+			addresses = append(addresses, urlAddress(member.url))
+		}
+		return "tcp", addresses
+	default:
+		return "", nil
+	}
+}
+
+// urlAddress returns the host and port of u, with the scheme's default port if it has none.
+func urlAddress(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	if u.Scheme == "https" || u.Scheme == "wss" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,79 @@
+package ingress
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DeadLetterLog appends a line to a file for every request of a rule that failed, to debug
+// transient failures. Its methods are safe to call on a nil DeadLetterLog, which writes nothing.
+type DeadLetterLog struct {
+	filename string
+
+	lock sync.Mutex
+	file *os.File
+}
+
+func newDeadLetterLog(filename string) *DeadLetterLog {
+	if filename == "" {
+		return nil
+	}
+	return &DeadLetterLog{filename: filename}
+}
+
+// validateDeadLetterLog checks that filename can be a file.
+func validateDeadLetterLog(filename string) error {
+	if filename == "" {
+		return nil
+	}
+	if strings.HasSuffix(filename, "/") {
+		return fmt.Errorf("deadLetterLog %q must name a file, not a directory", filename)
+	}
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		return fmt.Errorf("deadLetterLog %q is a directory, it must name a file", filename)
+	}
+	return nil
+}
+
+// Filename is the file that the log is written to.
+func (d *DeadLetterLog) Filename() string {
+	if d == nil {
+		return ""
+	}
+	return d.filename
+}
+
+// Write appends line to the file, which is opened by the first write.
+func (d *DeadLetterLog) Write(line []byte) error {
+	if d == nil {
+		return nil
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.file == nil {
+		file, err := openLogFile(d.filename, "dead letter log")
+		if err != nil {
+			return err
+		}
+		d.file = file
+	}
+	_, err := d.file.Write(line)
+	return err
+}
+
+// Close closes the file. Later writes open it again.
+func (d *DeadLetterLog) Close() error {
+	if d == nil {
+		return nil
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
@@ -0,0 +1,19 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDeadLetterLog(t *testing.T) {
+	for _, invalid := range []string{"/var/log/", t.TempDir()} {
+		_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     deadLetterLog: "` + invalid + `"
+`))
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,120 @@
+package ingress
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity of a Diagnostic found while parsing ingress rules.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("unknown severity %d", int(s))
+	}
+}
+
+// noRuleIndex is used for diagnostics that apply to the ingress config as a whole.
+const noRuleIndex = -1
+
+// Diagnostic is a message about the ingress config, optionally tied to a specific rule.
+type Diagnostic struct {
+	Severity Severity
+	// RuleIndex is the 0-based index of the rule this diagnostic is about, or -1 if it applies to
+	// the whole config.
+	RuleIndex int
+	Message   string
+}
+
+func (d Diagnostic) String() string {
+	if d.RuleIndex == noRuleIndex {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: rule #%d: %s", d.Severity, d.RuleIndex+1, d.Message)
+}
+
+// Diagnostics is the list of messages found while parsing ingress rules. Only error-level
+// diagnostics make parsing fail.
+type Diagnostics []Diagnostic
+
+func (d *Diagnostics) add(severity Severity, ruleIndex int, format string, args ...interface{}) {
+	*d = append(*d, Diagnostic{
+		Severity:  severity,
+		RuleIndex: ruleIndex,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *Diagnostics) warn(ruleIndex int, format string, args ...interface{}) {
+	d.add(SeverityWarning, ruleIndex, format, args...)
+}
+
+// HasErrors checks if any diagnostic is error-level.
+func (d Diagnostics) HasErrors() bool {
+	return len(d.BySeverity(SeverityError)) > 0
+}
+
+// BySeverity returns the diagnostics with the given severity, in their original order.
+func (d Diagnostics) BySeverity(severity Severity) Diagnostics {
+	var out Diagnostics
+	for _, diag := range d {
+		if diag.Severity == severity {
+			out = append(out, diag)
+		}
+	}
+	return out
+}
+
+func (d Diagnostics) String() string {
+	lines := make([]string, len(d))
+	for i, diag := range d {
+		lines[i] = diag.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkShadowedRules warns about rules that can never be matched, because an earlier rule
+// already matches every request they would match.
+func checkShadowedRules(rules []Rule, diags *Diagnostics) {
+	// The catch-all rule is deliberately matched by everything, so don't check it.
+	for i := 0; i < len(rules)-1; i++ {
+		for j := 0; j < i; j++ {
+			if shadows(&rules[j], &rules[i]) {
+				diags.warn(rules[i].Index, "this rule will never be matched, because rule #%d matches every request it would match", rules[j].Index+1)
+				break
+			}
+		}
+	}
+}
+
+// shadows checks if every request matched by the later rule is also matched by the earlier one.
+func shadows(earlier, later *Rule) bool {
+	if earlier.Schedule != nil || earlier.BodyMatch != nil || earlier.Shard != nil || len(earlier.Accepts) > 0 || earlier.PathSegments != nil {
+		return false
+	}
+	if earlier.Path != nil && (later.Path == nil || earlier.Path.String() != later.Path.String()) {
+		return false
+	}
+	if isCatchAllHostname(earlier.Hostname) || earlier.Hostname == later.Hostname {
+		return true
+	}
+	return strings.HasPrefix(earlier.Hostname, "*.") && !isCatchAllHostname(later.Hostname) &&
+		matchHost(earlier.Hostname, later.Hostname)
+}
+
+func isCatchAllHostname(hostname string) bool {
+	return hostname == "" || hostname == "*"
+}
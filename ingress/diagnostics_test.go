@@ -0,0 +1,106 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIngressWithDiagnostics(t *testing.T) {
+	tests := []struct {
+		name      string
+		rawYAML   string
+		wantDiags Diagnostics
+	}{
+		{
+			name: "No shadowed rules",
+			rawYAML: `
+ingress:
+ - hostname: b.example.com
+   path: /api
+   service: https://localhost:8001
+ - hostname: b.example.com
+   service: https://localhost:8002
+ - service: http_status:404
+`,
+		},
+		{
+			name: "Same hostname without path",
+			rawYAML: `
+ingress:
+ - hostname: a.example.com
+   service: https://localhost:8000
+ - hostname: a.example.com
+   path: /api
+   service: https://localhost:8001
+ - service: http_status:404
+`,
+			wantDiags: Diagnostics{
+				{
+					Severity:  SeverityWarning,
+					RuleIndex: 1,
+					Message:   "this rule will never be matched, because rule #1 matches every request it would match",
+				},
+			},
+		},
+		{
+			name: "Wildcard hostname shadows subdomain",
+			rawYAML: `
+ingress:
+ - hostname: "*.example.com"
+   service: https://localhost:8000
+ - hostname: a.example.com
+   service: https://localhost:8001
+ - service: http_status:404
+`,
+			wantDiags: Diagnostics{
+				{
+					Severity:  SeverityWarning,
+					RuleIndex: 1,
+					Message:   "this rule will never be matched, because rule #1 matches every request it would match",
+				},
+			},
+		},
+		{
+			name: "Same hostname and path",
+			rawYAML: `
+ingress:
+ - hostname: a.example.com
+   path: /api
+   service: https://localhost:8000
+ - hostname: a.example.com
+   path: /api
+   service: https://localhost:8001
+ - service: http_status:404
+`,
+			wantDiags: Diagnostics{
+				{
+					Severity:  SeverityWarning,
+					RuleIndex: 1,
+					Message:   "this rule will never be matched, because rule #1 matches every request it would match",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing, diags, err := ParseIngressWithDiagnostics(MustReadIngress(tt.rawYAML))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDiags, diags)
+			assert.False(t, diags.HasErrors())
+			assert.Len(t, ing.Rules, 3)
+		})
+	}
+}
+
+func TestDiagnosticsString(t *testing.T) {
+	diags := Diagnostics{
+		{Severity: SeverityError, RuleIndex: noRuleIndex, Message: "bad config"},
+		{Severity: SeverityWarning, RuleIndex: 0, Message: "bad rule"},
+		{Severity: SeverityInfo, RuleIndex: 2, Message: "fyi"},
+	}
+	assert.Equal(t, "error: bad config\nwarning: rule #1: bad rule\ninfo: rule #3: fyi", diags.String())
+	assert.True(t, diags.HasErrors())
+	assert.Equal(t, Diagnostics{diags[1]}, diags.BySeverity(SeverityWarning))
+}
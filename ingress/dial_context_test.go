@@ -0,0 +1,74 @@
+package ingress
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleDialContext(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer origin.Close()
+
+	rawYAML := `
+ingress:
+ - hostname: http.example.com
+   service: http://origin.invalid
+ - hostname: tcp.example.com
+   service: tcp://origin.invalid:5432
+ - service: http://other.invalid
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+
+	var dialed []string
+	var eyeballSide net.Conn
+	ing.Rules[0].DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialed = append(dialed, address)
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, origin.Listener.Addr().String())
+	}
+	ing.Rules[1].DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialed = append(dialed, address)
+		var originSide net.Conn
+		originSide, eyeballSide = net.Pipe()
+		return originSide, nil
+	}
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+	req, err := http.NewRequest(http.MethodGet, "http://http.example.com", nil)
+	require.NoError(t, err)
+	resp, err := ing.Rules[0].Service.(HTTPOriginProxy).RoundTrip(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, "http://tcp.example.com", nil)
+	require.NoError(t, err)
+	conn, _, err := ing.Rules[1].Service.(StreamBasedOriginProxy).EstablishConnection(req)
+	require.NoError(t, err)
+	conn.Close()
+	require.NotNil(t, eyeballSide)
+	eyeballSide.Close()
+
+	assert.Equal(t, []string{"origin.invalid:80", "origin.invalid:5432"}, dialed)
+
+	// Rules without a DialContext still dial the origin themselves.
+	req, err = http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	require.NoError(t, err)
+	_, err = ing.Rules[2].Service.(HTTPOriginProxy).RoundTrip(req)
+	assert.Error(t, err)
+	assert.Len(t, dialed, 2)
+}
@@ -0,0 +1,88 @@
+package ingress
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsCache resolves hostnames itself and remembers their addresses for ttl, however long the
+// records say they may be cached. Like ipPreference, it dials the addresses one after another.
+type dnsCache struct {
+	ttl        time.Duration
+	preference string
+	clock      func() time.Time
+	lookup     func(ctx context.Context, host string) ([]net.IPAddr, error)
+	dial       dialFunc
+
+	lock    sync.Mutex
+	entries map[string]dnsCacheEntry
+}
+
+type dnsCacheEntry struct {
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+// cacheDNSResolutions makes dial resolve hostnames at most once per ttl, and connect to their
+// addresses in the order of preference, if it's set. Without a ttl, DNS records are cached as
+// the resolver does.
+func cacheDNSResolutions(dial dialFunc, ttl time.Duration, preference string) dialFunc {
+	if ttl <= 0 {
+		return preferIPFamily(dial, preference)
+	}
+	cache := &dnsCache{
+		ttl:        ttl,
+		preference: preference,
+		clock:      time.Now,
+		lookup:     net.DefaultResolver.LookupIPAddr,
+		dial:       dial,
+		entries:    make(map[string]dnsCacheEntry),
+	}
+	return cache.dialContext
+}
+
+func (c *dnsCache) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" && network != "udp" {
+		return c.dial(ctx, network, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return c.dial(ctx, network, addr)
+	}
+	addrs, err := c.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if c.preference != "" {
+		addrs = orderByIPPreference(addrs, c.preference)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%s has no address allowed by ipPreference %s", host, c.preference)
+	}
+	return dialEach(ctx, c.dial, network, addrs, port)
+}
+
+// resolve returns the cached addresses of host, or looks them up. Failed lookups aren't
+// cached, dnsNegativeTTL is for those.
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]net.IPAddr, error) {
+	c.lock.Lock()
+	entry, ok := c.entries[host]
+	c.lock.Unlock()
+	if ok && c.clock().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[host] = dnsCacheEntry{addrs: addrs, expires: c.clock().Add(c.ttl)}
+	return addrs, nil
+}
@@ -0,0 +1,88 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSCache(t *testing.T) {
+	lookups := map[string]int{}
+	// The fake resolver only knows app.internal.
+	fakeLookup := func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups[host]++
+		if host != "app.internal" {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return []net.IPAddr{{IP: net.ParseIP("2001:db8::1")}, {IP: net.ParseIP("192.0.2.1")}}, nil
+	}
+	var dialed []string
+	fakeDial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return nil, errors.New("connection refused")
+	}
+	now := time.Unix(1600000000, 0)
+	cache := &dnsCache{
+		ttl:        30 * time.Second,
+		preference: IPPreferenceIPv4First,
+		clock:      func() time.Time { return now },
+		lookup:     fakeLookup,
+		dial:       fakeDial,
+		entries:    make(map[string]dnsCacheEntry),
+	}
+
+	_, err := cache.dialContext(context.Background(), "tcp", "app.internal:80")
+	assert.Error(t, err)
+	assert.Equal(t, 1, lookups["app.internal"])
+	assert.Equal(t, []string{"192.0.2.1:80", "[2001:db8::1]:80"}, dialed)
+
+	// Within the TTL the cached addresses are dialed without another lookup, on any port.
+	now = now.Add(29 * time.Second)
+	dialed = nil
+	_, err = cache.dialContext(context.Background(), "tcp", "app.internal:443")
+	assert.Error(t, err)
+	assert.Equal(t, 1, lookups["app.internal"])
+	assert.Equal(t, []string{"192.0.2.1:443", "[2001:db8::1]:443"}, dialed)
+
+	now = now.Add(time.Second)
+	_, _ = cache.dialContext(context.Background(), "tcp", "app.internal:80")
+	assert.Equal(t, 2, lookups["app.internal"])
+
+	// Failed lookups aren't cached.
+	for i := 0; i < 2; i++ {
+		_, err = cache.dialContext(context.Background(), "tcp", "missing.internal:80")
+		var dnsErr *net.DNSError
+		assert.True(t, errors.As(err, &dnsErr))
+	}
+	assert.Equal(t, 2, lookups["missing.internal"])
+
+	// Addresses are dialed as they are.
+	dialed = nil
+	_, _ = cache.dialContext(context.Background(), "tcp", "127.0.0.1:80")
+	assert.Equal(t, []string{"127.0.0.1:80"}, dialed)
+	assert.Len(t, lookups, 2)
+}
+
+func TestParseDNSCacheTTL(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     dnsCacheTTL: 30s
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, ing.Rules[0].Config.DNSCacheTTL)
+
+	_, err = ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     dnsCacheTTL: -1s
+`))
+	assert.Error(t, err)
+}
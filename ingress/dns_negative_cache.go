@@ -0,0 +1,72 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsNegativeCache remembers which hosts failed to resolve, so dials to them fail fast until
+// the failure expires.
+type dnsNegativeCache struct {
+	ttl   time.Duration
+	clock func() time.Time
+
+	lock     sync.Mutex
+	failures map[string]dnsFailure
+}
+
+type dnsFailure struct {
+	err     error
+	expires time.Time
+}
+
+// cacheDNSFailures makes dial return the previous error for a host that failed to resolve
+// less than ttl ago, without dialing again.
+func cacheDNSFailures(dial dialFunc, ttl time.Duration) dialFunc {
+	if ttl <= 0 {
+		return dial
+	}
+	cache := &dnsNegativeCache{ttl: ttl, clock: time.Now, failures: make(map[string]dnsFailure)}
+	return cache.wrap(dial)
+}
+
+func (c *dnsNegativeCache) wrap(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		if err := c.failure(host); err != nil {
+			return nil, err
+		}
+		conn, err := dial(ctx, network, addr)
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && !dnsErr.IsTimeout {
+			c.remember(host, err)
+		}
+		return conn, err
+	}
+}
+
+func (c *dnsNegativeCache) failure(host string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	failure, ok := c.failures[host]
+	if !ok {
+		return nil
+	}
+	if !c.clock().Before(failure.expires) {
+		delete(c.failures, host)
+		return nil
+	}
+	return failure.err
+}
+
+func (c *dnsNegativeCache) remember(host string, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.failures[host] = dnsFailure{err: err, expires: c.clock().Add(c.ttl)}
+}
@@ -0,0 +1,76 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSNegativeCache(t *testing.T) {
+	lookups := map[string]int{}
+	// The fake resolver only knows up.internal.
+	fakeDial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		require.NoError(t, err)
+		lookups[host]++
+		if host == "up.internal" {
+			return nil, errors.New("connection refused")
+		}
+		return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}}
+	}
+	now := time.Unix(1600000000, 0)
+	cache := &dnsNegativeCache{ttl: 5 * time.Second, clock: func() time.Time { return now }, failures: make(map[string]dnsFailure)}
+	dial := cache.wrap(fakeDial)
+
+	_, err := dial(context.Background(), "tcp", "down.internal:80")
+	var dnsErr *net.DNSError
+	require.True(t, errors.As(err, &dnsErr))
+	assert.Equal(t, 1, lookups["down.internal"])
+
+	// Within the TTL the failure is returned without another lookup, on any port.
+	now = now.Add(4 * time.Second)
+	_, cachedErr := dial(context.Background(), "tcp", "down.internal:443")
+	assert.Equal(t, err, cachedErr)
+	assert.Equal(t, 1, lookups["down.internal"])
+
+	// Other errors aren't cached.
+	for i := 0; i < 2; i++ {
+		_, err = dial(context.Background(), "tcp", "up.internal:80")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 2, lookups["up.internal"])
+
+	now = now.Add(time.Second)
+	_, err = dial(context.Background(), "tcp", "down.internal:80")
+	assert.Error(t, err)
+	assert.Equal(t, 2, lookups["down.internal"])
+}
+
+func TestCacheDNSFailuresDisabled(t *testing.T) {
+	dials := 0
+	fakeDial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials++
+		return nil, &net.DNSError{Err: "no such host", Name: "down.internal", IsNotFound: true}
+	}
+	dial := cacheDNSFailures(fakeDial, 0)
+	for i := 0; i < 2; i++ {
+		_, err := dial(context.Background(), "tcp", "down.internal:80")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 2, dials)
+}
+
+func TestParseDNSNegativeTTL(t *testing.T) {
+	_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     dnsNegativeTTL: -5s
+`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package ingress
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// drainRecheck is how long a draining group service gets no new requests, before it's tried
+// again to see if it's still draining.
+const drainRecheck = 30 * time.Second
+
+// drainingServices tracks the group services that asked, with drainHeader, to get no new
+// requests. Its methods are safe to call on a nil drainingServices, which drains nothing.
+type drainingServices struct {
+	header string
+	clock  func() time.Time
+
+	lock      sync.Mutex
+	drainedAt []time.Time
+}
+
+func newDrainingServices(header string, numServices int) *drainingServices {
+	if header == "" {
+		return nil
+	}
+	return &drainingServices{header: header, clock: time.Now, drainedAt: make([]time.Time, numServices)}
+}
+
+// isDraining checks if the service with the given index drained less than drainRecheck ago.
+func (d *drainingServices) isDraining(i int) bool {
+	if d == nil {
+		return false
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return !d.drainedAt[i].IsZero() && d.clock().Sub(d.drainedAt[i]) < drainRecheck
+}
+
+// observe drains the service with the given index if its response has the drain header, or
+// brings it back otherwise. The header is meant for cloudflared, so it's removed.
+func (d *drainingServices) observe(i int, resp *http.Response) {
+	if d == nil || resp == nil {
+		return
+	}
+	draining := strings.EqualFold(strings.TrimSpace(resp.Header.Get(d.header)), "true")
+	resp.Header.Del(d.header)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if draining {
+		d.drainedAt[i] = d.clock()
+	} else {
+		d.drainedAt[i] = time.Time{}
+	}
+}
@@ -0,0 +1,138 @@
+package ingress
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupDrainsServices(t *testing.T) {
+	var aDraining int32 = 1
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&aDraining) == 1 {
+			w.Header().Set("X-CF-Drain", "true")
+		}
+		_, _ = w.Write([]byte("a"))
+	}))
+	defer a.Close()
+	b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("b"))
+	}))
+	defer b.Close()
+
+	for _, strategy := range []string{groupStrategyWeighted, groupStrategyLeastTime} {
+		t.Run(strategy, func(t *testing.T) {
+			atomic.StoreInt32(&aDraining, 1)
+			rawYAML := fmt.Sprintf(`
+groups:
+  api:
+  - service: %s
+  - service: %s
+ingress:
+ - hostname: api.example.com
+   group: api
+   groupStrategy: %s
+   originRequest:
+     drainHeader: X-CF-Drain
+ - service: http_status:404
+`, a.URL, b.URL, strategy)
+			ing, err := ParseIngress(MustReadIngress(rawYAML))
+			require.NoError(t, err)
+			log := zerolog.Nop()
+			shutdownC := make(chan struct{})
+			defer close(shutdownC)
+			require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+			group := ing.Rules[0].Service.(*weightedGroup)
+			// a is picked first, by both strategies.
+			group.intn = func(n int) int { return 0 }
+			now := time.Unix(1600000000, 0)
+			group.draining.clock = func() time.Time { return now }
+			if group.responseTimes != nil {
+				// Every response takes no time, so least-time picks the first service it can.
+				group.responseTimes.clock = group.draining.clock
+			}
+
+			get := func() (string, http.Header) {
+				req, err := http.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+				require.NoError(t, err)
+				resp, err := group.RoundTrip(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				body, err := ioutil.ReadAll(resp.Body)
+				require.NoError(t, err)
+				return string(body), resp.Header
+			}
+
+			body, header := get()
+			assert.Equal(t, "a", body)
+			assert.Empty(t, header.Get("X-CF-Drain"))
+			for i := 0; i < 3; i++ {
+				body, _ = get()
+				assert.Equal(t, "b", body)
+			}
+
+			// After drainRecheck, a is tried again and stays drained while it sends the header.
+			now = now.Add(drainRecheck)
+			body, _ = get()
+			assert.Equal(t, "a", body)
+			body, _ = get()
+			assert.Equal(t, "b", body)
+
+			// It recovers once it stops sending the header.
+			atomic.StoreInt32(&aDraining, 0)
+			now = now.Add(drainRecheck)
+			for i := 0; i < 3; i++ {
+				body, _ = get()
+				assert.Equal(t, "a", body)
+			}
+		})
+	}
+}
+
+func TestGroupAllServicesDraining(t *testing.T) {
+	rawYAML := `
+groups:
+  api:
+  - service: http://a.internal
+  - service: http://b.internal
+ingress:
+ - hostname: api.example.com
+   group: api
+   originRequest:
+     drainHeader: X-CF-Drain
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	group := ing.Rules[0].Service.(*weightedGroup)
+	for i := range group.services {
+		group.draining.observe(i, &http.Response{Header: http.Header{"X-Cf-Drain": {"true"}}})
+	}
+
+	// Rather than failing every request, the group keeps using all its services.
+	group.intn = func(n int) int {
+		assert.Equal(t, 2, n)
+		return 1
+	}
+	assert.Equal(t, 1, group.pick(group.draining.isDraining))
+}
+
+func TestParseDrainHeader(t *testing.T) {
+	_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     drainHeader: "X CF Drain"
+`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package ingress
+
+import (
+	"net"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Equal reports whether both Ingresses route every request the same way, e.g. to decide if a
+// config reload changed anything.
+func (ing Ingress) Equal(other Ingress) bool {
+	return len(ing.Diff(other)) == 0 &&
+		reflect.DeepEqual(ing.defaults, other.defaults) &&
+		ing.rejectAbsoluteForm == other.rejectAbsoluteForm
+}
+
+// Diff returns the indices of the rules that differ between both Ingresses, in increasing
+// order. Rules that only exist in one of them are included.
+func (ing Ingress) Diff(other Ingress) []int {
+	var changed []int
+	for i := 0; i < len(ing.Rules) || i < len(other.Rules); i++ {
+		if i >= len(ing.Rules) || i >= len(other.Rules) || !ing.Rules[i].equal(&other.Rules[i]) {
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
+
+func (r *Rule) equal(other *Rule) bool {
+	return r.Hostname == other.Hostname &&
+		regexSource(r.Path) == regexSource(other.Path) &&
+		r.PathTemplate == other.PathTemplate &&
+		r.PathSuffix == other.PathSuffix &&
+		scheduleString(r.Schedule) == scheduleString(other.Schedule) &&
+		reflect.DeepEqual(r.BodyMatch, other.BodyMatch) &&
+		reflect.DeepEqual(r.Shard, other.Shard) &&
+		r.RequireClientCert == other.RequireClientCert &&
+		r.RequireTLS == other.RequireTLS &&
+		reflect.DeepEqual(r.Accepts, other.Accepts) &&
+		reflect.DeepEqual(r.PathSegments, other.PathSegments) &&
+		reflect.DeepEqual(r.Referer, other.Referer) &&
+		reflect.TypeOf(r.Service) == reflect.TypeOf(other.Service) &&
+		serviceKey(r.Service) == serviceKey(other.Service) &&
+		reflect.DeepEqual(r.Config, other.Config)
+}
+
+func regexSource(regex *regexp.Regexp) string {
+	if regex == nil {
+		return ""
+	}
+	return regex.String()
+}
+
+func scheduleString(schedule *Schedule) string {
+	if schedule == nil {
+		return ""
+	}
+	return schedule.String()
+}
+
+// serviceKey identifies a service, ignoring differences in how its URL was written, e.g. the
+// case of the hostname or an explicit default port.
+func serviceKey(service originService) string {
+	if group, ok := service.(*weightedGroup); ok {
+		return group.key()
+	}
+	httpService, ok := service.(*httpService)
+	if !ok {
+		return service.String()
+	}
+	u := *httpService.url
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+	return u.String()
+}
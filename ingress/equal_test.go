@@ -0,0 +1,84 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngressEqual(t *testing.T) {
+	const rawYAML = `
+ingress:
+ - hostname: api.example.com
+   path: ^/v1/
+   service: https://localhost:8000
+ - hostname: ssh.example.com
+   service: ssh://localhost:22
+ - service: http_status:404
+`
+	parse := func(rawYAML string) Ingress {
+		ing, err := ParseIngress(MustReadIngress(rawYAML))
+		require.NoError(t, err)
+		return ing
+	}
+	ing := parse(rawYAML)
+
+	assert.True(t, ing.Equal(parse(rawYAML)))
+	assert.Empty(t, ing.Diff(parse(rawYAML)))
+
+	// Only the way the URL is written changed.
+	normalized := parse(`
+ingress:
+ - hostname: api.example.com
+   path: ^/v1/
+   service: HTTPS://LocalHost:443
+ - hostname: ssh.example.com
+   service: ssh://localhost:22
+ - service: http_status:404
+`)
+	withoutPort := parse(`
+ingress:
+ - hostname: api.example.com
+   path: ^/v1/
+   service: https://localhost
+ - hostname: ssh.example.com
+   service: ssh://localhost:22
+ - service: http_status:404
+`)
+	assert.True(t, normalized.Equal(withoutPort))
+
+	changedService := parse(`
+ingress:
+ - hostname: api.example.com
+   path: ^/v1/
+   service: https://localhost:8000
+ - hostname: ssh.example.com
+   service: ssh://localhost:2222
+ - service: http_status:404
+`)
+	assert.False(t, ing.Equal(changedService))
+	assert.Equal(t, []int{1}, ing.Diff(changedService))
+
+	changedConfig := parse(`
+ingress:
+ - hostname: api.example.com
+   path: ^/v1/
+   service: https://localhost:8000
+   originRequest:
+     noTLSVerify: true
+ - hostname: ssh.example.com
+   service: ssh://localhost:22
+ - service: http_status:404
+`)
+	assert.Equal(t, []int{0}, ing.Diff(changedConfig))
+
+	fewerRules := parse(`
+ingress:
+ - hostname: api.example.com
+   path: ^/v1/
+   service: https://localhost:8000
+ - service: http_status:404
+`)
+	assert.Equal(t, []int{1, 2}, ing.Diff(fewerRules))
+}
@@ -0,0 +1,76 @@
+package ingress
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorPageData is what error page templates can refer to.
+type ErrorPageData struct {
+	// Host is the hostname that the eyeball requested.
+	Host string
+	// RequestID is the request's Cf-Ray, which users can quote when they report the error.
+	RequestID  string
+	Status     int
+	StatusText string
+}
+
+// ErrorPages renders the HTML pages that replace the bodies of responses with some statuses.
+// Its methods are safe to call on a nil ErrorPages, which has no pages.
+type ErrorPages struct {
+	pages map[int]*template.Template
+}
+
+// newErrorPages reads and parses the templates of the statuses in files.
+func newErrorPages(files map[int]string) (*ErrorPages, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	statuses := make([]int, 0, len(files))
+	for status := range files {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	pages := ErrorPages{pages: make(map[int]*template.Template, len(files))}
+	for _, status := range statuses {
+		if status < 400 || status > 599 {
+			return nil, fmt.Errorf("%d isn't an error status, error pages are for statuses from 400 to 599", status)
+		}
+		content, err := ioutil.ReadFile(files[status])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read the error page for %d", status)
+		}
+		page, err := template.New(files[status]).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return nil, errors.Wrapf(err, "the error page for %d is an invalid template", status)
+		}
+		// Templates referring to fields that don't exist only fail once they're executed.
+		if err := page.Execute(ioutil.Discard, ErrorPageData{Status: status, StatusText: http.StatusText(status)}); err != nil {
+			return nil, errors.Wrapf(err, "the error page for %d is an invalid template", status)
+		}
+		pages.pages[status] = page
+	}
+	return &pages, nil
+}
+
+// Render returns the page for the status, if there's one.
+func (p *ErrorPages) Render(status int, data ErrorPageData) ([]byte, bool, error) {
+	if p == nil {
+		return nil, false, nil
+	}
+	page, ok := p.pages[status]
+	if !ok {
+		return nil, false, nil
+	}
+	var rendered bytes.Buffer
+	if err := page.Execute(&rendered, data); err != nil {
+		return nil, true, err
+	}
+	return rendered.Bytes(), true, nil
+}
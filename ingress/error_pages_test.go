@@ -0,0 +1,60 @@
+package ingress
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeErrorPage(t *testing.T, content string) string {
+	filename := filepath.Join(t.TempDir(), "page.html")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0600))
+	return filename
+}
+
+func TestErrorPagesRender(t *testing.T) {
+	pages, err := newErrorPages(map[int]string{
+		404: writeErrorPage(t, "<h1>{{.Status}} {{.StatusText}}</h1><p>{{.Host}}</p><p>{{.RequestID}}</p>"),
+	})
+	require.NoError(t, err)
+
+	page, ok, err := pages.Render(404, ErrorPageData{Host: "<b>www.example.com</b>", RequestID: "abc-LHR", Status: 404, StatusText: "Not Found"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	// Values are escaped, like the request's Host that anyone can send.
+	assert.Equal(t, "<h1>404 Not Found</h1><p>&lt;b&gt;www.example.com&lt;/b&gt;</p><p>abc-LHR</p>", string(page))
+
+	_, ok, err = pages.Render(500, ErrorPageData{})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	var noPages *ErrorPages
+	_, ok, _ = noPages.Render(404, ErrorPageData{})
+	assert.False(t, ok)
+}
+
+func TestParseErrorPages(t *testing.T) {
+	valid := writeErrorPage(t, "<h1>{{.Host}}</h1>")
+	for name, files := range map[string]map[int]string{
+		"not an error status": {302: valid},
+		"missing file":        {404: filepath.Join(t.TempDir(), "missing.html")},
+		"invalid syntax":      {404: writeErrorPage(t, "<h1>{{.Host</h1>")},
+		"unknown field":       {404: writeErrorPage(t, "<h1>{{.Hostname}}</h1>")},
+	} {
+		_, err := newErrorPages(files)
+		assert.Error(t, err, name)
+	}
+
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     errorPages:
+       404: ` + valid + `
+`))
+	require.NoError(t, err)
+	assert.NotNil(t, ing.Rules[0].ErrorPages)
+}
@@ -0,0 +1,221 @@
+package ingress
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+const (
+	groupStrategyWeighted  = "weighted"
+	groupStrategyLeastTime = "least-time"
+)
+
+// weightedGroup spreads requests over the services of an ingress group, in proportion to their
+// weights. Every rule naming the group gets its own weightedGroup with the group's services and
+// weights, because groupStrategy, loadBalancer and drainHeader are set by the rule. Response
+// times, draining and health are therefore tracked by each rule separately.
+type weightedGroup struct {
+	name     string
+	services []*httpService
+	// cumulativeWeights[i] is the sum of the weights of services[0] to services[i].
+	cumulativeWeights []int
+	// intn returns a random number in [0, n).
+	intn func(n int) int
+	// responseTimes is set for the least-time strategy, which ignores the weights.
+	responseTimes *responseTimes
+	// draining is set if drainHeader is, and skips the services that asked for it.
+	draining *drainingServices
+	// health is set if minHealthy or selectTimeout is, and fails requests while too few
+	// services are healthy.
+	health *groupHealth
+	// selectTimeout is how long requests wait for a healthy service while none is.
+	selectTimeout time.Duration
+}
+
+// selectRetryInterval is how often a request waiting for a healthy service checks again.
+const selectRetryInterval = 50 * time.Millisecond
+
+// ErrSelectTimeout is returned by groups none of whose services became healthy within
+// selectTimeout.
+var ErrSelectTimeout = errors.New("None of the group's services became healthy within loadBalancer.selectTimeout")
+
+func newWeightedGroup(name string, members []config.WeightedService, strategy, drainHeader string, loadBalancer config.IngressLoadBalancer) (*weightedGroup, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("group %s has no services", name)
+	}
+	group := weightedGroup{name: name, intn: rand.Intn}
+	switch strategy {
+	case "", groupStrategyWeighted:
+	case groupStrategyLeastTime:
+		group.responseTimes = newResponseTimes(len(members))
+	default:
+		return nil, fmt.Errorf("unknown groupStrategy %q, it must be %s or %s", strategy, groupStrategyWeighted, groupStrategyLeastTime)
+	}
+	total := 0
+	for _, member := range members {
+		u, err := url.Parse(member.Service)
+		if err != nil {
+			return nil, errors.Wrapf(err, "group %s has an invalid service", name)
+		}
+		if !isHTTPService(u) || u.Hostname() == "" || u.Path != "" {
+			return nil, fmt.Errorf("group %s has an invalid service %s, group services must be HTTP addresses with a hostname and no path", name, member.Service)
+		}
+		weight := member.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("group %s has a negative weight %d for %s", name, member.Weight, member.Service)
+		}
+		total += weight
+		group.services = append(group.services, &httpService{url: u})
+		group.cumulativeWeights = append(group.cumulativeWeights, total)
+	}
+	minHealthy := loadBalancer.MinHealthy
+	if minHealthy < 0 || minHealthy > len(group.services) {
+		return nil, fmt.Errorf("group %s has %d services, so minHealthy must be between 0 and %d, got %d", name, len(group.services), len(group.services), minHealthy)
+	}
+	if loadBalancer.SelectTimeout < 0 {
+		return nil, fmt.Errorf("group %s has a negative selectTimeout %s", name, loadBalancer.SelectTimeout)
+	}
+	group.draining = newDrainingServices(drainHeader, len(group.services))
+	if minHealthy > 0 || loadBalancer.SelectTimeout > 0 {
+		group.health = newGroupHealth(minHealthy, len(group.services))
+	}
+	group.selectTimeout = loadBalancer.SelectTimeout
+	return &group, nil
+}
+
+// pick returns the index of the service for the next request. Unless the strategy is
+// least-time, that's a random service, with the probability of its share of the total weight.
+// Skipped services, e.g. draining ones, aren't picked, unless all of them are skipped.
+func (g *weightedGroup) pick(skip func(i int) bool) int {
+	if g.responseTimes != nil {
+		return g.responseTimes.fastest(skip)
+	}
+	cumulativeWeights := g.cumulativeWeights
+	if skip != nil {
+		cumulativeWeights = make([]int, len(g.cumulativeWeights))
+		total, previous := 0, 0
+		for i, cumulative := range g.cumulativeWeights {
+			if !skip(i) {
+				total += cumulative - previous
+			}
+			cumulativeWeights[i] = total
+			previous = cumulative
+		}
+		if total == 0 {
+			cumulativeWeights = g.cumulativeWeights
+		}
+	}
+	total := cumulativeWeights[len(cumulativeWeights)-1]
+	n := g.intn(total)
+	return sort.Search(len(cumulativeWeights), func(i int) bool { return cumulativeWeights[i] > n })
+}
+
+// selectService picks the service for req. With selectTimeout, it waits for a service that is
+// healthy and isn't draining, and fails with ErrSelectTimeout if none becomes so in time.
+func (g *weightedGroup) selectService(req *http.Request) (int, error) {
+	if !g.health.enoughHealthy(g.draining.isDraining) {
+		return 0, ErrTooFewHealthyServices
+	}
+	var skip func(i int) bool
+	if g.draining != nil {
+		skip = g.draining.isDraining
+	}
+	if g.selectTimeout <= 0 {
+		return g.pick(skip), nil
+	}
+	unavailable := func(i int) bool {
+		return g.draining.isDraining(i) || !g.health.isHealthy(i)
+	}
+	timeout := time.NewTimer(g.selectTimeout)
+	defer timeout.Stop()
+	retry := time.NewTicker(selectRetryInterval)
+	defer retry.Stop()
+	for {
+		for i := range g.services {
+			if !unavailable(i) {
+				return g.pick(unavailable), nil
+			}
+		}
+		select {
+		case <-retry.C:
+		case <-timeout.C:
+			return 0, ErrSelectTimeout
+		case <-req.Context().Done():
+			return 0, req.Context().Err()
+		}
+	}
+}
+
+func (g *weightedGroup) RoundTrip(req *http.Request) (*http.Response, error) {
+	i, err := g.selectService(req)
+	if err != nil {
+		return nil, err
+	}
+	timer := g.responseTimes.start(i)
+	resp, err := g.services[i].RoundTrip(req)
+	timer.stop(err)
+	g.health.record(i, err)
+	g.draining.observe(i, resp)
+	return resp, err
+}
+
+func (g *weightedGroup) EstablishConnection(req *http.Request) (OriginConnection, *http.Response, error) {
+	i, err := g.selectService(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	timer := g.responseTimes.start(i)
+	conn, resp, err := g.services[i].EstablishConnection(req)
+	timer.stop(err)
+	g.health.record(i, err)
+	g.draining.observe(i, resp)
+	return conn, resp, err
+}
+
+func (g *weightedGroup) String() string {
+	return "group:" + g.name
+}
+
+// key identifies the group's services and weights, see serviceKey.
+func (g *weightedGroup) key() string {
+	members := make([]string, len(g.services))
+	previous := 0
+	for i, service := range g.services {
+		members[i] = fmt.Sprintf("%s*%d", serviceKey(service), g.cumulativeWeights[i]-previous)
+		previous = g.cumulativeWeights[i]
+	}
+	key := g.String() + "=" + strings.Join(members, ",")
+	if g.responseTimes != nil {
+		key += ";" + groupStrategyLeastTime
+	}
+	if g.health != nil {
+		key += fmt.Sprintf(";minHealthy=%d", g.health.minHealthy)
+	}
+	if g.selectTimeout > 0 {
+		key += ";selectTimeout=" + g.selectTimeout.String()
+	}
+	return key
+}
+
+func (g *weightedGroup) start(wg *sync.WaitGroup, log *zerolog.Logger, shutdownC <-chan struct{}, errC chan error, cfg OriginRequestConfig) error {
+	for _, service := range g.services {
+		if err := service.start(wg, log, shutdownC, errC, cfg); err != nil {
+			return errors.Wrapf(err, "Error starting %s of %s", service, g)
+		}
+	}
+	return nil
+}
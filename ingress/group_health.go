@@ -0,0 +1,73 @@
+package ingress
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTooFewHealthyServices is returned by groups with fewer healthy services than minHealthy.
+var ErrTooFewHealthyServices = errors.New("Too few of the group's services are healthy to serve the request")
+
+// groupHealth tracks which of a group's services failed their last request, for minHealthy.
+// A service that failed counts as unhealthy for failedServiceBackoff, and is then tried again.
+// Its methods are safe to call on a nil groupHealth, which counts every service as healthy.
+type groupHealth struct {
+	minHealthy int
+	clock      func() time.Time
+
+	lock     sync.Mutex
+	failedAt []time.Time
+}
+
+func newGroupHealth(minHealthy, numServices int) *groupHealth {
+	return &groupHealth{minHealthy: minHealthy, clock: time.Now, failedAt: make([]time.Time, numServices)}
+}
+
+// enoughHealthy checks if at least minHealthy services are healthy, not counting the services
+// that are draining.
+func (h *groupHealth) enoughHealthy(isDraining func(i int) bool) bool {
+	if h == nil {
+		return true
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	now := h.clock()
+	healthy := 0
+	for i := range h.failedAt {
+		if h.healthyAt(i, now) && !isDraining(i) {
+			healthy++
+		}
+	}
+	return healthy >= h.minHealthy
+}
+
+// isHealthy returns whether the service with the given index didn't fail recently.
+func (h *groupHealth) isHealthy(i int) bool {
+	if h == nil {
+		return true
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.healthyAt(i, h.clock())
+}
+
+// healthyAt is isHealthy for callers that hold the lock.
+func (h *groupHealth) healthyAt(i int, now time.Time) bool {
+	return h.failedAt[i].IsZero() || now.Sub(h.failedAt[i]) >= failedServiceBackoff
+}
+
+// record marks the service with the given index as unhealthy if its request failed, or as
+// healthy otherwise.
+func (h *groupHealth) record(i int, err error) {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if err != nil {
+		h.failedAt[i] = h.clock()
+	} else {
+		h.failedAt[i] = time.Time{}
+	}
+}
@@ -0,0 +1,186 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupMinHealthy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	ing, err := ParseIngress(MustReadIngress(`
+groups:
+  api:
+  - service: http://a.internal
+  - service: http://b.internal
+ingress:
+ - hostname: api.example.com
+   group: api
+   loadBalancer:
+     minHealthy: 2
+ - service: http_status:404
+`))
+	require.NoError(t, err)
+	// Both services are the same backend, but b's connections fail while it's down.
+	var bDown int32
+	ing.Rules[0].DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if atomic.LoadInt32(&bDown) == 1 && address == "b.internal:80" {
+			return nil, errors.New("connection refused")
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, backend.Listener.Addr().String())
+	}
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+	group := ing.Rules[0].Service.(*weightedGroup)
+	now := time.Unix(1600000000, 0)
+	group.health.clock = func() time.Time { return now }
+	picked := 0
+	group.intn = func(n int) int { return picked }
+	roundTrip := func() error {
+		req, err := http.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+		require.NoError(t, err)
+		resp, err := group.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	assert.NoError(t, roundTrip())
+	atomic.StoreInt32(&bDown, 1)
+	picked = 1
+	assert.Error(t, roundTrip())
+	// Only a is healthy, so even the requests a would get fail.
+	picked = 0
+	assert.Equal(t, ErrTooFewHealthyServices, roundTrip())
+
+	// b is tried again after failedServiceBackoff, and serves again once it's back.
+	atomic.StoreInt32(&bDown, 0)
+	now = now.Add(failedServiceBackoff)
+	picked = 1
+	assert.NoError(t, roundTrip())
+	picked = 0
+	assert.NoError(t, roundTrip())
+}
+
+func TestGroupSelectTimeout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	ing, err := ParseIngress(MustReadIngress(`
+groups:
+  api:
+  - service: http://a.internal
+  - service: http://b.internal
+ingress:
+ - hostname: api.example.com
+   group: api
+   loadBalancer:
+     selectTimeout: 200ms
+ - service: http_status:404
+`))
+	require.NoError(t, err)
+	ing.Rules[0].DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, backend.Listener.Addr().String())
+	}
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+	group := ing.Rules[0].Service.(*weightedGroup)
+	var nowLock sync.Mutex
+	now := time.Unix(1600000000, 0)
+	group.health.clock = func() time.Time {
+		nowLock.Lock()
+		defer nowLock.Unlock()
+		return now
+	}
+	roundTrip := func() error {
+		req, err := http.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+		require.NoError(t, err)
+		resp, err := group.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// With both services down, the request waits for selectTimeout and then gives up.
+	group.health.record(0, errors.New("connection refused"))
+	group.health.record(1, errors.New("connection refused"))
+	start := time.Now()
+	assert.Equal(t, ErrSelectTimeout, roundTrip())
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+
+	// A service that recovers while the request waits serves it.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		nowLock.Lock()
+		defer nowLock.Unlock()
+		now = now.Add(failedServiceBackoff)
+	}()
+	assert.NoError(t, roundTrip())
+}
+
+func TestParseMinHealthy(t *testing.T) {
+	for _, invalid := range []string{
+		`
+groups:
+  api:
+  - service: http://a.internal
+  - service: http://b.internal
+ingress:
+ - hostname: api.example.com
+   group: api
+   loadBalancer:
+     minHealthy: 3
+ - service: http_status:404
+`, `
+groups:
+  api:
+  - service: http://a.internal
+ingress:
+ - hostname: api.example.com
+   group: api
+   loadBalancer:
+     minHealthy: -1
+ - service: http_status:404
+`, `
+ingress:
+ - hostname: api.example.com
+   service: http://a.internal
+   loadBalancer:
+     minHealthy: 1
+ - service: http_status:404
+`, `
+groups:
+  api:
+  - service: http://a.internal
+ingress:
+ - hostname: api.example.com
+   group: api
+   loadBalancer:
+     selectTimeout: -1s
+ - service: http_status:404
+`,
+	} {
+		_, err := ParseIngress(MustReadIngress(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,241 @@
+package ingress
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+func TestWeightedGroupUsedByRules(t *testing.T) {
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name))
+		}))
+	}
+	a, b := newBackend("a"), newBackend("b")
+	defer a.Close()
+	defer b.Close()
+
+	rawYAML := fmt.Sprintf(`
+groups:
+  api:
+  - service: %s
+    weight: 3
+  - service: %s
+ingress:
+ - hostname: api.example.com
+   group: api
+ - hostname: www.example.com
+   path: ^/api/
+   group: api
+ - service: http_status:404
+`, a.URL, b.URL)
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "group:api", ing.Rules[0].Service.String())
+
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+	// Count through every random number, so each backend gets exactly its share.
+	next := 0
+	countingIntn := func(n int) int {
+		defer func() { next++ }()
+		return next % n
+	}
+	for _, rule := range ing.Rules[:2] {
+		rule.Service.(*weightedGroup).intn = countingIntn
+	}
+
+	seen := make(map[string]int)
+	for _, url := range []string{"http://api.example.com/users", "http://www.example.com/api/users"} {
+		for i := 0; i < 4; i++ {
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			rule, _ := ing.FindMatchingRuleForRequest(req)
+			resp, err := rule.Service.(HTTPOriginProxy).RoundTrip(req)
+			require.NoError(t, err)
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			resp.Body.Close()
+			seen[string(body)]++
+		}
+	}
+	assert.Equal(t, map[string]int{"a": 6, "b": 2}, seen)
+}
+
+func TestWeightedGroupPick(t *testing.T) {
+	group, err := newWeightedGroup("api", nil, "", "", config.IngressLoadBalancer{})
+	assert.Error(t, err)
+	assert.Nil(t, group)
+
+	rawYAML := `
+groups:
+  api:
+  - service: http://a.internal
+    weight: 2
+  - service: http://b.internal
+    weight: 1
+  - service: http://c.internal
+    weight: 3
+ingress:
+ - hostname: api.example.com
+   group: api
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	group = ing.Rules[0].Service.(*weightedGroup)
+	var picked []string
+	for n := 0; n < 6; n++ {
+		n := n
+		group.intn = func(total int) int {
+			assert.Equal(t, 6, total)
+			return n
+		}
+		picked = append(picked, group.services[group.pick(nil)].url.Host)
+	}
+	assert.Equal(t, []string{"a.internal", "a.internal", "b.internal", "c.internal", "c.internal", "c.internal"}, picked)
+}
+
+func TestParseGroupsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawYAML string
+	}{
+		{name: "undefined group", rawYAML: `
+ingress:
+ - hostname: api.example.com
+   group: api
+ - service: http_status:404
+`},
+		{name: "service and group", rawYAML: `
+groups:
+  api:
+  - service: http://a.internal
+ingress:
+ - hostname: api.example.com
+   service: http://b.internal
+   group: api
+ - service: http_status:404
+`},
+		{name: "catch-all group", rawYAML: `
+groups:
+  api:
+  - service: http://a.internal
+ingress:
+ - group: api
+`},
+		{name: "negative weight", rawYAML: `
+groups:
+  api:
+  - service: http://a.internal
+    weight: -1
+ingress:
+ - hostname: api.example.com
+   group: api
+ - service: http_status:404
+`},
+		{name: "non-HTTP service", rawYAML: `
+groups:
+  api:
+  - service: tcp://a.internal:5432
+ingress:
+ - hostname: api.example.com
+   group: api
+ - service: http_status:404
+`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseIngress(MustReadIngress(test.rawYAML))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestWeightedGroupEqual(t *testing.T) {
+	parse := func(weight int) Ingress {
+		ing, err := ParseIngress(MustReadIngress(fmt.Sprintf(`
+groups:
+  api:
+  - service: http://a.internal
+    weight: %d
+  - service: http://b.internal
+ingress:
+ - hostname: api.example.com
+   group: api
+ - service: http_status:404
+`, weight)))
+		require.NoError(t, err)
+		return ing
+	}
+	assert.True(t, parse(2).Equal(parse(2)))
+	assert.Equal(t, []int{0}, parse(2).Diff(parse(3)))
+}
+
+func TestWeightedGroupDrainsByRule(t *testing.T) {
+	draining := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-CF-Drain", "true")
+		_, _ = w.Write([]byte("draining"))
+	}))
+	defer draining.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("healthy"))
+	}))
+	defer healthy.Close()
+
+	ing, err := ParseIngress(MustReadIngress(fmt.Sprintf(`
+groups:
+  api:
+  - service: %s
+  - service: %s
+ingress:
+ - hostname: api.example.com
+   group: api
+   originRequest:
+     drainHeader: X-CF-Drain
+ - hostname: www.example.com
+   group: api
+   originRequest:
+     drainHeader: X-CF-Drain
+ - service: http_status:404
+`, draining.URL, healthy.URL)))
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+	for _, rule := range ing.Rules[:2] {
+		// Pick the first service that isn't skipped.
+		rule.Service.(*weightedGroup).intn = func(n int) int { return 0 }
+	}
+
+	roundTrip := func(host string) string {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+		require.NoError(t, err)
+		rule, _ := ing.FindMatchingRuleForRequest(req)
+		resp, err := rule.Service.(HTTPOriginProxy).RoundTrip(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+	assert.Equal(t, "draining", roundTrip("api.example.com"))
+	assert.Equal(t, "healthy", roundTrip("api.example.com"))
+	// The other rule hasn't seen the service drain yet.
+	assert.Equal(t, "draining", roundTrip("www.example.com"))
+	assert.Equal(t, "healthy", roundTrip("www.example.com"))
+}
@@ -0,0 +1,78 @@
+package ingress
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/net/http2"
+)
+
+// h2cService is an HTTP origin that speaks HTTP/2 without TLS (h2c), e.g. a gRPC server. It's
+// reached with prior knowledge, without an HTTP/1.1 upgrade.
+type h2cService struct {
+	url        *url.URL
+	hostHeader string
+	transport  *http2.Transport
+	signer     *requestSigner
+}
+
+func newH2CService(u *url.URL) *h2cService {
+	addPortIfMissing(u, 80)
+	return &h2cService{url: u}
+}
+
+func (o *h2cService) String() string {
+	return o.url.String()
+}
+
+func (o *h2cService) start(wg *sync.WaitGroup, log *zerolog.Logger, shutdownC <-chan struct{}, errC chan error, cfg OriginRequestConfig) error {
+	dial := cacheDNSFailures(limitConcurrentDials(cfg.dialer(), cfg.MaxConcurrentDials, cfg.DialQueueTimeout), cfg.DNSNegativeTTL)
+	o.transport = &http2.Transport{
+		// The http scheme is only allowed along with a dialer that doesn't start TLS.
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dial(context.Background(), network, addr)
+		},
+	}
+	cfg.connections.addIdlePool(o.transport.CloseIdleConnections)
+	o.hostHeader = cfg.HTTPHostHeader
+	o.signer = newRequestSigner(cfg.SignRequests)
+	return nil
+}
+
+func (o *h2cService) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Host = o.url.Host
+	req.URL.Scheme = "http"
+	if o.hostHeader != "" {
+		req.Host = o.hostHeader
+	}
+	o.signer.sign(req)
+	return o.transport.RoundTrip(req)
+}
+
+// checkNoTLSOptions rejects the originRequest options that configure TLS, which h2c doesn't use.
+func checkNoTLSOptions(cfg OriginRequestConfig) error {
+	options := []struct {
+		name  string
+		isSet bool
+	}{
+		{"originServerName", cfg.OriginServerName != ""},
+		{"caPool", cfg.CAPool != ""},
+		{"noTLSVerify", cfg.NoTLSVerify},
+		{"tlsVerifyMode", cfg.TLSVerifyMode != ""},
+		{"alpn", len(cfg.ALPN) > 0},
+		{"forceLocalTLS", cfg.ForceLocalTLS},
+	}
+	for _, option := range options {
+		if option.isSet {
+			return fmt.Errorf("h2c services don't use TLS, but %s is set", option.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package ingress
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+func TestH2CService(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s %s", r.Proto, r.Host)
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go (&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+
+	rawYAML := fmt.Sprintf(`
+ingress:
+ - hostname: grpc.example.com
+   service: h2c://%s
+ - service: http_status:404
+`, listener.Addr())
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	assert.IsType(t, &h2cService{}, ing.Rules[0].Service)
+
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+	req, err := http.NewRequest(http.MethodGet, "https://grpc.example.com/", nil)
+	require.NoError(t, err)
+	resp, err := ing.Rules[0].Service.(HTTPOriginProxy).RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0 grpc.example.com", string(body))
+}
+
+func TestParseH2CWithTLSOptions(t *testing.T) {
+	for _, option := range []string{"noTLSVerify: true", "originServerName: grpc.internal", "caPool: /etc/ca.pem", "tlsVerifyMode: warn", "alpn: [h2]"} {
+		rawYAML := `
+ingress:
+ - service: h2c://localhost:50051
+   originRequest:
+     ` + option + `
+`
+		_, err := ParseIngress(MustReadIngress(rawYAML))
+		assert.Error(t, err, option)
+	}
+
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: h2c://localhost
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "h2c://localhost:80", ing.Rules[0].Service.String())
+}
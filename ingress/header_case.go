@@ -0,0 +1,27 @@
+package ingress
+
+import (
+	"net/http"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+// withEyeballHeaderCase returns a shallow copy of req whose header names are spelled the way
+// the eyeball sent them, for origins that don't treat them as case-insensitive. The transport
+// writes header names as they are in the map, so this undoes textproto's canonicalization.
+func withEyeballHeaderCase(req *http.Request) *http.Request {
+	names := connection.HeaderNames(req.Context())
+	if len(names) == 0 {
+		return req
+	}
+	header := make(http.Header, len(req.Header))
+	for name, values := range req.Header {
+		if original, ok := names[name]; ok {
+			name = original
+		}
+		header[name] = values
+	}
+	req = req.WithContext(req.Context())
+	req.Header = header
+	return req
+}
@@ -0,0 +1,79 @@
+package ingress
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/textproto"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+func TestCanonicalizeHeaders(t *testing.T) {
+	// A raw origin, so the test sees the header names as they were written on the wire.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	headerLines := make(chan []string, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := textproto.NewReader(bufio.NewReader(conn))
+			var lines []string
+			for {
+				line, err := reader.ReadLine()
+				if err != nil || line == "" {
+					break
+				}
+				lines = append(lines, line)
+			}
+			headerLines <- lines
+			_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"))
+			conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name     string
+		setting  string
+		expected string
+	}{
+		{name: "default", setting: "true", expected: "X-Custom-Header: value"},
+		{name: "disabled", setting: "false", expected: "x-custom-HEADER: value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://` + listener.Addr().String() + `
+   originRequest:
+     canonicalizeHeaders: ` + tt.setting + `
+`))
+			require.NoError(t, err)
+			log := zerolog.Nop()
+			shutdownC := make(chan struct{})
+			defer close(shutdownC)
+			require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+			req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+			require.NoError(t, err)
+			req.Header.Set("x-custom-HEADER", "value")
+			req = req.WithContext(connection.WithHeaderNames(req.Context(), map[string]string{"X-Custom-Header": "x-custom-HEADER"}))
+
+			resp, err := ing.Rules[0].Service.(HTTPOriginProxy).RoundTrip(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Contains(t, <-headerLines, tt.expected)
+			assert.Equal(t, "value", req.Header.Get("X-Custom-Header"), "the request must not be changed")
+		})
+	}
+}
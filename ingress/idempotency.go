@@ -0,0 +1,215 @@
+package ingress
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxIdempotentResponseSize bounds how much of a response body is kept for replaying.
+	// Larger responses aren't deduplicated.
+	maxIdempotentResponseSize = 1 << 20
+	// maxIdempotentEntries and maxIdempotentCacheSize bound the responses kept for replaying
+	// per rule, since every request can bring a new key. Past either, the oldest responses are
+	// forgotten before their window ends.
+	maxIdempotentEntries   = 10000
+	maxIdempotentCacheSize = 32 << 20
+
+	// IdempotentReplayedHeader is set on responses replayed from the idempotency cache.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// IdempotencyCache replays the first response to requests that repeat an idempotency key, so
+// the origin sees each key at most once per window. With a zero window, the response is only
+// shared with the requests that repeated the key while the first one was in flight. Error
+// responses aren't replayed, so a retry reaches the origin. Its methods
+// are safe to call on a nil cache, which deduplicates nothing.
+type IdempotencyCache struct {
+	header string
+	window time.Duration
+	clock  func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]*idempotentEntry
+	// stored holds the entries with a response to replay, from the most recently stored. They
+	// all have the same window, so they expire from the back.
+	stored     *list.List
+	storedSize int
+}
+
+type idempotentEntry struct {
+	key string
+	// done is closed once the first request finished, after which response is set unless the
+	// request failed.
+	done     chan struct{}
+	response *CachedResponse
+	expires  time.Time
+	// element is the entry's element in stored, once it has a response to replay.
+	element *list.Element
+}
+
+// CachedResponse is an origin response kept for replaying.
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+func newIdempotencyCache(header string, window time.Duration) *IdempotencyCache {
+	if header == "" {
+		return nil
+	}
+	return &IdempotencyCache{
+		header:  header,
+		window:  window,
+		clock:   time.Now,
+		entries: make(map[string]*idempotentEntry),
+		stored:  list.New(),
+	}
+}
+
+// Claim returns the response to replay for req, if an earlier request had the same idempotency
+// key. Otherwise, if req has a key, it returns a PendingResponse that the caller must complete
+// after proxying req. Requests repeating a key whose first request is still in flight wait for
+// it to finish.
+func (c *IdempotencyCache) Claim(req *http.Request) (*CachedResponse, *PendingResponse) {
+	if c == nil {
+		return nil, nil
+	}
+	key := req.Header.Get(c.header)
+	if key == "" {
+		return nil, nil
+	}
+	for {
+		c.lock.Lock()
+		now := c.clock()
+		c.removeExpired(now)
+		entry, ok := c.entries[key]
+		if !ok {
+			entry = &idempotentEntry{key: key, done: make(chan struct{})}
+			c.entries[key] = entry
+			c.lock.Unlock()
+			return nil, &PendingResponse{cache: c, key: key, entry: entry}
+		}
+		c.lock.Unlock()
+
+		select {
+		case <-entry.done:
+		case <-req.Context().Done():
+			return nil, nil
+		}
+		if entry.response != nil {
+			return entry.response, nil
+		}
+		// The first request failed, so it was removed and this request can claim the key.
+	}
+}
+
+// removeExpired forgets the responses whose window ended.
+func (c *IdempotencyCache) removeExpired(now time.Time) {
+	for oldest := c.stored.Back(); oldest != nil; oldest = c.stored.Back() {
+		entry := oldest.Value.(*idempotentEntry)
+		if now.Before(entry.expires) {
+			return
+		}
+		c.remove(entry)
+	}
+}
+
+// store keeps entry's response for replaying, forgetting the oldest responses if there are too
+// many.
+func (c *IdempotencyCache) store(entry *idempotentEntry) {
+	entry.element = c.stored.PushFront(entry)
+	c.storedSize += len(entry.response.Body)
+	for c.stored.Len() > maxIdempotentEntries || c.storedSize > maxIdempotentCacheSize {
+		c.remove(c.stored.Back().Value.(*idempotentEntry))
+	}
+}
+
+func (c *IdempotencyCache) remove(entry *idempotentEntry) {
+	c.stored.Remove(entry.element)
+	c.storedSize -= len(entry.response.Body)
+	delete(c.entries, entry.key)
+}
+
+// PendingResponse records the response to the first request with an idempotency key. Its
+// methods are safe to call on a nil PendingResponse.
+type PendingResponse struct {
+	cache  *IdempotencyCache
+	key    string
+	entry  *idempotentEntry
+	status int
+	header http.Header
+	body   *limitedBuffer
+}
+
+// Record keeps the response status and headers, and returns a writer that keeps a copy of
+// the body written to w.
+func (p *PendingResponse) Record(status int, header http.Header, w io.Writer) io.Writer {
+	if p == nil {
+		return w
+	}
+	p.status = status
+	p.header = header.Clone()
+	p.body = &limitedBuffer{limit: maxIdempotentResponseSize}
+	return io.MultiWriter(w, p.body)
+}
+
+// Complete stores the recorded response for replaying, or forgets the key if no complete
+// response was recorded or the origin answered with a 5xx, so a retry reaches the origin.
+// Calling it again has no effect.
+func (p *PendingResponse) Complete() {
+	if p == nil {
+		return
+	}
+	c := p.cache
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	select {
+	case <-p.entry.done:
+		return
+	default:
+	}
+	if p.body != nil && !p.body.overflowed && p.status < http.StatusInternalServerError {
+		p.entry.response = &CachedResponse{Status: p.status, Header: p.header, Body: p.body.Bytes()}
+		p.entry.expires = c.clock().Add(c.window)
+	}
+	if p.entry.response == nil || c.window == 0 {
+		delete(c.entries, p.key)
+	} else {
+		c.removeExpired(c.clock())
+		c.store(p.entry)
+	}
+	close(p.entry.done)
+}
+
+// Abandon forgets the key, e.g. because the origin couldn't be reached.
+func (p *PendingResponse) Abandon() {
+	if p == nil {
+		return
+	}
+	p.body = nil
+	p.Complete()
+}
+
+// limitedBuffer buffers writes until they exceed its limit, then discards everything.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit      int
+	overflowed bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if !b.overflowed && b.Len()+len(p) > b.limit {
+		b.overflowed = true
+		b.Reset()
+	}
+	if b.overflowed {
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
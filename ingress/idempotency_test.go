@@ -0,0 +1,245 @@
+package ingress
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func idempotentRequest(t *testing.T, key string) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/charges", nil)
+	require.NoError(t, err)
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	return req
+}
+
+func TestIdempotencyCacheReplaysResponse(t *testing.T) {
+	clock := newFakeClock()
+	cache := newIdempotencyCache("Idempotency-Key", time.Minute)
+	cache.clock = clock.Now
+
+	cached, pending := cache.Claim(idempotentRequest(t, "a"))
+	require.Nil(t, cached)
+	require.NotNil(t, pending)
+	var eyeball bytes.Buffer
+	_, err := pending.Record(http.StatusCreated, http.Header{"Location": {"/charges/1"}}, &eyeball).Write([]byte("charge 1"))
+	require.NoError(t, err)
+	pending.Complete()
+	pending.Abandon()
+	assert.Equal(t, "charge 1", eyeball.String())
+
+	cached, pending = cache.Claim(idempotentRequest(t, "a"))
+	assert.Nil(t, pending)
+	require.NotNil(t, cached)
+	assert.Equal(t, http.StatusCreated, cached.Status)
+	assert.Equal(t, "/charges/1", cached.Header.Get("Location"))
+	assert.Equal(t, "charge 1", string(cached.Body))
+
+	// Another key, or no key, isn't deduplicated.
+	cached, pending = cache.Claim(idempotentRequest(t, "b"))
+	assert.Nil(t, cached)
+	assert.NotNil(t, pending)
+	cached, pending = cache.Claim(idempotentRequest(t, ""))
+	assert.Nil(t, cached)
+	assert.Nil(t, pending)
+
+	// After the window, the key reaches the origin again.
+	clock.Sleep(time.Minute)
+	cached, pending = cache.Claim(idempotentRequest(t, "a"))
+	assert.Nil(t, cached)
+	assert.NotNil(t, pending)
+}
+
+func TestIdempotencyCacheForgetsFailedRequests(t *testing.T) {
+	cache := newIdempotencyCache("Idempotency-Key", time.Minute)
+
+	_, pending := cache.Claim(idempotentRequest(t, "a"))
+	require.NotNil(t, pending)
+	pending.Abandon()
+	cached, pending := cache.Claim(idempotentRequest(t, "a"))
+	assert.Nil(t, cached)
+	require.NotNil(t, pending)
+
+	// Responses too large to keep aren't replayed either.
+	var eyeball bytes.Buffer
+	_, err := pending.Record(http.StatusOK, http.Header{}, &eyeball).Write(make([]byte, maxIdempotentResponseSize+1))
+	require.NoError(t, err)
+	pending.Complete()
+	assert.Equal(t, maxIdempotentResponseSize+1, eyeball.Len())
+	cached, pending = cache.Claim(idempotentRequest(t, "a"))
+	assert.Nil(t, cached)
+	assert.NotNil(t, pending)
+}
+
+func TestIdempotencyCacheForgetsErrorResponses(t *testing.T) {
+	cache := newIdempotencyCache("Idempotency-Key", time.Minute)
+
+	_, pending := cache.Claim(idempotentRequest(t, "a"))
+	require.NotNil(t, pending)
+	_, err := pending.Record(http.StatusBadGateway, http.Header{}, &bytes.Buffer{}).Write([]byte("bad gateway"))
+	require.NoError(t, err)
+	pending.Complete()
+	cached, pending := cache.Claim(idempotentRequest(t, "a"))
+	assert.Nil(t, cached)
+	assert.NotNil(t, pending)
+}
+
+func TestIdempotencyCacheBoundsResponses(t *testing.T) {
+	clock := newFakeClock()
+	cache := newIdempotencyCache("Idempotency-Key", time.Minute)
+	cache.clock = clock.Now
+	replay := func(key string, body []byte) {
+		_, pending := cache.Claim(idempotentRequest(t, key))
+		require.NotNil(t, pending, key)
+		_, err := pending.Record(http.StatusOK, http.Header{}, &bytes.Buffer{}).Write(body)
+		require.NoError(t, err)
+		pending.Complete()
+	}
+
+	for i := 0; i <= maxIdempotentEntries; i++ {
+		replay(fmt.Sprintf("count-%d", i), nil)
+	}
+	assert.Len(t, cache.entries, maxIdempotentEntries)
+	assert.NotContains(t, cache.entries, "count-0")
+	assert.Contains(t, cache.entries, fmt.Sprintf("count-%d", maxIdempotentEntries))
+
+	body := make([]byte, maxIdempotentResponseSize)
+	for i := 0; i <= maxIdempotentCacheSize/maxIdempotentResponseSize; i++ {
+		replay(fmt.Sprintf("size-%d", i), body)
+	}
+	assert.LessOrEqual(t, cache.storedSize, maxIdempotentCacheSize)
+	assert.NotContains(t, cache.entries, "size-0")
+	assert.Contains(t, cache.entries, fmt.Sprintf("size-%d", maxIdempotentCacheSize/maxIdempotentResponseSize))
+
+	// Once their window ends, the responses are swept.
+	clock.Sleep(time.Minute)
+	replay("later", nil)
+	assert.Len(t, cache.entries, 1)
+	assert.Equal(t, 1, cache.stored.Len())
+	assert.Zero(t, cache.storedSize)
+}
+
+func TestIdempotencyCacheWaitsForFirstRequest(t *testing.T) {
+	cache := newIdempotencyCache("Idempotency-Key", time.Minute)
+	_, first := cache.Claim(idempotentRequest(t, "a"))
+	require.NotNil(t, first)
+
+	replayed := make(chan *CachedResponse)
+	go func() {
+		cached, _ := cache.Claim(idempotentRequest(t, "a"))
+		replayed <- cached
+	}()
+	_, err := first.Record(http.StatusOK, http.Header{}, &bytes.Buffer{}).Write([]byte("first"))
+	require.NoError(t, err)
+	first.Complete()
+
+	select {
+	case cached := <-replayed:
+		require.NotNil(t, cached)
+		assert.Equal(t, "first", string(cached.Body))
+	case <-time.After(5 * time.Second):
+		t.Fatal("duplicate request didn't get the first response")
+	}
+
+	// A waiting request gives up when it's cancelled.
+	_, pending := cache.Claim(idempotentRequest(t, "b"))
+	require.NotNil(t, pending)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cached, waiting := cache.Claim(idempotentRequest(t, "b").WithContext(ctx))
+	assert.Nil(t, cached)
+	assert.Nil(t, waiting)
+}
+
+func TestIdempotencyCacheCoalescesOnly(t *testing.T) {
+	cache := newIdempotencyCache("Idempotency-Key", 0)
+	_, first := cache.Claim(idempotentRequest(t, "a"))
+	require.NotNil(t, first)
+
+	shared := make(chan *CachedResponse)
+	go func() {
+		cached, _ := cache.Claim(idempotentRequest(t, "a"))
+		shared <- cached
+	}()
+	// Let the duplicate request start waiting.
+	time.Sleep(10 * time.Millisecond)
+	_, err := first.Record(http.StatusOK, http.Header{}, &bytes.Buffer{}).Write([]byte("first"))
+	require.NoError(t, err)
+	first.Complete()
+
+	select {
+	case cached := <-shared:
+		require.NotNil(t, cached)
+		assert.Equal(t, "first", string(cached.Body))
+	case <-time.After(5 * time.Second):
+		t.Fatal("duplicate request didn't get the first response")
+	}
+
+	// Once the first request finished, its response isn't replayed.
+	cached, pending := cache.Claim(idempotentRequest(t, "a"))
+	assert.Nil(t, cached)
+	assert.NotNil(t, pending)
+}
+
+func TestNilIdempotencyCache(t *testing.T) {
+	var cache *IdempotencyCache
+	assert.Nil(t, newIdempotencyCache("", 0))
+	cached, pending := cache.Claim(idempotentRequest(t, "a"))
+	assert.Nil(t, cached)
+	assert.Nil(t, pending)
+
+	var eyeball bytes.Buffer
+	assert.Equal(t, &eyeball, pending.Record(http.StatusOK, http.Header{}, &eyeball))
+	pending.Complete()
+	pending.Abandon()
+}
+
+func TestParseIdempotency(t *testing.T) {
+	rawYAML := `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     idempotencyHeader: Idempotency-Key
+     idempotencyWindow: 5m
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	require.NotNil(t, ing.Rules[0].Idempotency)
+	assert.Equal(t, 5*time.Minute, ing.Rules[0].Idempotency.window)
+
+	rawYAML = `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     idempotencyHeader: Idempotency-Key
+     idempotencyCoalesceOnly: true
+`
+	ing, err = ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	require.NotNil(t, ing.Rules[0].Idempotency)
+	assert.Zero(t, ing.Rules[0].Idempotency.window)
+
+	for _, invalid := range []string{
+		"idempotencyHeader: Idempotency-Key",
+		"idempotencyHeader: Idempotency Key\n     idempotencyWindow: 5m",
+		"idempotencyWindow: 5m",
+		"idempotencyCoalesceOnly: true",
+	} {
+		rawYAML := `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     ` + invalid + `
+`
+		_, err := ParseIngress(MustReadIngress(rawYAML))
+		assert.Error(t, err, invalid)
+	}
+}
@@ -3,8 +3,10 @@ package ingress
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,9 +21,10 @@ import (
 
 var (
 	ErrNoIngressRules             = errors.New("The config file doesn't contain any ingress rules")
-	errLastRuleNotCatchAll        = errors.New("The last ingress rule must match all URLs (i.e. it should not have a hostname or path filter)")
+	errLastRuleNotCatchAll        = errors.New("The last ingress rule must match all URLs (i.e. it should not have a hostname, path or schedule filter)")
 	errBadWildcard                = errors.New("Hostname patterns can have at most one wildcard character (\"*\") and it can only be used for subdomains, e.g. \"*.example.com\"")
 	errHostnameContainsPort       = errors.New("Hostname cannot contain a port")
+	errDefaultServiceWithCatchAll = errors.New("defaultService is set, but the last ingress rule already matches all URLs")
 	ErrURLIncompatibleWithIngress = errors.New("You can't set the --url flag (or $TUNNEL_URL) when using multiple-origin ingress rules")
 )
 
@@ -50,6 +53,39 @@ func (ing Ingress) FindMatchingRule(hostname, path string) (*Rule, int) {
 	return &ing.Rules[i], i
 }
 
+// FindMatchingRuleForRequest is like FindMatchingRule, but also checks the conditions that
+// depend on the rest of the request, like bodyMatch. If the body had to be read, req.Body is
+// replaced so that it can still be proxied intact.
+func (ing Ingress) FindMatchingRuleForRequest(req *http.Request) (*Rule, int) {
+	body := newRequestBody(req)
+	defer body.restore()
+	return ing.findMatchingRule(req, body)
+}
+
+// FindMatchingRuleForStream is FindMatchingRuleForRequest for websocket and other stream
+// requests, whose body is the eyeball's side of the stream. It's never read, so rules with
+// bodyMatch don't match, rather than waiting for the eyeball to send something.
+func (ing Ingress) FindMatchingRuleForStream(req *http.Request) (*Rule, int) {
+	return ing.findMatchingRule(req, &requestBody{req: req, stream: true})
+}
+
+func (ing Ingress) findMatchingRule(req *http.Request, body *requestBody) (*Rule, int) {
+	hostname := req.Host
+	if host, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = host
+	}
+	for i, rule := range ing.Rules {
+		if rule.Matches(hostname, req.URL.Path) && rule.matchesRequest(req, body) {
+			ing.recordMatch(i, true)
+			return &rule, i
+		}
+	}
+
+	i := len(ing.Rules) - 1
+	ing.recordMatch(i, false)
+	return &ing.Rules[i], i
+}
+
 func matchHost(ruleHost, reqHost string) bool {
 	if ruleHost == reqHost {
 		return true
@@ -67,6 +103,15 @@ func matchHost(ruleHost, reqHost string) bool {
 type Ingress struct {
 	Rules    []Rule
 	defaults OriginRequestConfig
+	statuses *ruleStatuses
+	streams  *activeStreams
+	// rejectAbsoluteForm rejects requests with an absolute-form target, instead of
+	// normalizing them.
+	rejectAbsoluteForm bool
+	connections        *originConnections
+	rollout            *rollout
+	// rolledOut is set on the rules of a configuration being rolled out.
+	rolledOut bool
 }
 
 // NewSingleOrigin constructs an Ingress set with only one rule, constructed from
@@ -88,6 +133,8 @@ func NewSingleOrigin(c *cli.Context, allowURLFromArgs bool) (Ingress, error) {
 			},
 		},
 		defaults: defaults,
+		statuses: newRuleStatuses(1),
+		streams:  newActiveStreams(1),
 	}
 	return ing, err
 }
@@ -144,32 +191,84 @@ func (ing Ingress) IsSingleRule() bool {
 }
 
 // StartOrigins will start any origin services managed by cloudflared, e.g. proxy servers or Hello World.
+// Rules' DialContext must be set before.
 func (ing Ingress) StartOrigins(
 	wg *sync.WaitGroup,
 	log *zerolog.Logger,
 	shutdownC <-chan struct{},
 	errC chan error,
 ) error {
+	transports := newSharedTransports()
 	for _, rule := range ing.Rules {
-		if err := rule.Service.start(wg, log, shutdownC, errC, rule.Config); err != nil {
+		cfg := rule.Config
+		cfg.dialContext = rule.DialContext
+		cfg.transports = transports
+		cfg.connections = ing.connections
+		if err := rule.Service.start(wg, log, shutdownC, errC, cfg); err != nil {
 			return errors.Wrapf(err, "Error starting local service %s", rule.Service)
 		}
+		rule.warmUp(wg, log, shutdownC)
+	}
+	if ing.rollout != nil {
+		// The connections of both configurations count towards the same limit.
+		next := ing.rollout.next
+		next.connections = ing.connections
+		if err := next.StartOrigins(wg, log, shutdownC, errC); err != nil {
+			return errors.Wrap(err, "Error starting the origins being rolled out")
+		}
 	}
 	return nil
 }
 
+// LimitOriginConnections caps how many connections to origins the rules can have open at once,
+// across all of them. Dials beyond the cap fail with ErrTooManyOriginConnections. It must be
+// called before StartOrigins; a max of 0 doesn't limit them.
+func (ing *Ingress) LimitOriginConnections(max int) {
+	ing.connections = newOriginConnections(max)
+}
+
 // CatchAll returns the catch-all rule (i.e. the last rule)
 func (ing Ingress) CatchAll() *Rule {
 	return &ing.Rules[len(ing.Rules)-1]
 }
 
-func validate(ingress []config.UnvalidatedIngressRule, defaults OriginRequestConfig) (Ingress, error) {
+func validate(ingress []config.UnvalidatedIngressRule, groups map[string][]config.WeightedService, defaults OriginRequestConfig, diags *Diagnostics) (Ingress, error) {
 	rules := make([]Rule, len(ingress))
 	for i, r := range ingress {
 		cfg := setConfig(defaults, r.OriginRequest)
+		if err := cfg.validate(); err != nil {
+			return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid originRequest", i+1)
+		}
+		if err := cfg.loadClientCertificate(); err != nil {
+			return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid origin client certificate", i+1)
+		}
 		var service originService
 
-		if prefix := "unix:"; strings.HasPrefix(r.Service, prefix) {
+		if r.Group != "" {
+			members, ok := groups[r.Group]
+			if !ok {
+				return Ingress{}, fmt.Errorf("Rule #%d uses group %s, which isn't defined in groups", i+1, r.Group)
+			}
+			if r.Service != "" {
+				return Ingress{}, fmt.Errorf("Rule #%d sets both service and group, but only one can be used", i+1)
+			}
+			if i == len(ingress)-1 {
+				return Ingress{}, fmt.Errorf("Rule #%d is the catch-all rule, which can't use a group", i+1)
+			}
+			var loadBalancer config.IngressLoadBalancer
+			if r.LoadBalancer != nil {
+				loadBalancer = *r.LoadBalancer
+			}
+			group, err := newWeightedGroup(r.Group, members, r.GroupStrategy, cfg.DrainHeader, loadBalancer)
+			if err != nil {
+				return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid group", i+1)
+			}
+			service = group
+		} else if r.GroupStrategy != "" {
+			return Ingress{}, fmt.Errorf("Rule #%d sets groupStrategy, which needs a group", i+1)
+		} else if r.LoadBalancer != nil {
+			return Ingress{}, fmt.Errorf("Rule #%d sets loadBalancer, which needs a group", i+1)
+		} else if prefix := "unix:"; strings.HasPrefix(r.Service, prefix) {
 			// No validation necessary for unix socket filepath services
 			path := strings.TrimPrefix(r.Service, prefix)
 			service = &unixSocketPath{path: path}
@@ -180,6 +279,12 @@ func validate(ingress []config.UnvalidatedIngressRule, defaults OriginRequestCon
 			}
 			srv := newStatusCode(status)
 			service = &srv
+		} else if strings.HasPrefix(r.Service, s3Scheme+"://") {
+			bucket, err := newS3Bucket(r.Service)
+			if err != nil {
+				return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid service", i+1)
+			}
+			service = bucket
 		} else if r.Service == "hello_world" || r.Service == "hello-world" || r.Service == "helloworld" {
 			service = new(helloWorld)
 		} else if r.Service == ServiceSocksProxy {
@@ -219,34 +324,212 @@ func validate(ingress []config.UnvalidatedIngressRule, defaults OriginRequestCon
 			if u.Path != "" {
 				return Ingress{}, fmt.Errorf("%s is an invalid address, ingress rules don't support proxying to a different path on the origin service. The path will be the same as the eyeball request's path", r.Service)
 			}
-			if isHTTPService(u) {
+			if u.Scheme == "h2c" {
+				if err := checkNoTLSOptions(cfg); err != nil {
+					return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid originRequest", i+1)
+				}
+				service = newH2CService(u)
+			} else if isHTTPService(u) {
 				service = &httpService{url: u}
 			} else {
 				service = newTCPOverWSService(u)
 			}
 		}
 
+		if len(cfg.SNIRoutes) > 0 {
+			tcpService, ok := service.(*tcpOverWSService)
+			if !ok || tcpService.isBastion {
+				return Ingress{}, fmt.Errorf("Rule #%d sets sniRoutes, which is only supported by TCP services", i+1)
+			}
+			router, err := newSNIRouter(cfg.SNIRoutes)
+			if err != nil {
+				return Ingress{}, errors.Wrapf(err, "Rule #%d has invalid sniRoutes", i+1)
+			}
+			tcpService.sniRouter = router
+		}
+
+		if cfg.TCPTLS.ServerName != "" {
+			tcpService, ok := service.(*tcpOverWSService)
+			if !ok || tcpService.isBastion {
+				return Ingress{}, fmt.Errorf("Rule #%d sets tcpTLS, which is only supported by TCP services", i+1)
+			}
+			if tcpService.sniRouter != nil {
+				return Ingress{}, fmt.Errorf("Rule #%d sets both tcpTLS and sniRoutes, but sniRoutes needs the eyeball's own TLS connection", i+1)
+			}
+		}
+
 		if err := validateHostname(r, i, len(ingress)); err != nil {
 			return Ingress{}, err
 		}
 
+		referer, err := newRefererPolicy(r.Referer)
+		if err != nil {
+			return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid referer", i+1)
+		}
+
 		var pathRegex *regexp.Regexp
-		if r.Path != "" {
+		if r.Path != "" && r.PathTemplate != "" {
+			return Ingress{}, fmt.Errorf("Rule #%d sets both path and pathTemplate, but only one can be used", i+1)
+		} else if r.PathSuffix != "" && (r.Path != "" || r.PathTemplate != "") {
+			return Ingress{}, fmt.Errorf("Rule #%d sets pathSuffix and also path or pathTemplate, but only one can be used", i+1)
+		} else if r.PathSuffix != "" {
+			pathRegex = regexp.MustCompile(regexp.QuoteMeta(r.PathSuffix) + "$")
+		} else if r.Path != "" {
 			var err error
 			pathRegex, err = regexp.Compile(r.Path)
 			if err != nil {
 				return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid regex", i+1)
 			}
+		} else if r.PathTemplate != "" {
+			var err error
+			pathRegex, err = compilePathTemplate(r.PathTemplate)
+			if err != nil {
+				return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid pathTemplate", i+1)
+			}
+		}
+
+		var schedule *Schedule
+		if r.Schedule != nil {
+			var err error
+			schedule, err = newSchedule(*r.Schedule)
+			if err != nil {
+				return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid schedule", i+1)
+			}
+		}
+
+		var bodyMatch *BodyMatch
+		if r.BodyMatch != nil {
+			var err error
+			bodyMatch, err = newBodyMatch(*r.BodyMatch)
+			if err != nil {
+				return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid bodyMatch", i+1)
+			}
+		}
+
+		var shard *Shard
+		if r.Shard != nil {
+			var err error
+			shard, err = newShard(*r.Shard)
+			if err != nil {
+				return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid shard", i+1)
+			}
+		}
+
+		var pathSegments *PathSegments
+		if r.PathSegments != nil {
+			var err error
+			pathSegments, err = newPathSegments(*r.PathSegments)
+			if err != nil {
+				return Ingress{}, errors.Wrapf(err, "Rule #%d has invalid pathSegments", i+1)
+			}
+		}
+
+		accepts, err := newAccepts(r.Accepts)
+		if err != nil {
+			return Ingress{}, errors.Wrapf(err, "Rule #%d has invalid accepts", i+1)
+		}
+
+		responseRewrite, err := newResponseRewriter(cfg.ResponseRewrite)
+		if err != nil {
+			return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid responseRewrite", i+1)
+		}
+
+		wellKnown, err := newWellKnownFiles(cfg.WellKnown)
+		if err != nil {
+			return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid wellKnown", i+1)
+		}
+
+		acmeChallenges, err := newACMEChallenges(cfg.ACMEChallenge)
+		if err != nil {
+			return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid acmeChallenge", i+1)
+		}
+
+		jsonSchema, err := newJSONSchema(cfg.JSONSchema)
+		if err != nil {
+			return Ingress{}, errors.Wrapf(err, "Rule #%d has an invalid jsonSchema", i+1)
+		}
+
+		errorPages, err := newErrorPages(cfg.ErrorPages)
+		if err != nil {
+			return Ingress{}, errors.Wrapf(err, "Rule #%d has invalid errorPages", i+1)
+		}
+
+		slo, err := newSLO(cfg.SLOGoodStatuses)
+		if err != nil {
+			return Ingress{}, errors.Wrapf(err, "Rule #%d has invalid sloGoodStatuses", i+1)
 		}
 
 		rules[i] = Rule{
-			Hostname: r.Hostname,
-			Service:  service,
-			Path:     pathRegex,
-			Config:   cfg,
+			Index:             i,
+			Hostname:          r.Hostname,
+			Service:           service,
+			Path:              pathRegex,
+			PathTemplate:      r.PathTemplate,
+			PathSuffix:        r.PathSuffix,
+			Schedule:          schedule,
+			BodyMatch:         bodyMatch,
+			Shard:             shard,
+			PathSegments:      pathSegments,
+			RequireClientCert: r.RequireClientCert,
+			RequireTLS:        r.RequireTLS,
+			Accepts:           accepts,
+			Referer:           referer,
+			Config:            cfg,
+			Bandwidth:         newBandwidthLimiter(cfg.MaxBytesPerSecond),
+			Idempotency:       newIdempotencyCache(cfg.IdempotencyHeader, cfg.idempotencyWindow()),
+			Websockets:        newSessionLimiter(cfg.MaxWebsockets),
+			ResponseRewrite:   responseRewrite,
+			WellKnown:         wellKnown,
+			ACMEChallenges:    acmeChallenges,
+			ResponseTimeouts:  newResponseTimeouts(cfg.ResponseTimeoutByMethod),
+			AdaptiveTimeout:   newAdaptiveTimeout(cfg.AdaptiveTimeout),
+			StartupGrace:      newStartupGrace(cfg.StartupGrace),
+			Concurrency:       newConcurrencyLimiter(cfg.AdaptiveConcurrency),
+			JSONSchema:        jsonSchema,
+			ErrorPages:        errorPages,
+			SLO:               slo,
+			AccessLogs:        newAccessLogs(cfg.AccessLogFile),
+			DeadLetterLog:     newDeadLetterLog(cfg.DeadLetterLog),
 		}
 	}
-	return Ingress{Rules: rules, defaults: defaults}, nil
+	if err := sortByPriority(rules, ingress); err != nil {
+		return Ingress{}, err
+	}
+	if err := checkShards(rules); err != nil {
+		return Ingress{}, err
+	}
+	checkShadowedRules(rules, diags)
+	return Ingress{
+		Rules:    rules,
+		defaults: defaults,
+		statuses: newRuleStatuses(len(rules)),
+		streams:  newActiveStreams(len(rules)),
+	}, nil
+}
+
+// sortByPriority stably sorts rules, which were validated from unvalidated, so that rules with
+// higher priorities are matched first. The catch-all rule stays last.
+func sortByPriority(rules []Rule, unvalidated []config.UnvalidatedIngressRule) error {
+	last := len(unvalidated) - 1
+	if last < 0 {
+		return nil
+	}
+	if unvalidated[last].Priority != 0 {
+		return fmt.Errorf("Rule #%d is the catch-all rule, which is always matched last, so it can't have a priority", last+1)
+	}
+	order := make([]int, last)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return unvalidated[order[i]].Priority > unvalidated[order[j]].Priority
+	})
+	sorted := make([]Rule, last)
+	for i, original := range order {
+		sorted[i] = rules[original]
+	}
+	copy(rules, sorted)
+	return nil
 }
 
 func validateHostname(r config.UnvalidatedIngressRule, ruleIndex, totalRules int) error {
@@ -262,7 +545,7 @@ func validateHostname(r config.UnvalidatedIngressRule, ruleIndex, totalRules int
 	}
 
 	// The last rule should catch all hostnames.
-	isCatchAllRule := (r.Hostname == "" || r.Hostname == "*") && r.Path == ""
+	isCatchAllRule := isCatchAllRule(r)
 	isLastRule := ruleIndex == totalRules-1
 	if isLastRule && !isCatchAllRule {
 		return errLastRuleNotCatchAll
@@ -274,6 +557,11 @@ func validateHostname(r config.UnvalidatedIngressRule, ruleIndex, totalRules int
 	return nil
 }
 
+// isCatchAllRule checks if the rule matches every request.
+func isCatchAllRule(r config.UnvalidatedIngressRule) bool {
+	return (r.Hostname == "" || r.Hostname == "*") && r.Path == "" && r.PathTemplate == "" && r.PathSuffix == "" && r.Schedule == nil && r.BodyMatch == nil && r.Shard == nil && len(r.Accepts) == 0 && r.PathSegments == nil
+}
+
 type errRuleShouldNotBeCatchAll struct {
 	index    int
 	hostname string
@@ -287,10 +575,32 @@ func (e errRuleShouldNotBeCatchAll) Error() string {
 
 // ParseIngress parses ingress rules, but does not send HTTP requests to the origins.
 func ParseIngress(conf *config.Configuration) (Ingress, error) {
-	if len(conf.Ingress) == 0 {
-		return Ingress{}, ErrNoIngressRules
+	ing, _, err := ParseIngressWithDiagnostics(conf)
+	return ing, err
+}
+
+// ParseIngressWithDiagnostics is like ParseIngress, but also returns the non-fatal diagnostics
+// (e.g. warnings about rules that will never be matched) found while parsing. These are returned
+// even if parsing fails.
+func ParseIngressWithDiagnostics(conf *config.Configuration) (Ingress, Diagnostics, error) {
+	rules, err := resolveServiceRefs(conf.Ingress, conf.Services)
+	if err != nil {
+		return Ingress{}, nil, err
+	}
+	if conf.DefaultService != "" {
+		if len(rules) > 0 && isCatchAllRule(rules[len(rules)-1]) {
+			return Ingress{}, nil, errDefaultServiceWithCatchAll
+		}
+		// Copy the rules, so the configuration isn't changed.
+		rules = append(append([]config.UnvalidatedIngressRule(nil), rules...), config.UnvalidatedIngressRule{Service: conf.DefaultService})
+	}
+	if len(rules) == 0 {
+		return Ingress{}, nil, ErrNoIngressRules
 	}
-	return validate(conf.Ingress, originRequestFromYAML(conf.OriginRequest))
+	var diags Diagnostics
+	ing, err := validate(rules, conf.Groups, originRequestFromYAML(conf.OriginRequest), &diags)
+	ing.rejectAbsoluteForm = conf.RejectAbsoluteForm
+	return ing, diags, err
 }
 
 func isHTTPService(url *url.URL) bool {
@@ -67,12 +67,36 @@ ingress:
 					Config:   defaultConfig,
 				},
 				{
+					Index:    1,
 					Hostname: "*",
 					Service:  &httpService{url: localhost8001},
 					Config:   defaultConfig,
 				},
 			},
 		},
+		{
+			name: "requireClientCert",
+			args: args{rawYAML: `
+ingress:
+ - hostname: tunnel1.example.com
+   service: https://localhost:8000
+   requireClientCert: true
+ - service: https://localhost:8001
+`},
+			want: []Rule{
+				{
+					Hostname:          "tunnel1.example.com",
+					Service:           &httpService{url: localhost8000},
+					RequireClientCert: true,
+					Config:            defaultConfig,
+				},
+				{
+					Index:   1,
+					Service: &httpService{url: localhost8001},
+					Config:  defaultConfig,
+				},
+			},
+		},
 		{
 			name: "Extra keys",
 			args: args{rawYAML: `
@@ -243,11 +267,13 @@ ingress:
 					Config:   defaultConfig,
 				},
 				{
+					Index:    1,
 					Hostname: "tcp2.foo.com",
 					Service:  newTCPOverWSService(MustParseURL(t, "tcp://localhost:8000")),
 					Config:   defaultConfig,
 				},
 				{
+					Index:   2,
 					Service: &fourOhFour,
 					Config:  defaultConfig,
 				},
@@ -327,6 +353,7 @@ ingress:
 					Config:   defaultConfig,
 				},
 				{
+					Index:   1,
 					Service: &fourOhFour,
 					Config:  defaultConfig,
 				},
@@ -348,6 +375,7 @@ ingress:
 					Config:   setConfig(originRequestFromYAML(config.OriginRequestConfig{}), config.OriginRequestConfig{BastionMode: &tr}),
 				},
 				{
+					Index:   1,
 					Service: &fourOhFour,
 					Config:  defaultConfig,
 				},
@@ -368,11 +396,95 @@ ingress:
 					Config:   setConfig(originRequestFromYAML(config.OriginRequestConfig{}), config.OriginRequestConfig{BastionMode: &tr}),
 				},
 				{
+					Index:   1,
 					Service: &fourOhFour,
 					Config:  defaultConfig,
 				},
 			},
 		},
+		{
+			name: "Negative followRedirects",
+			args: args{rawYAML: `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     followRedirects: -1
+`},
+			wantErr: true,
+		},
+		{
+			name: "Invalid tlsVerifyMode",
+			args: args{rawYAML: `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     tlsVerifyMode: lenient
+`},
+			wantErr: true,
+		},
+		{
+			name: "Negative readBufferBytes",
+			args: args{rawYAML: `
+ingress:
+ - service: tcp://localhost:8000
+   originRequest:
+     readBufferBytes: -1
+`},
+			wantErr: true,
+		},
+		{
+			name: "signRequests header without key",
+			args: args{rawYAML: `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     signRequests:
+       header: X-CF-Signature
+`},
+			wantErr: true,
+		},
+		{
+			name: "Invalid signRequests header",
+			args: args{rawYAML: `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     signRequests:
+       key: secret
+       header: "X CF Signature"
+`},
+			wantErr: true,
+		},
+		{
+			name: "Unsupported alpn protocol",
+			args: args{rawYAML: `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     alpn: [h2, spdy/3]
+`},
+			wantErr: true,
+		},
+		{
+			name: "Repeated alpn protocol",
+			args: args{rawYAML: `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     alpn: [http/1.1, h2, http/1.1]
+`},
+			wantErr: true,
+		},
+		{
+			name: "Negative maxConcurrentDials",
+			args: args{rawYAML: `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     maxConcurrentDials: -10
+`},
+			wantErr: true,
+		},
 		{
 			name: "Hostname contains port",
 			args: args{rawYAML: `
@@ -397,6 +509,48 @@ ingress:
 	}
 }
 
+func TestParseIngressDefaultService(t *testing.T) {
+	conf := MustReadIngress(`
+defaultService: http_status:404
+ingress:
+ - hostname: tunnel1.example.com
+   service: https://localhost:8000
+ - hostname: "*"
+   path: ^/api/
+   service: https://localhost:8001
+`)
+	ing, err := ParseIngress(conf)
+	require.NoError(t, err)
+	require.Len(t, ing.Rules, 3)
+	assert.Equal(t, "HTTP 404", ing.CatchAll().Service.String())
+	assert.Len(t, conf.Ingress, 2, "the configured rules must not change")
+
+	// The catch-all is the only rule.
+	ing, err = ParseIngress(MustReadIngress(`
+defaultService: https://localhost:8000
+`))
+	require.NoError(t, err)
+	require.Len(t, ing.Rules, 1)
+	assert.Equal(t, "https://localhost:8000", ing.Rules[0].Service.String())
+
+	_, err = ParseIngress(MustReadIngress(`
+defaultService: http_status:404
+ingress:
+ - hostname: tunnel1.example.com
+   service: https://localhost:8000
+ - service: https://localhost:8001
+`))
+	assert.Equal(t, errDefaultServiceWithCatchAll, err)
+
+	_, err = ParseIngress(MustReadIngress(`
+defaultService: localhost:8000
+ingress:
+ - hostname: tunnel1.example.com
+   service: https://localhost:8000
+`))
+	assert.Error(t, err)
+}
+
 func TestSingleOriginSetsConfig(t *testing.T) {
 	flagSet := flag.NewFlagSet(t.Name(), flag.PanicOnError)
 	flagSet.Bool("hello-world", true, "")
@@ -0,0 +1,93 @@
+package ingress
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// The values of ipPreference.
+const (
+	IPPreferenceIPv4Only  = "ipv4-only"
+	IPPreferenceIPv6Only  = "ipv6-only"
+	IPPreferenceIPv4First = "ipv4-first"
+	IPPreferenceIPv6First = "ipv6-first"
+)
+
+// ipPreferenceDialer resolves hostnames itself, to dial their addresses one after another in
+// the preferred order.
+type ipPreferenceDialer struct {
+	preference string
+	lookup     func(ctx context.Context, host string) ([]net.IPAddr, error)
+	dial       dialFunc
+}
+
+// preferIPFamily makes dial connect to the addresses of the IP families allowed by
+// preference, preferred family first.
+func preferIPFamily(dial dialFunc, preference string) dialFunc {
+	if preference == "" {
+		return dial
+	}
+	d := ipPreferenceDialer{preference: preference, lookup: net.DefaultResolver.LookupIPAddr, dial: dial}
+	return d.dialContext
+}
+
+func (d *ipPreferenceDialer) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" && network != "udp" {
+		return d.dial(ctx, network, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []net.IPAddr
+	if ip := net.ParseIP(host); ip != nil {
+		addrs = []net.IPAddr{{IP: ip}}
+	} else if addrs, err = d.lookup(ctx, host); err != nil {
+		return nil, err
+	}
+	ordered := orderByIPPreference(addrs, d.preference)
+	if len(ordered) == 0 {
+		return nil, fmt.Errorf("%s has no address allowed by ipPreference %s", host, d.preference)
+	}
+	return dialEach(ctx, d.dial, network, ordered, port)
+}
+
+// dialEach dials the addresses one after another, until one of them connects.
+func dialEach(ctx context.Context, dial dialFunc, network string, addrs []net.IPAddr, port string) (net.Conn, error) {
+	var lastErr error
+	for _, ipAddr := range addrs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		conn, err := dial(ctx, network, net.JoinHostPort(ipAddr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// orderByIPPreference drops the addresses of a family the preference doesn't allow, and moves
+// those of the preferred family first. Within a family, the resolver's order is kept.
+func orderByIPPreference(addrs []net.IPAddr, preference string) []net.IPAddr {
+	var ipv4, ipv6 []net.IPAddr
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			ipv4 = append(ipv4, addr)
+		} else {
+			ipv6 = append(ipv6, addr)
+		}
+	}
+	switch preference {
+	case IPPreferenceIPv4Only:
+		return ipv4
+	case IPPreferenceIPv6Only:
+		return ipv6
+	case IPPreferenceIPv6First:
+		return append(ipv6, ipv4...)
+	default:
+		return append(ipv4, ipv6...)
+	}
+}
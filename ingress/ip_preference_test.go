@@ -0,0 +1,103 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPPreferenceDialer(t *testing.T) {
+	lookup := func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		require.Equal(t, "origin.example.com", host)
+		return []net.IPAddr{
+			{IP: net.ParseIP("192.0.2.1")},
+			{IP: net.ParseIP("2001:db8::1")},
+			{IP: net.ParseIP("192.0.2.2")},
+			{IP: net.ParseIP("2001:db8::2")},
+		}, nil
+	}
+	errUnreachable := errors.New("unreachable")
+
+	tests := []struct {
+		preference string
+		// reachable is the only address that accepts connections, if set.
+		reachable      string
+		expectAttempts []string
+		expectErr      bool
+	}{
+		{
+			preference:     IPPreferenceIPv6First,
+			reachable:      "[2001:db8::1]:443",
+			expectAttempts: []string{"[2001:db8::1]:443"},
+		},
+		{
+			preference:     IPPreferenceIPv6First,
+			reachable:      "192.0.2.1:443",
+			expectAttempts: []string{"[2001:db8::1]:443", "[2001:db8::2]:443", "192.0.2.1:443"},
+		},
+		{
+			preference:     IPPreferenceIPv4First,
+			reachable:      "[2001:db8::2]:443",
+			expectAttempts: []string{"192.0.2.1:443", "192.0.2.2:443", "[2001:db8::1]:443", "[2001:db8::2]:443"},
+		},
+		{
+			preference:     IPPreferenceIPv4Only,
+			reachable:      "[2001:db8::1]:443",
+			expectAttempts: []string{"192.0.2.1:443", "192.0.2.2:443"},
+			expectErr:      true,
+		},
+		{
+			preference:     IPPreferenceIPv6Only,
+			reachable:      "[2001:db8::2]:443",
+			expectAttempts: []string{"[2001:db8::1]:443", "[2001:db8::2]:443"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.preference+" "+test.reachable, func(t *testing.T) {
+			var attempts []string
+			dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+				attempts = append(attempts, addr)
+				if addr != test.reachable {
+					return nil, errUnreachable
+				}
+				client, server := net.Pipe()
+				server.Close()
+				return client, nil
+			}
+			d := ipPreferenceDialer{preference: test.preference, lookup: lookup, dial: dial}
+			conn, err := d.dialContext(context.Background(), "tcp", "origin.example.com:443")
+			assert.Equal(t, test.expectAttempts, attempts)
+			if test.expectErr {
+				assert.Equal(t, errUnreachable, err)
+				return
+			}
+			require.NoError(t, err)
+			conn.Close()
+		})
+	}
+}
+
+func TestIPPreferenceDialerNoAllowedAddress(t *testing.T) {
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		t.Fatalf("dialed %s", addr)
+		return nil, nil
+	}
+	d := ipPreferenceDialer{preference: IPPreferenceIPv6Only, dial: dial}
+	_, err := d.dialContext(context.Background(), "tcp", "127.0.0.1:8080")
+	assert.Error(t, err)
+}
+
+func TestParseIPPreferenceInvalid(t *testing.T) {
+	rawYAML := `
+ingress:
+ - service: http://localhost:8080
+   originRequest:
+     ipPreference: ipv6
+`
+	_, err := ParseIngress(MustReadIngress(rawYAML))
+	assert.Error(t, err)
+}
@@ -0,0 +1,424 @@
+package ingress
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// jsonSchemaMaxBytes bounds the request bodies that are buffered to be validated.
+const jsonSchemaMaxBytes = 1 << 20
+
+// ErrJSONBodyTooLarge is returned for request bodies that are too large to be validated.
+var ErrJSONBodyTooLarge = fmt.Errorf("request body is larger than the %d bytes that can be validated against the JSON schema", jsonSchemaMaxBytes)
+
+// jsonSchemaAnnotations are keywords that describe a schema without constraining it.
+var jsonSchemaAnnotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// JSONSchema validates request bodies against a JSON Schema. It supports the keywords that
+// constrain a document's structure: type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength, pattern, minimum,
+// maximum, exclusiveMinimum, exclusiveMaximum, allOf, anyOf, oneOf and not. Schemas with other
+// keywords, like $ref, are rejected rather than half enforced. Its methods are safe to call on a
+// nil JSONSchema, which accepts every request.
+type JSONSchema struct {
+	filename string
+	root     *schemaNode
+}
+
+type schemaNode struct {
+	// never is set by the false schema, which nothing matches.
+	never bool
+
+	types    []string
+	enum     []interface{}
+	hasConst bool
+	constant interface{}
+
+	properties           map[string]*schemaNode
+	required             []string
+	additionalProperties *schemaNode
+	items                *schemaNode
+	minItems, maxItems   *int
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+
+	allOf, anyOf, oneOf []*schemaNode
+	not                 *schemaNode
+}
+
+func newJSONSchema(filename string) (*JSONSchema, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading the JSON schema")
+	}
+	var schema interface{}
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return nil, errors.Wrapf(err, "%s isn't valid JSON", filename)
+	}
+	root, err := compileSchema(schema, "#")
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s is an invalid JSON schema", filename)
+	}
+	return &JSONSchema{filename: filename, root: root}, nil
+}
+
+func compileSchema(schema interface{}, location string) (*schemaNode, error) {
+	if b, ok := schema.(bool); ok {
+		return &schemaNode{never: !b}, nil
+	}
+	keywords, ok := schema.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object or a boolean", location)
+	}
+	var node schemaNode
+	for keyword, value := range keywords {
+		at := location + "/" + keyword
+		var err error
+		switch keyword {
+		case "type":
+			node.types, err = compileTypes(value, at)
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s must be an array", at)
+			}
+			node.enum = values
+		case "const":
+			node.hasConst, node.constant = true, value
+		case "properties":
+			properties, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s must be an object", at)
+			}
+			node.properties = make(map[string]*schemaNode, len(properties))
+			for name, property := range properties {
+				if node.properties[name], err = compileSchema(property, at+"/"+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			node.required, err = compileStrings(value, at)
+		case "additionalProperties":
+			node.additionalProperties, err = compileSchema(value, at)
+		case "items":
+			node.items, err = compileSchema(value, at)
+		case "minItems":
+			node.minItems, err = compileCount(value, at)
+		case "maxItems":
+			node.maxItems, err = compileCount(value, at)
+		case "minLength":
+			node.minLength, err = compileCount(value, at)
+		case "maxLength":
+			node.maxLength, err = compileCount(value, at)
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string", at)
+			}
+			if node.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, errors.Wrapf(err, "%s is an invalid pattern", at)
+			}
+		case "minimum":
+			node.minimum, err = compileNumber(value, at)
+		case "maximum":
+			node.maximum, err = compileNumber(value, at)
+		case "exclusiveMinimum":
+			node.exclusiveMinimum, err = compileNumber(value, at)
+		case "exclusiveMaximum":
+			node.exclusiveMaximum, err = compileNumber(value, at)
+		case "allOf":
+			node.allOf, err = compileSchemas(value, at)
+		case "anyOf":
+			node.anyOf, err = compileSchemas(value, at)
+		case "oneOf":
+			node.oneOf, err = compileSchemas(value, at)
+		case "not":
+			node.not, err = compileSchema(value, at)
+		default:
+			if !jsonSchemaAnnotations[keyword] {
+				return nil, fmt.Errorf("%s is an unsupported keyword", at)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &node, nil
+}
+
+func compileTypes(value interface{}, location string) ([]string, error) {
+	types, err := compileStrings(value, location)
+	if s, ok := value.(string); ok {
+		types, err = []string{s}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		switch t {
+		case "null", "boolean", "object", "array", "number", "integer", "string":
+		default:
+			return nil, fmt.Errorf("%s has an unknown type %q", location, t)
+		}
+	}
+	return types, nil
+}
+
+func compileStrings(value interface{}, location string) ([]string, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings", location)
+	}
+	strs := make([]string, len(values))
+	for i, v := range values {
+		if strs[i], ok = v.(string); !ok {
+			return nil, fmt.Errorf("%s must be an array of strings", location)
+		}
+	}
+	return strs, nil
+}
+
+func compileSchemas(value interface{}, location string) ([]*schemaNode, error) {
+	schemas, ok := value.([]interface{})
+	if !ok || len(schemas) == 0 {
+		return nil, fmt.Errorf("%s must be a non-empty array of schemas", location)
+	}
+	nodes := make([]*schemaNode, len(schemas))
+	for i, schema := range schemas {
+		var err error
+		if nodes[i], err = compileSchema(schema, location+"/"+strconv.Itoa(i)); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+func compileNumber(value interface{}, location string) (*float64, error) {
+	n, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a number", location)
+	}
+	return &n, nil
+}
+
+func compileCount(value interface{}, location string) (*int, error) {
+	n, ok := value.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("%s must be a non-negative integer", location)
+	}
+	count := int(n)
+	return &count, nil
+}
+
+func (s *JSONSchema) String() string {
+	if s == nil {
+		return ""
+	}
+	return s.filename
+}
+
+// ValidateRequest checks that the JSON body of req matches the schema. Requests without a body,
+// like most GETs, aren't validated. The body is put back, so it can still be proxied.
+func (s *JSONSchema) ValidateRequest(req *http.Request) error {
+	if s == nil {
+		return nil
+	}
+	body := newRequestBody(req)
+	defer body.restore()
+	data, complete := body.peek(jsonSchemaMaxBytes)
+	if !complete {
+		return ErrJSONBodyTooLarge
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return errors.Wrap(err, "request body isn't valid JSON")
+	}
+	if err := s.root.validate(document, "$"); err != nil {
+		return errors.Wrap(err, "request body doesn't match the JSON schema")
+	}
+	return nil
+}
+
+func (n *schemaNode) validate(value interface{}, path string) error {
+	if n.never {
+		return fmt.Errorf("%s isn't allowed", path)
+	}
+	if len(n.types) > 0 && !hasJSONType(value, n.types) {
+		return fmt.Errorf("%s should be of type %s", path, joinTypes(n.types))
+	}
+	if n.enum != nil && !containsJSONValue(n.enum, value) {
+		return fmt.Errorf("%s should be one of the enum values", path)
+	}
+	if n.hasConst && !reflect.DeepEqual(n.constant, value) {
+		return fmt.Errorf("%s should be the constant value", path)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range n.required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s is missing the required property %q", path, name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		// Sorted, so the same body is always rejected with the same error.
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := n.properties[name]
+			if !ok {
+				property = n.additionalProperties
+			}
+			if property == nil {
+				continue
+			}
+			if property.never && !ok {
+				return fmt.Errorf("%s has the unexpected property %q", path, name)
+			}
+			if err := property.validate(v[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if n.minItems != nil && len(v) < *n.minItems {
+			return fmt.Errorf("%s should have at least %d items", path, *n.minItems)
+		}
+		if n.maxItems != nil && len(v) > *n.maxItems {
+			return fmt.Errorf("%s should have at most %d items", path, *n.maxItems)
+		}
+		if n.items != nil {
+			for i, item := range v {
+				if err := n.items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n.minLength != nil && length < *n.minLength {
+			return fmt.Errorf("%s should be at least %d characters long", path, *n.minLength)
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			return fmt.Errorf("%s should be at most %d characters long", path, *n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(v) {
+			return fmt.Errorf("%s should match the pattern %s", path, n.pattern)
+		}
+	case float64:
+		if n.minimum != nil && v < *n.minimum {
+			return fmt.Errorf("%s should be at least %v", path, *n.minimum)
+		}
+		if n.maximum != nil && v > *n.maximum {
+			return fmt.Errorf("%s should be at most %v", path, *n.maximum)
+		}
+		if n.exclusiveMinimum != nil && v <= *n.exclusiveMinimum {
+			return fmt.Errorf("%s should be greater than %v", path, *n.exclusiveMinimum)
+		}
+		if n.exclusiveMaximum != nil && v >= *n.exclusiveMaximum {
+			return fmt.Errorf("%s should be less than %v", path, *n.exclusiveMaximum)
+		}
+	}
+
+	for _, schema := range n.allOf {
+		if err := schema.validate(value, path); err != nil {
+			return err
+		}
+	}
+	if n.anyOf != nil && countMatches(n.anyOf, value, path) == 0 {
+		return fmt.Errorf("%s should match at least one schema of anyOf", path)
+	}
+	if n.oneOf != nil && countMatches(n.oneOf, value, path) != 1 {
+		return fmt.Errorf("%s should match exactly one schema of oneOf", path)
+	}
+	if n.not != nil && n.not.validate(value, path) == nil {
+		return fmt.Errorf("%s shouldn't match the schema of not", path)
+	}
+	return nil
+}
+
+func countMatches(schemas []*schemaNode, value interface{}, path string) int {
+	matches := 0
+	for _, schema := range schemas {
+		if schema.validate(value, path) == nil {
+			matches++
+		}
+	}
+	return matches
+}
+
+func hasJSONType(value interface{}, types []string) bool {
+	for _, t := range types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	joined := types[0]
+	for _, t := range types[1 : len(types)-1] {
+		joined += ", " + t
+	}
+	return joined + " or " + types[len(types)-1]
+}
+
+func containsJSONValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
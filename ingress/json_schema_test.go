@@ -0,0 +1,118 @@
+package ingress
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJSONSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["name", "quantity"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 10},
+    "quantity": {"type": "integer", "minimum": 1, "exclusiveMaximum": 100},
+    "sku": {"type": "string", "pattern": "^[A-Z]{3}-[0-9]+$"},
+    "color": {"enum": ["red", "green"]},
+    "tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+    "note": {"anyOf": [{"type": "string"}, {"type": "null"}]}
+  }
+}`
+
+func writeJSONSchema(t *testing.T, schema string) string {
+	filename := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(schema), 0600))
+	return filename
+}
+
+func TestJSONSchemaValidateRequest(t *testing.T) {
+	schema, err := newJSONSchema(writeJSONSchema(t, testJSONSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		body        string
+		expectError string
+	}{
+		{body: `{"name": "widget", "quantity": 3}`},
+		{body: `{"name": "widget", "quantity": 3, "sku": "ABC-12", "color": "red", "tags": ["a"], "note": null}`},
+		{body: ""},
+		{body: `{"name": "widget"}`, expectError: `$ is missing the required property "quantity"`},
+		{body: `{"name": 7, "quantity": 3}`, expectError: "$.name should be of type string"},
+		{body: `{"name": "", "quantity": 3}`, expectError: "$.name should be at least 1 characters long"},
+		{body: `{"name": "widget", "quantity": 2.5}`, expectError: "$.quantity should be of type integer"},
+		{body: `{"name": "widget", "quantity": 100}`, expectError: "$.quantity should be less than 100"},
+		{body: `{"name": "widget", "quantity": 3, "sku": "abc"}`, expectError: "$.sku should match the pattern"},
+		{body: `{"name": "widget", "quantity": 3, "color": "blue"}`, expectError: "$.color should be one of the enum values"},
+		{body: `{"name": "widget", "quantity": 3, "tags": ["a", 1]}`, expectError: "$.tags[1] should be of type string"},
+		{body: `{"name": "widget", "quantity": 3, "tags": ["a", "b", "c"]}`, expectError: "$.tags should have at most 2 items"},
+		{body: `{"name": "widget", "quantity": 3, "note": 1}`, expectError: "$.note should match at least one schema of anyOf"},
+		{body: `{"name": "widget", "quantity": 3, "price": 1}`, expectError: `$ has the unexpected property "price"`},
+		{body: `[]`, expectError: "$ should be of type object"},
+		{body: `{"name": `, expectError: "request body isn't valid JSON"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodPost, "http://api.example.com/orders", strings.NewReader(test.body))
+		require.NoError(t, err)
+		err = schema.ValidateRequest(req)
+		if test.expectError == "" {
+			assert.NoError(t, err, test.body)
+		} else if assert.Error(t, err, test.body) {
+			assert.Contains(t, err.Error(), test.expectError, test.body)
+		}
+		// The body can still be proxied after it was validated.
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, test.body, string(body))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "http://api.example.com/orders", strings.NewReader(strings.Repeat(" ", jsonSchemaMaxBytes+1)))
+	require.NoError(t, err)
+	assert.Equal(t, ErrJSONBodyTooLarge, schema.ValidateRequest(req))
+
+	var unset *JSONSchema
+	assert.NoError(t, unset.ValidateRequest(req))
+}
+
+func TestParseJSONSchema(t *testing.T) {
+	for _, invalid := range []string{
+		`not json`,
+		`[]`,
+		`{"type": "text"}`,
+		`{"$ref": "#/definitions/order"}`,
+		`{"properties": {"name": {"minLength": -1}}}`,
+		`{"pattern": "("}`,
+		`{"anyOf": []}`,
+	} {
+		_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     jsonSchema: ` + writeJSONSchema(t, invalid) + `
+`))
+		assert.Error(t, err, invalid)
+	}
+
+	_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     jsonSchema: /nonexistent/schema.json
+`))
+	assert.Error(t, err)
+
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     jsonSchema: ` + writeJSONSchema(t, testJSONSchema) + `
+`))
+	require.NoError(t, err)
+	assert.NotNil(t, ing.Rules[0].JSONSchema)
+}
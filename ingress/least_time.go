@@ -0,0 +1,111 @@
+package ingress
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// responseTimeWeight is how much each new response time moves a service's average.
+	responseTimeWeight = 0.3
+	// failedServiceBackoff is how long a service whose last request failed is avoided.
+	failedServiceBackoff = 10 * time.Second
+)
+
+// responseTimes keeps an exponentially weighted moving average of each group service's
+// response time, for the least-time strategy. Its methods are safe to call on a nil
+// responseTimes, which measures nothing.
+type responseTimes struct {
+	clock func() time.Time
+
+	lock     sync.Mutex
+	services []serviceResponseTime
+}
+
+type serviceResponseTime struct {
+	average  time.Duration
+	measured bool
+	failedAt time.Time
+}
+
+func newResponseTimes(numServices int) *responseTimes {
+	return &responseTimes{clock: time.Now, services: make([]serviceResponseTime, numServices)}
+}
+
+// fastest returns the healthy service with the lowest average response time. Services that
+// haven't been measured yet are tried first, and services that failed recently are avoided,
+// unless all of them did. So are the skipped services, unless all of them are skipped.
+func (r *responseTimes) fastest(skip func(i int) bool) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if i := r.fastestOf(skip); i >= 0 {
+		return i
+	}
+	return r.fastestOf(nil)
+}
+
+func (r *responseTimes) fastestOf(skip func(i int) bool) int {
+	now := r.clock()
+	best, bestFailed := -1, -1
+	for i, service := range r.services {
+		if skip != nil && skip(i) {
+			continue
+		}
+		if !service.measured {
+			return i
+		}
+		if !service.failedAt.IsZero() && now.Sub(service.failedAt) < failedServiceBackoff {
+			if bestFailed < 0 || service.average < r.services[bestFailed].average {
+				bestFailed = i
+			}
+			continue
+		}
+		if best < 0 || service.average < r.services[best].average {
+			best = i
+		}
+	}
+	if best < 0 {
+		return bestFailed
+	}
+	return best
+}
+
+func (r *responseTimes) record(i int, elapsed time.Duration, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	service := &r.services[i]
+	if err != nil {
+		service.failedAt = r.clock()
+	} else {
+		service.failedAt = time.Time{}
+	}
+	if !service.measured {
+		service.average = elapsed
+		service.measured = true
+		return
+	}
+	service.average += time.Duration(responseTimeWeight * float64(elapsed-service.average))
+}
+
+// start begins measuring a request to the service with the given index.
+func (r *responseTimes) start(i int) timedRequest {
+	if r == nil {
+		return timedRequest{}
+	}
+	return timedRequest{times: r, service: i, started: r.clock()}
+}
+
+// timedRequest measures one request, see responseTimes.start.
+type timedRequest struct {
+	times   *responseTimes
+	service int
+	started time.Time
+}
+
+// stop records how long the request took, and whether it failed.
+func (t timedRequest) stop(err error) {
+	if t.times == nil {
+		return
+	}
+	t.times.record(t.service, t.times.clock().Sub(t.started), err)
+}
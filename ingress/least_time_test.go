@@ -0,0 +1,126 @@
+package ingress
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseTimesFastest(t *testing.T) {
+	now := time.Unix(0, 0)
+	times := newResponseTimes(3)
+	times.clock = func() time.Time { return now }
+
+	// Unmeasured services are tried first.
+	assert.Equal(t, 0, times.fastest(nil))
+	times.record(0, 30*time.Millisecond, nil)
+	assert.Equal(t, 1, times.fastest(nil))
+	times.record(1, 10*time.Millisecond, nil)
+	assert.Equal(t, 2, times.fastest(nil))
+	times.record(2, 20*time.Millisecond, nil)
+	assert.Equal(t, 1, times.fastest(nil))
+
+	// The average moves towards new response times.
+	times.record(1, 50*time.Millisecond, nil)
+	assert.Equal(t, 22*time.Millisecond, times.services[1].average)
+	assert.Equal(t, 2, times.fastest(nil))
+
+	// Failed services are avoided for a while.
+	times.record(2, time.Millisecond, errors.New("connection refused"))
+	assert.Equal(t, 1, times.fastest(nil))
+	now = now.Add(failedServiceBackoff)
+	assert.Equal(t, 2, times.fastest(nil))
+
+	// If every service failed recently, the fastest one is still used.
+	times.record(0, time.Millisecond, errors.New("connection refused"))
+	times.record(1, 50*time.Millisecond, errors.New("connection refused"))
+	times.record(2, 50*time.Millisecond, errors.New("connection refused"))
+	assert.Equal(t, 0, times.fastest(nil))
+}
+
+func TestGroupLeastTime(t *testing.T) {
+	newBackend := func(latency time.Duration) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(latency)
+		}))
+	}
+	slow, fast := newBackend(50*time.Millisecond), newBackend(0)
+	defer slow.Close()
+	defer fast.Close()
+
+	rawYAML := fmt.Sprintf(`
+groups:
+  api:
+  - service: %s
+  - service: %s
+ingress:
+ - hostname: api.example.com
+   group: api
+   groupStrategy: least-time
+ - service: http_status:404
+`, slow.URL, fast.URL)
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+	seen := make(map[string]int)
+	group := ing.Rules[0].Service.(*weightedGroup)
+	for i := 0; i < 10; i++ {
+		req, err := http.NewRequest(http.MethodGet, "http://api.example.com", nil)
+		require.NoError(t, err)
+		resp, err := group.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		seen[req.URL.Host]++
+	}
+	assert.Equal(t, 1, seen[slow.Listener.Addr().String()], "only the first request measures the slow backend")
+	assert.Equal(t, 9, seen[fast.Listener.Addr().String()])
+}
+
+func TestParseGroupStrategyInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawYAML string
+	}{
+		{
+			name: "Unknown strategy",
+			rawYAML: `
+groups:
+  api:
+  - service: http://a.internal
+ingress:
+ - hostname: api.example.com
+   group: api
+   groupStrategy: fastest
+ - service: http_status:404
+`,
+		},
+		{
+			name: "Strategy without group",
+			rawYAML: `
+ingress:
+ - hostname: api.example.com
+   service: http://a.internal
+   groupStrategy: least-time
+ - service: http_status:404
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseIngress(MustReadIngress(tt.rawYAML))
+			assert.Error(t, err)
+		})
+	}
+}
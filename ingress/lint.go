@@ -0,0 +1,51 @@
+package ingress
+
+import (
+	"strings"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// Lint parses the ingress rules of conf like ParseIngressWithDiagnostics, and also reports
+// settings that are valid but usually mistakes, like unanchored path regexes or disabled TLS
+// verification. Rule indices refer to the rules in the order they are written.
+func Lint(conf *config.Configuration) Diagnostics {
+	_, diags, err := ParseIngressWithDiagnostics(conf)
+	if err != nil {
+		diags.add(SeverityError, noRuleIndex, "%s", err)
+	}
+	defaults := originRequestFromYAML(conf.OriginRequest)
+	if defaults.tlsVerifyMode() == TLSVerifyOff {
+		diags.warn(noRuleIndex, "originRequest disables TLS verification by default, so a man in the middle could impersonate the origins")
+	}
+	// Lint what the rules using serviceRef resolve to. If they don't, parsing already failed.
+	rules, err := resolveServiceRefs(conf.Ingress, conf.Services)
+	if err != nil {
+		rules = conf.Ingress
+	}
+	for i, r := range rules {
+		if r.Path != "" && !strings.HasPrefix(r.Path, "^") {
+			diags.warn(i, "path %s isn't anchored with ^, so it matches anywhere in the request path", r.Path)
+		}
+		// Rules that inherit disabled verification are covered by the warning above.
+		if cfg := setConfig(defaults, r.OriginRequest); cfg.tlsVerifyMode() == TLSVerifyOff && defaults.tlsVerifyMode() != TLSVerifyOff {
+			diags.warn(i, "originRequest disables TLS verification, so a man in the middle could impersonate the origin")
+		}
+		if isTLDWildcard(r.Hostname) {
+			diags.warn(i, "hostname %s matches every subdomain of a top-level domain, it should probably name your own domain", r.Hostname)
+		}
+		for j := 0; j < i; j++ {
+			earlier := rules[j]
+			if earlier.Hostname == r.Hostname && earlier.Path == r.Path && earlier.Service == r.Service && r.Service != "" {
+				diags.add(SeverityInfo, i, "this rule routes the same hostname and path to the same service as rule #%d", j+1)
+				break
+			}
+		}
+	}
+	return diags
+}
+
+// isTLDWildcard checks for hostnames like *.com, which match other people's domains.
+func isTLDWildcard(hostname string) bool {
+	return strings.HasPrefix(hostname, "*.") && !strings.Contains(hostname[2:], ".")
+}
@@ -0,0 +1,159 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name      string
+		rawYAML   string
+		wantDiags Diagnostics
+	}{
+		{
+			name: "Clean config",
+			rawYAML: `
+ingress:
+ - hostname: api.example.com
+   path: ^/v1/
+   service: https://localhost:8000
+ - hostname: "*.example.com"
+   service: https://localhost:8001
+ - service: http_status:404
+`,
+		},
+		{
+			name: "Unanchored path and noTLSVerify",
+			rawYAML: `
+ingress:
+ - hostname: api.example.com
+   path: /v1/
+   service: https://localhost:8000
+   originRequest:
+     noTLSVerify: true
+ - service: http_status:404
+`,
+			wantDiags: Diagnostics{
+				{Severity: SeverityWarning, RuleIndex: 0, Message: "path /v1/ isn't anchored with ^, so it matches anywhere in the request path"},
+				{Severity: SeverityWarning, RuleIndex: 0, Message: "originRequest disables TLS verification, so a man in the middle could impersonate the origin"},
+			},
+		},
+		{
+			name: "tlsVerifyMode off for every rule",
+			rawYAML: `
+originRequest:
+  tlsVerifyMode: "off"
+ingress:
+ - service: https://localhost:8000
+`,
+			wantDiags: Diagnostics{
+				{Severity: SeverityWarning, RuleIndex: noRuleIndex, Message: "originRequest disables TLS verification by default, so a man in the middle could impersonate the origins"},
+			},
+		},
+		{
+			name: "tlsVerifyMode overrides noTLSVerify",
+			rawYAML: `
+originRequest:
+  noTLSVerify: true
+  tlsVerifyMode: strict
+ingress:
+ - hostname: api.example.com
+   service: https://localhost:8000
+   originRequest:
+     tlsVerifyMode: "off"
+ - hostname: www.example.com
+   service: https://localhost:8001
+   originRequest:
+     noTLSVerify: true
+ - service: http_status:404
+`,
+			wantDiags: Diagnostics{
+				{Severity: SeverityWarning, RuleIndex: 0, Message: "originRequest disables TLS verification, so a man in the middle could impersonate the origin"},
+			},
+		},
+		{
+			name: "Rule enables TLS verification",
+			rawYAML: `
+originRequest:
+  noTLSVerify: true
+ingress:
+ - hostname: api.example.com
+   service: https://localhost:8000
+   originRequest:
+     tlsVerifyMode: strict
+ - service: http_status:404
+`,
+			wantDiags: Diagnostics{
+				{Severity: SeverityWarning, RuleIndex: noRuleIndex, Message: "originRequest disables TLS verification by default, so a man in the middle could impersonate the origins"},
+			},
+		},
+		{
+			name: "serviceRef disables TLS verification",
+			rawYAML: `
+services:
+  api:
+    service: https://localhost:8000
+    originRequest:
+      noTLSVerify: true
+ingress:
+ - hostname: api.example.com
+   serviceRef: api
+ - hostname: api.example.com
+   serviceRef: api
+ - service: http_status:404
+`,
+			wantDiags: Diagnostics{
+				{Severity: SeverityWarning, RuleIndex: 1, Message: "this rule will never be matched, because rule #1 matches every request it would match"},
+				{Severity: SeverityWarning, RuleIndex: 0, Message: "originRequest disables TLS verification, so a man in the middle could impersonate the origin"},
+				{Severity: SeverityWarning, RuleIndex: 1, Message: "originRequest disables TLS verification, so a man in the middle could impersonate the origin"},
+				{Severity: SeverityInfo, RuleIndex: 1, Message: "this rule routes the same hostname and path to the same service as rule #1"},
+			},
+		},
+		{
+			name: "Missing catch-all",
+			rawYAML: `
+ingress:
+ - hostname: api.example.com
+   service: https://localhost:8000
+`,
+			wantDiags: Diagnostics{
+				{Severity: SeverityError, RuleIndex: noRuleIndex, Message: errLastRuleNotCatchAll.Error()},
+			},
+		},
+		{
+			name: "Wildcard for a top-level domain",
+			rawYAML: `
+ingress:
+ - hostname: "*.com"
+   service: https://localhost:8000
+ - service: http_status:404
+`,
+			wantDiags: Diagnostics{
+				{Severity: SeverityWarning, RuleIndex: 0, Message: "hostname *.com matches every subdomain of a top-level domain, it should probably name your own domain"},
+			},
+		},
+		{
+			name: "Duplicate hostname",
+			rawYAML: `
+ingress:
+ - hostname: api.example.com
+   service: https://localhost:8000
+ - hostname: api.example.com
+   service: https://localhost:8000
+ - service: http_status:404
+`,
+			wantDiags: Diagnostics{
+				{Severity: SeverityWarning, RuleIndex: 1, Message: "this rule will never be matched, because rule #1 matches every request it would match"},
+				{Severity: SeverityInfo, RuleIndex: 1, Message: "this rule routes the same hostname and path to the same service as rule #1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := Lint(MustReadIngress(tt.rawYAML))
+			assert.Equal(t, tt.wantDiags, diags)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package ingress
+
+import (
+	"crypto/tls"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// localTLSUpgrade finds out if an http://localhost origin also accepts TLS, so that it can be
+// reached over https instead. The first request after the origin could be reached decides.
+type localTLSUpgrade struct {
+	addr     string
+	config   *tls.Config
+	timeout  time.Duration
+	log      *zerolog.Logger
+	lock     sync.Mutex
+	decided  bool
+	useHTTPS bool
+}
+
+func newLocalTLSUpgrade(origin *url.URL, tlsConfig *tls.Config, timeout time.Duration, log *zerolog.Logger) *localTLSUpgrade {
+	if origin.Scheme != "http" || !isLocalhost(origin.Hostname()) {
+		return nil
+	}
+	config := tlsConfig.Clone()
+	if config.ServerName == "" {
+		config.ServerName = origin.Hostname()
+	}
+	port := origin.Port()
+	if port == "" {
+		port = "80"
+	}
+	return &localTLSUpgrade{
+		addr:    net.JoinHostPort(origin.Hostname(), port),
+		config:  config,
+		timeout: timeout,
+		log:     log,
+	}
+}
+
+func isLocalhost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// scheme returns the scheme to reach the origin with, https if it completed a TLS handshake.
+// It's safe to call on a nil localTLSUpgrade, which always returns http.
+func (u *localTLSUpgrade) scheme() string {
+	if u == nil {
+		return "http"
+	}
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if !u.decided {
+		u.probe()
+	}
+	if u.useHTTPS {
+		return "https"
+	}
+	return "http"
+}
+
+func (u *localTLSUpgrade) probe() {
+	conn, err := net.DialTimeout("tcp", u.addr, u.timeout)
+	if err != nil {
+		// Retry once the origin is up.
+		return
+	}
+	defer conn.Close()
+	u.decided = true
+	if u.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(u.timeout))
+	}
+	if err := tls.Client(conn, u.config).Handshake(); err != nil {
+		u.log.Warn().Err(err).Msgf("forceLocalTLS is set, but the origin at %s doesn't accept TLS. Falling back to plain HTTP", u.addr)
+		return
+	}
+	u.useHTTPS = true
+	u.log.Info().Msgf("forceLocalTLS is set and the origin at %s accepts TLS, so it will be reached over HTTPS", u.addr)
+}
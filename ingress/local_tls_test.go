@@ -0,0 +1,83 @@
+package ingress
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForceLocalTLS(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Write([]byte("tls"))
+		} else {
+			w.Write([]byte("plain"))
+		}
+	})
+	tlsOrigin := httptest.NewTLSServer(handler)
+	defer tlsOrigin.Close()
+	plainOrigin := httptest.NewServer(handler)
+	defer plainOrigin.Close()
+
+	tests := []struct {
+		name       string
+		originURL  string
+		expectBody string
+		expectWarn bool
+	}{
+		{name: "origin with TLS", originURL: strings.Replace(tlsOrigin.URL, "https://", "http://", 1), expectBody: "tls"},
+		{name: "http-only origin", originURL: plainOrigin.URL, expectBody: "plain", expectWarn: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			originURL, err := url.Parse(test.originURL)
+			require.NoError(t, err)
+			var logs bytes.Buffer
+			log := zerolog.New(&logs)
+			service := &httpService{url: originURL}
+			cfg := OriginRequestConfig{ForceLocalTLS: true, NoTLSVerify: true}
+			require.NoError(t, service.start(&sync.WaitGroup{}, &log, make(chan struct{}), make(chan error), cfg))
+
+			// The second request reuses the first one's decision.
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+				require.NoError(t, err)
+				resp, err := service.RoundTrip(req)
+				require.NoError(t, err)
+				body := new(bytes.Buffer)
+				_, err = body.ReadFrom(resp.Body)
+				require.NoError(t, err)
+				resp.Body.Close()
+				assert.Equal(t, test.expectBody, body.String())
+			}
+			assert.Equal(t, test.expectWarn, strings.Contains(logs.String(), "doesn't accept TLS"), logs.String())
+			assert.LessOrEqual(t, strings.Count(logs.String(), "doesn't accept TLS"), 1, "warned more than once")
+		})
+	}
+}
+
+func TestForceLocalTLSOnlyUpgradesLocalHTTP(t *testing.T) {
+	for rawURL, expectUpgrade := range map[string]bool{
+		"http://localhost:8000":        true,
+		"http://127.0.0.1:8000":        true,
+		"http://[::1]:8000":            true,
+		"http://example.com:8000":      false,
+		"https://localhost:8000":       false,
+		"http://192.168.1.1":           false,
+		"http://localhost.example.com": false,
+	} {
+		originURL, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		service := &httpService{url: originURL}
+		require.NoError(t, service.start(&sync.WaitGroup{}, testLogger, make(chan struct{}), make(chan error), OriginRequestConfig{ForceLocalTLS: true}))
+		assert.Equal(t, expectUpgrade, service.localTLS != nil, rawURL)
+	}
+}
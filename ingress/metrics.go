@@ -0,0 +1,90 @@
+package ingress
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// metricsNamespace is the same as connection.MetricsNamespace, which this package can't
+	// depend on.
+	metricsNamespace = "cloudflared"
+	ingressSubsystem = "ingress"
+	originSubsystem  = "origin"
+)
+
+var (
+	catchAllRequests = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: ingressSubsystem,
+			Name:      "catchall_total",
+			Help:      "Count of requests routed by the catch-all rule because no other ingress rule matched",
+		},
+	)
+	noMatchRequests = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: ingressSubsystem,
+			Name:      "nomatch_total",
+			Help:      "Count of requests that matched no ingress rule, not even the last one, which routed them anyway",
+		},
+	)
+	tlsHandshakeErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: originSubsystem,
+			Name:      "tls_handshake_errors_total",
+			Help:      "Count of failed TLS handshakes with the origin, e.g. because its certificate couldn't be verified, by ingress rule",
+		},
+		[]string{"rule", "config"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		catchAllRequests,
+		noMatchRequests,
+		tlsHandshakeErrors,
+	)
+}
+
+// recordMatch counts the requests that fell through to the last rule. Configs with a single
+// rule, like --url, are left out because that rule routes all their traffic.
+func (ing Ingress) recordMatch(ruleIndex int, matched bool) {
+	if ing.IsSingleRule() || ruleIndex != len(ing.Rules)-1 {
+		return
+	}
+	if matched {
+		catchAllRequests.Inc()
+	} else {
+		noMatchRequests.Inc()
+	}
+}
+
+// recordTLSHandshakeError counts err if it's caused by a failed TLS handshake with the origin.
+func recordTLSHandshakeError(ruleIndex int, config string, err error) {
+	if isTLSHandshakeError(err) {
+		tlsHandshakeErrors.WithLabelValues(strconv.Itoa(ruleIndex), config).Inc()
+	}
+}
+
+func isTLSHandshakeError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+		recordHeader     tls.RecordHeaderError
+	)
+	return errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostname) ||
+		errors.As(err, &invalid) ||
+		errors.As(err, &recordHeader)
+}
@@ -0,0 +1,111 @@
+package ingress
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	var metric dto.Metric
+	require.NoError(t, counter.Write(&metric))
+	return metric.GetCounter().GetValue()
+}
+
+func TestCatchAllMetrics(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - hostname: tunnel.example.com
+   service: https://localhost:8000
+ - service: http_status:404
+`))
+	require.NoError(t, err)
+
+	request := func(ing Ingress, host string) int {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+		require.NoError(t, err)
+		_, i := ing.FindMatchingRuleForRequest(req)
+		return i
+	}
+
+	catchAll, noMatch := counterValue(t, catchAllRequests), counterValue(t, noMatchRequests)
+	assert.Equal(t, 0, request(ing, "tunnel.example.com"))
+	assert.Equal(t, catchAll, counterValue(t, catchAllRequests))
+
+	assert.Equal(t, 1, request(ing, "unexpected.example.com"))
+	assert.Equal(t, catchAll+1, counterValue(t, catchAllRequests))
+	assert.Equal(t, noMatch, counterValue(t, noMatchRequests))
+
+	// Validated configs always end with a catch-all, so no-match needs a last rule that
+	// doesn't match everything.
+	ok, notFound := newStatusCode(http.StatusOK), newStatusCode(http.StatusNotFound)
+	unvalidated := Ingress{Rules: []Rule{
+		{Hostname: "tunnel.example.com", Service: &ok},
+		{Hostname: "other.example.com", Service: &notFound},
+	}}
+	assert.Equal(t, 1, request(unvalidated, "unexpected.example.com"))
+	assert.Equal(t, noMatch+1, counterValue(t, noMatchRequests))
+	assert.Equal(t, catchAll+1, counterValue(t, catchAllRequests))
+
+	// A single rule routes everything, which isn't worth alerting on.
+	single, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 0, request(single, "unexpected.example.com"))
+	assert.Equal(t, catchAll+1, counterValue(t, catchAllRequests))
+}
+
+func TestTLSHandshakeErrorMetrics(t *testing.T) {
+	// httptest's certificate isn't trusted, so verifying it fails.
+	badCertOrigin := httptest.NewTLSServer(http.NotFoundHandler())
+	defer badCertOrigin.Close()
+	plainOrigin := httptest.NewServer(http.NotFoundHandler())
+	defer plainOrigin.Close()
+
+	ing, err := ParseIngress(MustReadIngress(fmt.Sprintf(`
+ingress:
+ - hostname: bad-cert.example.com
+   service: %s
+ - hostname: plain.example.com
+   service: https://%s
+ - service: %s
+`, badCertOrigin.URL, plainOrigin.Listener.Addr(), plainOrigin.URL)))
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+	handshakeErrors := func(rule string) float64 {
+		return counterValue(t, tlsHandshakeErrors.WithLabelValues(rule, ConfigCurrent))
+	}
+	before := []float64{handshakeErrors("0"), handshakeErrors("1"), handshakeErrors("2")}
+	for _, host := range []string{"bad-cert.example.com", "plain.example.com", "www.example.com"} {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+		require.NoError(t, err)
+		rule, i := ing.FindMatchingRuleForRequest(req)
+		resp, err := rule.Service.(HTTPOriginProxy).RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		ing.RecordRequest(i, err)
+	}
+	// A bad certificate, and an origin that doesn't speak TLS, fail the handshake.
+	assert.Equal(t, before[0]+1, handshakeErrors("0"))
+	assert.Equal(t, before[1]+1, handshakeErrors("1"))
+	assert.Equal(t, before[2], handshakeErrors("2"))
+
+	ing.RecordRequest(2, errors.New("connection refused"))
+	assert.Equal(t, before[2], handshakeErrors("2"))
+}
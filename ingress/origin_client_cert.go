@@ -0,0 +1,38 @@
+package ingress
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// getenv reads the environment variables that clientCertEnv and clientKeyEnv name. Tests
+// replace it, so they don't depend on the environment they run in.
+var getenv = os.Getenv
+
+// loadClientCertificate loads the client certificate that is presented to the origin for mTLS,
+// if clientCertEnv and clientKeyEnv are set. The PEM comes from the environment, so secret
+// managers can inject it without writing it to disk.
+func (cfg *OriginRequestConfig) loadClientCertificate() error {
+	if cfg.ClientCertEnv == "" && cfg.ClientKeyEnv == "" {
+		return nil
+	}
+	if cfg.ClientCertEnv == "" || cfg.ClientKeyEnv == "" {
+		return errors.New("clientCertEnv and clientKeyEnv must be set together")
+	}
+	certPEM, keyPEM := getenv(cfg.ClientCertEnv), getenv(cfg.ClientKeyEnv)
+	if certPEM == "" {
+		return fmt.Errorf("clientCertEnv names the environment variable %s, which isn't set", cfg.ClientCertEnv)
+	}
+	if keyPEM == "" {
+		return fmt.Errorf("clientKeyEnv names the environment variable %s, which isn't set", cfg.ClientKeyEnv)
+	}
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return errors.Wrapf(err, "the PEM in %s and %s isn't a valid certificate and key", cfg.ClientCertEnv, cfg.ClientKeyEnv)
+	}
+	cfg.clientCertificate = &cert
+	return nil
+}
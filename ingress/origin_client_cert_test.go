@@ -0,0 +1,124 @@
+package ingress
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClientCertificate returns the PEM of a self-signed client certificate and its key.
+func newTestClientCertificate(t *testing.T, commonName string) (certPEM, keyPEM string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func withTestEnv(t *testing.T, env map[string]string) {
+	getenv = func(name string) string { return env[name] }
+	t.Cleanup(func() { getenv = os.Getenv })
+}
+
+func TestOriginClientCertificateFromEnv(t *testing.T) {
+	certPEM, keyPEM := newTestClientCertificate(t, "cloudflared.example.com")
+	withTestEnv(t, map[string]string{"CF_CERT": certPEM, "CF_KEY": keyPEM})
+
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	origin.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	origin.StartTLS()
+	defer origin.Close()
+
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: ` + origin.URL + `
+   originRequest:
+     noTLSVerify: true
+     clientCertEnv: CF_CERT
+     clientKeyEnv: CF_KEY
+`))
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	var wg sync.WaitGroup
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&wg, &log, shutdownC, make(chan error)))
+
+	req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+	require.NoError(t, err)
+	resp, err := ing.Rules[0].Service.(HTTPOriginProxy).RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "cloudflared.example.com", string(body))
+}
+
+func TestParseOriginClientCertificate(t *testing.T) {
+	certPEM, keyPEM := newTestClientCertificate(t, "cloudflared.example.com")
+	_, otherKeyPEM := newTestClientCertificate(t, "other.example.com")
+	withTestEnv(t, map[string]string{
+		"CF_CERT":      certPEM,
+		"CF_KEY":       keyPEM,
+		"CF_OTHER_KEY": otherKeyPEM,
+		"CF_GARBAGE":   "-----BEGIN CERTIFICATE-----\nnot base64\n-----END CERTIFICATE-----\n",
+	})
+
+	tests := []struct {
+		name      string
+		certEnv   string
+		keyEnv    string
+		expectErr bool
+	}{
+		{name: "valid", certEnv: "CF_CERT", keyEnv: "CF_KEY"},
+		{name: "key without cert", keyEnv: "CF_KEY", expectErr: true},
+		{name: "unset variable", certEnv: "CF_CERT", keyEnv: "CF_UNSET", expectErr: true},
+		{name: "malformed certificate", certEnv: "CF_GARBAGE", keyEnv: "CF_KEY", expectErr: true},
+		{name: "key of another certificate", certEnv: "CF_CERT", keyEnv: "CF_OTHER_KEY", expectErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     clientCertEnv: "` + test.certEnv + `"
+     clientKeyEnv: "` + test.keyEnv + `"
+`))
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, ing.Rules[0].Config.clientCertificate)
+		})
+	}
+}
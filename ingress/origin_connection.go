@@ -28,6 +28,14 @@ func DefaultStreamHandler(originConn io.ReadWriter, remoteConn net.Conn, log *ze
 	websocket.Stream(originConn, remoteConn, log)
 }
 
+// bufferedStreamHandler returns a streamHandlerFunc like DefaultStreamHandler that copies
+// through buffers of the given sizes.
+func bufferedStreamHandler(sizes websocket.BufferSizes) streamHandlerFunc {
+	return func(originConn io.ReadWriter, remoteConn net.Conn, log *zerolog.Logger) {
+		websocket.StreamWithBuffers(originConn, remoteConn, sizes, log)
+	}
+}
+
 // tcpConnection is an OriginConnection that directly streams to raw TCP.
 type tcpConnection struct {
 	conn net.Conn
@@ -71,11 +79,12 @@ func (sp *socksProxyOverWSConnection) Close() {
 
 // wsProxyConnection represents a bidirectional stream for a websocket connection to the origin
 type wsProxyConnection struct {
-	rwc io.ReadWriteCloser
+	rwc         io.ReadWriteCloser
+	bufferSizes websocket.BufferSizes
 }
 
 func (conn *wsProxyConnection) Stream(ctx context.Context, tunnelConn io.ReadWriter, log *zerolog.Logger) {
-	websocket.Stream(tunnelConn, conn.rwc, log)
+	websocket.StreamWithBuffers(tunnelConn, conn.rwc, conn.bufferSizes, log)
 }
 
 func (conn *wsProxyConnection) Close() {
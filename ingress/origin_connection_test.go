@@ -255,6 +255,16 @@ func TestStreamWSConnection(t *testing.T) {
 	require.NoError(t, errGroup.Wait())
 }
 
+func TestWSConnectionBufferSizes(t *testing.T) {
+	var svc httpService
+	err := svc.start(&sync.WaitGroup{}, testLogger, nil, nil, OriginRequestConfig{
+		ReadBufferBytes:  65536,
+		WriteBufferBytes: 16384,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BufferSizes{Read: 65536, Write: 16384}, svc.bufferSizes)
+}
+
 type wsEyeball struct {
 	conn net.Conn
 }
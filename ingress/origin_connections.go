@@ -0,0 +1,91 @@
+package ingress
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrTooManyOriginConnections is returned by dials while --max-total-origin-connections
+// connections to origins are open.
+var ErrTooManyOriginConnections = errors.New("Too many connections to origins are open to start another one")
+
+// originConnections caps how many connections to origins are open at once, across all the
+// rules. Its methods are safe to call on a nil originConnections, which doesn't limit them.
+type originConnections struct {
+	slots chan struct{}
+
+	lock sync.Mutex
+	// closeIdle closes the idle connections of the pools whose dials are limited.
+	closeIdle []func()
+}
+
+func newOriginConnections(max int) *originConnections {
+	if max <= 0 {
+		return nil
+	}
+	return &originConnections{slots: make(chan struct{}, max)}
+}
+
+// addIdlePool lets dials take the slots of a pool's idle connections, which closeIdle closes.
+func (c *originConnections) addIdlePool(closeIdle func()) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closeIdle = append(c.closeIdle, closeIdle)
+}
+
+// limit makes dial fail with ErrTooManyOriginConnections instead of opening a connection while
+// all the slots are taken by connections in use. The slot of a connection is freed when it's
+// closed, so idle pooled connections are closed to free theirs if needed.
+func (c *originConnections) limit(dial dialFunc) dialFunc {
+	if c == nil {
+		return dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if !c.take() {
+			return nil, ErrTooManyOriginConnections
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			<-c.slots
+			return nil, err
+		}
+		return &limitedConn{Conn: conn, release: func() { <-c.slots }}, nil
+	}
+}
+
+func (c *originConnections) take() bool {
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	default:
+	}
+	c.lock.Lock()
+	for _, closeIdle := range c.closeIdle {
+		closeIdle()
+	}
+	c.lock.Unlock()
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// limitedConn frees its slot the first time it's closed.
+type limitedConn struct {
+	net.Conn
+	release     func()
+	releaseOnce sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	c.releaseOnce.Do(c.release)
+	return c.Conn.Close()
+}
@@ -0,0 +1,91 @@
+package ingress
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitOriginConnections(t *testing.T) {
+	httpOrigin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer httpOrigin.Close()
+	tcpOrigin, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcpOrigin.Close()
+	go func() {
+		for {
+			conn, err := tcpOrigin.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = ioutil.ReadAll(conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - hostname: http.example.com
+   service: ` + httpOrigin.URL + `
+ - service: tcp://` + tcpOrigin.Addr().String() + `
+`))
+	require.NoError(t, err)
+	ing.LimitOriginConnections(1)
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+	httpOriginService := ing.Rules[0].Service.(*httpService)
+	tcpOriginService := ing.Rules[1].Service.(*tcpOverWSService)
+
+	startRoundTrip := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, "http://http.example.com/", nil)
+		require.NoError(t, err)
+		return httpOriginService.RoundTrip(req)
+	}
+	roundTrip := func() error {
+		resp, err := startRoundTrip()
+		if err != nil {
+			return err
+		}
+		_, _ = ioutil.ReadAll(resp.Body)
+		return resp.Body.Close()
+	}
+	establish := func() (OriginConnection, error) {
+		req, err := http.NewRequest(http.MethodGet, "http://tcp.example.com/", nil)
+		require.NoError(t, err)
+		conn, _, err := tcpOriginService.EstablishConnection(req)
+		return conn, err
+	}
+
+	// The HTTP origin's connection takes the only slot while the response is read.
+	resp, err := startRoundTrip()
+	require.NoError(t, err)
+	_, err = establish()
+	assert.True(t, errors.Is(err, ErrTooManyOriginConnections), err)
+	_, _ = ioutil.ReadAll(resp.Body)
+	require.NoError(t, resp.Body.Close())
+	// Reusing the open connection doesn't need another slot.
+	require.NoError(t, roundTrip())
+
+	// Once it's idle, it's closed to free the slot.
+	conn, err := establish()
+	require.NoError(t, err)
+	err = roundTrip()
+	assert.True(t, errors.Is(err, ErrTooManyOriginConnections), err)
+
+	conn.Close()
+	assert.NoError(t, roundTrip())
+}
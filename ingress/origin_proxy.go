@@ -1,11 +1,13 @@
 package ingress
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -30,17 +32,25 @@ type StreamBasedOriginProxy interface {
 }
 
 func (o *unixSocketPath) RoundTrip(req *http.Request) (*http.Response, error) {
+	o.signer.sign(req)
+	if o.keepHeaderCase {
+		req = withEyeballHeaderCase(req)
+	}
 	return o.transport.RoundTrip(req)
 }
 
 func (o *httpService) RoundTrip(req *http.Request) (*http.Response, error) {
 	// Rewrite the request URL so that it goes to the origin service.
 	req.URL.Host = o.url.Host
-	req.URL.Scheme = o.url.Scheme
+	req.URL.Scheme = o.scheme()
 	if o.hostHeader != "" {
 		// For incoming requests, the Host header is promoted to the Request.Host field and removed from the Header map.
 		req.Host = o.hostHeader
 	}
+	o.signer.sign(req)
+	if o.keepHeaderCase {
+		req = withEyeballHeaderCase(req)
+	}
 	return o.transport.RoundTrip(req)
 }
 
@@ -48,7 +58,7 @@ func (o *httpService) EstablishConnection(req *http.Request) (OriginConnection,
 	req = req.Clone(req.Context())
 
 	req.URL.Host = o.url.Host
-	req.URL.Scheme = o.url.Scheme
+	req.URL.Scheme = o.scheme()
 	// allow ws(s) scheme for websocket-only origins, normal http(s) requests will fail
 	switch req.URL.Scheme {
 	case "ws":
@@ -61,6 +71,7 @@ func (o *httpService) EstablishConnection(req *http.Request) (OriginConnection,
 		// For incoming requests, the Host header is promoted to the Request.Host field and removed from the Header map.
 		req.Host = o.hostHeader
 	}
+	o.signer.sign(req)
 
 	return o.newWebsocketProxyConnection(req)
 }
@@ -97,7 +108,8 @@ func (o *httpService) newWebsocketProxyConnection(req *http.Request) (OriginConn
 		return nil, nil, errUnsupportedConnectionType
 	}
 	conn := wsProxyConnection{
-		rwc: rwc,
+		rwc:         rwc,
+		bufferSizes: o.bufferSizes,
 	}
 	// clear to prevent defer from closing
 	toClose = nil
@@ -142,6 +154,22 @@ func getRequestHost(r *http.Request) (string, error) {
 }
 
 func (o *tcpOverWSService) EstablishConnection(r *http.Request) (OriginConnection, *http.Response, error) {
+	if o.sniRouter != nil {
+		// The destination isn't known until the eyeball sends its ClientHello.
+		originConn := &sniRoutingConnection{
+			router:      o.sniRouter,
+			defaultDest: o.dest,
+			dial:        o.dial,
+		}
+		resp := &http.Response{
+			Status:        switchingProtocolText,
+			StatusCode:    http.StatusSwitchingProtocols,
+			Header:        websocket.NewResponseHeader(r),
+			ContentLength: -1,
+		}
+		return originConn, resp, nil
+	}
+
 	var err error
 	dest := o.dest
 	if o.isBastion {
@@ -151,10 +179,20 @@ func (o *tcpOverWSService) EstablishConnection(r *http.Request) (OriginConnectio
 		}
 	}
 
-	conn, err := net.Dial("tcp", dest)
+	var conn net.Conn
+	if o.dial != nil {
+		conn, err = o.dial(r.Context(), "tcp", dest)
+	} else {
+		conn, err = net.Dial("tcp", dest)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
+	if o.tlsConfig != nil {
+		if conn, err = o.tlsHandshake(conn); err != nil {
+			return nil, nil, err
+		}
+	}
 	originConn := &tcpOverWSConnection{
 		conn:          conn,
 		streamHandler: o.streamHandler,
@@ -169,6 +207,20 @@ func (o *tcpOverWSService) EstablishConnection(r *http.Request) (OriginConnectio
 
 }
 
+// tlsHandshake wraps conn in TLS, closing it if the handshake fails.
+func (o *tcpOverWSService) tlsHandshake(conn net.Conn) (net.Conn, error) {
+	tlsConn := tls.Client(conn, o.tlsConfig)
+	if o.tlsTimeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(o.tlsTimeout))
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrapf(err, "TLS handshake with %s failed", o.dest)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
 func (o *socksProxyOverWSService) EstablishConnection(r *http.Request) (OriginConnection, *http.Response, error) {
 	originConn := o.conn
 	resp := &http.Response{
@@ -1,16 +1,25 @@
 package ingress
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -181,6 +190,74 @@ func TestTCPOverWSServiceEstablishConnection(t *testing.T) {
 	}
 }
 
+func TestTCPOverWSServiceTLS(t *testing.T) {
+	serverNames := make(chan string, 1)
+	origin := httptest.NewUnstartedServer(http.NotFoundHandler())
+	origin.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverNames <- hello.ServerName
+			return nil, nil
+		},
+	}
+	origin.StartTLS()
+	defer origin.Close()
+
+	rawYAML := fmt.Sprintf(`
+ingress:
+ - hostname: tcp.example.com
+   service: tcp://%s
+   originRequest:
+     noTLSVerify: true
+     tcpTLS:
+       serverName: backend.internal
+ - service: http_status:404
+`, origin.Listener.Addr())
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	service := ing.Rules[0].Service.(*tcpOverWSService)
+	log := zerolog.Nop()
+	require.NoError(t, service.start(&sync.WaitGroup{}, &log, nil, nil, ing.Rules[0].Config))
+
+	req, err := http.NewRequest(http.MethodGet, "https://place-holder", nil)
+	require.NoError(t, err)
+	req.Header.Set("Sec-Websocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	originConn, resp, err := service.EstablishConnection(req)
+	require.NoError(t, err)
+	defer originConn.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "backend.internal", <-serverNames)
+	_, isTLS := originConn.(*tcpOverWSConnection).conn.(*tls.Conn)
+	assert.True(t, isTLS)
+}
+
+func TestParseTCPTLS(t *testing.T) {
+	invalid := []string{`
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     tcpTLS:
+       serverName: backend.internal
+`, `
+ingress:
+ - service: bastion
+   originRequest:
+     tcpTLS:
+       serverName: backend.internal
+`, `
+ingress:
+ - service: tcp://localhost:4430
+   originRequest:
+     tcpTLS:
+       serverName: backend.internal
+     sniRoutes:
+       a.example.com: localhost:4431
+`}
+	for _, rawYAML := range invalid {
+		_, err := ParseIngress(MustReadIngress(rawYAML))
+		assert.Error(t, err, rawYAML)
+	}
+}
+
 func TestHTTPServiceHostHeaderOverride(t *testing.T) {
 	cfg := OriginRequestConfig{
 		HTTPHostHeader: t.Name(),
@@ -224,6 +301,154 @@ func TestHTTPServiceHostHeaderOverride(t *testing.T) {
 	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
 }
 
+func TestHTTPServiceTLSVerifyMode(t *testing.T) {
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer origin.Close()
+	originURL, err := url.Parse(origin.URL)
+	require.NoError(t, err)
+
+	// A CA pool that trusts the test server's self-signed certificate.
+	caPool := filepath.Join(t.TempDir(), "origin-ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: origin.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caPool, certPEM, 0600))
+
+	tests := []struct {
+		name        string
+		cfg         OriginRequestConfig
+		expectErr   bool
+		expectWarns bool
+	}{
+		{name: "strict rejects untrusted cert", cfg: OriginRequestConfig{TLSVerifyMode: TLSVerifyStrict}, expectErr: true},
+		{name: "default is strict", cfg: OriginRequestConfig{}, expectErr: true},
+		{name: "warn proxies untrusted cert", cfg: OriginRequestConfig{TLSVerifyMode: TLSVerifyWarn}, expectWarns: true},
+		{name: "warn accepts trusted cert silently", cfg: OriginRequestConfig{TLSVerifyMode: TLSVerifyWarn, CAPool: caPool}},
+		{name: "off", cfg: OriginRequestConfig{TLSVerifyMode: TLSVerifyOff}},
+		{name: "tlsVerifyMode overrides noTLSVerify", cfg: OriginRequestConfig{TLSVerifyMode: TLSVerifyStrict, NoTLSVerify: true}, expectErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log := zerolog.New(&logs)
+			service := &httpService{url: originURL}
+			require.NoError(t, service.start(&sync.WaitGroup{}, &log, make(chan struct{}), make(chan error), test.cfg))
+
+			req, err := http.NewRequest(http.MethodGet, originURL.String(), nil)
+			require.NoError(t, err)
+			resp, err := service.RoundTrip(req)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, test.expectWarns, strings.Contains(logs.String(), "failed verification"), logs.String())
+		})
+	}
+}
+
+func TestHTTPServiceTLSRenegotiation(t *testing.T) {
+	tests := []struct {
+		tlsRenegotiation string
+		expect           tls.RenegotiationSupport
+	}{
+		{tlsRenegotiation: "", expect: tls.RenegotiateNever},
+		{tlsRenegotiation: TLSRenegotiationNever, expect: tls.RenegotiateNever},
+		{tlsRenegotiation: TLSRenegotiationOnce, expect: tls.RenegotiateOnceAsClient},
+		{tlsRenegotiation: TLSRenegotiationFreely, expect: tls.RenegotiateFreelyAsClient},
+	}
+	for _, test := range tests {
+		ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     tlsRenegotiation: "` + test.tlsRenegotiation + `"
+`))
+		require.NoError(t, err)
+		log := zerolog.Nop()
+		require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, make(chan struct{}), make(chan error)))
+		service := ing.Rules[0].Service.(*httpService)
+		assert.Equal(t, test.expect, service.transport.TLSClientConfig.Renegotiation, test.tlsRenegotiation)
+	}
+
+	_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     tlsRenegotiation: always
+`))
+	assert.Error(t, err)
+}
+
+func TestHTTPServicePoolKey(t *testing.T) {
+	var newConns int32
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	origin.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&newConns, 1)
+		}
+	}
+	origin.StartTLS()
+	defer origin.Close()
+
+	tests := []struct {
+		poolKey     string
+		override    string
+		expectConns int32
+	}{
+		{poolKey: "", expectConns: 2},
+		{poolKey: PoolKeyBySNI, expectConns: 2},
+		{poolKey: PoolKeyByOrigin, expectConns: 1},
+		// Rules with other TLS settings or timeouts don't share the connections.
+		{poolKey: PoolKeyByOrigin, override: "tlsVerifyMode: warn", expectConns: 2},
+		{poolKey: PoolKeyByOrigin, override: "connectTimeout: 5s", expectConns: 2},
+	}
+	for _, test := range tests {
+		atomic.StoreInt32(&newConns, 0)
+		ing, err := ParseIngress(MustReadIngress(fmt.Sprintf(`
+originRequest:
+  noTLSVerify: true
+  poolKey: "%s"
+ingress:
+ - hostname: a.example.com
+   service: %s
+   originRequest:
+     originServerName: a.example.com
+ - service: %s
+   originRequest:
+     originServerName: b.example.com
+     %s
+`, test.poolKey, origin.URL, origin.URL, test.override)))
+		require.NoError(t, err)
+		log := zerolog.Nop()
+		shutdownC := make(chan struct{})
+		require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+		for _, rule := range ing.Rules {
+			req, err := http.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			require.NoError(t, err)
+			resp, err := rule.Service.(*httpService).RoundTrip(req)
+			require.NoError(t, err)
+			_, _ = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		close(shutdownC)
+		assert.Equal(t, test.expectConns, atomic.LoadInt32(&newConns), test.poolKey)
+		for _, rule := range ing.Rules {
+			rule.Service.(*httpService).transport.CloseIdleConnections()
+		}
+	}
+
+	_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     poolKey: byHost
+`))
+	assert.Error(t, err)
+}
+
 func tcpListenRoutine(listener net.Listener, closeChan chan struct{}) {
 	go func() {
 		for {
@@ -237,3 +462,108 @@ func tcpListenRoutine(listener net.Listener, closeChan chan struct{}) {
 		}
 	}()
 }
+
+func TestHTTPServiceALPN(t *testing.T) {
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	origin.EnableHTTP2 = true
+	origin.StartTLS()
+	defer origin.Close()
+	originURL, err := url.Parse(origin.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		alpn            []string
+		expectNextProto []string
+		expectProto     string
+	}{
+		{name: "default", expectProto: "HTTP/1.1"},
+		{name: "h2", alpn: []string{"h2", "http/1.1"}, expectNextProto: []string{"h2", "http/1.1"}, expectProto: "HTTP/2.0"},
+		{name: "http/1.1 only", alpn: []string{"http/1.1"}, expectNextProto: []string{"http/1.1"}, expectProto: "HTTP/1.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := &httpService{url: originURL}
+			cfg := OriginRequestConfig{NoTLSVerify: true, ALPN: test.alpn}
+			require.NoError(t, service.start(&sync.WaitGroup{}, testLogger, make(chan struct{}), make(chan error), cfg))
+			assert.Equal(t, test.expectNextProto, service.transport.TLSClientConfig.NextProtos)
+
+			req, err := http.NewRequest(http.MethodGet, originURL.String(), nil)
+			require.NoError(t, err)
+			resp, err := service.RoundTrip(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectProto, string(body))
+		})
+	}
+}
+
+func TestLimitConcurrentDials(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	var lock sync.Mutex
+	inFlight, maxInFlight, dials := 0, 0, 0
+	var dialer net.Dialer
+	instrumented := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		lock.Lock()
+		inFlight++
+		dials++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		lock.Unlock()
+		defer func() {
+			lock.Lock()
+			inFlight--
+			lock.Unlock()
+		}()
+		// Keep the dial in flight long enough for the other requests to pile up.
+		time.Sleep(20 * time.Millisecond)
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	const maxDials = 2
+	transport := &http.Transport{DialContext: limitConcurrentDials(instrumented, maxDials, 0), DisableKeepAlives: true}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+			require.NoError(t, err)
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, dials)
+	assert.LessOrEqual(t, maxInFlight, maxDials)
+	assert.Equal(t, maxDials, maxInFlight, "the limit should still allow dialing in parallel")
+}
+
+func TestLimitConcurrentDialsCanceled(t *testing.T) {
+	blocked := make(chan struct{})
+	defer close(blocked)
+	dial := limitConcurrentDials(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		<-blocked
+		return nil, errors.New("dial finished")
+	}, 1, 0)
+	go dial(context.Background(), "tcp", "localhost:80")
+
+	// The only slot is taken, so this dial waits until its context ends.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	time.Sleep(10 * time.Millisecond)
+	_, err := dial(ctx, "tcp", "localhost:80")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -1,13 +1,22 @@
 package ingress
 
 import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/net/http/httpguts"
 
 	"github.com/cloudflare/cloudflared/config"
 	"github.com/cloudflare/cloudflared/ipaccess"
 	"github.com/cloudflare/cloudflared/tlsconfig"
+	"github.com/cloudflare/cloudflared/websocket"
 )
 
 const (
@@ -32,6 +41,7 @@ const (
 	NoChunkedEncodingFlag         = "no-chunked-encoding"
 	ProxyAddressFlag              = "proxy-address"
 	ProxyPortFlag                 = "proxy-port"
+	StrictRequestFramingFlag      = "strict-request-framing"
 )
 
 const (
@@ -54,6 +64,7 @@ func originRequestFromSingeRule(c *cli.Context) OriginRequestConfig {
 	var proxyAddress = defaultProxyAddress
 	var proxyPort uint
 	var proxyType string
+	var strictRequestFraming = true
 	if flag := ProxyConnectTimeoutFlag; c.IsSet(flag) {
 		connectTimeout = c.Duration(flag)
 	}
@@ -100,6 +111,9 @@ func originRequestFromSingeRule(c *cli.Context) OriginRequestConfig {
 	if c.IsSet(Socks5Flag) {
 		proxyType = socksProxy
 	}
+	if flag := StrictRequestFramingFlag; c.IsSet(flag) {
+		strictRequestFraming = c.Bool(flag)
+	}
 	return OriginRequestConfig{
 		ConnectTimeout:         connectTimeout,
 		TLSTimeout:             tlsTimeout,
@@ -116,7 +130,23 @@ func originRequestFromSingeRule(c *cli.Context) OriginRequestConfig {
 		ProxyAddress:           proxyAddress,
 		ProxyPort:              proxyPort,
 		ProxyType:              proxyType,
+		StrictRequestFraming:   strictRequestFraming,
+		CanonicalizeHeaders:    true,
+		ForwardAuthorization:   true,
+		AllowExtendedConnect:   true,
+	}
+}
+
+// WithOriginRequestFlags returns a copy of conf whose originRequest defaults to the rules are
+// overridden by the originRequest flags that also apply to ingress rules, e.g.
+// --strict-request-framing. The rules' own originRequest still takes precedence.
+func WithOriginRequestFlags(c *cli.Context, conf *config.Configuration) *config.Configuration {
+	withFlags := *conf
+	if flag := StrictRequestFramingFlag; c.IsSet(flag) {
+		strictRequestFraming := c.Bool(flag)
+		withFlags.OriginRequest.StrictRequestFraming = &strictRequestFraming
 	}
+	return &withFlags
 }
 
 func originRequestFromYAML(y config.OriginRequestConfig) OriginRequestConfig {
@@ -127,6 +157,10 @@ func originRequestFromYAML(y config.OriginRequestConfig) OriginRequestConfig {
 		KeepAliveConnections: defaultKeepAliveConnections,
 		KeepAliveTimeout:     defaultKeepAliveTimeout,
 		ProxyAddress:         defaultProxyAddress,
+		StrictRequestFraming: true,
+		CanonicalizeHeaders:  true,
+		ForwardAuthorization: true,
+		AllowExtendedConnect: true,
 	}
 	if y.ConnectTimeout != nil {
 		out.ConnectTimeout = *y.ConnectTimeout
@@ -173,6 +207,216 @@ func originRequestFromYAML(y config.OriginRequestConfig) OriginRequestConfig {
 	if y.ProxyType != nil {
 		out.ProxyType = *y.ProxyType
 	}
+	if y.FollowRedirects != nil {
+		out.FollowRedirects = *y.FollowRedirects
+	}
+	if y.MaxBytesPerSecond != nil {
+		out.MaxBytesPerSecond = *y.MaxBytesPerSecond
+	}
+	if y.SNIRoutes != nil {
+		out.SNIRoutes = y.SNIRoutes
+	}
+	if y.TLSVerifyMode != nil {
+		out.TLSVerifyMode = *y.TLSVerifyMode
+	}
+	if y.StrictRequestFraming != nil {
+		out.StrictRequestFraming = *y.StrictRequestFraming
+	}
+	if y.IdempotencyHeader != nil {
+		out.IdempotencyHeader = *y.IdempotencyHeader
+	}
+	if y.IdempotencyWindow != nil {
+		out.IdempotencyWindow = *y.IdempotencyWindow
+	}
+	if y.MaxWebsockets != nil {
+		out.MaxWebsockets = *y.MaxWebsockets
+	}
+	if y.ResponseRewrite != nil {
+		out.ResponseRewrite = y.ResponseRewrite
+	}
+	if y.ReadBufferBytes != nil {
+		out.ReadBufferBytes = *y.ReadBufferBytes
+	}
+	if y.WriteBufferBytes != nil {
+		out.WriteBufferBytes = *y.WriteBufferBytes
+	}
+	if y.SignRequests != nil {
+		out.SignRequests = *y.SignRequests
+	}
+	if y.KeepConnectionHeader != nil {
+		out.KeepConnectionHeader = *y.KeepConnectionHeader
+	}
+	if y.ForceLocalTLS != nil {
+		out.ForceLocalTLS = *y.ForceLocalTLS
+	}
+	if y.ALPN != nil {
+		out.ALPN = y.ALPN
+	}
+	if y.MaxConcurrentDials != nil {
+		out.MaxConcurrentDials = *y.MaxConcurrentDials
+	}
+	if y.WellKnown != nil {
+		out.WellKnown = *y.WellKnown
+	}
+	if y.TraceContext != nil {
+		out.TraceContext = *y.TraceContext
+	}
+	if y.TCPTLS != nil {
+		out.TCPTLS = *y.TCPTLS
+	}
+	if y.ResponseTimeoutByMethod != nil {
+		out.ResponseTimeoutByMethod = y.ResponseTimeoutByMethod
+	}
+	if y.ACMEChallenge != nil {
+		out.ACMEChallenge = *y.ACMEChallenge
+	}
+	if y.RewritePath != nil {
+		out.RewritePath = *y.RewritePath
+	}
+	if y.RewriteCookieDomain != nil {
+		out.RewriteCookieDomain = *y.RewriteCookieDomain
+	}
+	if y.ForceSecureCookies != nil {
+		out.ForceSecureCookies = *y.ForceSecureCookies
+	}
+	if y.CookieSameSite != nil {
+		out.CookieSameSite = *y.CookieSameSite
+	}
+	if y.DNSNegativeTTL != nil {
+		out.DNSNegativeTTL = *y.DNSNegativeTTL
+	}
+	if y.ClientRequestTimeout != nil {
+		out.ClientRequestTimeout = *y.ClientRequestTimeout
+	}
+	if y.StartupGrace != nil {
+		out.StartupGrace = *y.StartupGrace
+	}
+	if y.CanonicalizeHeaders != nil {
+		out.CanonicalizeHeaders = *y.CanonicalizeHeaders
+	}
+	if y.ClientWriteTimeout != nil {
+		out.ClientWriteTimeout = *y.ClientWriteTimeout
+	}
+	if y.OriginScheme != nil {
+		out.OriginScheme = *y.OriginScheme
+	}
+	if y.AssumeHTTP10 != nil {
+		out.AssumeHTTP10 = *y.AssumeHTTP10
+	}
+	if y.Deadline != nil {
+		out.Deadline = *y.Deadline
+	}
+	if y.IPPreference != nil {
+		out.IPPreference = *y.IPPreference
+	}
+	if y.SynthesizeHead != nil {
+		out.SynthesizeHead = *y.SynthesizeHead
+	}
+	if y.DialQueueTimeout != nil {
+		out.DialQueueTimeout = *y.DialQueueTimeout
+	}
+	if y.RedirectHost != nil {
+		out.RedirectHost = *y.RedirectHost
+	}
+	if y.ForwardAuthorization != nil {
+		out.ForwardAuthorization = *y.ForwardAuthorization
+	}
+	if y.AdaptiveConcurrency != nil {
+		out.AdaptiveConcurrency = *y.AdaptiveConcurrency
+	}
+	if y.AllowExtendedConnect != nil {
+		out.AllowExtendedConnect = *y.AllowExtendedConnect
+	}
+	if y.IdempotencyCoalesceOnly != nil {
+		out.IdempotencyCoalesceOnly = *y.IdempotencyCoalesceOnly
+	}
+	if y.TrailingSlash != nil {
+		out.TrailingSlash = *y.TrailingSlash
+	}
+	if y.ServerTiming != nil {
+		out.ServerTiming = *y.ServerTiming
+	}
+	if y.MaxResponseHeaderBytes != nil {
+		out.MaxResponseHeaderBytes = *y.MaxResponseHeaderBytes
+	}
+	if y.RewriteLocation != nil {
+		out.RewriteLocation = *y.RewriteLocation
+	}
+	if y.JSONSchema != nil {
+		out.JSONSchema = *y.JSONSchema
+	}
+	if y.AllowMethods != nil {
+		out.AllowMethods = y.AllowMethods
+	}
+	if y.ClientCertEnv != nil {
+		out.ClientCertEnv = *y.ClientCertEnv
+	}
+	if y.ClientKeyEnv != nil {
+		out.ClientKeyEnv = *y.ClientKeyEnv
+	}
+	if y.SlowRequestThreshold != nil {
+		out.SlowRequestThreshold = *y.SlowRequestThreshold
+	}
+	if y.TLSRenegotiation != nil {
+		out.TLSRenegotiation = *y.TLSRenegotiation
+	}
+	if y.AccessLogFile != nil {
+		out.AccessLogFile = *y.AccessLogFile
+	}
+	if y.AllowUnrequestedUpgrade != nil {
+		out.AllowUnrequestedUpgrade = *y.AllowUnrequestedUpgrade
+	}
+	if y.DNSCacheTTL != nil {
+		out.DNSCacheTTL = *y.DNSCacheTTL
+	}
+	if y.DrainHeader != nil {
+		out.DrainHeader = *y.DrainHeader
+	}
+	if y.ReportTo != nil {
+		out.ReportTo = *y.ReportTo
+	}
+	if y.AdaptiveTimeout != nil {
+		out.AdaptiveTimeout = *y.AdaptiveTimeout
+	}
+	if y.SortQueryParams != nil {
+		out.SortQueryParams = *y.SortQueryParams
+	}
+	if y.DropQueryParams != nil {
+		out.DropQueryParams = y.DropQueryParams
+	}
+	if y.ErrorPages != nil {
+		out.ErrorPages = y.ErrorPages
+	}
+	if y.PoolKey != nil {
+		out.PoolKey = *y.PoolKey
+	}
+	if y.SLOGoodStatuses != nil {
+		out.SLOGoodStatuses = y.SLOGoodStatuses
+	}
+	if y.HealthResponsePath != nil {
+		out.HealthResponsePath = *y.HealthResponsePath
+	}
+	if y.StrictChunked != nil {
+		out.StrictChunked = *y.StrictChunked
+	}
+	if y.WarmupPath != nil {
+		out.WarmupPath = *y.WarmupPath
+	}
+	if y.WarmupOnStart != nil {
+		out.WarmupOnStart = *y.WarmupOnStart
+	}
+	if y.DeadLetterLog != nil {
+		out.DeadLetterLog = *y.DeadLetterLog
+	}
+	if y.SetCookieSameSite != nil {
+		out.SetCookieSameSite = *y.SetCookieSameSite
+	}
+	if y.ForwardedHeader != nil {
+		out.ForwardedHeader = *y.ForwardedHeader
+	}
+	if y.RequestIDHeader != nil {
+		out.RequestIDHeader = *y.RequestIDHeader
+	}
 	return out
 }
 
@@ -216,6 +460,302 @@ type OriginRequestConfig struct {
 	ProxyType string `yaml:"proxyType"`
 	// IP rules for the proxy service
 	IPRules []ipaccess.Rule `yaml:"ipRules"`
+	// Maximum number of redirects from the origin that cloudflared follows itself, instead of
+	// passing them to the client. 0 passes every redirect through.
+	FollowRedirects int `yaml:"followRedirects"`
+	// Maximum bytes per second proxied for this rule, in each direction. 0 means unlimited.
+	MaxBytesPerSecond int `yaml:"maxBytesPerSecond"`
+	// Routes TCP connections to a different origin address (host:port) based on the SNI
+	// of the eyeball's TLS ClientHello, e.g. for a tcp:// service fronting several TLS backends.
+	SNIRoutes map[string]string `yaml:"sniRoutes"`
+	// How to handle an origin certificate that fails verification: strict (the default) aborts the
+	// request, warn logs a warning but still proxies, off skips verification like noTLSVerify.
+	TLSVerifyMode string `yaml:"tlsVerifyMode"`
+	// Reject requests whose framing is ambiguous, e.g. with both Content-Length and Transfer-Encoding,
+	// with 400 instead of proxying them, to protect the origin from request smuggling. On by default.
+	StrictRequestFraming bool `yaml:"strictRequestFraming"`
+	// Requests repeating the value of this header, e.g. Idempotency-Key, within idempotencyWindow
+	// get the first request's response instead of being proxied to the origin again. 5xx and
+	// incomplete responses aren't replayed.
+	IdempotencyHeader string `yaml:"idempotencyHeader"`
+	// How long the response to a request with an idempotencyHeader is replayed for.
+	IdempotencyWindow time.Duration `yaml:"idempotencyWindow"`
+	// Maximum number of simultaneous websocket sessions for this rule. Further upgrade requests
+	// are rejected with 503 until a session closes. No limit if unset.
+	MaxWebsockets int `yaml:"maxWebsockets"`
+	// Regex replacements applied to text response bodies, e.g. to rewrite internal URLs in HTML
+	// or JSON. Binary, compressed and large responses are proxied unchanged.
+	ResponseRewrite []config.ResponseRewriteRule `yaml:"responseRewrite"`
+	// ReadBufferBytes sets the size of the buffer used to copy data read from the origin of
+	// a websocket or TCP stream. The default is 32KiB.
+	ReadBufferBytes int `yaml:"readBufferBytes"`
+	// WriteBufferBytes sets the size of the buffer used to copy data written to the origin
+	// of a websocket or TCP stream. The default is 32KiB.
+	WriteBufferBytes int `yaml:"writeBufferBytes"`
+	// SignRequests adds a header with an HMAC signature of each request, so the origin can
+	// verify the request came through cloudflared.
+	SignRequests config.SignRequestsConfig `yaml:"signRequests"`
+	// KeepConnectionHeader forwards the eyeball's Connection header, and the headers it names,
+	// instead of replacing them with Connection: keep-alive, or close with assumeHTTP10. Other
+	// hop-by-hop headers are still removed.
+	KeepConnectionHeader bool `yaml:"keepConnectionHeader"`
+	// ForceLocalTLS connects to http://localhost origins with TLS, if they accept it. Origins
+	// that don't are still reached over plain HTTP, with a warning.
+	ForceLocalTLS bool `yaml:"forceLocalTLS"`
+	// ALPN lists the application protocols offered in the TLS handshake with the origin, in
+	// order of preference: h2 and/or http/1.1. Offering h2 enables HTTP/2 to the origin.
+	ALPN []string `yaml:"alpn"`
+	// MaxConcurrentDials limits the connection attempts to the origin that may be in flight at
+	// once. Further attempts wait until one of them finishes. 0 means no limit.
+	MaxConcurrentDials int `yaml:"maxConcurrentDials"`
+	// WellKnown serves robots.txt and security.txt from local files, without asking the origin.
+	WellKnown config.WellKnownConfig `yaml:"wellKnown"`
+	// TraceContext sends W3C trace context to the origin: a traceparent header from the
+	// eyeball is passed on, and one is generated for requests without it.
+	TraceContext bool `yaml:"traceContext"`
+	// TCPTLS wraps the connection to a TCP origin in TLS, for TCP services that are
+	// actually TLS.
+	TCPTLS config.TCPTLSConfig `yaml:"tcpTLS"`
+	// ResponseTimeoutByMethod bounds how long the origin may take to respond to requests,
+	// depending on their method, e.g. to give up on slow reads sooner than on slow writes.
+	ResponseTimeoutByMethod map[string]time.Duration `yaml:"responseTimeoutByMethod"`
+	// ACMEChallenge is a directory of ACME HTTP-01 challenge responses, served by cloudflared
+	// for /.well-known/acme-challenge/<token> instead of the origin.
+	ACMEChallenge string `yaml:"acmeChallenge"`
+	// RewritePath replaces the path of every request with a fixed path, e.g. /fixed/endpoint.
+	// The query is kept.
+	RewritePath string `yaml:"rewritePath"`
+	// RewriteCookieDomain rewrites the Domain of cookies set by the origin, e.g. from its
+	// internal domain to the public hostname.
+	RewriteCookieDomain config.CookieDomainRewrite `yaml:"rewriteCookieDomain"`
+	// ForceSecureCookies adds the Secure attribute to cookies set by the origin, e.g. for
+	// origins that are reached over HTTP and don't know the eyeball uses HTTPS.
+	ForceSecureCookies bool `yaml:"forceSecureCookies"`
+	// CookieSameSite adds a SameSite attribute, Strict, Lax or None, to cookies set by the
+	// origin without one.
+	CookieSameSite string `yaml:"cookieSameSite"`
+	// DNSNegativeTTL caches failed DNS lookups of the origin's hostname for this long, so
+	// requests fail fast instead of querying the resolver again. 0 disables caching.
+	DNSNegativeTTL time.Duration `yaml:"dnsNegativeTTL"`
+	// ClientRequestTimeout bounds how long the eyeball may take to send the whole request body.
+	// Slower requests are answered with 408 Request Timeout. 0 means no limit.
+	ClientRequestTimeout time.Duration `yaml:"clientRequestTimeout"`
+	// StartupGrace answers requests with 503 and Retry-After, instead of 502, while the origin
+	// can't be reached during this long after startup, until it has responded once.
+	StartupGrace time.Duration `yaml:"startupGrace"`
+	// CanonicalizeHeaders sends request headers to the origin in Go's canonical casing. Turn it off
+	// for origins that expect the exact casing the eyeball sent.
+	CanonicalizeHeaders bool `yaml:"canonicalizeHeaders"`
+
+	// ClientWriteTimeout aborts a response if writing to the eyeball stays blocked for this long,
+	// e.g. because the client stopped reading, to release the origin connection.
+	ClientWriteTimeout time.Duration `yaml:"clientWriteTimeout"`
+	// OriginScheme, http or https, replaces the scheme of an HTTP service's URL to connect to the
+	// same host and port, e.g. to switch a templated service to TLS.
+	OriginScheme string `yaml:"originScheme"`
+	// AssumeHTTP10 treats the origin like an HTTP/1.0 server: every request asks the origin to
+	// close the connection and connections aren't reused, so a response without a Content-Length
+	// ends when the origin closes the connection.
+	AssumeHTTP10 bool `yaml:"assumeHTTP10"`
+	// Deadline bounds connecting to the origin, waiting for its response and reading the response
+	// body, together. Requests that exceed it are answered with 504 Gateway Timeout, if the
+	// response hasn't started yet.
+	Deadline time.Duration `yaml:"deadline"`
+	// IPPreference restricts or orders the IP families the origin's addresses are dialed with:
+	// ipv4-only, ipv6-only, ipv4-first or ipv6-first. The addresses of the other family are only
+	// tried if dialing the preferred ones fails. By default, Go's dialer picks the order.
+	IPPreference string `yaml:"ipPreference"`
+	// SynthesizeHead sends HEAD requests to the origin as GET requests, and drops the response
+	// body, for origins that don't implement HEAD.
+	SynthesizeHead bool `yaml:"synthesizeHead"`
+	// DialQueueTimeout bounds how long a request waits for one of the maxConcurrentDials slots
+	// to connect to the origin. Requests that wait longer are answered with 503 Service
+	// Unavailable. By default, they wait as long as the request lasts.
+	DialQueueTimeout time.Duration `yaml:"dialQueueTimeout"`
+	// RedirectHost redirects requests for one host to the same path and query on another, e.g.
+	// from example.com to its canonical www.example.com, instead of proxying them.
+	RedirectHost config.HostRedirect `yaml:"redirectHost"`
+	// ForwardAuthorization passes the eyeball's Authorization header to the origin. Turn it off
+	// if the origin shouldn't see the eyeball's credentials, e.g. because Access authenticated
+	// the request already.
+	ForwardAuthorization bool `yaml:"forwardAuthorization"`
+	// AdaptiveConcurrency caps the requests in flight to the origin, lowering the cap when the
+	// origin's response times rise above their usual level, and answers 503 beyond it.
+	AdaptiveConcurrency bool `yaml:"adaptiveConcurrency"`
+	// AllowExtendedConnect forwards extended CONNECT requests, which carry a :protocol pseudo-header,
+	// e.g. for WebTransport. Turn it off to reject them with 400 instead.
+	AllowExtendedConnect bool `yaml:"allowExtendedConnect"`
+	// IdempotencyCoalesceOnly shares the response to a request with an idempotencyHeader only with
+	// the requests repeating its key while it's in flight, instead of replaying it afterwards.
+	// idempotencyWindow isn't needed then, and is ignored.
+	IdempotencyCoalesceOnly bool `yaml:"idempotencyCoalesceOnly"`
+	// TrailingSlash normalizes the trailing slash of request paths before they're proxied: add
+	// appends one, strip removes it, and preserve, the default, leaves paths as they are.
+	TrailingSlash string `yaml:"trailingSlash"`
+	// ServerTiming adds a Server-Timing header to responses, with how long cloudflared took to
+	// connect to the origin (dial), to get the first response byte (ttfb) and in total (total).
+	ServerTiming bool `yaml:"serverTiming"`
+	// MaxResponseHeaderBytes bounds the size of the origin's response headers. Larger ones are
+	// answered with 502 instead of being passed to the eyeball. 0 keeps the default of 10 MB.
+	MaxResponseHeaderBytes int64 `yaml:"maxResponseHeaderBytes"`
+	// RewriteLocation rewrites the Location header of redirects from the origin, e.g. from its
+	// internal URL to the public one.
+	RewriteLocation config.LocationRewrite `yaml:"rewriteLocation"`
+	// JSONSchema is a JSON Schema file that request bodies must match, or they're rejected with 400
+	// before reaching the origin. Bodies are buffered up to 1 MiB to be validated.
+	JSONSchema string `yaml:"jsonSchema"`
+	// AllowMethods lists the request methods that the rule proxies, like [GET, POST]. Other
+	// methods are answered 405 with an Allow header, instead of reaching the origin.
+	AllowMethods []string `yaml:"allowMethods"`
+	// ClientCertEnv names the environment variable with the PEM client certificate that
+	// cloudflared presents to the origin for mTLS, along with the key in clientKeyEnv.
+	ClientCertEnv string `yaml:"clientCertEnv"`
+	// ClientKeyEnv names the environment variable with the PEM private key of clientCertEnv.
+	ClientKeyEnv string `yaml:"clientKeyEnv"`
+	// SlowRequestThreshold logs a warning, with how long dialing the origin, its first response
+	// byte and the whole request took, for every request that takes longer than this.
+	SlowRequestThreshold time.Duration `yaml:"slowRequestThreshold"`
+	// TLSRenegotiation lets legacy origins renegotiate TLS: never (the default), once per
+	// connection, or freely.
+	TLSRenegotiation string `yaml:"tlsRenegotiation"`
+	// AccessLogFile appends a JSON line for every request to a file of the request's hostname,
+	// e.g. /var/log/cloudflared/{hostname}.log.
+	AccessLogFile string `yaml:"accessLogFile"`
+	// AllowUnrequestedUpgrade proxies 101 Switching Protocols responses to requests that didn't
+	// ask to upgrade. By default they're answered with 502, instead of tunneling whatever follows.
+	AllowUnrequestedUpgrade bool `yaml:"allowUnrequestedUpgrade"`
+	// DNSCacheTTL caches the addresses that the origin's hostname resolves to for this long,
+	// whatever the TTL of its DNS records.
+	DNSCacheTTL time.Duration `yaml:"dnsCacheTTL"`
+	// Group services answering with this header set to true, e.g. X-CF-Drain: true, are tried
+	// again only after 30 seconds, in case they stopped draining.
+	DrainHeader string `yaml:"drainHeader"`
+	// ReportTo adds a Report-To header for this endpoint group to the responses that don't
+	// have one, so browsers know where to send security reports.
+	ReportTo config.ReportTo `yaml:"reportTo"`
+	// AdaptiveTimeout answers 504 when the origin takes longer to respond than a percentile of
+	// its recent response times, times a multiplier, e.g. twice the p99.
+	AdaptiveTimeout config.AdaptiveTimeout `yaml:"adaptiveTimeout"`
+	// SortQueryParams sorts the query parameters of requests by name before they're proxied, for
+	// origins or caches that are sensitive to their order.
+	SortQueryParams bool `yaml:"sortQueryParams"`
+	// DropQueryParams removes these query parameters from requests before they're proxied, e.g.
+	// [utm_source, utm_medium].
+	DropQueryParams []string `yaml:"dropQueryParams"`
+	// ErrorPages replaces the body of responses with these statuses, e.g. 404 or 503, with the
+	// HTML template in the file, which may use {{.Host}}, {{.RequestID}}, {{.Status}} and
+	// {{.StatusText}}.
+	ErrorPages map[int]string `yaml:"errorPages"`
+	// PoolKey decides which rules share connections to the same origin: byOrigin shares them
+	// between the byOrigin rules of the origin that have the same TLS settings and timeouts, and
+	// they're opened with the SNI of the first one. bySNI, the default, keeps each rule's
+	// connections to itself.
+	PoolKey string `yaml:"poolKey"`
+	// SLOGoodStatuses counts the responses with these statuses, or ranges of statuses like
+	// 200-399, as good in the SLO metrics of the rule.
+	SLOGoodStatuses []string `yaml:"sloGoodStatuses"`
+	// HealthResponsePath answers GET and HEAD requests to exactly this path, e.g. /cf-health, with
+	// 200 from cloudflared itself, for load balancer health checks that shouldn't reach the origin.
+	HealthResponsePath string `yaml:"healthResponsePath"`
+	// StrictChunked reads the whole chunked response of the origin before sending any of it, so
+	// that the eyeball gets 502 instead of a truncated body if the chunked encoding is malformed.
+	// Server-sent events are still streamed.
+	StrictChunked bool `yaml:"strictChunked"`
+	// WarmupPath is the path, e.g. /warmup, that warmupOnStart requests from the origin.
+	WarmupPath string `yaml:"warmupPath"`
+	// WarmupOnStart sends a GET request for warmupPath to the origin when cloudflared starts
+	// serving the rule, e.g. so that the origin fills its caches before eyeballs arrive.
+	WarmupOnStart bool `yaml:"warmupOnStart"`
+	// DeadLetterLog appends a JSON line with the method, path, status and error of every request
+	// that failed, with an error or a 5xx status, to this file. Bodies and queries aren't logged.
+	DeadLetterLog string `yaml:"deadLetterLog"`
+	// SetCookieSameSite sets the SameSite attribute of every cookie set by the origin to Strict,
+	// Lax or None, replacing the origin's. Cookies with None also get Secure. It can't be set
+	// along with cookieSameSite, which only adds the attribute to cookies that don't have one.
+	SetCookieSameSite string `yaml:"setCookieSameSite"`
+	// ForwardedHeader adds a Forwarded header (RFC 7239) with the eyeball's proto, host and for to
+	// requests, next to the X-Forwarded-* headers that Cloudflare sets.
+	ForwardedHeader bool `yaml:"forwardedHeader"`
+	// RequestIDHeader, e.g. X-Request-Id, is the header that identifies each request. A request
+	// without it gets a new random ID, and the response echoes the ID to the eyeball.
+	RequestIDHeader string `yaml:"requestIdHeader"`
+
+	// clientCertificate is loaded from clientCertEnv and clientKeyEnv when the rule is parsed.
+	clientCertificate *tls.Certificate
+	// dialContext is the rule's DialContext, see StartOrigins.
+	dialContext dialFunc
+	// transports are shared by the rules whose poolKey is byOrigin, see StartOrigins.
+	transports *sharedTransports
+	// connections limits the connections to all origins, see LimitOriginConnections.
+	connections *originConnections
+}
+
+// cookieDomainFormat matches the domains that rewriteCookieDomain accepts. Like browsers, it
+// allows a leading dot.
+var cookieDomainFormat = regexp.MustCompile(`^\.?[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*$`)
+
+// redirectHostFormat matches the hostnames that redirectHost accepts.
+var redirectHostFormat = regexp.MustCompile(`^[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*$`)
+
+// isLocationPrefix returns whether s is an http or https URL that rewriteLocation accepts, with
+// an optional path but no query or fragment.
+func isLocationPrefix(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil && u.RawQuery == "" && !u.ForceQuery && u.Fragment == ""
+}
+
+// Values for tlsVerifyMode.
+const (
+	TLSVerifyStrict = "strict"
+	TLSVerifyWarn   = "warn"
+	TLSVerifyOff    = "off"
+)
+
+// Values for trailingSlash.
+const (
+	TrailingSlashAdd      = "add"
+	TrailingSlashStrip    = "strip"
+	TrailingSlashPreserve = "preserve"
+)
+
+// Values for tlsRenegotiation.
+const (
+	TLSRenegotiationNever  = "never"
+	TLSRenegotiationOnce   = "once"
+	TLSRenegotiationFreely = "freely"
+)
+
+// Values for poolKey.
+const (
+	PoolKeyByOrigin = "byOrigin"
+	PoolKeyBySNI    = "bySNI"
+)
+
+// tlsVerifyMode returns how origin certificates should be verified. An explicit tlsVerifyMode
+// takes precedence over noTLSVerify.
+func (cfg *OriginRequestConfig) tlsVerifyMode() string {
+	if cfg.TLSVerifyMode != "" {
+		return cfg.TLSVerifyMode
+	}
+	if cfg.NoTLSVerify {
+		return TLSVerifyOff
+	}
+	return TLSVerifyStrict
+}
+
+// tlsRenegotiation returns whether origins may ask to renegotiate TLS.
+func (cfg *OriginRequestConfig) tlsRenegotiation() tls.RenegotiationSupport {
+	switch cfg.TLSRenegotiation {
+	case TLSRenegotiationOnce:
+		return tls.RenegotiateOnceAsClient
+	case TLSRenegotiationFreely:
+		return tls.RenegotiateFreelyAsClient
+	default:
+		return tls.RenegotiateNever
+	}
 }
 
 func (defaults *OriginRequestConfig) setConnectTimeout(overrides config.OriginRequestConfig) {
@@ -308,6 +848,426 @@ func (defaults *OriginRequestConfig) setProxyType(overrides config.OriginRequest
 	}
 }
 
+func (defaults *OriginRequestConfig) setFollowRedirects(overrides config.OriginRequestConfig) {
+	if val := overrides.FollowRedirects; val != nil {
+		defaults.FollowRedirects = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setMaxBytesPerSecond(overrides config.OriginRequestConfig) {
+	if val := overrides.MaxBytesPerSecond; val != nil {
+		defaults.MaxBytesPerSecond = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setSNIRoutes(overrides config.OriginRequestConfig) {
+	if val := overrides.SNIRoutes; val != nil {
+		defaults.SNIRoutes = val
+	}
+}
+
+func (defaults *OriginRequestConfig) setTLSVerifyMode(overrides config.OriginRequestConfig) {
+	if val := overrides.TLSVerifyMode; val != nil {
+		defaults.TLSVerifyMode = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setStrictRequestFraming(overrides config.OriginRequestConfig) {
+	if val := overrides.StrictRequestFraming; val != nil {
+		defaults.StrictRequestFraming = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setIdempotencyHeader(overrides config.OriginRequestConfig) {
+	if val := overrides.IdempotencyHeader; val != nil {
+		defaults.IdempotencyHeader = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setIdempotencyWindow(overrides config.OriginRequestConfig) {
+	if val := overrides.IdempotencyWindow; val != nil {
+		defaults.IdempotencyWindow = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setMaxWebsockets(overrides config.OriginRequestConfig) {
+	if val := overrides.MaxWebsockets; val != nil {
+		defaults.MaxWebsockets = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setResponseRewrite(overrides config.OriginRequestConfig) {
+	if val := overrides.ResponseRewrite; val != nil {
+		defaults.ResponseRewrite = val
+	}
+}
+
+func (defaults *OriginRequestConfig) setReadBufferBytes(overrides config.OriginRequestConfig) {
+	if val := overrides.ReadBufferBytes; val != nil {
+		defaults.ReadBufferBytes = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setWriteBufferBytes(overrides config.OriginRequestConfig) {
+	if val := overrides.WriteBufferBytes; val != nil {
+		defaults.WriteBufferBytes = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setSignRequests(overrides config.OriginRequestConfig) {
+	if val := overrides.SignRequests; val != nil {
+		defaults.SignRequests = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setKeepConnectionHeader(overrides config.OriginRequestConfig) {
+	if val := overrides.KeepConnectionHeader; val != nil {
+		defaults.KeepConnectionHeader = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setForceLocalTLS(overrides config.OriginRequestConfig) {
+	if val := overrides.ForceLocalTLS; val != nil {
+		defaults.ForceLocalTLS = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setALPN(overrides config.OriginRequestConfig) {
+	if val := overrides.ALPN; val != nil {
+		defaults.ALPN = val
+	}
+}
+
+func (defaults *OriginRequestConfig) setMaxConcurrentDials(overrides config.OriginRequestConfig) {
+	if val := overrides.MaxConcurrentDials; val != nil {
+		defaults.MaxConcurrentDials = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setWellKnown(overrides config.OriginRequestConfig) {
+	if val := overrides.WellKnown; val != nil {
+		defaults.WellKnown = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setTraceContext(overrides config.OriginRequestConfig) {
+	if val := overrides.TraceContext; val != nil {
+		defaults.TraceContext = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setTCPTLS(overrides config.OriginRequestConfig) {
+	if val := overrides.TCPTLS; val != nil {
+		defaults.TCPTLS = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setResponseTimeoutByMethod(overrides config.OriginRequestConfig) {
+	if val := overrides.ResponseTimeoutByMethod; val != nil {
+		defaults.ResponseTimeoutByMethod = val
+	}
+}
+
+func (defaults *OriginRequestConfig) setACMEChallenge(overrides config.OriginRequestConfig) {
+	if val := overrides.ACMEChallenge; val != nil {
+		defaults.ACMEChallenge = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setRewritePath(overrides config.OriginRequestConfig) {
+	if val := overrides.RewritePath; val != nil {
+		defaults.RewritePath = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setRewriteCookieDomain(overrides config.OriginRequestConfig) {
+	if val := overrides.RewriteCookieDomain; val != nil {
+		defaults.RewriteCookieDomain = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setForceSecureCookies(overrides config.OriginRequestConfig) {
+	if val := overrides.ForceSecureCookies; val != nil {
+		defaults.ForceSecureCookies = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setCookieSameSite(overrides config.OriginRequestConfig) {
+	if val := overrides.CookieSameSite; val != nil {
+		defaults.CookieSameSite = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setDNSNegativeTTL(overrides config.OriginRequestConfig) {
+	if val := overrides.DNSNegativeTTL; val != nil {
+		defaults.DNSNegativeTTL = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setClientRequestTimeout(overrides config.OriginRequestConfig) {
+	if val := overrides.ClientRequestTimeout; val != nil {
+		defaults.ClientRequestTimeout = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setStartupGrace(overrides config.OriginRequestConfig) {
+	if val := overrides.StartupGrace; val != nil {
+		defaults.StartupGrace = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setCanonicalizeHeaders(overrides config.OriginRequestConfig) {
+	if val := overrides.CanonicalizeHeaders; val != nil {
+		defaults.CanonicalizeHeaders = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setClientWriteTimeout(overrides config.OriginRequestConfig) {
+	if val := overrides.ClientWriteTimeout; val != nil {
+		defaults.ClientWriteTimeout = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setOriginScheme(overrides config.OriginRequestConfig) {
+	if val := overrides.OriginScheme; val != nil {
+		defaults.OriginScheme = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setAssumeHTTP10(overrides config.OriginRequestConfig) {
+	if val := overrides.AssumeHTTP10; val != nil {
+		defaults.AssumeHTTP10 = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setDeadline(overrides config.OriginRequestConfig) {
+	if val := overrides.Deadline; val != nil {
+		defaults.Deadline = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setIPPreference(overrides config.OriginRequestConfig) {
+	if val := overrides.IPPreference; val != nil {
+		defaults.IPPreference = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setSynthesizeHead(overrides config.OriginRequestConfig) {
+	if val := overrides.SynthesizeHead; val != nil {
+		defaults.SynthesizeHead = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setDialQueueTimeout(overrides config.OriginRequestConfig) {
+	if val := overrides.DialQueueTimeout; val != nil {
+		defaults.DialQueueTimeout = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setRedirectHost(overrides config.OriginRequestConfig) {
+	if val := overrides.RedirectHost; val != nil {
+		defaults.RedirectHost = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setForwardAuthorization(overrides config.OriginRequestConfig) {
+	if val := overrides.ForwardAuthorization; val != nil {
+		defaults.ForwardAuthorization = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setAdaptiveConcurrency(overrides config.OriginRequestConfig) {
+	if val := overrides.AdaptiveConcurrency; val != nil {
+		defaults.AdaptiveConcurrency = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setAllowExtendedConnect(overrides config.OriginRequestConfig) {
+	if val := overrides.AllowExtendedConnect; val != nil {
+		defaults.AllowExtendedConnect = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setIdempotencyCoalesceOnly(overrides config.OriginRequestConfig) {
+	if val := overrides.IdempotencyCoalesceOnly; val != nil {
+		defaults.IdempotencyCoalesceOnly = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setTrailingSlash(overrides config.OriginRequestConfig) {
+	if val := overrides.TrailingSlash; val != nil {
+		defaults.TrailingSlash = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setServerTiming(overrides config.OriginRequestConfig) {
+	if val := overrides.ServerTiming; val != nil {
+		defaults.ServerTiming = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setMaxResponseHeaderBytes(overrides config.OriginRequestConfig) {
+	if val := overrides.MaxResponseHeaderBytes; val != nil {
+		defaults.MaxResponseHeaderBytes = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setRewriteLocation(overrides config.OriginRequestConfig) {
+	if val := overrides.RewriteLocation; val != nil {
+		defaults.RewriteLocation = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setJSONSchema(overrides config.OriginRequestConfig) {
+	if val := overrides.JSONSchema; val != nil {
+		defaults.JSONSchema = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setAllowMethods(overrides config.OriginRequestConfig) {
+	if val := overrides.AllowMethods; val != nil {
+		defaults.AllowMethods = val
+	}
+}
+
+func (defaults *OriginRequestConfig) setClientCertEnv(overrides config.OriginRequestConfig) {
+	if val := overrides.ClientCertEnv; val != nil {
+		defaults.ClientCertEnv = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setClientKeyEnv(overrides config.OriginRequestConfig) {
+	if val := overrides.ClientKeyEnv; val != nil {
+		defaults.ClientKeyEnv = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setSlowRequestThreshold(overrides config.OriginRequestConfig) {
+	if val := overrides.SlowRequestThreshold; val != nil {
+		defaults.SlowRequestThreshold = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setTLSRenegotiation(overrides config.OriginRequestConfig) {
+	if val := overrides.TLSRenegotiation; val != nil {
+		defaults.TLSRenegotiation = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setAccessLogFile(overrides config.OriginRequestConfig) {
+	if val := overrides.AccessLogFile; val != nil {
+		defaults.AccessLogFile = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setAllowUnrequestedUpgrade(overrides config.OriginRequestConfig) {
+	if val := overrides.AllowUnrequestedUpgrade; val != nil {
+		defaults.AllowUnrequestedUpgrade = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setDNSCacheTTL(overrides config.OriginRequestConfig) {
+	if val := overrides.DNSCacheTTL; val != nil {
+		defaults.DNSCacheTTL = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setDrainHeader(overrides config.OriginRequestConfig) {
+	if val := overrides.DrainHeader; val != nil {
+		defaults.DrainHeader = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setReportTo(overrides config.OriginRequestConfig) {
+	if val := overrides.ReportTo; val != nil {
+		defaults.ReportTo = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setAdaptiveTimeout(overrides config.OriginRequestConfig) {
+	if val := overrides.AdaptiveTimeout; val != nil {
+		defaults.AdaptiveTimeout = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setSortQueryParams(overrides config.OriginRequestConfig) {
+	if val := overrides.SortQueryParams; val != nil {
+		defaults.SortQueryParams = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setDropQueryParams(overrides config.OriginRequestConfig) {
+	if val := overrides.DropQueryParams; val != nil {
+		defaults.DropQueryParams = val
+	}
+}
+
+func (defaults *OriginRequestConfig) setErrorPages(overrides config.OriginRequestConfig) {
+	if val := overrides.ErrorPages; val != nil {
+		defaults.ErrorPages = val
+	}
+}
+
+func (defaults *OriginRequestConfig) setPoolKey(overrides config.OriginRequestConfig) {
+	if val := overrides.PoolKey; val != nil {
+		defaults.PoolKey = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setSLOGoodStatuses(overrides config.OriginRequestConfig) {
+	if val := overrides.SLOGoodStatuses; val != nil {
+		defaults.SLOGoodStatuses = val
+	}
+}
+
+func (defaults *OriginRequestConfig) setHealthResponsePath(overrides config.OriginRequestConfig) {
+	if val := overrides.HealthResponsePath; val != nil {
+		defaults.HealthResponsePath = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setStrictChunked(overrides config.OriginRequestConfig) {
+	if val := overrides.StrictChunked; val != nil {
+		defaults.StrictChunked = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setWarmupPath(overrides config.OriginRequestConfig) {
+	if val := overrides.WarmupPath; val != nil {
+		defaults.WarmupPath = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setWarmupOnStart(overrides config.OriginRequestConfig) {
+	if val := overrides.WarmupOnStart; val != nil {
+		defaults.WarmupOnStart = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setDeadLetterLog(overrides config.OriginRequestConfig) {
+	if val := overrides.DeadLetterLog; val != nil {
+		defaults.DeadLetterLog = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setSetCookieSameSite(overrides config.OriginRequestConfig) {
+	if val := overrides.SetCookieSameSite; val != nil {
+		defaults.SetCookieSameSite = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setForwardedHeader(overrides config.OriginRequestConfig) {
+	if val := overrides.ForwardedHeader; val != nil {
+		defaults.ForwardedHeader = *val
+	}
+}
+
+func (defaults *OriginRequestConfig) setRequestIDHeader(overrides config.OriginRequestConfig) {
+	if val := overrides.RequestIDHeader; val != nil {
+		defaults.RequestIDHeader = *val
+	}
+}
+
 // SetConfig gets config for the requests that cloudflared sends to origins.
 // Each field has a setter method which sets a value for the field by trying to find:
 //   1. The user config for this rule
@@ -332,5 +1292,314 @@ func setConfig(defaults OriginRequestConfig, overrides config.OriginRequestConfi
 	cfg.setProxyPort(overrides)
 	cfg.setProxyAddress(overrides)
 	cfg.setProxyType(overrides)
+	cfg.setFollowRedirects(overrides)
+	cfg.setMaxBytesPerSecond(overrides)
+	cfg.setSNIRoutes(overrides)
+	cfg.setTLSVerifyMode(overrides)
+	cfg.setStrictRequestFraming(overrides)
+	cfg.setIdempotencyHeader(overrides)
+	cfg.setIdempotencyWindow(overrides)
+	cfg.setMaxWebsockets(overrides)
+	cfg.setResponseRewrite(overrides)
+	cfg.setReadBufferBytes(overrides)
+	cfg.setWriteBufferBytes(overrides)
+	cfg.setSignRequests(overrides)
+	cfg.setKeepConnectionHeader(overrides)
+	cfg.setForceLocalTLS(overrides)
+	cfg.setALPN(overrides)
+	cfg.setMaxConcurrentDials(overrides)
+	cfg.setWellKnown(overrides)
+	cfg.setTraceContext(overrides)
+	cfg.setTCPTLS(overrides)
+	cfg.setResponseTimeoutByMethod(overrides)
+	cfg.setACMEChallenge(overrides)
+	cfg.setRewritePath(overrides)
+	cfg.setRewriteCookieDomain(overrides)
+	cfg.setForceSecureCookies(overrides)
+	cfg.setCookieSameSite(overrides)
+	cfg.setDNSNegativeTTL(overrides)
+	cfg.setClientRequestTimeout(overrides)
+	cfg.setStartupGrace(overrides)
+	cfg.setCanonicalizeHeaders(overrides)
+	cfg.setClientWriteTimeout(overrides)
+	cfg.setOriginScheme(overrides)
+	cfg.setAssumeHTTP10(overrides)
+	cfg.setDeadline(overrides)
+	cfg.setIPPreference(overrides)
+	cfg.setSynthesizeHead(overrides)
+	cfg.setDialQueueTimeout(overrides)
+	cfg.setRedirectHost(overrides)
+	cfg.setForwardAuthorization(overrides)
+	cfg.setAdaptiveConcurrency(overrides)
+	cfg.setAllowExtendedConnect(overrides)
+	cfg.setIdempotencyCoalesceOnly(overrides)
+	cfg.setTrailingSlash(overrides)
+	cfg.setServerTiming(overrides)
+	cfg.setMaxResponseHeaderBytes(overrides)
+	cfg.setRewriteLocation(overrides)
+	cfg.setJSONSchema(overrides)
+	cfg.setAllowMethods(overrides)
+	cfg.setClientCertEnv(overrides)
+	cfg.setClientKeyEnv(overrides)
+	cfg.setSlowRequestThreshold(overrides)
+	cfg.setTLSRenegotiation(overrides)
+	cfg.setAccessLogFile(overrides)
+	cfg.setAllowUnrequestedUpgrade(overrides)
+	cfg.setDNSCacheTTL(overrides)
+	cfg.setDrainHeader(overrides)
+	cfg.setReportTo(overrides)
+	cfg.setAdaptiveTimeout(overrides)
+	cfg.setSortQueryParams(overrides)
+	cfg.setDropQueryParams(overrides)
+	cfg.setErrorPages(overrides)
+	cfg.setPoolKey(overrides)
+	cfg.setSLOGoodStatuses(overrides)
+	cfg.setHealthResponsePath(overrides)
+	cfg.setStrictChunked(overrides)
+	cfg.setWarmupPath(overrides)
+	cfg.setWarmupOnStart(overrides)
+	cfg.setDeadLetterLog(overrides)
+	cfg.setSetCookieSameSite(overrides)
+	cfg.setForwardedHeader(overrides)
+	cfg.setRequestIDHeader(overrides)
 	return cfg
 }
+
+// The protocols that can be offered to origins with ALPN.
+const (
+	alpnHTTP2  = "h2"
+	alpnHTTP11 = "http/1.1"
+)
+
+// bufferSizes returns the sizes of the buffers that websocket and TCP streams are copied through.
+func (cfg *OriginRequestConfig) bufferSizes() websocket.BufferSizes {
+	return websocket.BufferSizes{Read: cfg.ReadBufferBytes, Write: cfg.WriteBufferBytes}
+}
+
+// idempotencyWindow returns how long responses to requests with an idempotencyHeader are kept
+// for replaying, which is not at all when they're only shared with requests in flight.
+func (cfg *OriginRequestConfig) idempotencyWindow() time.Duration {
+	if cfg.IdempotencyCoalesceOnly {
+		return 0
+	}
+	return cfg.IdempotencyWindow
+}
+
+// validate checks the config values which can't be checked by YAML parsing alone.
+func (cfg *OriginRequestConfig) validate() error {
+	if cfg.FollowRedirects < 0 {
+		return fmt.Errorf("followRedirects must not be negative, got %d", cfg.FollowRedirects)
+	}
+	if cfg.MaxBytesPerSecond < 0 {
+		return fmt.Errorf("maxBytesPerSecond must be positive, got %d", cfg.MaxBytesPerSecond)
+	}
+	if cfg.MaxWebsockets < 0 {
+		return fmt.Errorf("maxWebsockets must be positive, got %d", cfg.MaxWebsockets)
+	}
+	if cfg.MaxConcurrentDials < 0 {
+		return fmt.Errorf("maxConcurrentDials must be positive, got %d", cfg.MaxConcurrentDials)
+	}
+	dropped := make(map[string]bool, len(cfg.DropQueryParams))
+	for _, name := range cfg.DropQueryParams {
+		if name == "" {
+			return errors.New("dropQueryParams has an empty parameter name")
+		}
+		if dropped[name] {
+			return fmt.Errorf("dropQueryParams lists %s more than once", name)
+		}
+		dropped[name] = true
+	}
+	if adaptive := cfg.AdaptiveTimeout; adaptive != (config.AdaptiveTimeout{}) {
+		if adaptive.Percentile <= 0 || adaptive.Percentile > 100 {
+			return fmt.Errorf("adaptiveTimeout.percentile must be more than 0 and at most 100, got %v", adaptive.Percentile)
+		}
+		if adaptive.Multiplier < 1 {
+			return fmt.Errorf("adaptiveTimeout.multiplier must be at least 1, got %v", adaptive.Multiplier)
+		}
+	}
+	if reportTo := cfg.ReportTo; reportTo != (config.ReportTo{}) {
+		if u, err := url.Parse(reportTo.Endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("reportTo.endpoint must be an https URL, got %q", reportTo.Endpoint)
+		}
+		if reportTo.MaxAge <= 0 {
+			return fmt.Errorf("reportTo.maxAge must be positive, got %d", reportTo.MaxAge)
+		}
+	}
+	if cfg.DrainHeader != "" && !httpguts.ValidHeaderFieldName(cfg.DrainHeader) {
+		return fmt.Errorf("drainHeader %q is not a valid HTTP header name", cfg.DrainHeader)
+	}
+	if cfg.RequestIDHeader != "" && !httpguts.ValidHeaderFieldName(cfg.RequestIDHeader) {
+		return fmt.Errorf("requestIdHeader %q is not a valid HTTP header name", cfg.RequestIDHeader)
+	}
+	if cfg.DNSCacheTTL < 0 {
+		return fmt.Errorf("dnsCacheTTL must not be negative, got %s", cfg.DNSCacheTTL)
+	}
+	if err := validateAccessLogFile(cfg.AccessLogFile); err != nil {
+		return err
+	}
+	if err := validateDeadLetterLog(cfg.DeadLetterLog); err != nil {
+		return err
+	}
+	if cfg.SlowRequestThreshold < 0 {
+		return fmt.Errorf("slowRequestThreshold must not be negative, got %s", cfg.SlowRequestThreshold)
+	}
+	allowed := make(map[string]bool, len(cfg.AllowMethods))
+	for _, method := range cfg.AllowMethods {
+		if !httpguts.ValidHeaderFieldName(method) || strings.ToUpper(method) != method {
+			return fmt.Errorf("allowMethods has an invalid method %q, methods must be upper case like GET", method)
+		}
+		if allowed[method] {
+			return fmt.Errorf("allowMethods lists %s more than once", method)
+		}
+		allowed[method] = true
+	}
+	if rewrite := cfg.RewriteLocation; rewrite != (config.LocationRewrite{}) {
+		if !isLocationPrefix(rewrite.From) || !isLocationPrefix(rewrite.To) {
+			return fmt.Errorf("rewriteLocation needs from and to URLs like http://internal:8080, got %q and %q", rewrite.From, rewrite.To)
+		}
+	}
+	if cfg.MaxResponseHeaderBytes < 0 {
+		return fmt.Errorf("maxResponseHeaderBytes must be positive, got %d", cfg.MaxResponseHeaderBytes)
+	}
+	switch cfg.TLSRenegotiation {
+	case "", TLSRenegotiationNever, TLSRenegotiationOnce, TLSRenegotiationFreely:
+	default:
+		return fmt.Errorf("tlsRenegotiation must be %s, %s or %s, got %q", TLSRenegotiationNever, TLSRenegotiationOnce, TLSRenegotiationFreely, cfg.TLSRenegotiation)
+	}
+	switch cfg.PoolKey {
+	case "", PoolKeyByOrigin, PoolKeyBySNI:
+	default:
+		return fmt.Errorf("poolKey must be %s or %s, got %q", PoolKeyByOrigin, PoolKeyBySNI, cfg.PoolKey)
+	}
+	switch cfg.TrailingSlash {
+	case "", TrailingSlashAdd, TrailingSlashStrip, TrailingSlashPreserve:
+	default:
+		return fmt.Errorf("trailingSlash must be %s, %s or %s, got %q", TrailingSlashAdd, TrailingSlashStrip, TrailingSlashPreserve, cfg.TrailingSlash)
+	}
+	if redirect := cfg.RedirectHost; redirect != (config.HostRedirect{}) {
+		if !redirectHostFormat.MatchString(redirect.From) || !redirectHostFormat.MatchString(redirect.To) {
+			return fmt.Errorf("redirectHost needs from and to hostnames like www.example.com, got %q and %q", redirect.From, redirect.To)
+		}
+		if strings.EqualFold(redirect.From, redirect.To) {
+			return fmt.Errorf("redirectHost would redirect %s to itself", redirect.From)
+		}
+		switch redirect.Status {
+		case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return fmt.Errorf("redirectHost status must be 301, 302, 303, 307 or 308, got %d", redirect.Status)
+		}
+	}
+	if cfg.DialQueueTimeout < 0 {
+		return fmt.Errorf("dialQueueTimeout must not be negative, got %s", cfg.DialQueueTimeout)
+	}
+	switch cfg.IPPreference {
+	case "", IPPreferenceIPv4Only, IPPreferenceIPv6Only, IPPreferenceIPv4First, IPPreferenceIPv6First:
+	default:
+		return fmt.Errorf("ipPreference must be %s, %s, %s or %s, got %q", IPPreferenceIPv4Only, IPPreferenceIPv6Only, IPPreferenceIPv4First, IPPreferenceIPv6First, cfg.IPPreference)
+	}
+	if cfg.Deadline < 0 {
+		return fmt.Errorf("deadline must not be negative, got %s", cfg.Deadline)
+	}
+	switch cfg.OriginScheme {
+	case "", "http", "https":
+	default:
+		return fmt.Errorf("originScheme must be http or https, got %q", cfg.OriginScheme)
+	}
+	if cfg.ClientWriteTimeout < 0 {
+		return fmt.Errorf("clientWriteTimeout must not be negative, got %s", cfg.ClientWriteTimeout)
+	}
+	if cfg.StartupGrace < 0 {
+		return fmt.Errorf("startupGrace must not be negative, got %s", cfg.StartupGrace)
+	}
+	if cfg.ClientRequestTimeout < 0 {
+		return fmt.Errorf("clientRequestTimeout must not be negative, got %s", cfg.ClientRequestTimeout)
+	}
+	if cfg.DNSNegativeTTL < 0 {
+		return fmt.Errorf("dnsNegativeTTL must not be negative, got %s", cfg.DNSNegativeTTL)
+	}
+	if cfg.ReadBufferBytes < 0 {
+		return fmt.Errorf("readBufferBytes must be positive, got %d", cfg.ReadBufferBytes)
+	}
+	if cfg.WriteBufferBytes < 0 {
+		return fmt.Errorf("writeBufferBytes must be positive, got %d", cfg.WriteBufferBytes)
+	}
+	if cfg.IdempotencyHeader != "" {
+		if !httpguts.ValidHeaderFieldName(cfg.IdempotencyHeader) {
+			return fmt.Errorf("idempotencyHeader %q is not a valid HTTP header name", cfg.IdempotencyHeader)
+		}
+		if cfg.IdempotencyWindow <= 0 && !cfg.IdempotencyCoalesceOnly {
+			return fmt.Errorf("idempotencyWindow must be positive when idempotencyHeader is set, got %s", cfg.IdempotencyWindow)
+		}
+	} else if cfg.IdempotencyWindow != 0 {
+		return errors.New("idempotencyWindow is set, but idempotencyHeader isn't")
+	} else if cfg.IdempotencyCoalesceOnly {
+		return errors.New("idempotencyCoalesceOnly is set, but idempotencyHeader isn't")
+	}
+	if cfg.SignRequests.Key == "" && cfg.SignRequests.Header != "" {
+		return errors.New("signRequests.header is set, but signRequests.key isn't")
+	}
+	if header := cfg.SignRequests.Header; header != "" && !httpguts.ValidHeaderFieldName(header) {
+		return fmt.Errorf("signRequests.header %q is not a valid HTTP header name", header)
+	}
+	offered := make(map[string]bool, len(cfg.ALPN))
+	for _, protocol := range cfg.ALPN {
+		if protocol != alpnHTTP2 && protocol != alpnHTTP11 {
+			return fmt.Errorf("alpn protocol %q isn't supported, only %s and %s are", protocol, alpnHTTP2, alpnHTTP11)
+		}
+		if offered[protocol] {
+			return fmt.Errorf("alpn lists %s more than once", protocol)
+		}
+		offered[protocol] = true
+	}
+	for method, timeout := range cfg.ResponseTimeoutByMethod {
+		// Methods are tokens, just like header names.
+		if !httpguts.ValidHeaderFieldName(method) || strings.ToUpper(method) != method {
+			return fmt.Errorf("responseTimeoutByMethod has an invalid method %q, methods must be upper case like GET", method)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("responseTimeoutByMethod for %s must be positive, got %s", method, timeout)
+		}
+	}
+	if path := cfg.RewritePath; path != "" && (!strings.HasPrefix(path, "/") || strings.ContainsAny(path, "?#")) {
+		return fmt.Errorf("rewritePath must be an absolute path like /fixed/endpoint, without a query, got %q", path)
+	}
+	if path := cfg.HealthResponsePath; path != "" && (!strings.HasPrefix(path, "/") || strings.ContainsAny(path, "?#")) {
+		return fmt.Errorf("healthResponsePath must be an absolute path like /cf-health, without a query, got %q", path)
+	}
+	if path := cfg.WarmupPath; path != "" && (!strings.HasPrefix(path, "/") || strings.ContainsAny(path, "#")) {
+		return fmt.Errorf("warmupPath must be an absolute path like /warmup, got %q", path)
+	}
+	if cfg.WarmupOnStart && cfg.WarmupPath == "" {
+		return errors.New("warmupOnStart needs warmupPath with the path to request")
+	}
+	if rewrite := cfg.RewriteCookieDomain; rewrite != (config.CookieDomainRewrite{}) {
+		if !cookieDomainFormat.MatchString(rewrite.From) || !cookieDomainFormat.MatchString(rewrite.To) {
+			return fmt.Errorf("rewriteCookieDomain needs from and to domains like internal.local, got %q and %q", rewrite.From, rewrite.To)
+		}
+	}
+	switch cfg.CookieSameSite {
+	case "", "Strict", "Lax":
+	case "None":
+		// Browsers reject SameSite=None cookies that aren't Secure.
+		if !cfg.ForceSecureCookies {
+			return errors.New("cookieSameSite None needs forceSecureCookies")
+		}
+	default:
+		return fmt.Errorf("cookieSameSite must be one of Strict, Lax or None, got %s", cfg.CookieSameSite)
+	}
+	switch cfg.SetCookieSameSite {
+	case "", "Strict", "Lax", "None":
+	default:
+		return fmt.Errorf("setCookieSameSite must be one of Strict, Lax or None, got %s", cfg.SetCookieSameSite)
+	}
+	if cfg.CookieSameSite != "" && cfg.SetCookieSameSite != "" {
+		// setCookieSameSite would replace the attribute that cookieSameSite adds.
+		return errors.New("cookieSameSite and setCookieSameSite can't both be set, use setCookieSameSite to replace the origin's SameSite attributes")
+	}
+	switch cfg.TLSVerifyMode {
+	case "", TLSVerifyStrict, TLSVerifyWarn, TLSVerifyOff:
+	default:
+		return fmt.Errorf("tlsVerifyMode must be one of %s, %s or %s, got %s", TLSVerifyStrict, TLSVerifyWarn, TLSVerifyOff, cfg.TLSVerifyMode)
+	}
+	return nil
+}
@@ -52,6 +52,84 @@ originRequest:
   proxyAddress: 127.1.2.3
   proxyPort: 100
   proxyType: socks5
+  followRedirects: 1
+  maxBytesPerSecond: 1000
+  tlsVerifyMode: warn
+  strictRequestFraming: false
+  idempotencyHeader: Idempotency-Key
+  idempotencyWindow: 5m
+  maxWebsockets: 100
+  responseRewrite:
+  - match: http://internal
+    replace: https://public
+  readBufferBytes: 65536
+  writeBufferBytes: 65536
+  signRequests:
+    key: root-secret
+  keepConnectionHeader: true
+  forceLocalTLS: true
+  alpn:
+  - h2
+  - http/1.1
+  maxConcurrentDials: 10
+  traceContext: true
+  responseTimeoutByMethod:
+    GET: 10s
+  rewritePath: /root
+  rewriteCookieDomain:
+    from: root.local
+    to: root.example.com
+  forceSecureCookies: true
+  cookieSameSite: Strict
+  dnsNegativeTTL: 5s
+  clientRequestTimeout: 30s
+  startupGrace: 5s
+  canonicalizeHeaders: false
+  clientWriteTimeout: 30s
+  originScheme: https
+  assumeHTTP10: true
+  deadline: 45s
+  ipPreference: ipv6-first
+  synthesizeHead: true
+  dialQueueTimeout: 2s
+  redirectHost:
+    from: root.example.com
+    to: www.root.example.com
+  forwardAuthorization: false
+  adaptiveConcurrency: true
+  allowExtendedConnect: false
+  idempotencyCoalesceOnly: true
+  trailingSlash: add
+  serverTiming: true
+  maxResponseHeaderBytes: 32768
+  rewriteLocation:
+    from: http://root.local:8080
+    to: https://root.example.com
+  allowMethods: [GET]
+  slowRequestThreshold: 2s
+  tlsRenegotiation: once
+  accessLogFile: /var/log/cloudflared/access.log
+  allowUnrequestedUpgrade: true
+  dnsCacheTTL: 30s
+  drainHeader: X-CF-Drain
+  reportTo:
+    endpoint: https://reports.example.com
+    maxAge: 86400
+  adaptiveTimeout:
+    percentile: 99
+    multiplier: 2
+  sortQueryParams: true
+  dropQueryParams: [utm_source]
+  poolKey: bySNI
+  sloGoodStatuses: ["200-399"]
+  healthResponsePath: /healthz
+  strictChunked: false
+  warmupPath: /warm
+  warmupOnStart: false
+  deadLetterLog: /var/log/failed.log
+  setCookieSameSite: ""
+  forwardedHeader: false
+  requestIdHeader: X-Request-Id
 ingress:
 - hostname: tun.example.com
   service: https://localhost:8000
@@ -73,6 +151,85 @@ ingress:
     proxyAddress: interface
     proxyPort: 200
     proxyType: ""
+    followRedirects: 2
+    maxBytesPerSecond: 2000
+    tlsVerifyMode: off
+    strictRequestFraming: true
+    idempotencyHeader: X-Request-Key
+    idempotencyWindow: 1h
+    maxWebsockets: 5
+    responseRewrite:
+    - match: foo
+      replace: bar
+    readBufferBytes: 131072
+    writeBufferBytes: 16384
+    signRequests:
+      key: rule-secret
+      header: X-Origin-Signature
+    keepConnectionHeader: false
+    forceLocalTLS: false
+    alpn:
+    - http/1.1
+    maxConcurrentDials: 2
+    traceContext: false
+    responseTimeoutByMethod:
+      POST: 1m
+    rewritePath: /rule
+    rewriteCookieDomain:
+      from: rule.local
+      to: rule.example.com
+    forceSecureCookies: false
+    cookieSameSite: ""
+    dnsNegativeTTL: 10s
+    clientRequestTimeout: 1m
+    startupGrace: 10s
+    canonicalizeHeaders: true
+    clientWriteTimeout: 1m
+    originScheme: http
+    assumeHTTP10: false
+    deadline: 10s
+    ipPreference: ipv4-only
+    synthesizeHead: false
+    dialQueueTimeout: 500ms
+    redirectHost:
+      from: rule.example.com
+      to: www.rule.example.com
+      status: 301
+    forwardAuthorization: true
+    adaptiveConcurrency: false
+    allowExtendedConnect: true
+    idempotencyCoalesceOnly: false
+    trailingSlash: strip
+    serverTiming: false
+    maxResponseHeaderBytes: 65536
+    rewriteLocation:
+      from: http://rule.local:8080/app
+      to: https://rule.example.com
+    allowMethods: [GET, POST]
+    slowRequestThreshold: 500ms
+    tlsRenegotiation: freely
+    accessLogFile: /var/log/cloudflared/{hostname}.log
+    allowUnrequestedUpgrade: false
+    dnsCacheTTL: 1m
+    drainHeader: X-Drain
+    reportTo:
+      endpoint: https://rule-reports.example.com
+      maxAge: 3600
+    adaptiveTimeout:
+      percentile: 95
+      multiplier: 1.5
+    sortQueryParams: false
+    dropQueryParams: [utm_source, utm_medium]
+    poolKey: byOrigin
+    sloGoodStatuses: ["200-299", "404"]
+    healthResponsePath: /cf-health
+    strictChunked: true
+    warmupPath: /warmup
+    warmupOnStart: true
+    deadLetterLog: /var/log/cf-failed.log
+    setCookieSameSite: None
+    forwardedHeader: true
+    requestIdHeader: X-Trace-Id
 `
 	ing, err := ParseIngress(MustReadIngress(rulesYAML))
 	if err != nil {
@@ -83,42 +240,166 @@ ingress:
 	// root-level configuration.
 	actual0 := ing.Rules[0].Config
 	expected0 := OriginRequestConfig{
-		ConnectTimeout:         1 * time.Minute,
-		TLSTimeout:             1 * time.Second,
-		NoHappyEyeballs:        true,
-		TCPKeepAlive:           1 * time.Second,
-		KeepAliveConnections:   1,
-		KeepAliveTimeout:       1 * time.Second,
-		HTTPHostHeader:         "abc",
-		OriginServerName:       "a1",
-		CAPool:                 "/tmp/path0",
-		NoTLSVerify:            true,
-		DisableChunkedEncoding: true,
-		BastionMode:            true,
-		ProxyAddress:           "127.1.2.3",
-		ProxyPort:              uint(100),
-		ProxyType:              "socks5",
+		ConnectTimeout:          1 * time.Minute,
+		TLSTimeout:              1 * time.Second,
+		NoHappyEyeballs:         true,
+		TCPKeepAlive:            1 * time.Second,
+		KeepAliveConnections:    1,
+		KeepAliveTimeout:        1 * time.Second,
+		HTTPHostHeader:          "abc",
+		OriginServerName:        "a1",
+		CAPool:                  "/tmp/path0",
+		NoTLSVerify:             true,
+		DisableChunkedEncoding:  true,
+		BastionMode:             true,
+		ProxyAddress:            "127.1.2.3",
+		ProxyPort:               uint(100),
+		ProxyType:               "socks5",
+		FollowRedirects:         1,
+		MaxBytesPerSecond:       1000,
+		TLSVerifyMode:           TLSVerifyWarn,
+		StrictRequestFraming:    false,
+		IdempotencyHeader:       "Idempotency-Key",
+		IdempotencyWindow:       5 * time.Minute,
+		MaxWebsockets:           100,
+		ResponseRewrite:         []config.ResponseRewriteRule{{Match: "http://internal", Replace: "https://public"}},
+		ReadBufferBytes:         65536,
+		WriteBufferBytes:        65536,
+		SignRequests:            config.SignRequestsConfig{Key: "root-secret"},
+		KeepConnectionHeader:    true,
+		ForceLocalTLS:           true,
+		ALPN:                    []string{"h2", "http/1.1"},
+		MaxConcurrentDials:      10,
+		TraceContext:            true,
+		ResponseTimeoutByMethod: map[string]time.Duration{"GET": 10 * time.Second},
+		RewritePath:             "/root",
+		RewriteCookieDomain:     config.CookieDomainRewrite{From: "root.local", To: "root.example.com"},
+		ForceSecureCookies:      true,
+		CookieSameSite:          "Strict",
+		DNSNegativeTTL:          5 * time.Second,
+		ClientRequestTimeout:    30 * time.Second,
+		StartupGrace:            5 * time.Second,
+		CanonicalizeHeaders:     false,
+		ClientWriteTimeout:      30 * time.Second,
+		OriginScheme:            "https",
+		AssumeHTTP10:            true,
+		Deadline:                45 * time.Second,
+		IPPreference:            "ipv6-first",
+		SynthesizeHead:          true,
+		DialQueueTimeout:        2 * time.Second,
+		RedirectHost:            config.HostRedirect{From: "root.example.com", To: "www.root.example.com"},
+		ForwardAuthorization:    false,
+		AdaptiveConcurrency:     true,
+		AllowExtendedConnect:    false,
+		IdempotencyCoalesceOnly: true,
+		TrailingSlash:           "add",
+		ServerTiming:            true,
+		MaxResponseHeaderBytes:  32768,
+		RewriteLocation:         config.LocationRewrite{From: "http://root.local:8080", To: "https://root.example.com"},
+		AllowMethods:            []string{"GET"},
+		SlowRequestThreshold:    2 * time.Second,
+		TLSRenegotiation:        TLSRenegotiationOnce,
+		AccessLogFile:           "/var/log/cloudflared/access.log",
+		AllowUnrequestedUpgrade: true,
+		DNSCacheTTL:             30 * time.Second,
+		DrainHeader:             "X-CF-Drain",
+		ReportTo:                config.ReportTo{Endpoint: "https://reports.example.com", MaxAge: 86400},
+		AdaptiveTimeout:         config.AdaptiveTimeout{Percentile: 99, Multiplier: 2},
+		SortQueryParams:         true,
+		DropQueryParams:         []string{"utm_source"},
+		PoolKey:                 "bySNI",
+		SLOGoodStatuses:         []string{"200-399"},
+		HealthResponsePath:      "/healthz",
+		StrictChunked:           false,
+		WarmupPath:              "/warm",
+		WarmupOnStart:           false,
+		DeadLetterLog:           "/var/log/failed.log",
+		SetCookieSameSite:       "",
+		ForwardedHeader:         false,
+		RequestIDHeader:         "X-Request-Id",
 	}
 	require.Equal(t, expected0, actual0)
 
 	// Rule 1 overrode all the root-level config.
 	actual1 := ing.Rules[1].Config
 	expected1 := OriginRequestConfig{
-		ConnectTimeout:         2 * time.Minute,
-		TLSTimeout:             2 * time.Second,
-		NoHappyEyeballs:        false,
-		TCPKeepAlive:           2 * time.Second,
-		KeepAliveConnections:   2,
-		KeepAliveTimeout:       2 * time.Second,
-		HTTPHostHeader:         "def",
-		OriginServerName:       "b2",
-		CAPool:                 "/tmp/path1",
-		NoTLSVerify:            false,
-		DisableChunkedEncoding: false,
-		BastionMode:            false,
-		ProxyAddress:           "interface",
-		ProxyPort:              uint(200),
-		ProxyType:              "",
+		ConnectTimeout:          2 * time.Minute,
+		TLSTimeout:              2 * time.Second,
+		NoHappyEyeballs:         false,
+		TCPKeepAlive:            2 * time.Second,
+		KeepAliveConnections:    2,
+		KeepAliveTimeout:        2 * time.Second,
+		HTTPHostHeader:          "def",
+		OriginServerName:        "b2",
+		CAPool:                  "/tmp/path1",
+		NoTLSVerify:             false,
+		DisableChunkedEncoding:  false,
+		BastionMode:             false,
+		ProxyAddress:            "interface",
+		ProxyPort:               uint(200),
+		ProxyType:               "",
+		FollowRedirects:         2,
+		MaxBytesPerSecond:       2000,
+		TLSVerifyMode:           TLSVerifyOff,
+		StrictRequestFraming:    true,
+		IdempotencyHeader:       "X-Request-Key",
+		IdempotencyWindow:       time.Hour,
+		MaxWebsockets:           5,
+		ResponseRewrite:         []config.ResponseRewriteRule{{Match: "foo", Replace: "bar"}},
+		ReadBufferBytes:         131072,
+		WriteBufferBytes:        16384,
+		SignRequests:            config.SignRequestsConfig{Key: "rule-secret", Header: "X-Origin-Signature"},
+		KeepConnectionHeader:    false,
+		ForceLocalTLS:           false,
+		ALPN:                    []string{"http/1.1"},
+		MaxConcurrentDials:      2,
+		TraceContext:            false,
+		ResponseTimeoutByMethod: map[string]time.Duration{"POST": time.Minute},
+		RewritePath:             "/rule",
+		RewriteCookieDomain:     config.CookieDomainRewrite{From: "rule.local", To: "rule.example.com"},
+		ForceSecureCookies:      false,
+		CookieSameSite:          "",
+		DNSNegativeTTL:          10 * time.Second,
+		ClientRequestTimeout:    time.Minute,
+		StartupGrace:            10 * time.Second,
+		CanonicalizeHeaders:     true,
+		ClientWriteTimeout:      time.Minute,
+		OriginScheme:            "http",
+		AssumeHTTP10:            false,
+		Deadline:                10 * time.Second,
+		IPPreference:            "ipv4-only",
+		SynthesizeHead:          false,
+		DialQueueTimeout:        500 * time.Millisecond,
+		RedirectHost:            config.HostRedirect{From: "rule.example.com", To: "www.rule.example.com", Status: 301},
+		ForwardAuthorization:    true,
+		AdaptiveConcurrency:     false,
+		AllowExtendedConnect:    true,
+		IdempotencyCoalesceOnly: false,
+		TrailingSlash:           "strip",
+		ServerTiming:            false,
+		MaxResponseHeaderBytes:  65536,
+		RewriteLocation:         config.LocationRewrite{From: "http://rule.local:8080/app", To: "https://rule.example.com"},
+		AllowMethods:            []string{"GET", "POST"},
+		SlowRequestThreshold:    500 * time.Millisecond,
+		TLSRenegotiation:        TLSRenegotiationFreely,
+		AccessLogFile:           "/var/log/cloudflared/{hostname}.log",
+		AllowUnrequestedUpgrade: false,
+		DNSCacheTTL:             time.Minute,
+		DrainHeader:             "X-Drain",
+		ReportTo:                config.ReportTo{Endpoint: "https://rule-reports.example.com", MaxAge: 3600},
+		AdaptiveTimeout:         config.AdaptiveTimeout{Percentile: 95, Multiplier: 1.5},
+		SortQueryParams:         false,
+		DropQueryParams:         []string{"utm_source", "utm_medium"},
+		PoolKey:                 "byOrigin",
+		SLOGoodStatuses:         []string{"200-299", "404"},
+		HealthResponsePath:      "/cf-health",
+		StrictChunked:           true,
+		WarmupPath:              "/warmup",
+		WarmupOnStart:           true,
+		DeadLetterLog:           "/var/log/cf-failed.log",
+		SetCookieSameSite:       "None",
+		ForwardedHeader:         true,
+		RequestIDHeader:         "X-Trace-Id",
 	}
 	require.Equal(t, expected1, actual1)
 }
@@ -146,6 +427,85 @@ ingress:
     proxyAddress: interface
     proxyPort: 200
     proxyType: ""
+    followRedirects: 2
+    maxBytesPerSecond: 2000
+    tlsVerifyMode: off
+    strictRequestFraming: false
+    idempotencyHeader: X-Request-Key
+    idempotencyWindow: 1h
+    maxWebsockets: 5
+    responseRewrite:
+    - match: foo
+      replace: bar
+    readBufferBytes: 131072
+    writeBufferBytes: 16384
+    signRequests:
+      key: rule-secret
+      header: X-Origin-Signature
+    keepConnectionHeader: false
+    forceLocalTLS: false
+    alpn:
+    - http/1.1
+    maxConcurrentDials: 2
+    traceContext: false
+    responseTimeoutByMethod:
+      POST: 1m
+    rewritePath: /rule
+    rewriteCookieDomain:
+      from: rule.local
+      to: rule.example.com
+    forceSecureCookies: false
+    cookieSameSite: Lax
+    dnsNegativeTTL: 10s
+    clientRequestTimeout: 1m
+    startupGrace: 10s
+    canonicalizeHeaders: true
+    clientWriteTimeout: 1m
+    originScheme: http
+    assumeHTTP10: false
+    deadline: 10s
+    ipPreference: ipv4-only
+    synthesizeHead: false
+    dialQueueTimeout: 500ms
+    redirectHost:
+      from: rule.example.com
+      to: www.rule.example.com
+      status: 301
+    forwardAuthorization: true
+    adaptiveConcurrency: false
+    allowExtendedConnect: true
+    idempotencyCoalesceOnly: false
+    trailingSlash: strip
+    serverTiming: false
+    maxResponseHeaderBytes: 65536
+    rewriteLocation:
+      from: http://rule.local:8080/app
+      to: https://rule.example.com
+    allowMethods: [GET, POST]
+    slowRequestThreshold: 500ms
+    tlsRenegotiation: freely
+    accessLogFile: /var/log/cloudflared/{hostname}.log
+    allowUnrequestedUpgrade: false
+    dnsCacheTTL: 1m
+    drainHeader: X-Drain
+    reportTo:
+      endpoint: https://rule-reports.example.com
+      maxAge: 3600
+    adaptiveTimeout:
+      percentile: 95
+      multiplier: 1.5
+    sortQueryParams: false
+    dropQueryParams: [utm_source, utm_medium]
+    poolKey: byOrigin
+    sloGoodStatuses: ["200-299", "404"]
+    healthResponsePath: /cf-health
+    strictChunked: true
+    warmupPath: /warmup
+    warmupOnStart: true
+    deadLetterLog: /var/log/cf-failed.log
+    setCookieSameSite: ""
+    forwardedHeader: true
+    requestIdHeader: X-Trace-Id
 `
 	ing, err := ParseIngress(MustReadIngress(rulesYAML))
 	if err != nil {
@@ -161,27 +521,93 @@ ingress:
 		KeepAliveConnections: defaultKeepAliveConnections,
 		KeepAliveTimeout:     defaultKeepAliveTimeout,
 		ProxyAddress:         defaultProxyAddress,
+		StrictRequestFraming: true,
+		CanonicalizeHeaders:  true,
+		ForwardAuthorization: true,
+		AllowExtendedConnect: true,
 	}
 	require.Equal(t, expected0, actual0)
 
 	// Rule 1 overrode all defaults.
 	actual1 := ing.Rules[1].Config
 	expected1 := OriginRequestConfig{
-		ConnectTimeout:         2 * time.Minute,
-		TLSTimeout:             2 * time.Second,
-		NoHappyEyeballs:        false,
-		TCPKeepAlive:           2 * time.Second,
-		KeepAliveConnections:   2,
-		KeepAliveTimeout:       2 * time.Second,
-		HTTPHostHeader:         "def",
-		OriginServerName:       "b2",
-		CAPool:                 "/tmp/path1",
-		NoTLSVerify:            false,
-		DisableChunkedEncoding: false,
-		BastionMode:            false,
-		ProxyAddress:           "interface",
-		ProxyPort:              uint(200),
-		ProxyType:              "",
+		ConnectTimeout:          2 * time.Minute,
+		TLSTimeout:              2 * time.Second,
+		NoHappyEyeballs:         false,
+		TCPKeepAlive:            2 * time.Second,
+		KeepAliveConnections:    2,
+		KeepAliveTimeout:        2 * time.Second,
+		HTTPHostHeader:          "def",
+		OriginServerName:        "b2",
+		CAPool:                  "/tmp/path1",
+		NoTLSVerify:             false,
+		DisableChunkedEncoding:  false,
+		BastionMode:             false,
+		ProxyAddress:            "interface",
+		ProxyPort:               uint(200),
+		ProxyType:               "",
+		FollowRedirects:         2,
+		MaxBytesPerSecond:       2000,
+		TLSVerifyMode:           TLSVerifyOff,
+		StrictRequestFraming:    false,
+		IdempotencyHeader:       "X-Request-Key",
+		IdempotencyWindow:       time.Hour,
+		MaxWebsockets:           5,
+		ResponseRewrite:         []config.ResponseRewriteRule{{Match: "foo", Replace: "bar"}},
+		ReadBufferBytes:         131072,
+		WriteBufferBytes:        16384,
+		SignRequests:            config.SignRequestsConfig{Key: "rule-secret", Header: "X-Origin-Signature"},
+		KeepConnectionHeader:    false,
+		ForceLocalTLS:           false,
+		ALPN:                    []string{"http/1.1"},
+		MaxConcurrentDials:      2,
+		TraceContext:            false,
+		ResponseTimeoutByMethod: map[string]time.Duration{"POST": time.Minute},
+		RewritePath:             "/rule",
+		RewriteCookieDomain:     config.CookieDomainRewrite{From: "rule.local", To: "rule.example.com"},
+		ForceSecureCookies:      false,
+		CookieSameSite:          "Lax",
+		DNSNegativeTTL:          10 * time.Second,
+		ClientRequestTimeout:    time.Minute,
+		StartupGrace:            10 * time.Second,
+		CanonicalizeHeaders:     true,
+		ClientWriteTimeout:      time.Minute,
+		OriginScheme:            "http",
+		AssumeHTTP10:            false,
+		Deadline:                10 * time.Second,
+		IPPreference:            "ipv4-only",
+		SynthesizeHead:          false,
+		DialQueueTimeout:        500 * time.Millisecond,
+		RedirectHost:            config.HostRedirect{From: "rule.example.com", To: "www.rule.example.com", Status: 301},
+		ForwardAuthorization:    true,
+		AdaptiveConcurrency:     false,
+		AllowExtendedConnect:    true,
+		IdempotencyCoalesceOnly: false,
+		TrailingSlash:           "strip",
+		ServerTiming:            false,
+		MaxResponseHeaderBytes:  65536,
+		RewriteLocation:         config.LocationRewrite{From: "http://rule.local:8080/app", To: "https://rule.example.com"},
+		AllowMethods:            []string{"GET", "POST"},
+		SlowRequestThreshold:    500 * time.Millisecond,
+		TLSRenegotiation:        TLSRenegotiationFreely,
+		AccessLogFile:           "/var/log/cloudflared/{hostname}.log",
+		AllowUnrequestedUpgrade: false,
+		DNSCacheTTL:             time.Minute,
+		DrainHeader:             "X-Drain",
+		ReportTo:                config.ReportTo{Endpoint: "https://rule-reports.example.com", MaxAge: 3600},
+		AdaptiveTimeout:         config.AdaptiveTimeout{Percentile: 95, Multiplier: 1.5},
+		SortQueryParams:         false,
+		DropQueryParams:         []string{"utm_source", "utm_medium"},
+		PoolKey:                 "byOrigin",
+		SLOGoodStatuses:         []string{"200-299", "404"},
+		HealthResponsePath:      "/cf-health",
+		StrictChunked:           true,
+		WarmupPath:              "/warmup",
+		WarmupOnStart:           true,
+		DeadLetterLog:           "/var/log/cf-failed.log",
+		SetCookieSameSite:       "",
+		ForwardedHeader:         true,
+		RequestIDHeader:         "X-Trace-Id",
 	}
 	require.Equal(t, expected1, actual1)
 }
@@ -197,7 +623,37 @@ func TestDefaultConfigFromCLI(t *testing.T) {
 		KeepAliveConnections: defaultKeepAliveConnections,
 		KeepAliveTimeout:     defaultKeepAliveTimeout,
 		ProxyAddress:         defaultProxyAddress,
+		StrictRequestFraming: true,
+		CanonicalizeHeaders:  true,
+		ForwardAuthorization: true,
+		AllowExtendedConnect: true,
 	}
 	actual := originRequestFromSingeRule(c)
 	require.Equal(t, expected, actual)
 }
+
+func TestWithOriginRequestFlags(t *testing.T) {
+	set := flag.NewFlagSet("contrive", 0)
+	set.Bool(StrictRequestFramingFlag, true, "")
+	require.NoError(t, set.Parse([]string{"--" + StrictRequestFramingFlag + "=false"}))
+	c := cli.NewContext(nil, set, nil)
+
+	conf := MustReadIngress(`
+ingress:
+ - hostname: strict.example.com
+   service: https://localhost:8000
+   originRequest:
+     strictRequestFraming: true
+ - service: https://localhost:8001
+`)
+	ing, err := ParseIngress(WithOriginRequestFlags(c, conf))
+	require.NoError(t, err)
+	require.True(t, ing.Rules[0].Config.StrictRequestFraming)
+	require.False(t, ing.Rules[1].Config.StrictRequestFraming)
+	require.Nil(t, conf.OriginRequest.StrictRequestFraming, "the configuration must not change")
+
+	// Without the flag, rules are strict by default.
+	ing, err = ParseIngress(WithOriginRequestFlags(cli.NewContext(nil, flag.NewFlagSet("contrive", 0), nil), conf))
+	require.NoError(t, err)
+	require.True(t, ing.Rules[1].Config.StrictRequestFraming)
+}
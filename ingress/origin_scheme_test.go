@@ -0,0 +1,56 @@
+package ingress
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOriginScheme(t *testing.T) {
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer origin.Close()
+
+	rawYAML := `
+ingress:
+ - service: http://` + origin.Listener.Addr().String() + `
+   originRequest:
+     originScheme: https
+     noTLSVerify: true
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+	req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	require.NoError(t, err)
+	resp, err := ing.Rules[0].Service.(HTTPOriginProxy).RoundTrip(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https", req.URL.Scheme)
+}
+
+func TestParseOriginSchemeInvalid(t *testing.T) {
+	rawYAML := `
+ingress:
+ - service: http://localhost:8080
+   originRequest:
+     originScheme: ftp
+`
+	_, err := ParseIngress(MustReadIngress(rawYAML))
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package ingress
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
@@ -18,6 +19,7 @@ import (
 	"github.com/cloudflare/cloudflared/ipaccess"
 	"github.com/cloudflare/cloudflared/socks"
 	"github.com/cloudflare/cloudflared/tlsconfig"
+	"github.com/cloudflare/cloudflared/websocket"
 )
 
 // originService is something a tunnel can proxy traffic to.
@@ -31,8 +33,10 @@ type originService interface {
 
 // unixSocketPath is an OriginService representing a unix socket (which accepts HTTP)
 type unixSocketPath struct {
-	path      string
-	transport *http.Transport
+	path           string
+	transport      *http.Transport
+	signer         *requestSigner
+	keepHeaderCase bool
 }
 
 func (o *unixSocketPath) String() string {
@@ -45,25 +49,61 @@ func (o *unixSocketPath) start(wg *sync.WaitGroup, log *zerolog.Logger, shutdown
 		return err
 	}
 	o.transport = transport
+	o.signer = newRequestSigner(cfg.SignRequests)
+	o.keepHeaderCase = !cfg.CanonicalizeHeaders
 	return nil
 }
 
 type httpService struct {
-	url        *url.URL
-	hostHeader string
-	transport  *http.Transport
+	url         *url.URL
+	hostHeader  string
+	transport   *http.Transport
+	bufferSizes websocket.BufferSizes
+	signer      *requestSigner
+	// keepHeaderCase sends request headers with the names spelled like the eyeball did.
+	keepHeaderCase bool
+	// originScheme replaces the scheme of url, if set.
+	originScheme string
+	// localTLS is set if forceLocalTLS may upgrade this origin to https.
+	localTLS *localTLSUpgrade
 }
 
 func (o *httpService) start(wg *sync.WaitGroup, log *zerolog.Logger, shutdownC <-chan struct{}, errC chan error, cfg OriginRequestConfig) error {
-	transport, err := newHTTPTransport(o, cfg, log)
+	var (
+		transport *http.Transport
+		err       error
+	)
+	if cfg.PoolKey == PoolKeyByOrigin {
+		transport, err = cfg.transports.get(o.url, cfg, func() (*http.Transport, error) { return newHTTPTransport(o, cfg, log) })
+	} else {
+		transport, err = newHTTPTransport(o, cfg, log)
+	}
 	if err != nil {
 		return err
 	}
 	o.hostHeader = cfg.HTTPHostHeader
 	o.transport = transport
+	o.bufferSizes = cfg.bufferSizes()
+	o.signer = newRequestSigner(cfg.SignRequests)
+	o.keepHeaderCase = !cfg.CanonicalizeHeaders
+	o.originScheme = cfg.OriginScheme
+	if cfg.ForceLocalTLS && o.originScheme == "" {
+		o.localTLS = newLocalTLSUpgrade(o.url, transport.TLSClientConfig, cfg.ConnectTimeout, log)
+	}
 	return nil
 }
 
+// scheme returns the scheme to reach the origin with.
+func (o *httpService) scheme() string {
+	if o.originScheme != "" {
+		return o.originScheme
+	}
+	if o.localTLS != nil {
+		return o.localTLS.scheme()
+	}
+	return o.url.Scheme
+}
+
 func (o *httpService) String() string {
 	return o.url.String()
 }
@@ -88,6 +128,13 @@ type tcpOverWSService struct {
 	dest          string
 	isBastion     bool
 	streamHandler streamHandlerFunc
+	// sniRouter optionally picks a different destination based on the eyeball's TLS SNI.
+	sniRouter *sniRouter
+	// tlsConfig, if set, wraps connections to the origin in TLS.
+	tlsConfig  *tls.Config
+	tlsTimeout time.Duration
+	// dial connects to the origin with the rule's dialer, once the service started.
+	dial dialFunc
 }
 
 type socksProxyOverWSService struct {
@@ -142,9 +189,29 @@ func (o *tcpOverWSService) String() string {
 func (o *tcpOverWSService) start(wg *sync.WaitGroup, log *zerolog.Logger, shutdownC <-chan struct{}, errC chan error, cfg OriginRequestConfig) error {
 	if cfg.ProxyType == socksProxy {
 		o.streamHandler = socks.StreamHandler
+	} else if sizes := cfg.bufferSizes(); sizes != (websocket.BufferSizes{}) {
+		o.streamHandler = bufferedStreamHandler(sizes)
 	} else {
 		o.streamHandler = DefaultStreamHandler
 	}
+	if serverName := cfg.TCPTLS.ServerName; serverName != "" {
+		originCertPool, err := tlsconfig.LoadOriginCA(cfg.CAPool, log)
+		if err != nil {
+			return errors.Wrap(err, "Error loading cert pool")
+		}
+		o.tlsConfig = &tls.Config{ServerName: serverName, RootCAs: originCertPool, Renegotiation: cfg.tlsRenegotiation()}
+		if cfg.clientCertificate != nil {
+			o.tlsConfig.Certificates = []tls.Certificate{*cfg.clientCertificate}
+		}
+		switch cfg.tlsVerifyMode() {
+		case TLSVerifyOff:
+			o.tlsConfig.InsecureSkipVerify = true
+		case TLSVerifyWarn:
+			warnOnUnverifiedCert(o.tlsConfig, o, log)
+		}
+		o.tlsTimeout = cfg.TLSTimeout
+	}
+	o.dial = cfg.dialer()
 	return nil
 }
 
@@ -251,22 +318,35 @@ func newHTTPTransport(service originService, cfg OriginRequestConfig, log *zerol
 		IdleConnTimeout:       cfg.KeepAliveTimeout,
 		TLSHandshakeTimeout:   cfg.TLSTimeout,
 		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig:       &tls.Config{RootCAs: originCertPool, InsecureSkipVerify: cfg.NoTLSVerify},
+		TLSClientConfig:       &tls.Config{RootCAs: originCertPool, Renegotiation: cfg.tlsRenegotiation()},
+		// Sends Connection: close with every request.
+		DisableKeepAlives:      cfg.AssumeHTTP10,
+		MaxResponseHeaderBytes: cfg.MaxResponseHeaderBytes,
+	}
+	if cfg.clientCertificate != nil {
+		httpTransport.TLSClientConfig.Certificates = []tls.Certificate{*cfg.clientCertificate}
 	}
 	if _, isHelloWorld := service.(*helloWorld); !isHelloWorld && cfg.OriginServerName != "" {
 		httpTransport.TLSClientConfig.ServerName = cfg.OriginServerName
 	}
-
-	dialer := &net.Dialer{
-		Timeout:   cfg.ConnectTimeout,
-		KeepAlive: cfg.TCPKeepAlive,
+	if len(cfg.ALPN) > 0 {
+		httpTransport.TLSClientConfig.NextProtos = append([]string(nil), cfg.ALPN...)
+		// A custom TLS config disables HTTP/2, unless it's forced.
+		for _, protocol := range cfg.ALPN {
+			if protocol == alpnHTTP2 {
+				httpTransport.ForceAttemptHTTP2 = true
+			}
+		}
 	}
-	if cfg.NoHappyEyeballs {
-		dialer.FallbackDelay = -1 // As of Golang 1.12, a negative delay disables "happy eyeballs"
+	switch cfg.tlsVerifyMode() {
+	case TLSVerifyOff:
+		httpTransport.TLSClientConfig.InsecureSkipVerify = true
+	case TLSVerifyWarn:
+		warnOnUnverifiedCert(httpTransport.TLSClientConfig, service, log)
 	}
 
 	// DialContext depends on which kind of origin is being used.
-	dialContext := dialer.DialContext
+	dialContext := cacheDNSFailures(limitConcurrentDials(cfg.dialer(), cfg.MaxConcurrentDials, cfg.DialQueueTimeout), cfg.DNSNegativeTTL)
 	switch service := service.(type) {
 
 	// If this origin is a unix socket, enforce network type "unix".
@@ -279,10 +359,87 @@ func newHTTPTransport(service originService, cfg OriginRequestConfig, log *zerol
 	default:
 		httpTransport.DialContext = dialContext
 	}
+	cfg.connections.addIdlePool(httpTransport.CloseIdleConnections)
 
 	return &httpTransport, nil
 }
 
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dialer returns how to connect to the origin, before limits like maxConcurrentDials apply.
+func (cfg *OriginRequestConfig) dialer() dialFunc {
+	if cfg.dialContext != nil {
+		return cfg.connections.limit(cfg.dialContext)
+	}
+	dialer := &net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: cfg.TCPKeepAlive,
+	}
+	if cfg.NoHappyEyeballs {
+		dialer.FallbackDelay = -1 // As of Golang 1.12, a negative delay disables "happy eyeballs"
+	}
+	return cfg.connections.limit(cacheDNSResolutions(dialer.DialContext, cfg.DNSCacheTTL, cfg.IPPreference))
+}
+
+// ErrDialQueueTimeout is returned by dials that waited longer than dialQueueTimeout for one of
+// the maxConcurrentDials slots.
+var ErrDialQueueTimeout = errors.New("Timed out waiting for a free maxConcurrentDials slot to connect to the origin")
+
+// limitConcurrentDials makes dial wait while maxDials other dials are in flight, for at most
+// queueTimeout if it's set. It doesn't limit how many connections stay open.
+func limitConcurrentDials(dial dialFunc, maxDials int, queueTimeout time.Duration) dialFunc {
+	if maxDials <= 0 {
+		return dial
+	}
+	slots := make(chan struct{}, maxDials)
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var queueTimeoutC <-chan time.Time
+		if queueTimeout > 0 {
+			timer := time.NewTimer(queueTimeout)
+			defer timer.Stop()
+			queueTimeoutC = timer.C
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-queueTimeoutC:
+			return nil, ErrDialQueueTimeout
+		}
+		defer func() { <-slots }()
+		return dial(ctx, network, addr)
+	}
+}
+
+// warnOnUnverifiedCert makes tlsConfig accept origin certificates that fail verification, but
+// still verifies them itself so that it can log a warning about every such certificate.
+func warnOnUnverifiedCert(tlsConfig *tls.Config, service originService, log *zerolog.Logger) {
+	// The SNI isn't sent for IP addresses, so find the name the certificate should be valid for
+	// the same way Go's HTTP client does.
+	serverName := tlsConfig.ServerName
+	if httpService, ok := service.(*httpService); ok && serverName == "" {
+		serverName = httpService.url.Hostname()
+	}
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("origin didn't present a certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         tlsConfig.RootCAs,
+			DNSName:       serverName,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+			log.Warn().Err(err).Msgf("Origin %s presented a certificate that failed verification, proxying anyway because tlsVerifyMode is %s", service, TLSVerifyWarn)
+		}
+		return nil
+	}
+}
+
 // MockOriginHTTPService should only be used by other packages to mock OriginService. Set Transport to configure desired RoundTripper behavior.
 type MockOriginHTTPService struct {
 	Transport http.RoundTripper
@@ -0,0 +1,48 @@
+package ingress
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// PathSegments matches the paths with a number of segments. Empty segments, like the one
+// between // or after a trailing /, aren't counted.
+type PathSegments struct {
+	min int
+	// max is 0 if there's no upper bound.
+	max int
+}
+
+func newPathSegments(c config.IngressPathSegments) (*PathSegments, error) {
+	if c.Min < 0 || c.Max < 0 {
+		return nil, fmt.Errorf("min and max must not be negative, got %d and %d", c.Min, c.Max)
+	}
+	if c.Min == 0 && c.Max == 0 {
+		return nil, errors.New("min or max must be set")
+	}
+	if c.Max != 0 && c.Max < c.Min {
+		return nil, fmt.Errorf("max must not be less than min, got %d and %d", c.Max, c.Min)
+	}
+	return &PathSegments{min: c.Min, max: c.Max}, nil
+}
+
+func (s *PathSegments) String() string {
+	if s.max == 0 {
+		return fmt.Sprintf("at least %d", s.min)
+	}
+	return fmt.Sprintf("%d to %d", s.min, s.max)
+}
+
+func (s *PathSegments) matches(path string) bool {
+	segments := 0
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments++
+		}
+	}
+	return segments >= s.min && (s.max == 0 || segments <= s.max)
+}
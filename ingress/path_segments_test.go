@@ -0,0 +1,64 @@
+package ingress
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathSegmentsRouting(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - hostname: api.example.com
+   service: http_status:200
+   pathSegments: {min: 2, max: 3}
+ - hostname: api.example.com
+   service: http_status:201
+   pathSegments: {min: 4}
+ - service: http_status:404
+`))
+	require.NoError(t, err)
+
+	for path, expectedRule := range map[string]int{
+		"/a/b":       0,
+		"/a/b/c":     0,
+		"/a/b/":      0,
+		"//a//b":     0,
+		"/a/b/c/d":   1,
+		"/a/b/c/d/e": 1,
+		// Too few segments fall through to the catch-all.
+		"/a": 2,
+		"/":  2,
+	} {
+		req, err := http.NewRequest(http.MethodGet, "http://api.example.com"+path, nil)
+		require.NoError(t, err)
+		_, i := ing.FindMatchingRuleForRequest(req)
+		assert.Equal(t, expectedRule, i, path)
+	}
+}
+
+func TestParsePathSegments(t *testing.T) {
+	for _, invalid := range []string{
+		"{min: -1}",
+		"{min: 0, max: 0}",
+		"{min: 3, max: 2}",
+	} {
+		_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http_status:200
+   pathSegments: ` + invalid + `
+ - service: http_status:404
+`))
+		assert.Error(t, err, invalid)
+	}
+
+	// A rule with pathSegments doesn't match every request, so it can't be the last one.
+	_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http_status:200
+   pathSegments: {max: 1}
+`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package ingress
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var pathTemplateVariable = regexp.MustCompile(`^\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// compilePathTemplate turns a template like /users/{id}/posts into a regex matching the whole
+// path, where each {variable} matches exactly one path segment.
+func compilePathTemplate(template string) (*regexp.Regexp, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, fmt.Errorf("pathTemplate %s must start with /", template)
+	}
+	variables := make(map[string]bool)
+	segments := strings.Split(template, "/")
+	for i, segment := range segments {
+		if match := pathTemplateVariable.FindStringSubmatch(segment); match != nil {
+			if variables[match[1]] {
+				return nil, fmt.Errorf("pathTemplate %s uses the variable {%s} more than once", template, match[1])
+			}
+			variables[match[1]] = true
+			segments[i] = fmt.Sprintf("(?P<%s>[^/]+)", match[1])
+			continue
+		}
+		if strings.ContainsAny(segment, "{}") {
+			return nil, fmt.Errorf("pathTemplate %s has an invalid segment %s, variables must be a whole segment like {id}", template, segment)
+		}
+		segments[i] = regexp.QuoteMeta(segment)
+	}
+	return regexp.Compile("^" + strings.Join(segments, "/") + "$")
+}
@@ -0,0 +1,96 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompilePathTemplate(t *testing.T) {
+	regex, err := compilePathTemplate("/users/{id}/posts")
+	require.NoError(t, err)
+	assert.True(t, regex.MatchString("/users/42/posts"))
+	assert.False(t, regex.MatchString("/users/42/posts/7"))
+	assert.False(t, regex.MatchString("/users//posts"))
+	assert.False(t, regex.MatchString("/users/4/2/posts"))
+	assert.False(t, regex.MatchString("/api/users/42/posts"))
+
+	_, err = compilePathTemplate("/files/{name}.txt")
+	assert.Error(t, err)
+	regex, err = compilePathTemplate("/v1.0/{user}/{post}")
+	require.NoError(t, err)
+	assert.True(t, regex.MatchString("/v1.0/alice/hello-world"))
+	assert.False(t, regex.MatchString("/v1x0/alice/hello-world"))
+
+	for _, invalid := range []string{"users/{id}", "/users/{id}/{id}", "/users/{}", "/users/{1d}"} {
+		_, err := compilePathTemplate(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParsePathTemplate(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: api.example.com
+   pathTemplate: /users/{id}/posts
+   service: https://localhost:8000
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	rule, i := ing.FindMatchingRule("api.example.com", "/users/42/posts")
+	assert.Equal(t, 0, i)
+	assert.Equal(t, "/users/{id}/posts", rule.PathTemplate)
+	_, i = ing.FindMatchingRule("api.example.com", "/users/42")
+	assert.Equal(t, 1, i)
+
+	rawYAML = `
+ingress:
+ - path: ^/users
+   pathTemplate: /users/{id}/posts
+   service: https://localhost:8000
+ - service: http_status:404
+`
+	_, err = ParseIngress(MustReadIngress(rawYAML))
+	assert.Error(t, err)
+}
+
+func TestParsePathSuffix(t *testing.T) {
+	rawYAML := `
+ingress:
+ - pathSuffix: .json
+   service: https://localhost:8000
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	rule, i := ing.FindMatchingRule("www.example.com", "/a/b.json")
+	assert.Equal(t, 0, i)
+	assert.Equal(t, ".json", rule.PathSuffix)
+	assert.Contains(t, rule.MultiLineString(), "pathSuffix: .json")
+	for _, path := range []string{"/a/b.html", "/a/bxjson", "/a/b.json/c"} {
+		_, i = ing.FindMatchingRule("www.example.com", path)
+		assert.Equal(t, 1, i, path)
+	}
+
+	for _, pathMode := range []string{"path: ^/api", "pathTemplate: /users/{id}"} {
+		rawYAML = `
+ingress:
+ - pathSuffix: .json
+   ` + pathMode + `
+   service: https://localhost:8000
+ - service: http_status:404
+`
+		_, err = ParseIngress(MustReadIngress(rawYAML))
+		assert.Error(t, err, pathMode)
+	}
+
+	// A rule with only a pathSuffix isn't a catch-all rule.
+	_, err = ParseIngress(MustReadIngress(`
+ingress:
+ - pathSuffix: .json
+   service: https://localhost:8000
+`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriority(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - hostname: "*.example.com"
+   service: http_status:200
+ - hostname: api.example.com
+   service: http_status:201
+   priority: 10
+ - hostname: www.example.com
+   service: http_status:202
+ - hostname: admin.example.com
+   service: http_status:203
+   priority: 10
+ - hostname: old.example.com
+   service: http_status:204
+   priority: -1
+ - service: http_status:404
+`))
+	require.NoError(t, err)
+
+	var hostnames []string
+	var indices []int
+	for _, rule := range ing.Rules {
+		hostnames = append(hostnames, rule.Hostname)
+		indices = append(indices, rule.Index)
+	}
+	// Rules with the same priority keep the order they were written in.
+	assert.Equal(t, []string{"api.example.com", "admin.example.com", "*.example.com", "www.example.com", "old.example.com", ""}, hostnames)
+	// But they're still reported by their position in the file.
+	assert.Equal(t, []int{1, 3, 0, 2, 4, 5}, indices)
+	assert.Equal(t, 3, ing.Status()[1].Rule)
+
+	// The higher priority rule wins, even though the wildcard rule comes first in the file.
+	rule, _ := ing.FindMatchingRule("api.example.com", "/")
+	assert.Equal(t, "HTTP 201", rule.Service.String())
+	rule, _ = ing.FindMatchingRule("www.example.com", "/")
+	assert.Equal(t, "HTTP 200", rule.Service.String())
+	rule, _ = ing.FindMatchingRule("other.org", "/")
+	assert.Equal(t, "HTTP 404", rule.Service.String())
+}
+
+func TestPriorityOnCatchAll(t *testing.T) {
+	_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - hostname: api.example.com
+   service: http_status:201
+ - service: http_status:404
+   priority: 5
+`))
+	assert.Error(t, err)
+}
+
+func TestPriorityReportsFileOrder(t *testing.T) {
+	_, diags, err := ParseIngressWithDiagnostics(MustReadIngress(`
+ingress:
+ - hostname: www.example.com
+   service: http_status:200
+ - hostname: "*.example.com"
+   service: http_status:201
+   priority: 10
+ - service: http_status:404
+`))
+	require.NoError(t, err)
+	require.Len(t, diags, 1)
+	assert.Equal(t, "warning: rule #1: this rule will never be matched, because rule #2 matches every request it would match", diags[0].String())
+
+	_, err = ParseIngress(MustReadIngress(`
+ingress:
+ - hostname: www.example.com
+   service: http_status:200
+   shard:
+     by: header:X-User
+     buckets: 2
+     bucket: 0
+ - hostname: api.example.com
+   service: http_status:201
+   priority: 10
+   shard:
+     by: header:X-User
+     buckets: 3
+     bucket: 0
+ - service: http_status:404
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Rule #1 shards by header:X-User into 2 buckets, but rule #2 shards it into 3")
+}
@@ -0,0 +1,70 @@
+package ingress
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// RefererPolicy only lets requests through that were referred by an allowed origin. Its
+// methods are safe to call on a nil RefererPolicy, which allows every request.
+type RefererPolicy struct {
+	// origins are the allowed scheme://host[:port], in lower case.
+	origins      []string
+	allowMissing bool
+}
+
+func newRefererPolicy(c *config.IngressReferer) (*RefererPolicy, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if len(c.Allow) == 0 {
+		return nil, fmt.Errorf("allow must list at least one origin")
+	}
+	policy := RefererPolicy{allowMissing: c.AllowMissing}
+	for _, allowed := range c.Allow {
+		u, err := url.Parse(allowed)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" || u.RawQuery != "" {
+			return nil, fmt.Errorf("%q should be an origin like https://www.example.com", allowed)
+		}
+		policy.origins = append(policy.origins, refererOrigin(u))
+	}
+	return &policy, nil
+}
+
+func refererOrigin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// Allows checks if the origin of req's Referer is allowed.
+func (p *RefererPolicy) Allows(req *http.Request) bool {
+	if p == nil {
+		return true
+	}
+	referer := req.Header.Get("Referer")
+	if referer == "" {
+		return p.allowMissing
+	}
+	u, err := url.Parse(referer)
+	if err != nil {
+		return false
+	}
+	origin := refererOrigin(u)
+	for _, allowed := range p.origins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RefererPolicy) String() string {
+	s := "allow " + strings.Join(p.origins, ", ")
+	if p.allowMissing {
+		s += " or no referer"
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+package ingress
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefererPolicy(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: images.example.com
+   service: http_status:200
+   referer:
+     allow:
+     - https://site.example.com
+     - http://localhost:8080/
+     allowMissing: true
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	policy := ing.Rules[0].Referer
+	require.NotNil(t, policy)
+	assert.Nil(t, ing.Rules[1].Referer)
+
+	tests := []struct {
+		referer string
+		allowed bool
+	}{
+		{referer: "https://site.example.com/posts/1?page=2", allowed: true},
+		{referer: "https://SITE.example.com/", allowed: true},
+		{referer: "http://localhost:8080/index.html", allowed: true},
+		{referer: "", allowed: true},
+		{referer: "http://site.example.com/", allowed: false},
+		{referer: "https://site.example.com.evil.example/", allowed: false},
+		{referer: "http://localhost:8081/", allowed: false},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, "http://images.example.com/cat.png", nil)
+		require.NoError(t, err)
+		if test.referer != "" {
+			req.Header.Set("Referer", test.referer)
+		}
+		assert.Equal(t, test.allowed, policy.Allows(req), test.referer)
+	}
+	assert.Contains(t, ing.Rules[0].MultiLineString(), "referer: allow https://site.example.com, http://localhost:8080 or no referer")
+}
+
+func TestParseRefererInvalid(t *testing.T) {
+	for _, allow := range []string{"[]", "[site.example.com]", "[ftp://site.example.com]", "[https://site.example.com/posts]"} {
+		rawYAML := `
+ingress:
+ - hostname: images.example.com
+   service: http_status:200
+   referer:
+     allow: ` + allow + `
+ - service: http_status:404
+`
+		_, err := ParseIngress(MustReadIngress(rawYAML))
+		assert.Error(t, err, allow)
+	}
+}
@@ -0,0 +1,95 @@
+package ingress
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// verifySignature checks a signature header the way an origin would.
+func verifySignature(t *testing.T, key string, r *http.Request, value string) {
+	parts := strings.Split(value, ",")
+	require.Len(t, parts, 2, value)
+	require.True(t, strings.HasPrefix(parts[0], "t="), value)
+	require.True(t, strings.HasPrefix(parts[1], "sig="), value)
+	timestamp := strings.TrimPrefix(parts[0], "t=")
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + "\n" + r.Method + "\n" + r.Host + "\n" + r.URL.RequestURI()))
+	sig, err := hex.DecodeString(strings.TrimPrefix(parts[1], "sig="))
+	require.NoError(t, err)
+	assert.True(t, hmac.Equal(mac.Sum(nil), sig), "signature doesn't verify")
+}
+
+func TestRequestSignerSign(t *testing.T) {
+	signer := newRequestSigner(config.SignRequestsConfig{Key: "secret"})
+	signer.clock = func() time.Time { return time.Unix(1600000000, 0) }
+
+	req, err := http.NewRequest(http.MethodPost, "http://origin.example.com/hooks?id=1", nil)
+	require.NoError(t, err)
+	req.Header.Set(DefaultSignatureHeader, "forged by the eyeball")
+	signer.sign(req)
+
+	value := req.Header.Get(DefaultSignatureHeader)
+	assert.True(t, strings.HasPrefix(value, "t=1600000000,sig="), value)
+	verifySignature(t, "secret", req, value)
+
+	// Any change to the signed elements changes the signature.
+	other := req.Clone(req.Context())
+	other.URL.RawQuery = "id=2"
+	signer.sign(other)
+	assert.NotEqual(t, value, other.Header.Get(DefaultSignatureHeader))
+}
+
+func TestRequestSignerDisabled(t *testing.T) {
+	signer := newRequestSigner(config.SignRequestsConfig{})
+	assert.Nil(t, signer)
+
+	req, err := http.NewRequest(http.MethodGet, "http://origin.example.com/", nil)
+	require.NoError(t, err)
+	signer.sign(req)
+	assert.Empty(t, req.Header.Get(DefaultSignatureHeader))
+}
+
+func TestHTTPServiceSignsRequests(t *testing.T) {
+	const key = "0123456789abcdef"
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get("X-Origin-Auth")
+		if value == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		verifySignature(t, key, r, value)
+		assert.Equal(t, "app.internal", r.Host)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+	originURL, err := url.Parse(origin.URL)
+	require.NoError(t, err)
+
+	service := &httpService{url: originURL}
+	cfg := OriginRequestConfig{
+		HTTPHostHeader: "app.internal",
+		SignRequests:   config.SignRequestsConfig{Key: key, Header: "X-Origin-Auth"},
+	}
+	require.NoError(t, service.start(&sync.WaitGroup{}, testLogger, make(chan struct{}), make(chan error), cfg))
+
+	req, err := http.NewRequest(http.MethodGet, "http://tunnel.example.com/api/users?page=2", nil)
+	require.NoError(t, err)
+	resp, err := service.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
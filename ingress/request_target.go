@@ -0,0 +1,35 @@
+package ingress
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+var errAbsoluteForm = errors.New("Requests with an absolute-form target, like the ones sent to a forward proxy, aren't accepted")
+
+// NormalizeRequestTarget turns an absolute-form request target, e.g. GET http://example.com/path,
+// into the origin-form that rules are matched against and origins expect. The target's host
+// replaces the Host header, as RFC 7230 section 5.4 requires. If rejectAbsoluteForm is set,
+// such requests return an error instead.
+func (ing Ingress) NormalizeRequestTarget(req *http.Request) error {
+	if req.RequestURI == "" || req.RequestURI[0] == '/' || req.RequestURI == "*" {
+		return nil
+	}
+	target, err := url.ParseRequestURI(req.RequestURI)
+	if err != nil || !target.IsAbs() {
+		return nil
+	}
+	if ing.rejectAbsoluteForm {
+		return errAbsoluteForm
+	}
+	if target.Host != "" {
+		req.Host = target.Host
+	}
+	req.URL.Path = target.Path
+	req.URL.RawPath = target.RawPath
+	req.URL.RawQuery = target.RawQuery
+	req.RequestURI = req.URL.RequestURI()
+	return nil
+}
@@ -0,0 +1,86 @@
+package ingress
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+func TestNormalizeRequestTarget(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: api.example.com
+   path: ^/v1/
+   service: http://localhost:8000
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		requestURI   string
+		host         string
+		expectedURI  string
+		expectedHost string
+		expectedRule int
+	}{
+		{
+			name:         "absolute-form",
+			requestURI:   "http://api.example.com/v1/users?id=1",
+			host:         "other.example.com",
+			expectedURI:  "/v1/users?id=1",
+			expectedHost: "api.example.com",
+			expectedRule: 0,
+		},
+		{
+			name:         "origin-form",
+			requestURI:   "/v1/users",
+			host:         "other.example.com",
+			expectedURI:  "/v1/users",
+			expectedHost: "other.example.com",
+			expectedRule: 1,
+		},
+		{
+			name:         "asterisk-form",
+			requestURI:   "*",
+			host:         "api.example.com",
+			expectedURI:  "*",
+			expectedHost: "api.example.com",
+			expectedRule: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := url.ParseRequestURI(tt.requestURI)
+			require.NoError(t, err)
+			req := &http.Request{Method: http.MethodGet, URL: target, RequestURI: tt.requestURI, Host: tt.host, Header: http.Header{}}
+			require.NoError(t, ing.NormalizeRequestTarget(req))
+			assert.Equal(t, tt.expectedURI, req.RequestURI)
+			assert.Equal(t, tt.expectedHost, req.Host)
+			_, ruleNum := ing.FindMatchingRuleForRequest(req)
+			assert.Equal(t, tt.expectedRule, ruleNum)
+		})
+	}
+}
+
+func TestRejectAbsoluteForm(t *testing.T) {
+	ing, err := ParseIngress(&config.Configuration{
+		Ingress:            []config.UnvalidatedIngressRule{{Service: "http://localhost:8000"}},
+		RejectAbsoluteForm: true,
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "http://api.example.com/v1", nil)
+	require.NoError(t, err)
+	req.RequestURI = "http://api.example.com/v1"
+	assert.Equal(t, errAbsoluteForm, ing.NormalizeRequestTarget(req))
+
+	req.RequestURI = "/v1"
+	assert.NoError(t, ing.NormalizeRequestTarget(req))
+}
@@ -0,0 +1,104 @@
+package ingress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// maxRewrittenBodySize bounds the response bodies that are buffered for rewriting. Larger
+// responses are proxied unchanged.
+const maxRewrittenBodySize = 1 << 20
+
+// ResponseRewriter applies regex replacements to text response bodies. Its methods are safe
+// to call on a nil ResponseRewriter, which leaves responses unchanged.
+type ResponseRewriter struct {
+	rules []responseRewriteRule
+}
+
+type responseRewriteRule struct {
+	match   *regexp.Regexp
+	replace []byte
+}
+
+func newResponseRewriter(rules []config.ResponseRewriteRule) (*ResponseRewriter, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	rewriter := ResponseRewriter{rules: make([]responseRewriteRule, len(rules))}
+	for i, rule := range rules {
+		if rule.Match == "" {
+			return nil, errors.Errorf("responseRewrite #%d has no match regex", i+1)
+		}
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, errors.Wrapf(err, "responseRewrite #%d has an invalid match regex", i+1)
+		}
+		rewriter.rules[i] = responseRewriteRule{match: match, replace: []byte(rule.Replace)}
+	}
+	return &rewriter, nil
+}
+
+// Rewrite replaces resp's body with the rewritten body, unless the response isn't
+// uncompressed text or is larger than maxRewrittenBodySize.
+func (rw *ResponseRewriter) Rewrite(resp *http.Response) error {
+	if rw == nil || !isRewritableText(resp.Header) || resp.ContentLength > maxRewrittenBodySize {
+		return nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxRewrittenBodySize+1))
+	if err != nil {
+		return errors.Wrap(err, "Error reading the response body to rewrite")
+	}
+	if len(body) > maxRewrittenBodySize {
+		// The length was unknown, so give back what was read in front of the rest of the body.
+		resp.Body = &readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return nil
+	}
+	rewritten := body
+	for _, rule := range rw.rules {
+		rewritten = rule.match.ReplaceAll(rewritten, rule.replace)
+	}
+	resp.Body = &readCloser{Reader: bytes.NewReader(rewritten), Closer: resp.Body}
+	resp.ContentLength = int64(len(rewritten))
+	if !bytes.Equal(body, rewritten) {
+		resp.Header.Set("Content-Length", strconv.Itoa(len(rewritten)))
+		// The origin's ETag identifies the original body.
+		resp.Header.Del("ETag")
+	}
+	return nil
+}
+
+// isRewritableText checks that the body is text, e.g. HTML or JSON, and not compressed. Server-sent
+// events are text too, but they're an open-ended stream that can't be buffered.
+func isRewritableText(header http.Header) bool {
+	if encoding := header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "text/event-stream":
+		return false
+	case "application/json", "application/javascript", "application/xml", "application/x-www-form-urlencoded":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml")
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
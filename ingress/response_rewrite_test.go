@@ -0,0 +1,134 @@
+package ingress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+func textResponse(contentType string, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {contentType}, "Etag": {`"v1"`}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+func TestResponseRewriter(t *testing.T) {
+	rewriter, err := newResponseRewriter([]config.ResponseRewriteRule{
+		{Match: `http://internal(\.corp)?`, Replace: "https://public"},
+		{Match: `port=(\d+)`, Replace: "port=[$1]"},
+	})
+	require.NoError(t, err)
+
+	resp := textResponse("text/html; charset=utf-8", []byte(`<a href="http://internal.corp/a">port=8080</a>`))
+	require.NoError(t, rewriter.Rewrite(resp))
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	expected := `<a href="https://public/a">port=[8080]</a>`
+	assert.Equal(t, expected, string(body))
+	assert.Equal(t, int64(len(expected)), resp.ContentLength)
+	assert.Empty(t, resp.Header.Get("ETag"))
+
+	resp = textResponse("application/problem+json", []byte(`{"self":"http://internal/x"}`))
+	require.NoError(t, rewriter.Rewrite(resp))
+	body, err = ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"self":"https://public/x"}`, string(body))
+}
+
+func TestResponseRewriterSkipsUnsupportedBodies(t *testing.T) {
+	rewriter, err := newResponseRewriter([]config.ResponseRewriteRule{{Match: "http://internal", Replace: "https://public"}})
+	require.NoError(t, err)
+
+	original := []byte("http://internal/logo.png")
+	gzipped := textResponse("text/html", original)
+	gzipped.Header.Set("Content-Encoding", "gzip")
+	unknownLength := textResponse("text/plain", []byte("http://internal "+strings.Repeat("a", maxRewrittenBodySize)))
+	unknownLength.ContentLength = -1
+	tests := map[string]*http.Response{
+		"binary":         textResponse("image/png", original),
+		"no type":        textResponse("", original),
+		"compressed":     gzipped,
+		"too large":      textResponse("text/plain", make([]byte, maxRewrittenBodySize+1)),
+		"unknown length": unknownLength,
+		"event stream":   textResponse("text/event-stream", original),
+	}
+	for name, resp := range tests {
+		expected, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body = ioutil.NopCloser(bytes.NewReader(expected))
+
+		require.NoError(t, rewriter.Rewrite(resp), name)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, expected, body, name)
+		assert.Equal(t, `"v1"`, resp.Header.Get("ETag"), name)
+	}
+
+	var noRewriter *ResponseRewriter
+	resp := textResponse("text/html", original)
+	require.NoError(t, noRewriter.Rewrite(resp))
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, original, body)
+}
+
+func TestResponseRewriterDoesntBufferEventStreams(t *testing.T) {
+	rewriter, err := newResponseRewriter([]config.ResponseRewriteRule{{Match: "http://internal", Replace: "https://public"}})
+	require.NoError(t, err)
+
+	// The origin holds the stream open, so reading it to the end would never return.
+	events, origin := io.Pipe()
+	defer origin.Close()
+	resp := textResponse("text/event-stream", nil)
+	resp.Body = events
+	resp.ContentLength = -1
+	done := make(chan error, 1)
+	go func() {
+		done <- rewriter.Rewrite(resp)
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Rewrite blocked reading the event stream")
+	}
+	assert.Equal(t, events, resp.Body)
+}
+
+func TestParseResponseRewrite(t *testing.T) {
+	rawYAML := `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     responseRewrite:
+       - match: http://internal
+         replace: https://public
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	require.NotNil(t, ing.Rules[0].ResponseRewrite)
+	assert.Len(t, ing.Rules[0].ResponseRewrite.rules, 1)
+
+	rawYAML = `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     responseRewrite:
+       - match: "http://(internal"
+         replace: https://public
+`
+	_, err = ParseIngress(MustReadIngress(rawYAML))
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package ingress
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ResponseTimeouts bounds how long the origin may take to send the response headers, depending
+// on the request method. Its methods are safe to call on a nil ResponseTimeouts, which doesn't
+// time out anything.
+type ResponseTimeouts struct {
+	byMethod  map[string]time.Duration
+	afterFunc func(d time.Duration, f func()) (stop func() bool)
+}
+
+func newResponseTimeouts(byMethod map[string]time.Duration) *ResponseTimeouts {
+	if len(byMethod) == 0 {
+		return nil
+	}
+	return &ResponseTimeouts{
+		byMethod: byMethod,
+		afterFunc: func(d time.Duration, f func()) func() bool {
+			return time.AfterFunc(d, f).Stop
+		},
+	}
+}
+
+// Start returns req with a context that is canceled once the timeout for its method passes,
+// and a ResponseTimer that must be stopped once the response headers arrive.
+func (t *ResponseTimeouts) Start(req *http.Request) (*http.Request, *ResponseTimer) {
+	if t == nil {
+		return req, nil
+	}
+	timeout, ok := t.byMethod[req.Method]
+	if !ok {
+		return req, nil
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := ResponseTimer{timeout: timeout}
+	timer.stop = t.afterFunc(timeout, func() {
+		atomic.StoreInt32(&timer.expired, 1)
+		cancel()
+	})
+	return req.WithContext(ctx), &timer
+}
+
+// ResponseTimer is started by ResponseTimeouts.Start or AdaptiveTimeout.Start. Its methods are
+// safe to call on a nil ResponseTimer, which never expires.
+type ResponseTimer struct {
+	timeout time.Duration
+	stop    func() bool
+	expired int32
+	// observed, if set, is called once the timer is stopped.
+	observed func()
+}
+
+// Stop keeps the timer from expiring, e.g. because the response headers arrived.
+func (t *ResponseTimer) Stop() {
+	if t == nil {
+		return
+	}
+	t.stop()
+	if t.observed != nil {
+		t.observed()
+		t.observed = nil
+	}
+}
+
+// Expired reports whether the request was canceled because the origin took too long.
+func (t *ResponseTimer) Expired() bool {
+	return t != nil && atomic.LoadInt32(&t.expired) == 1
+}
+
+// Timeout returns how long the origin had to respond.
+func (t *ResponseTimer) Timeout() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.timeout
+}
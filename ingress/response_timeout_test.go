@@ -0,0 +1,105 @@
+package ingress
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTimers replaces time.AfterFunc, so tests decide when timers fire.
+type fakeTimers struct {
+	durations []time.Duration
+	fire      []func()
+	stopped   []bool
+}
+
+func (f *fakeTimers) afterFunc(d time.Duration, fire func()) func() bool {
+	i := len(f.durations)
+	f.durations = append(f.durations, d)
+	f.fire = append(f.fire, fire)
+	f.stopped = append(f.stopped, false)
+	return func() bool {
+		f.stopped[i] = true
+		return true
+	}
+}
+
+func TestResponseTimeoutsByMethod(t *testing.T) {
+	rawYAML := `
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     responseTimeoutByMethod:
+       GET: 10s
+       POST: 60s
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	timeouts := ing.Rules[0].ResponseTimeouts
+	require.NotNil(t, timeouts)
+	timers := &fakeTimers{}
+	timeouts.afterFunc = timers.afterFunc
+
+	get, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	get, getTimer := timeouts.Start(get)
+	post, err := http.NewRequest(http.MethodPost, "http://example.com", nil)
+	require.NoError(t, err)
+	post, postTimer := timeouts.Start(post)
+	require.Equal(t, []time.Duration{10 * time.Second, 60 * time.Second}, timers.durations)
+	assert.Equal(t, 10*time.Second, getTimer.Timeout())
+	assert.Equal(t, 60*time.Second, postTimer.Timeout())
+
+	// The slow GET times out, while the POST's response arrives in time.
+	timers.fire[0]()
+	postTimer.Stop()
+	assert.True(t, getTimer.Expired())
+	assert.Error(t, get.Context().Err())
+	assert.False(t, postTimer.Expired())
+	assert.NoError(t, post.Context().Err())
+	assert.True(t, timers.stopped[1])
+
+	put, err := http.NewRequest(http.MethodPut, "http://example.com", nil)
+	require.NoError(t, err)
+	started, putTimer := timeouts.Start(put)
+	assert.Nil(t, putTimer)
+	assert.Equal(t, put, started)
+	assert.False(t, putTimer.Expired())
+	assert.Len(t, timers.durations, 2)
+}
+
+func TestParseResponseTimeoutByMethod(t *testing.T) {
+	invalid := []string{`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     responseTimeoutByMethod:
+       get: 10s
+`, `
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     responseTimeoutByMethod:
+       "GET POST": 10s
+`, `
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     responseTimeoutByMethod:
+       GET: 0s
+`}
+	for _, rawYAML := range invalid {
+		_, err := ParseIngress(MustReadIngress(rawYAML))
+		assert.Error(t, err, rawYAML)
+	}
+
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+`))
+	require.NoError(t, err)
+	assert.Nil(t, ing.Rules[0].ResponseTimeouts)
+}
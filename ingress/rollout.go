@@ -0,0 +1,63 @@
+package ingress
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// Names of the configurations while one is rolled out, which label the rules of each in metrics,
+// logs and the rule status.
+const (
+	ConfigCurrent = "current"
+	ConfigRollout = "rollout"
+)
+
+// rollout routes a share of the requests with the rules of another configuration, to roll it
+// out gradually.
+type rollout struct {
+	next    Ingress
+	percent float64
+
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+// RollOut makes ForRequest pick the rules of next for percent of the requests, at random from
+// seed, and these rules for the others. next's origins are started with StartOrigins.
+func (ing *Ingress) RollOut(next Ingress, percent float64, seed int64) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("the rollout percent must be from 0 to 100, got %v", percent)
+	}
+	if next.IsEmpty() {
+		return ErrNoIngressRules
+	}
+	next.rolledOut = true
+	ing.rollout = &rollout{next: next, percent: percent, rand: rand.New(rand.NewSource(seed))}
+	return nil
+}
+
+// ConfigName returns the name of the configuration these rules are from, ConfigRollout if they
+// are being rolled out and ConfigCurrent otherwise.
+func (ing Ingress) ConfigName() string {
+	if ing.rolledOut {
+		return ConfigRollout
+	}
+	return ConfigCurrent
+}
+
+// ForRequest returns the rules that a request should be routed with: the rules being rolled
+// out for their share of the requests, and these rules otherwise.
+func (ing Ingress) ForRequest() Ingress {
+	r := ing.rollout
+	if r == nil {
+		return ing
+	}
+	r.lock.Lock()
+	roll := r.rand.Float64() * 100
+	r.lock.Unlock()
+	if roll < r.percent {
+		return r.next
+	}
+	return ing
+}
@@ -0,0 +1,83 @@
+package ingress
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollOut(t *testing.T) {
+	current, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+`))
+	require.NoError(t, err)
+	next, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:9000
+`))
+	require.NoError(t, err)
+
+	for _, percent := range []float64{0, 5, 30, 100} {
+		ing := current
+		require.NoError(t, ing.RollOut(next, percent, 42))
+		const requests = 10000
+		routedToNext := 0
+		for i := 0; i < requests; i++ {
+			if ing.ForRequest().Rules[0].Service.String() == "http://localhost:9000" {
+				routedToNext++
+			}
+		}
+		assert.InDelta(t, percent, float64(routedToNext)*100/requests, 2, percent)
+	}
+	// Without a rollout, every request uses the same rules.
+	assert.Equal(t, "http://localhost:8000", current.ForRequest().Rules[0].Service.String())
+
+	for _, invalid := range []float64{-1, 100.5} {
+		ing := current
+		assert.Error(t, ing.RollOut(next, invalid, 42), invalid)
+	}
+	ing := current
+	assert.Error(t, ing.RollOut(Ingress{}, 10, 42))
+}
+
+func TestRollOutStatus(t *testing.T) {
+	current, err := ParseIngress(MustReadIngress(`
+ingress:
+ - hostname: www.example.com
+   service: http://localhost:8000
+ - service: http_status:404
+`))
+	require.NoError(t, err)
+	next, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:9000
+`))
+	require.NoError(t, err)
+	require.NoError(t, current.RollOut(next, 100, 42))
+	assert.Equal(t, ConfigCurrent, current.ConfigName())
+	assert.Equal(t, ConfigRollout, current.ForRequest().ConfigName())
+
+	// Both configurations are served, and their rules told apart by config.
+	current.ForRequest().RecordRequest(0, errors.New("connection refused"))
+	status := current.Status()
+	require.Len(t, status, 3)
+	assert.Equal(t, []string{ConfigCurrent, ConfigCurrent, ConfigRollout}, []string{status[0].Config, status[1].Config, status[2].Config})
+	assert.Equal(t, 0, status[2].Rule)
+	assert.Equal(t, "http://localhost:9000", status[2].Service)
+	assert.Equal(t, "connection refused", status[2].LastError)
+	assert.Empty(t, status[0].LastError)
+
+	req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	require.NoError(t, err)
+	closeStream := current.ForRequest().OpenStream(req, 0)
+	defer closeStream()
+	streams := current.Streams()
+	require.Len(t, streams.Rules, 3)
+	assert.Equal(t, ConfigRollout, streams.Rules[2].Config)
+	assert.Equal(t, 1, streams.Rules[2].ActiveStreams)
+	assert.Equal(t, 0, streams.Rules[0].ActiveStreams)
+}
@@ -0,0 +1,65 @@
+package ingress
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// RouteFlag builds ingress rules from the command line, without a config file.
+const RouteFlag = "route"
+
+// routeFieldSeparator separates the fields of a --route value. It isn't a comma, which
+// TUNNEL_ROUTE uses to separate routes, and which path regexes may contain.
+const routeFieldSeparator = ";"
+
+var errRoutesWithIngress = errors.New("You can't set the --route flag when the config file has ingress rules")
+
+// ParseRouteFlags builds an Ingress from --route values like
+// "hostname=api.example.com;path=^/v1/;service=http://localhost:8000", in order. They're
+// validated like ingress rules from the config file, so the last one must catch all requests.
+// Other settings, e.g. the default originRequest, still come from conf.
+func ParseRouteFlags(routes []string, conf *config.Configuration) (Ingress, error) {
+	if len(conf.Ingress) > 0 {
+		return Ingress{}, errRoutesWithIngress
+	}
+	rules := make([]config.UnvalidatedIngressRule, len(routes))
+	for i, route := range routes {
+		rule, err := parseRouteFlag(route)
+		if err != nil {
+			return Ingress{}, errors.Wrapf(err, "Invalid --%s %q", RouteFlag, route)
+		}
+		rules[i] = rule
+	}
+	fromFlags := *conf
+	fromFlags.Ingress = rules
+	return ParseIngress(&fromFlags)
+}
+
+func parseRouteFlag(route string) (config.UnvalidatedIngressRule, error) {
+	var rule config.UnvalidatedIngressRule
+	for _, field := range strings.Split(route, routeFieldSeparator) {
+		eq := strings.Index(field, "=")
+		if eq < 0 {
+			return rule, fmt.Errorf("%q should look like key=value", field)
+		}
+		key, value := strings.TrimSpace(field[:eq]), strings.TrimSpace(field[eq+1:])
+		switch key {
+		case "hostname":
+			rule.Hostname = value
+		case "path":
+			rule.Path = value
+		case "service":
+			rule.Service = value
+		default:
+			return rule, fmt.Errorf("unknown key %q, only hostname, path and service are supported", key)
+		}
+	}
+	if rule.Service == "" {
+		return rule, errors.New("service is missing")
+	}
+	return rule, nil
+}
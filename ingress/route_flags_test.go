@@ -0,0 +1,86 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+func TestParseRouteFlags(t *testing.T) {
+	fromYAML, err := ParseIngress(MustReadIngress(`
+ingress:
+  - hostname: api.example.com
+    path: ^/v1/
+    service: http://localhost:8000
+  - hostname: "*.example.com"
+    service: http://localhost:8001
+  - service: http_status:404
+`))
+	require.NoError(t, err)
+	fromFlags, err := ParseRouteFlags([]string{
+		"hostname=api.example.com;path=^/v1/;service=http://localhost:8000",
+		"hostname=*.example.com; service=http://localhost:8001",
+		"service=http_status:404",
+	}, &config.Configuration{})
+	require.NoError(t, err)
+	assert.True(t, fromFlags.Equal(fromYAML))
+}
+
+func TestParseRouteFlagsPathWithComma(t *testing.T) {
+	ing, err := ParseRouteFlags([]string{
+		"path=^/v[0-9]{1,2}/;service=http://localhost:8000",
+		"service=http_status:404",
+	}, &config.Configuration{})
+	require.NoError(t, err)
+	assert.Equal(t, "^/v[0-9]{1,2}/", ing.Rules[0].Path.String())
+}
+
+func TestParseRouteFlagsUsesDefaultOriginRequest(t *testing.T) {
+	conf := config.Configuration{}
+	noTLSVerify := true
+	conf.OriginRequest.NoTLSVerify = &noTLSVerify
+	ing, err := ParseRouteFlags([]string{"service=https://localhost:8000"}, &conf)
+	require.NoError(t, err)
+	assert.True(t, ing.Rules[0].Config.NoTLSVerify)
+}
+
+func TestParseRouteFlagsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []string
+		conf   config.Configuration
+	}{
+		{
+			name:   "Missing catch-all",
+			routes: []string{"hostname=api.example.com;service=http://localhost:8000"},
+		},
+		{
+			name:   "Missing service",
+			routes: []string{"hostname=api.example.com", "service=http_status:404"},
+		},
+		{
+			name:   "Unknown key",
+			routes: []string{"hostname=api.example.com;originRequest=x;service=http://localhost:8000", "service=http_status:404"},
+		},
+		{
+			name:   "Not key=value",
+			routes: []string{"http://localhost:8000"},
+		},
+		{
+			name:   "Config file has ingress rules",
+			routes: []string{"service=http://localhost:8000"},
+			conf: config.Configuration{
+				Ingress: []config.UnvalidatedIngressRule{{Service: "http://localhost:8001"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRouteFlags(tt.routes, &tt.conf)
+			assert.Error(t, err)
+		})
+	}
+}
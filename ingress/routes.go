@@ -0,0 +1,52 @@
+package ingress
+
+// anyMethod is listed as a route's method when the rule accepts every HTTP method.
+const anyMethod = "*"
+
+// Route describes an HTTP ingress rule, e.g. for documentation or importing into an API gateway.
+type Route struct {
+	// Hostname pattern, or "*" if the rule matches every hostname.
+	Hostname string `json:"hostname" yaml:"hostname"`
+	// Path regex, empty if the rule matches every path or has a PathTemplate or PathSuffix.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// PathTemplate is the rule's pathTemplate, e.g. /users/{id}, if it has one.
+	PathTemplate string `json:"pathTemplate,omitempty" yaml:"pathTemplate,omitempty"`
+	// PathSuffix is the rule's pathSuffix, e.g. .json, if it has one.
+	PathSuffix string `json:"pathSuffix,omitempty" yaml:"pathSuffix,omitempty"`
+	// Methods are the rule's allowMethods, or "*" if it proxies every method.
+	Methods []string `json:"methods" yaml:"methods"`
+	// Service describes the origin the rule proxies to, e.g. https://localhost:8000 or HTTP 404.
+	Service string `json:"service" yaml:"service"`
+}
+
+// Routes lists the rules that proxy HTTP requests, in the order they are matched. Rules for
+// TCP-based services, e.g. SSH or SOCKS, are left out.
+func (ing Ingress) Routes() []Route {
+	var routes []Route
+	for _, rule := range ing.Rules {
+		if _, ok := rule.Service.(HTTPOriginProxy); !ok {
+			continue
+		}
+		route := Route{
+			Hostname: rule.Hostname,
+			Service:  rule.Service.String(),
+		}
+		if len(rule.Config.AllowMethods) > 0 {
+			route.Methods = append([]string(nil), rule.Config.AllowMethods...)
+		} else {
+			route.Methods = []string{anyMethod}
+		}
+		if route.Hostname == "" {
+			route.Hostname = "*"
+		}
+		if rule.PathTemplate != "" {
+			route.PathTemplate = rule.PathTemplate
+		} else if rule.PathSuffix != "" {
+			route.PathSuffix = rule.PathSuffix
+		} else if rule.Path != nil {
+			route.Path = rule.Path.String()
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
@@ -0,0 +1,64 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutes(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: api.example.com
+   path: ^/v1/
+   service: https://localhost:8000
+   originRequest:
+     allowMethods: [GET, POST]
+ - hostname: api.example.com
+   pathTemplate: /v2/users/{id}
+   service: https://localhost:8001
+ - hostname: api.example.com
+   pathSuffix: .json
+   service: https://localhost:8002
+ - hostname: ssh.example.com
+   service: ssh://localhost:22
+ - hostname: "*.example.com"
+   service: unix:/tmp/app.sock
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+
+	expected := []Route{
+		{
+			Hostname: "api.example.com",
+			Path:     "^/v1/",
+			Methods:  []string{"GET", "POST"},
+			Service:  "https://localhost:8000",
+		},
+		{
+			Hostname:     "api.example.com",
+			PathTemplate: "/v2/users/{id}",
+			Methods:      []string{"*"},
+			Service:      "https://localhost:8001",
+		},
+		{
+			Hostname:   "api.example.com",
+			PathSuffix: ".json",
+			Methods:    []string{"*"},
+			Service:    "https://localhost:8002",
+		},
+		{
+			Hostname: "*.example.com",
+			Methods:  []string{"*"},
+			Service:  "unix socket: /tmp/app.sock",
+		},
+		{
+			Hostname: "*",
+			Methods:  []string{"*"},
+			Service:  "HTTP 404",
+		},
+	}
+	assert.Equal(t, expected, ing.Routes())
+}
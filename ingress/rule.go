@@ -1,6 +1,9 @@
 package ingress
 
 import (
+	"context"
+	"net"
+	"net/http"
 	"regexp"
 	"strings"
 )
@@ -8,12 +11,47 @@ import (
 // Rule routes traffic from a hostname/path on the public internet to the
 // service running on the given URL.
 type Rule struct {
+	// Index is the rule's position in the config file. Rules are reported by it, even if
+	// their priorities changed the order they're matched in.
+	Index int
+
 	// Requests for this hostname will be proxied to this rule's service.
 	Hostname string
 
 	// Path is an optional regex that can specify path-driven ingress rules.
 	Path *regexp.Regexp
 
+	// PathTemplate is set if Path was compiled from a template like /users/{id}/posts. It
+	// labels the requests matching this rule in logs, instead of their exact path.
+	PathTemplate string
+
+	// PathSuffix is set if Path was compiled to match the paths ending with it.
+	PathSuffix string
+
+	// Schedule optionally restricts this rule to a daily time window.
+	Schedule *Schedule
+
+	// BodyMatch optionally restricts this rule to requests with a given JSON field value.
+	BodyMatch *BodyMatch
+
+	// Shard optionally restricts this rule to requests whose key hashes to its bucket.
+	Shard *Shard
+
+	// RequireClientCert rejects requests that didn't present a client certificate.
+	RequireClientCert bool
+
+	// RequireTLS rejects requests that didn't come to Cloudflare over TLS.
+	RequireTLS bool
+
+	// Accepts, if set, restricts the rule to requests that prefer one of these media types.
+	Accepts []string
+
+	// PathSegments optionally restricts this rule to paths with a number of segments.
+	PathSegments *PathSegments
+
+	// Referer rejects requests that weren't referred by an allowed origin, if referer is set.
+	Referer *RefererPolicy
+
 	// A (probably local) address. Requests for a hostname which matches this
 	// rule's hostname pattern will be proxied to the service running on this
 	// address.
@@ -21,6 +59,60 @@ type Rule struct {
 
 	// Configure the request cloudflared sends to this specific origin.
 	Config OriginRequestConfig
+
+	// Bandwidth throttles the bytes proxied for this rule, if maxBytesPerSecond is set.
+	Bandwidth *BandwidthLimiter
+
+	// Idempotency replays responses to repeated requests, if idempotencyHeader is set.
+	Idempotency *IdempotencyCache
+
+	// Websockets caps the simultaneous websocket sessions, if maxWebsockets is set.
+	Websockets *SessionLimiter
+
+	// ResponseRewrite rewrites text response bodies, if responseRewrite is set.
+	ResponseRewrite *ResponseRewriter
+
+	// WellKnown serves robots.txt and security.txt, if wellKnown is set.
+	WellKnown *WellKnownFiles
+
+	// ACMEChallenges serves ACME HTTP-01 challenge responses, if acmeChallenge is set.
+	ACMEChallenges *ACMEChallenges
+
+	// ResponseTimeouts bounds how long the origin may take to respond, if
+	// responseTimeoutByMethod is set.
+	ResponseTimeouts *ResponseTimeouts
+
+	// AdaptiveTimeout bounds how long the origin may take to respond by its recent response
+	// times, if adaptiveTimeout is set.
+	AdaptiveTimeout *AdaptiveTimeout
+
+	// StartupGrace asks eyeballs to retry while the origin is still starting up, if
+	// startupGrace is set.
+	StartupGrace *StartupGrace
+
+	// Concurrency caps the requests in flight to the origin by its response times, if
+	// adaptiveConcurrency is set.
+	Concurrency *ConcurrencyLimiter
+
+	// JSONSchema validates request bodies, if jsonSchema is set.
+	JSONSchema *JSONSchema
+
+	// ErrorPages replaces the bodies of error responses, if errorPages is set.
+	ErrorPages *ErrorPages
+
+	// SLO counts the rule's good and total requests, if sloGoodStatuses is set.
+	SLO *SLO
+
+	// AccessLogs writes the access logs of each hostname to its own file, if accessLogFile is
+	// set.
+	AccessLogs *AccessLogs
+
+	// DeadLetterLog records the rule's failed requests, if deadLetterLog is set.
+	DeadLetterLog *DeadLetterLog
+
+	// DialContext, if set, replaces how connections to the rule's HTTP or TCP origin are
+	// opened, e.g. so that tests or programs embedding cloudflared can intercept them.
+	DialContext func(ctx context.Context, network, address string) (net.Conn, error)
 }
 
 // MultiLineString is for outputting rules in a human-friendly way when Cloudflared
@@ -32,11 +124,55 @@ func (r Rule) MultiLineString() string {
 		out.WriteString(r.Hostname)
 		out.WriteRune('\n')
 	}
-	if r.Path != nil {
+	if r.PathTemplate != "" {
+		out.WriteString("\tpathTemplate: ")
+		out.WriteString(r.PathTemplate)
+		out.WriteRune('\n')
+	} else if r.PathSuffix != "" {
+		out.WriteString("\tpathSuffix: ")
+		out.WriteString(r.PathSuffix)
+		out.WriteRune('\n')
+	} else if r.Path != nil {
 		out.WriteString("\tpath: ")
 		out.WriteString(r.Path.String())
 		out.WriteRune('\n')
 	}
+	if r.Schedule != nil {
+		out.WriteString("\tschedule: ")
+		out.WriteString(r.Schedule.String())
+		out.WriteRune('\n')
+	}
+	if r.BodyMatch != nil {
+		out.WriteString("\tbodyMatch: ")
+		out.WriteString(r.BodyMatch.String())
+		out.WriteRune('\n')
+	}
+	if r.Shard != nil {
+		out.WriteString("\tshard: ")
+		out.WriteString(r.Shard.String())
+		out.WriteRune('\n')
+	}
+	if r.RequireClientCert {
+		out.WriteString("\trequireClientCert: true\n")
+	}
+	if r.RequireTLS {
+		out.WriteString("\trequireTLS: true\n")
+	}
+	if len(r.Accepts) > 0 {
+		out.WriteString("\taccepts: ")
+		out.WriteString(strings.Join(r.Accepts, ", "))
+		out.WriteRune('\n')
+	}
+	if r.PathSegments != nil {
+		out.WriteString("\tpathSegments: ")
+		out.WriteString(r.PathSegments.String())
+		out.WriteRune('\n')
+	}
+	if r.Referer != nil {
+		out.WriteString("\treferer: ")
+		out.WriteString(r.Referer.String())
+		out.WriteRune('\n')
+	}
 	out.WriteString("\tservice: ")
 	out.WriteString(r.Service.String())
 	return out.String()
@@ -45,6 +181,14 @@ func (r Rule) MultiLineString() string {
 // Matches checks if the rule matches a given hostname/path combination.
 func (r *Rule) Matches(hostname, path string) bool {
 	hostMatch := r.Hostname == "" || r.Hostname == "*" || matchHost(r.Hostname, hostname)
-	pathMatch := r.Path == nil || r.Path.MatchString(path)
-	return hostMatch && pathMatch
+	pathMatch := (r.Path == nil || r.Path.MatchString(path)) && (r.PathSegments == nil || r.PathSegments.matches(path))
+	scheduleMatch := r.Schedule == nil || r.Schedule.active()
+	return hostMatch && pathMatch && scheduleMatch
+}
+
+// matchesRequest checks the conditions that depend on more than the request's hostname and path.
+func (r *Rule) matchesRequest(req *http.Request, body *requestBody) bool {
+	return (r.Shard == nil || r.Shard.matches(req)) &&
+		(len(r.Accepts) == 0 || matchesAccept(r.Accepts, req)) &&
+		(r.BodyMatch == nil || r.BodyMatch.matches(body))
 }
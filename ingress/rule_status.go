@@ -0,0 +1,130 @@
+package ingress
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// statusWindow is how many of each rule's most recent requests its success rate is based on.
+const statusWindow = 100
+
+// RuleStatus summarizes how well an ingress rule's origin has been serving requests recently.
+type RuleStatus struct {
+	Rule int `json:"rule"`
+	// Config is the configuration the rule is from, ConfigCurrent or ConfigRollout.
+	Config   string `json:"config"`
+	Hostname string `json:"hostname"`
+	Service  string `json:"service"`
+	// Requests counts the recent requests that SuccessRate is based on, at most statusWindow.
+	Requests    int        `json:"requests"`
+	SuccessRate float64    `json:"successRate"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// ruleStatuses records the outcome of the requests proxied by each rule. Its methods are safe
+// to call on a nil ruleStatuses, which records nothing.
+type ruleStatuses struct {
+	lock  sync.Mutex
+	rules []ruleOutcomes
+	clock func() time.Time
+}
+
+type ruleOutcomes struct {
+	// failed is a ring buffer of the most recent outcomes.
+	failed      [statusWindow]bool
+	count, next int
+	lastError   string
+	lastErrorAt time.Time
+}
+
+func newRuleStatuses(numRules int) *ruleStatuses {
+	return &ruleStatuses{rules: make([]ruleOutcomes, numRules), clock: time.Now}
+}
+
+func (s *ruleStatuses) record(ruleIndex int, err error) {
+	if s == nil || ruleIndex < 0 || ruleIndex >= len(s.rules) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	outcomes := &s.rules[ruleIndex]
+	outcomes.failed[outcomes.next] = err != nil
+	outcomes.next = (outcomes.next + 1) % statusWindow
+	if outcomes.count < statusWindow {
+		outcomes.count++
+	}
+	if err != nil {
+		outcomes.lastError = err.Error()
+		outcomes.lastErrorAt = s.clock()
+	}
+}
+
+// RecordRequest records whether a request proxied by the rule with the given index failed,
+// and counts failed TLS handshakes with its origin.
+func (ing Ingress) RecordRequest(ruleIndex int, err error) {
+	ing.statuses.record(ruleIndex, err)
+	if ruleIndex >= 0 && ruleIndex < len(ing.Rules) {
+		recordTLSHandshakeError(ing.Rules[ruleIndex].Index, ing.ConfigName(), err)
+	}
+}
+
+// Status returns the recent status of every rule, in the order they are matched, followed by
+// the rules being rolled out, if any.
+func (ing Ingress) Status() []RuleStatus {
+	statuses := ing.ruleStatuses()
+	if ing.rollout != nil {
+		statuses = append(statuses, ing.rollout.next.ruleStatuses()...)
+	}
+	return statuses
+}
+
+func (ing Ingress) ruleStatuses() []RuleStatus {
+	statuses := make([]RuleStatus, len(ing.Rules))
+	for i, rule := range ing.Rules {
+		statuses[i] = RuleStatus{
+			Rule:        rule.Index,
+			Config:      ing.ConfigName(),
+			Hostname:    rule.Hostname,
+			Service:     rule.Service.String(),
+			SuccessRate: 1,
+		}
+	}
+	if ing.statuses == nil {
+		return statuses
+	}
+	ing.statuses.lock.Lock()
+	defer ing.statuses.lock.Unlock()
+	for i := range statuses {
+		if i >= len(ing.statuses.rules) {
+			break
+		}
+		outcomes := ing.statuses.rules[i]
+		statuses[i].Requests = outcomes.count
+		if outcomes.count > 0 {
+			failures := 0
+			for _, failed := range outcomes.failed[:outcomes.count] {
+				if failed {
+					failures++
+				}
+			}
+			statuses[i].SuccessRate = float64(outcomes.count-failures) / float64(outcomes.count)
+		}
+		if outcomes.lastError != "" {
+			lastErrorAt := outcomes.lastErrorAt
+			statuses[i].LastError = outcomes.lastError
+			statuses[i].LastErrorAt = &lastErrorAt
+		}
+	}
+	return statuses
+}
+
+// StatusHandler serves the status of every rule as JSON.
+func (ing Ingress) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ing.Status())
+	})
+}
@@ -0,0 +1,74 @@
+package ingress
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleStatus(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: api.example.com
+   service: https://localhost:8000
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	failedAt := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	ing.statuses.clock = func() time.Time { return failedAt }
+
+	for i := 0; i < 3; i++ {
+		ing.RecordRequest(0, nil)
+	}
+	ing.RecordRequest(0, errors.New("connection refused"))
+	// Out of range indices, e.g. from warp routing, are ignored.
+	ing.RecordRequest(-1, errors.New("ignored"))
+
+	status := ing.Status()
+	require.Len(t, status, 2)
+	assert.Equal(t, RuleStatus{
+		Rule:        0,
+		Config:      ConfigCurrent,
+		Hostname:    "api.example.com",
+		Service:     "https://localhost:8000",
+		Requests:    4,
+		SuccessRate: 0.75,
+		LastError:   "connection refused",
+		LastErrorAt: &failedAt,
+	}, status[0])
+	assert.Equal(t, RuleStatus{Rule: 1, Config: ConfigCurrent, Service: "HTTP 404", SuccessRate: 1}, status[1])
+
+	// The success rate only considers the most recent requests.
+	for i := 0; i < statusWindow; i++ {
+		ing.RecordRequest(0, nil)
+	}
+	status = ing.Status()
+	assert.Equal(t, statusWindow, status[0].Requests)
+	assert.Equal(t, float64(1), status[0].SuccessRate)
+	assert.Equal(t, "connection refused", status[0].LastError)
+}
+
+func TestRuleStatusHandler(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: https://localhost:8000
+`))
+	require.NoError(t, err)
+	ing.RecordRequest(0, errors.New("origin is down"))
+
+	recorder := httptest.NewRecorder()
+	ing.StatusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ingress", nil))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var status []RuleStatus
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	require.Len(t, status, 1)
+	assert.Equal(t, "origin is down", status[0].LastError)
+	assert.Equal(t, float64(0), status[0].SuccessRate)
+}
@@ -0,0 +1,289 @@
+package ingress
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	s3Scheme = "s3"
+
+	// The sources of the credentials that requests to a bucket are signed with.
+	s3CredentialsEnv       = "env"
+	s3CredentialsAnonymous = "anonymous"
+
+	s3IndexDocument = "index.html"
+	// s3EmptyPayloadHash is the SHA-256 of the empty body of GET and HEAD requests.
+	s3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3TimeFormat       = "20060102T150405Z"
+)
+
+// s3ForwardedRequestHeaders are the eyeball's headers that are passed on to the bucket, for
+// range and conditional requests. Other headers, like cookies, aren't meant for the bucket.
+var s3ForwardedRequestHeaders = []string{"Range", "If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"}
+
+// s3ForwardedResponseHeaders are the bucket's headers that are passed on to the eyeball. The
+// x-amz-* headers describe the bucket, not the object.
+var s3ForwardedResponseHeaders = []string{
+	"Accept-Ranges", "Cache-Control", "Content-Disposition", "Content-Encoding", "Content-Language",
+	"Content-Length", "Content-Range", "Content-Type", "ETag", "Expires", "Last-Modified",
+}
+
+// s3Bucket is an origin that serves the objects of an S3-compatible bucket, like AWS S3, GCS
+// or MinIO, keyed by the request path. Paths ending with / are served index.html.
+type s3Bucket struct {
+	raw         string
+	bucket      string
+	region      string
+	endpoint    *url.URL
+	credentials string
+
+	transport http.RoundTripper
+	getenv    func(string) string
+	clock     func() time.Time
+	// Set by start when credentials come from the environment.
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// newS3Bucket parses a service like s3://bucket.region?endpoint=https://minio.local:9000. The
+// endpoint defaults to AWS S3 in the region, and credentials to the AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY environment variables; credentials=anonymous sends unsigned requests
+// to a public bucket.
+func newS3Bucket(service string) (*s3Bucket, error) {
+	u, err := url.Parse(service)
+	if err != nil {
+		return nil, err
+	}
+	dot := strings.LastIndex(u.Host, ".")
+	if dot <= 0 || dot == len(u.Host)-1 || u.Port() != "" {
+		return nil, fmt.Errorf("%s is an invalid bucket, it should look like s3://bucket.region", service)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("%s is an invalid bucket, the request path is used as the object key", service)
+	}
+	b := s3Bucket{
+		raw:         service,
+		bucket:      u.Host[:dot],
+		region:      u.Host[dot+1:],
+		credentials: s3CredentialsEnv,
+		getenv:      os.Getenv,
+		clock:       time.Now,
+	}
+	query := u.Query()
+	for key := range query {
+		if key != "endpoint" && key != "credentials" {
+			return nil, fmt.Errorf("%s has unknown parameter %s, only endpoint and credentials are supported", service, key)
+		}
+	}
+	endpoint := "https://s3." + b.region + ".amazonaws.com"
+	if e := query.Get("endpoint"); e != "" {
+		endpoint = e
+	}
+	if b.endpoint, err = url.Parse(endpoint); err != nil || (b.endpoint.Scheme != "http" && b.endpoint.Scheme != "https") || b.endpoint.Host == "" {
+		return nil, fmt.Errorf("%s has an invalid endpoint %q, it should be an http or https URL", service, endpoint)
+	}
+	if c := query.Get("credentials"); c != "" {
+		if c != s3CredentialsEnv && c != s3CredentialsAnonymous {
+			return nil, fmt.Errorf("%s has invalid credentials %q, it should be %s or %s", service, c, s3CredentialsEnv, s3CredentialsAnonymous)
+		}
+		b.credentials = c
+	}
+	return &b, nil
+}
+
+func (o *s3Bucket) String() string {
+	return o.raw
+}
+
+func (o *s3Bucket) start(wg *sync.WaitGroup, log *zerolog.Logger, shutdownC <-chan struct{}, errC chan error, cfg OriginRequestConfig) error {
+	if o.credentials == s3CredentialsEnv {
+		o.accessKeyID = o.getenv("AWS_ACCESS_KEY_ID")
+		o.secretAccessKey = o.getenv("AWS_SECRET_ACCESS_KEY")
+		o.sessionToken = o.getenv("AWS_SESSION_TOKEN")
+		if o.accessKeyID == "" || o.secretAccessKey == "" {
+			return fmt.Errorf("%s needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY to be set, or credentials=anonymous for a public bucket", o.raw)
+		}
+	}
+	transport, err := newHTTPTransport(o, cfg, log)
+	if err != nil {
+		return err
+	}
+	o.transport = transport
+	return nil
+}
+
+// RoundTrip fetches the object that the request's path names from the bucket.
+func (o *s3Bucket) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return s3Response(req, http.StatusMethodNotAllowed, http.Header{"Allow": {"GET, HEAD"}}), nil
+	}
+	key := strings.TrimPrefix(req.URL.Path, "/")
+	// Endpoints that normalize paths would resolve them outside the bucket.
+	if hasDotSegment(key) {
+		return s3Response(req, http.StatusNotFound, http.Header{}), nil
+	}
+	if key == "" || strings.HasSuffix(key, "/") {
+		key += s3IndexDocument
+	}
+
+	objectPath := path.Join("/", o.endpoint.Path, o.bucket) + "/" + key
+	objectURL := *o.endpoint
+	objectURL.Path = objectPath
+	objectURL.RawPath = s3EscapePath(objectPath)
+	objectReq, err := http.NewRequestWithContext(req.Context(), req.Method, objectURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range s3ForwardedRequestHeaders {
+		if values := req.Header.Values(name); len(values) > 0 {
+			objectReq.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	o.sign(objectReq)
+
+	resp, err := o.transport.RoundTrip(objectReq)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode < 300, resp.StatusCode == http.StatusNotModified,
+		resp.StatusCode == http.StatusPreconditionFailed, resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+	case resp.StatusCode == http.StatusNotFound:
+		_ = resp.Body.Close()
+		return s3Response(req, http.StatusNotFound, http.Header{}), nil
+	default:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("bucket %s answered %s for %s", o.bucket, resp.Status, key)
+	}
+
+	header := http.Header{}
+	for _, name := range s3ForwardedResponseHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	// Objects uploaded without a content type get a generic one.
+	if contentType := header.Get("Content-Type"); contentType == "" || contentType == "binary/octet-stream" || contentType == "application/octet-stream" {
+		if guessed := mime.TypeByExtension(path.Ext(key)); guessed != "" {
+			header.Set("Content-Type", guessed)
+		}
+	}
+	resp.Header = header
+	return resp, nil
+}
+
+// hasDotSegment checks if key has a . or .. segment, like a/../b.
+func hasDotSegment(key string) bool {
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// s3Response is a response that the bucket service answers itself.
+func s3Response(req *http.Request, status int, header http.Header) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Body:       http.NoBody,
+		Request:    req,
+	}
+}
+
+// sign adds an AWS Signature Version 4 to req, unless the bucket is accessed anonymously. See
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (o *s3Bucket) sign(req *http.Request) {
+	if o.credentials == s3CredentialsAnonymous {
+		return
+	}
+	now := o.clock().UTC()
+	amzDate := now.Format(s3TimeFormat)
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3EmptyPayloadHash)
+	if o.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", o.sessionToken)
+	}
+
+	signed := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			signed[lower] = strings.Join(values, ",")
+		}
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(signed[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3EmptyPayloadHash,
+	}, "\n")
+	scope := date + "/" + o.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+o.secretAccessKey), date)
+	for _, part := range []string{o.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", o.accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// s3EscapePath percent-encodes every byte of p except the unreserved characters and /, like
+// the canonical URIs of AWS signatures.
+func s3EscapePath(p string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || strings.IndexByte("-._~/", c) >= 0 {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
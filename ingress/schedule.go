@@ -0,0 +1,81 @@
+package ingress
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+const scheduleTimeLayout = "15:04"
+
+// Schedule is a daily time window during which a rule matches.
+type Schedule struct {
+	// after and before are offsets from midnight. If after > before, the window wraps around
+	// midnight.
+	after    time.Duration
+	before   time.Duration
+	location *time.Location
+	// clock returns the current time, and can be replaced by tests.
+	clock func() time.Time
+}
+
+func newSchedule(s config.IngressSchedule) (*Schedule, error) {
+	if s.After == "" && s.Before == "" {
+		return nil, errors.New("schedule must set at least one of after or before")
+	}
+	after, err := parseScheduleTime(s.After, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid schedule after time")
+	}
+	before, err := parseScheduleTime(s.Before, 24*time.Hour)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid schedule before time")
+	}
+	if after == before {
+		return nil, fmt.Errorf("schedule after and before can't both be %s", formatScheduleOffset(after))
+	}
+	location, err := time.LoadLocation(s.TZ)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid schedule time zone")
+	}
+	return &Schedule{
+		after:    after,
+		before:   before,
+		location: location,
+		clock:    time.Now,
+	}, nil
+}
+
+func parseScheduleTime(s string, defaultOffset time.Duration) (time.Duration, error) {
+	if s == "" {
+		return defaultOffset, nil
+	}
+	t, err := time.Parse(scheduleTimeLayout, s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// active checks if the current time is inside the schedule's window.
+func (s *Schedule) active() bool {
+	now := s.clock().In(s.location)
+	sinceMidnight := time.Duration(now.Hour())*time.Hour +
+		time.Duration(now.Minute())*time.Minute +
+		time.Duration(now.Second())*time.Second
+	if s.after < s.before {
+		return s.after <= sinceMidnight && sinceMidnight < s.before
+	}
+	return s.after <= sinceMidnight || sinceMidnight < s.before
+}
+
+func (s *Schedule) String() string {
+	return fmt.Sprintf("after %s, before %s %s", formatScheduleOffset(s.after), formatScheduleOffset(s.before), s.location)
+}
+
+func formatScheduleOffset(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,141 @@
+package ingress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+func fixedClock(t *testing.T, value string) func() time.Time {
+	now, err := time.Parse(time.RFC3339, value)
+	require.NoError(t, err)
+	return func() time.Time {
+		return now
+	}
+}
+
+func TestScheduleActive(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule config.IngressSchedule
+		now      string
+		want     bool
+	}{
+		{
+			name:     "Inside window",
+			schedule: config.IngressSchedule{After: "09:00", Before: "17:00"},
+			now:      "2021-06-01T12:30:00Z",
+			want:     true,
+		},
+		{
+			name:     "Before window",
+			schedule: config.IngressSchedule{After: "09:00", Before: "17:00"},
+			now:      "2021-06-01T08:59:59Z",
+			want:     false,
+		},
+		{
+			name:     "End of window is exclusive",
+			schedule: config.IngressSchedule{After: "09:00", Before: "17:00"},
+			now:      "2021-06-01T17:00:00Z",
+			want:     false,
+		},
+		{
+			name:     "Window wrapping midnight, late evening",
+			schedule: config.IngressSchedule{After: "22:00", Before: "06:00"},
+			now:      "2021-06-01T23:15:00Z",
+			want:     true,
+		},
+		{
+			name:     "Window wrapping midnight, early morning",
+			schedule: config.IngressSchedule{After: "22:00", Before: "06:00"},
+			now:      "2021-06-01T05:59:00Z",
+			want:     true,
+		},
+		{
+			name:     "Window wrapping midnight, daytime",
+			schedule: config.IngressSchedule{After: "22:00", Before: "06:00"},
+			now:      "2021-06-01T12:00:00Z",
+			want:     false,
+		},
+		{
+			name:     "Only after is set",
+			schedule: config.IngressSchedule{After: "22:00"},
+			now:      "2021-06-01T23:00:00Z",
+			want:     true,
+		},
+		{
+			name:     "Time zone is applied",
+			schedule: config.IngressSchedule{After: "22:00", Before: "06:00", TZ: "America/New_York"},
+			// 23:00 in New York
+			now:  "2021-06-02T03:00:00Z",
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := newSchedule(tt.schedule)
+			require.NoError(t, err)
+			schedule.clock = fixedClock(t, tt.now)
+			assert.Equal(t, tt.want, schedule.active())
+		})
+	}
+}
+
+func TestNewScheduleErrors(t *testing.T) {
+	tests := []config.IngressSchedule{
+		{},
+		{After: "25:00"},
+		{Before: "6pm"},
+		{After: "10:00", Before: "10:00"},
+		{After: "10:00", TZ: "Not/AZone"},
+	}
+	for _, schedule := range tests {
+		_, err := newSchedule(schedule)
+		assert.Error(t, err, "%+v", schedule)
+	}
+}
+
+func TestScheduledRuleMatching(t *testing.T) {
+	rawYAML := `
+ingress:
+ - service: http_status:503
+   schedule:
+     after: "22:00"
+     before: "06:00"
+     tz: UTC
+ - service: https://localhost:8000
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+
+	ing.Rules[0].Schedule.clock = fixedClock(t, "2021-06-01T23:00:00Z")
+	_, i := ing.FindMatchingRule("example.com", "/")
+	assert.Equal(t, 0, i)
+
+	ing.Rules[0].Schedule.clock = fixedClock(t, "2021-06-01T12:00:00Z")
+	_, i = ing.FindMatchingRule("example.com", "/")
+	assert.Equal(t, 1, i)
+}
+
+func TestCatchAllCantHaveSchedule(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: example.com
+   service: https://localhost:8000
+ - service: http_status:503
+   schedule:
+     after: "22:00"
+`
+	_, err := ParseIngress(MustReadIngress(rawYAML))
+	assert.Equal(t, errLastRuleNotCatchAll, err)
+}
+
+func TestNewScheduleErrorFormatsTimes(t *testing.T) {
+	_, err := newSchedule(config.IngressSchedule{Before: "00:00"})
+	require.Error(t, err)
+	assert.Equal(t, "schedule after and before can't both be 00:00", err.Error())
+}
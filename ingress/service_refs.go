@@ -0,0 +1,55 @@
+package ingress
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// resolveServiceRefs returns the rules with every serviceRef replaced by the service of that
+// name. Its originRequest settings apply to the rule, unless the rule sets them itself.
+func resolveServiceRefs(rules []config.UnvalidatedIngressRule, services map[string]config.NamedService) ([]config.UnvalidatedIngressRule, error) {
+	var resolved []config.UnvalidatedIngressRule
+	for i, r := range rules {
+		if r.ServiceRef == "" {
+			continue
+		}
+		named, ok := services[r.ServiceRef]
+		if !ok {
+			return nil, fmt.Errorf("Rule #%d uses service %s, which isn't defined in services", i+1, r.ServiceRef)
+		}
+		if r.Service != "" || r.Group != "" {
+			return nil, fmt.Errorf("Rule #%d sets serviceRef and also service or group, but only one can be used", i+1)
+		}
+		if named.Service == "" {
+			return nil, fmt.Errorf("Service %s in services has no service", r.ServiceRef)
+		}
+		if resolved == nil {
+			// Copy the rules, so the configuration isn't changed.
+			resolved = append([]config.UnvalidatedIngressRule(nil), rules...)
+		}
+		r.Service = named.Service
+		r.ServiceRef = ""
+		r.OriginRequest = overrideOriginRequest(named.OriginRequest, r.OriginRequest)
+		resolved[i] = r
+	}
+	if resolved == nil {
+		return rules, nil
+	}
+	return resolved, nil
+}
+
+// overrideOriginRequest returns base with the settings that overrides sets. Unset settings are
+// nil, or empty, in config.OriginRequestConfig.
+func overrideOriginRequest(base, overrides config.OriginRequestConfig) config.OriginRequestConfig {
+	merged := base
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	overridesValue := reflect.ValueOf(overrides)
+	for i := 0; i < overridesValue.NumField(); i++ {
+		if field := overridesValue.Field(i); !field.IsZero() {
+			mergedValue.Field(i).Set(field)
+		}
+	}
+	return merged
+}
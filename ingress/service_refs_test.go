@@ -0,0 +1,102 @@
+package ingress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServiceRefs(t *testing.T) {
+	rawYAML := `
+originRequest:
+  connectTimeout: 5s
+services:
+  backendA:
+    service: https://backend-a.internal:8443
+    originRequest:
+      originServerName: backend-a.example.com
+      keepAliveConnections: 10
+ingress:
+ - hostname: tenant1.example.com
+   serviceRef: backendA
+ - hostname: tenant2.example.com
+   serviceRef: backendA
+ - hostname: tenant3.example.com
+   serviceRef: backendA
+   originRequest:
+     keepAliveConnections: 20
+ - service: http_status:404
+`
+	conf := MustReadIngress(rawYAML)
+	ing, err := ParseIngress(conf)
+	require.NoError(t, err)
+
+	first, second, third := ing.Rules[0], ing.Rules[1], ing.Rules[2]
+	assert.Equal(t, "https://backend-a.internal:8443", first.Service.String())
+	assert.Equal(t, first.Service.String(), second.Service.String())
+	assert.Equal(t, first.Config, second.Config)
+	assert.Equal(t, "backend-a.example.com", first.Config.OriginServerName)
+	assert.Equal(t, 10, first.Config.KeepAliveConnections)
+	assert.Equal(t, 5*time.Second, first.Config.ConnectTimeout)
+
+	// The rule's own originRequest takes precedence over the named service's.
+	assert.Equal(t, 20, third.Config.KeepAliveConnections)
+	assert.Equal(t, "backend-a.example.com", third.Config.OriginServerName)
+
+	assert.Equal(t, "backendA", conf.Ingress[0].ServiceRef, "the configuration must not be changed")
+	assert.Empty(t, conf.Ingress[0].Service)
+}
+
+func TestParseServiceRefsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawYAML string
+	}{
+		{
+			name: "unknown ref",
+			rawYAML: `
+services:
+  backendA:
+    service: http://localhost:8080
+ingress:
+ - hostname: tenant1.example.com
+   serviceRef: backendB
+ - service: http_status:404
+`,
+		},
+		{
+			name: "ref and service",
+			rawYAML: `
+services:
+  backendA:
+    service: http://localhost:8080
+ingress:
+ - hostname: tenant1.example.com
+   serviceRef: backendA
+   service: http://localhost:8081
+ - service: http_status:404
+`,
+		},
+		{
+			name: "named service without service",
+			rawYAML: `
+services:
+  backendA:
+    originRequest:
+      noTLSVerify: true
+ingress:
+ - hostname: tenant1.example.com
+   serviceRef: backendA
+ - service: http_status:404
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseIngress(MustReadIngress(test.rawYAML))
+			assert.Error(t, err)
+		})
+	}
+}
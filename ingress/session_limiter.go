@@ -0,0 +1,36 @@
+package ingress
+
+// SessionLimiter caps how many sessions, e.g. websockets, can be open at the same time. Its
+// methods are safe to call on a nil SessionLimiter, which doesn't limit anything.
+type SessionLimiter struct {
+	slots chan struct{}
+}
+
+func newSessionLimiter(maxSessions int) *SessionLimiter {
+	if maxSessions <= 0 {
+		return nil
+	}
+	return &SessionLimiter{slots: make(chan struct{}, maxSessions)}
+}
+
+// TryAcquire opens a session if the limit isn't reached yet. Each successful call must be
+// followed by a call to Release once the session closes.
+func (l *SessionLimiter) TryAcquire() bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release frees the capacity of a session opened by TryAcquire.
+func (l *SessionLimiter) Release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
@@ -0,0 +1,51 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionLimiter(t *testing.T) {
+	limiter := newSessionLimiter(2)
+	assert.True(t, limiter.TryAcquire())
+	assert.True(t, limiter.TryAcquire())
+	assert.False(t, limiter.TryAcquire())
+
+	limiter.Release()
+	assert.True(t, limiter.TryAcquire())
+	assert.False(t, limiter.TryAcquire())
+
+	var unlimited *SessionLimiter
+	assert.Nil(t, newSessionLimiter(0))
+	for i := 0; i < 10; i++ {
+		assert.True(t, unlimited.TryAcquire())
+	}
+	unlimited.Release()
+}
+
+func TestParseMaxWebsockets(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: ws.example.com
+   service: https://localhost:8000
+   originRequest:
+     maxWebsockets: 100
+ - service: https://localhost:8001
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	require.NotNil(t, ing.Rules[0].Websockets)
+	assert.Equal(t, 100, cap(ing.Rules[0].Websockets.slots))
+	assert.Nil(t, ing.Rules[1].Websockets)
+
+	rawYAML = `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     maxWebsockets: -1
+`
+	_, err = ParseIngress(MustReadIngress(rawYAML))
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package ingress
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// Shard matches the requests whose key hashes to one of a number of buckets.
+type Shard struct {
+	by      string
+	source  string
+	name    string
+	buckets int
+	bucket  int
+}
+
+func newShard(c config.IngressShard) (*Shard, error) {
+	parts := strings.SplitN(c.By, ":", 2)
+	if len(parts) != 2 || parts[1] == "" || (parts[0] != "header" && parts[0] != "query") {
+		return nil, fmt.Errorf("by must be header:<name> or query:<name>, got %q", c.By)
+	}
+	if c.Buckets < 1 {
+		return nil, fmt.Errorf("buckets must be positive, got %d", c.Buckets)
+	}
+	if c.Bucket < 0 || c.Bucket >= c.Buckets {
+		return nil, fmt.Errorf("bucket must be between 0 and %d, got %d", c.Buckets-1, c.Bucket)
+	}
+	return &Shard{by: c.By, source: parts[0], name: parts[1], buckets: c.Buckets, bucket: c.Bucket}, nil
+}
+
+func (s *Shard) String() string {
+	return fmt.Sprintf("bucket %d of %d by %s", s.bucket, s.buckets, s.by)
+}
+
+func (s *Shard) matches(req *http.Request) bool {
+	var key string
+	if s.source == "header" {
+		key = req.Header.Get(s.name)
+	} else {
+		key = req.URL.Query().Get(s.name)
+	}
+	return key != "" && shardBucket(key, s.buckets) == s.bucket
+}
+
+// shardBucket hashes key with 32-bit FNV-1a. The hash must never change, or requests would be
+// routed to other shards after an upgrade.
+func shardBucket(key string, buckets int) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(buckets))
+}
+
+// checkShards makes sure all rules sharding by the same key agree on the number of buckets.
+func checkShards(rules []Rule) error {
+	first := make(map[string]int)
+	for i, rule := range rules {
+		if rule.Shard == nil {
+			continue
+		}
+		j, ok := first[rule.Shard.by]
+		if !ok {
+			first[rule.Shard.by] = i
+			continue
+		}
+		if other := rules[j].Shard; other.buckets != rule.Shard.buckets {
+			return errors.Errorf("Rule #%d shards by %s into %d buckets, but rule #%d shards it into %d", rule.Index+1, rule.Shard.by, rule.Shard.buckets, rules[j].Index+1, other.buckets)
+		}
+	}
+	return nil
+}
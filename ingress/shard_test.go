@@ -0,0 +1,114 @@
+package ingress
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardBucket(t *testing.T) {
+	// The buckets must stay the same across releases, so these are fixed.
+	for key, bucket := range map[string]int{
+		"alice": 3,
+		"bob":   0,
+		"carol": 2,
+		"erin":  1,
+	} {
+		assert.Equal(t, bucket, shardBucket(key, 4), key)
+	}
+}
+
+func TestShardRouting(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http_status:200
+   shard: {by: header:X-User-Id, buckets: 4, bucket: 0}
+ - service: http_status:201
+   shard: {by: header:X-User-Id, buckets: 4, bucket: 1}
+ - service: http_status:202
+   shard: {by: header:X-User-Id, buckets: 4, bucket: 2}
+ - service: http_status:203
+   shard: {by: header:X-User-Id, buckets: 4, bucket: 3}
+ - service: http_status:404
+`))
+	require.NoError(t, err)
+
+	for userID, expectedRule := range map[string]int{
+		"alice": 3,
+		"bob":   0,
+		"carol": 2,
+		"erin":  1,
+		// Requests without the key fall through to the catch-all.
+		"": 4,
+	} {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+		if userID != "" {
+			req.Header.Set("X-User-Id", userID)
+		}
+		_, i := ing.FindMatchingRuleForRequest(req)
+		assert.Equal(t, expectedRule, i, userID)
+	}
+}
+
+func TestShardByQuery(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http_status:200
+   shard: {by: query:user, buckets: 2, bucket: 0}
+ - service: http_status:404
+`))
+	require.NoError(t, err)
+
+	for user, expectedRule := range map[string]int{"alice": 1, "bob": 0} {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/?user="+user, nil)
+		require.NoError(t, err)
+		_, i := ing.FindMatchingRuleForRequest(req)
+		assert.Equal(t, expectedRule, i, user)
+	}
+}
+
+func TestParseShard(t *testing.T) {
+	tests := []struct {
+		name    string
+		shard   string
+		another string
+	}{
+		{name: "unknown key source", shard: "{by: cookie:session, buckets: 2, bucket: 0}"},
+		{name: "empty key name", shard: "{by: 'header:', buckets: 2, bucket: 0}"},
+		{name: "no buckets", shard: "{by: header:X-User-Id, buckets: 0, bucket: 0}"},
+		{name: "bucket out of range", shard: "{by: header:X-User-Id, buckets: 2, bucket: 2}"},
+		{name: "negative bucket", shard: "{by: header:X-User-Id, buckets: 2, bucket: -1}"},
+		{
+			name:    "inconsistent buckets",
+			shard:   "{by: header:X-User-Id, buckets: 2, bucket: 0}",
+			another: "{by: header:X-User-Id, buckets: 4, bucket: 1}",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			another := "{by: header:X-Other, buckets: 3, bucket: 0}"
+			if test.another != "" {
+				another = test.another
+			}
+			_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http_status:200
+   shard: ` + test.shard + `
+ - service: http_status:201
+   shard: ` + another + `
+ - service: http_status:404
+`))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http_status:200
+   shard: {by: header:X-User-Id, buckets: 2, bucket: 0}
+`))
+	assert.Error(t, err, "a sharded rule can't be the catch-all")
+}
@@ -0,0 +1,99 @@
+package ingress
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sharedTransports are the transports, and so the connection pools, of the origins that rules
+// with poolKey byOrigin share. An origin's connections are opened with the SNI of the first of
+// its rules to start, but only the rules that would otherwise make the same transport share
+// one, so a rule never connects with another rule's TLS settings or timeouts. Its methods are
+// safe to call on a nil sharedTransports, which shares nothing.
+type sharedTransports struct {
+	lock     sync.Mutex
+	byOrigin map[sharedTransportKey]*http.Transport
+}
+
+type sharedTransportKey struct {
+	origin   string
+	settings transportSettings
+}
+
+// transportSettings are the settings that newHTTPTransport and dialer make a transport with,
+// other than the SNI.
+type transportSettings struct {
+	connectTimeout         time.Duration
+	tlsTimeout             time.Duration
+	tcpKeepAlive           time.Duration
+	noHappyEyeballs        bool
+	keepAliveConnections   int
+	keepAliveTimeout       time.Duration
+	caPool                 string
+	tlsVerifyMode          string
+	tlsRenegotiation       string
+	clientCertEnv          string
+	clientKeyEnv           string
+	alpn                   string
+	maxConcurrentDials     int
+	dialQueueTimeout       time.Duration
+	dnsCacheTTL            time.Duration
+	dnsNegativeTTL         time.Duration
+	ipPreference           string
+	assumeHTTP10           bool
+	maxResponseHeaderBytes int64
+}
+
+func newTransportSettings(cfg OriginRequestConfig) transportSettings {
+	return transportSettings{
+		connectTimeout:         cfg.ConnectTimeout,
+		tlsTimeout:             cfg.TLSTimeout,
+		tcpKeepAlive:           cfg.TCPKeepAlive,
+		noHappyEyeballs:        cfg.NoHappyEyeballs,
+		keepAliveConnections:   cfg.KeepAliveConnections,
+		keepAliveTimeout:       cfg.KeepAliveTimeout,
+		caPool:                 cfg.CAPool,
+		tlsVerifyMode:          cfg.tlsVerifyMode(),
+		tlsRenegotiation:       cfg.TLSRenegotiation,
+		clientCertEnv:          cfg.ClientCertEnv,
+		clientKeyEnv:           cfg.ClientKeyEnv,
+		alpn:                   strings.Join(cfg.ALPN, ","),
+		maxConcurrentDials:     cfg.MaxConcurrentDials,
+		dialQueueTimeout:       cfg.DialQueueTimeout,
+		dnsCacheTTL:            cfg.DNSCacheTTL,
+		dnsNegativeTTL:         cfg.DNSNegativeTTL,
+		ipPreference:           cfg.IPPreference,
+		assumeHTTP10:           cfg.AssumeHTTP10,
+		maxResponseHeaderBytes: cfg.MaxResponseHeaderBytes,
+	}
+}
+
+func newSharedTransports() *sharedTransports {
+	return &sharedTransports{byOrigin: make(map[sharedTransportKey]*http.Transport)}
+}
+
+// get returns the transport of the origin at u for a rule with cfg, which newTransport makes if
+// there's none yet. Rules with a DialContext always get a transport of their own.
+func (s *sharedTransports) get(u *url.URL, cfg OriginRequestConfig, newTransport func() (*http.Transport, error)) (*http.Transport, error) {
+	if s == nil || cfg.dialContext != nil {
+		return newTransport()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := sharedTransportKey{
+		origin:   strings.ToLower(u.Scheme + "://" + u.Host),
+		settings: newTransportSettings(cfg),
+	}
+	if transport, ok := s.byOrigin[key]; ok {
+		return transport, nil
+	}
+	transport, err := newTransport()
+	if err != nil {
+		return nil, err
+	}
+	s.byOrigin[key] = transport
+	return transport, nil
+}
@@ -0,0 +1,98 @@
+package ingress
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// statusRange is an inclusive range of HTTP statuses.
+type statusRange struct {
+	from, to int
+}
+
+// SLO counts how many of a rule's requests got a good status, for SLO and error budget
+// dashboards. Its methods are safe to call on a nil SLO, which counts nothing.
+type SLO struct {
+	good []statusRange
+
+	lock         sync.Mutex
+	requests     uint64
+	goodRequests uint64
+}
+
+// newSLO parses good statuses like 200, or ranges of statuses like 200-399.
+func newSLO(goodStatuses []string) (*SLO, error) {
+	if len(goodStatuses) == 0 {
+		return nil, nil
+	}
+	slo := SLO{good: make([]statusRange, 0, len(goodStatuses))}
+	for _, spec := range goodStatuses {
+		r, err := parseStatusRange(spec)
+		if err != nil {
+			return nil, err
+		}
+		slo.good = append(slo.good, r)
+	}
+	return &slo, nil
+}
+
+func parseStatusRange(spec string) (statusRange, error) {
+	from, to := spec, spec
+	if dash := strings.Index(spec, "-"); dash >= 0 {
+		from, to = spec[:dash], spec[dash+1:]
+	}
+	var r statusRange
+	var err error
+	if r.from, err = parseStatus(strings.TrimSpace(from)); err != nil {
+		return statusRange{}, fmt.Errorf("%q is an invalid status, it should look like 200 or 200-399", spec)
+	}
+	if r.to, err = parseStatus(strings.TrimSpace(to)); err != nil {
+		return statusRange{}, fmt.Errorf("%q is an invalid status, it should look like 200 or 200-399", spec)
+	}
+	if r.from > r.to {
+		return statusRange{}, fmt.Errorf("%q is an invalid range of statuses, it ends before it starts", spec)
+	}
+	return r, nil
+}
+
+func parseStatus(s string) (int, error) {
+	status, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if status < 100 || status > 599 {
+		return 0, fmt.Errorf("%d isn't an HTTP status", status)
+	}
+	return status, nil
+}
+
+// IsGood returns whether a response with the status counts as good.
+func (s *SLO) IsGood(status int) bool {
+	if s == nil {
+		return false
+	}
+	for _, r := range s.good {
+		if r.from <= status && status <= r.to {
+			return true
+		}
+	}
+	return false
+}
+
+// Record counts a request that got the status, and returns whether it was good and the ratio of
+// good requests to all the requests recorded so far.
+func (s *SLO) Record(status int) (good bool, ratio float64) {
+	if s == nil {
+		return false, 0
+	}
+	good = s.IsGood(status)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests++
+	if good {
+		s.goodRequests++
+	}
+	return good, float64(s.goodRequests) / float64(s.requests)
+}
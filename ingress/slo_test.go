@@ -0,0 +1,51 @@
+package ingress
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLOGoodStatuses(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     sloGoodStatuses: [200-399, 429]
+`))
+	require.NoError(t, err)
+	slo := ing.Rules[0].SLO
+	require.NotNil(t, slo)
+	for _, status := range []int{http.StatusOK, http.StatusNoContent, http.StatusNotModified, 399, http.StatusTooManyRequests} {
+		assert.True(t, slo.IsGood(status), status)
+	}
+	for _, status := range []int{http.StatusContinue, http.StatusBadRequest, http.StatusNotFound, http.StatusBadGateway} {
+		assert.False(t, slo.IsGood(status), status)
+	}
+
+	good, ratio := slo.Record(http.StatusOK)
+	assert.True(t, good)
+	assert.Equal(t, float64(1), ratio)
+	good, ratio = slo.Record(http.StatusServiceUnavailable)
+	assert.False(t, good)
+	assert.Equal(t, 0.5, ratio)
+
+	var unset *SLO
+	good, ratio = unset.Record(http.StatusOK)
+	assert.False(t, good)
+	assert.Equal(t, float64(0), ratio)
+}
+
+func TestSLOGoodStatusesInvalid(t *testing.T) {
+	for _, invalid := range []string{`["2xx"]`, `["200-"]`, `["399-200"]`, `["200-600"]`, `[99]`} {
+		_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     sloGoodStatuses: ` + invalid + `
+`))
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,176 @@
+package ingress
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudflare/cloudflared/websocket"
+)
+
+var errClientHelloRead = errors.New("finished reading ClientHello")
+
+// sniRouter picks a TCP backend based on the server name (SNI) that the eyeball's TLS
+// ClientHello asks for, without terminating TLS.
+type sniRouter struct {
+	exact map[string]string
+	// wildcards are ordered from most to least specific.
+	wildcards []sniWildcardRoute
+}
+
+type sniWildcardRoute struct {
+	pattern string
+	dest    string
+}
+
+func newSNIRouter(routes map[string]string) (*sniRouter, error) {
+	router := sniRouter{exact: make(map[string]string)}
+	for hostname, dest := range routes {
+		if strings.LastIndex(hostname, "*") > 0 || (strings.HasPrefix(hostname, "*") && !strings.HasPrefix(hostname, "*.")) {
+			return nil, errors.Wrapf(errBadWildcard, "invalid sniRoutes hostname %s", hostname)
+		}
+		if _, _, err := net.SplitHostPort(dest); err != nil {
+			return nil, fmt.Errorf("sniRoutes destination for %s must be an address like host:port, got %s", hostname, dest)
+		}
+		if strings.HasPrefix(hostname, "*.") {
+			router.wildcards = append(router.wildcards, sniWildcardRoute{pattern: hostname, dest: dest})
+		} else {
+			router.exact[hostname] = dest
+		}
+	}
+	sort.Slice(router.wildcards, func(i, j int) bool {
+		return len(router.wildcards[i].pattern) > len(router.wildcards[j].pattern)
+	})
+	return &router, nil
+}
+
+// destination returns the backend for the given server name, if any route matches it.
+func (r *sniRouter) destination(serverName string) (string, bool) {
+	if dest, ok := r.exact[serverName]; ok {
+		return dest, true
+	}
+	for _, route := range r.wildcards {
+		if matchHost(route.pattern, serverName) {
+			return route.dest, true
+		}
+	}
+	return "", false
+}
+
+// readClientHelloServerName reads a TLS ClientHello from r and returns the server name it
+// requests, which is empty if the client didn't send SNI.
+func readClientHelloServerName(r io.Reader) (string, error) {
+	var serverName string
+	err := tls.Server(readOnlyConn{r: r}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, errClientHelloRead
+		},
+	}).Handshake()
+	if err != errClientHelloRead {
+		return "", errors.Wrap(err, "eyeball didn't send a TLS ClientHello")
+	}
+	return serverName, nil
+}
+
+// readOnlyConn is a net.Conn that can only be read from. It lets crypto/tls parse a
+// ClientHello without replying to the client.
+type readOnlyConn struct {
+	r io.Reader
+}
+
+func (c readOnlyConn) Read(p []byte) (int, error)         { return c.r.Read(p) }
+func (c readOnlyConn) Write(p []byte) (int, error)        { return 0, io.ErrClosedPipe }
+func (c readOnlyConn) Close() error                       { return nil }
+func (c readOnlyConn) LocalAddr() net.Addr                { return nil }
+func (c readOnlyConn) RemoteAddr() net.Addr               { return nil }
+func (c readOnlyConn) SetDeadline(t time.Time) error      { return nil }
+func (c readOnlyConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c readOnlyConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// sniRoutingConnection is an OriginConnection that only dials the origin once it has read the
+// eyeball's ClientHello, so that it can pick the origin by SNI.
+type sniRoutingConnection struct {
+	router      *sniRouter
+	defaultDest string
+	// dial replaces net.Dial, if set.
+	dial dialFunc
+
+	connLock sync.Mutex
+	conn     net.Conn
+	closed   bool
+}
+
+func (sc *sniRoutingConnection) Stream(ctx context.Context, tunnelConn io.ReadWriter, log *zerolog.Logger) {
+	sc.stream(ctx, websocket.NewConn(ctx, tunnelConn, log), log)
+}
+
+func (sc *sniRoutingConnection) stream(ctx context.Context, eyeball io.ReadWriter, log *zerolog.Logger) {
+	// Keep the ClientHello so it can be forwarded to the origin unchanged.
+	var clientHello bytes.Buffer
+	serverName, err := readClientHelloServerName(io.TeeReader(eyeball, &clientHello))
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot route TCP connection by SNI")
+		return
+	}
+	dest, ok := sc.router.destination(serverName)
+	if !ok {
+		dest = sc.defaultDest
+	}
+	log.Debug().Msgf("Routing TCP connection for SNI %q to %s", serverName, dest)
+
+	dial := sc.dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	conn, err := dial(ctx, "tcp", dest)
+	if err != nil {
+		log.Error().Err(err).Msgf("Cannot dial origin %s", dest)
+		return
+	}
+	if !sc.setConn(conn) {
+		conn.Close()
+		return
+	}
+
+	eyeballStream := &readWriter{
+		Reader: io.MultiReader(&clientHello, eyeball),
+		Writer: eyeball,
+	}
+	websocket.Stream(eyeballStream, conn, log)
+}
+
+// setConn stores the origin connection, unless the OriginConnection was already closed.
+func (sc *sniRoutingConnection) setConn(conn net.Conn) bool {
+	sc.connLock.Lock()
+	defer sc.connLock.Unlock()
+	if sc.closed {
+		return false
+	}
+	sc.conn = conn
+	return true
+}
+
+func (sc *sniRoutingConnection) Close() {
+	sc.connLock.Lock()
+	defer sc.connLock.Unlock()
+	sc.closed = true
+	if sc.conn != nil {
+		sc.conn.Close()
+	}
+}
+
+type readWriter struct {
+	io.Reader
+	io.Writer
+}
@@ -0,0 +1,209 @@
+package ingress
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingConn records what a TLS client writes, and fails every read so the handshake stops
+// after the ClientHello.
+type recordingConn struct {
+	readOnlyConn
+	written bytes.Buffer
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	return c.written.Write(p)
+}
+
+func clientHello(t *testing.T, serverName string) []byte {
+	conn := &recordingConn{}
+	_ = tls.Client(conn, &tls.Config{ServerName: serverName}).Handshake()
+	require.NotZero(t, conn.written.Len())
+	return conn.written.Bytes()
+}
+
+func TestReadClientHelloServerName(t *testing.T) {
+	serverName, err := readClientHelloServerName(bytes.NewReader(clientHello(t, "a.example.com")))
+	require.NoError(t, err)
+	assert.Equal(t, "a.example.com", serverName)
+
+	_, err = readClientHelloServerName(bytes.NewReader([]byte("GET / HTTP/1.1\r\n\r\n")))
+	assert.Error(t, err)
+}
+
+func TestSNIRouterDestination(t *testing.T) {
+	router, err := newSNIRouter(map[string]string{
+		"a.example.com":     "10.0.0.1:443",
+		"*.example.com":     "10.0.0.2:443",
+		"*.bar.example.com": "10.0.0.3:443",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		serverName string
+		dest       string
+		ok         bool
+	}{
+		{serverName: "a.example.com", dest: "10.0.0.1:443", ok: true},
+		{serverName: "b.example.com", dest: "10.0.0.2:443", ok: true},
+		{serverName: "foo.bar.example.com", dest: "10.0.0.3:443", ok: true},
+		{serverName: "example.org", ok: false},
+		{serverName: "", ok: false},
+	}
+	for _, test := range tests {
+		dest, ok := router.destination(test.serverName)
+		assert.Equal(t, test.ok, ok, test.serverName)
+		assert.Equal(t, test.dest, dest, test.serverName)
+	}
+
+	_, err = newSNIRouter(map[string]string{"a.*.example.com": "10.0.0.1:443"})
+	assert.Error(t, err)
+	_, err = newSNIRouter(map[string]string{"a.example.com": "10.0.0.1"})
+	assert.Error(t, err)
+}
+
+// runSNIBackend accepts a single connection, checks that it receives the expected ClientHello,
+// then replies with its name.
+func runSNIBackend(t *testing.T, name string, expectHello []byte) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		received := make([]byte, len(expectHello))
+		if _, err := io.ReadFull(conn, received); err != nil || !bytes.Equal(expectHello, received) {
+			return
+		}
+		_, _ = conn.Write([]byte(name))
+	}()
+	return ln.Addr().String()
+}
+
+func TestSNIRoutingConnection(t *testing.T) {
+	helloA := clientHello(t, "a.example.com")
+	helloB := clientHello(t, "b.example.com")
+	helloOther := clientHello(t, "other.example.com")
+	router, err := newSNIRouter(map[string]string{
+		"a.example.com": runSNIBackend(t, "backend-a", helloA),
+		"b.example.com": runSNIBackend(t, "backend-b", helloB),
+	})
+	require.NoError(t, err)
+	defaultDest := runSNIBackend(t, "backend-default", helloOther)
+
+	tests := []struct {
+		hello    []byte
+		expected string
+	}{
+		{hello: helloA, expected: "backend-a"},
+		{hello: helloB, expected: "backend-b"},
+		{hello: helloOther, expected: "backend-default"},
+	}
+	for _, test := range tests {
+		originConn := &sniRoutingConnection{router: router, defaultDest: defaultDest}
+		eyeball, tunnel := net.Pipe()
+		go func() {
+			originConn.stream(context.Background(), tunnel, testLogger)
+			tunnel.Close()
+		}()
+
+		_, err := eyeball.Write(test.hello)
+		require.NoError(t, err)
+		require.NoError(t, eyeball.SetReadDeadline(time.Now().Add(5*time.Second)))
+		reply, err := ioutil.ReadAll(eyeball)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, string(reply))
+		originConn.Close()
+		eyeball.Close()
+	}
+}
+
+func TestSNIRoutingUsesRuleDialer(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: tcp://default.invalid:4430
+   originRequest:
+     sniRoutes:
+       a.example.com: a.invalid:4431
+`))
+	require.NoError(t, err)
+	dialed := make(chan string, 1)
+	ing.Rules[0].DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialed <- address
+		return nil, errors.New("no route to origin")
+	}
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+
+	req, err := http.NewRequest(http.MethodGet, "http://tcp.example.com", nil)
+	require.NoError(t, err)
+	originConn, _, err := ing.Rules[0].Service.(StreamBasedOriginProxy).EstablishConnection(req)
+	require.NoError(t, err)
+	defer originConn.Close()
+	eyeball, tunnel := net.Pipe()
+	defer eyeball.Close()
+	go originConn.(*sniRoutingConnection).stream(context.Background(), tunnel, &log)
+	_, err = eyeball.Write(clientHello(t, "a.example.com"))
+	require.NoError(t, err)
+
+	select {
+	case address := <-dialed:
+		assert.Equal(t, "a.invalid:4431", address)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the SNI route wasn't dialed with the rule's dialer")
+	}
+}
+
+func TestParseSNIRoutes(t *testing.T) {
+	rawYAML := `
+ingress:
+ - hostname: tcp.example.com
+   service: tcp://localhost:4430
+   originRequest:
+     sniRoutes:
+       a.example.com: localhost:4431
+       "*.b.example.com": localhost:4432
+ - service: http_status:404
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	service, ok := ing.Rules[0].Service.(*tcpOverWSService)
+	require.True(t, ok)
+	require.NotNil(t, service.sniRouter)
+	dest, ok := service.sniRouter.destination("x.b.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "localhost:4432", dest)
+
+	rawYAML = `
+ingress:
+ - service: https://localhost:8000
+   originRequest:
+     sniRoutes:
+       a.example.com: localhost:4431
+`
+	_, err = ParseIngress(MustReadIngress(rawYAML))
+	assert.Error(t, err)
+}
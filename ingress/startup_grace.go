@@ -0,0 +1,54 @@
+package ingress
+
+import (
+	"sync"
+	"time"
+)
+
+// StartupGrace tracks whether the origin has been confirmed to be up, so requests that can't
+// reach it shortly after startup are answered with a retryable 503 instead of a 502. Its
+// methods are safe to call on a nil StartupGrace, which never asks for a retry.
+type StartupGrace struct {
+	clock    func() time.Time
+	deadline time.Time
+
+	lock  sync.Mutex
+	ready bool
+}
+
+func newStartupGrace(grace time.Duration) *StartupGrace {
+	if grace <= 0 {
+		return nil
+	}
+	return &StartupGrace{clock: time.Now, deadline: time.Now().Add(grace)}
+}
+
+// RetryAfter returns how long is left of the grace period, if the origin hasn't responded
+// yet and the grace period isn't over.
+func (g *StartupGrace) RetryAfter() (time.Duration, bool) {
+	if g == nil {
+		return 0, false
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.ready {
+		return 0, false
+	}
+	left := g.deadline.Sub(g.clock())
+	if left <= 0 {
+		// Once the grace period is over, errors are reported as usual.
+		g.ready = true
+		return 0, false
+	}
+	return left, true
+}
+
+// Ready records that the origin responded, which ends the grace period.
+func (g *StartupGrace) Ready() {
+	if g == nil {
+		return
+	}
+	g.lock.Lock()
+	g.ready = true
+	g.lock.Unlock()
+}
@@ -0,0 +1,63 @@
+package ingress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupGrace(t *testing.T) {
+	rawYAML := `
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     startupGrace: 5s
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	grace := ing.Rules[0].StartupGrace
+	require.NotNil(t, grace)
+
+	now := grace.deadline.Add(-5 * time.Second)
+	grace.clock = func() time.Time { return now }
+	retryAfter, ok := grace.RetryAfter()
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, retryAfter)
+
+	now = now.Add(4 * time.Second)
+	retryAfter, ok = grace.RetryAfter()
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, retryAfter)
+
+	now = now.Add(time.Second)
+	_, ok = grace.RetryAfter()
+	assert.False(t, ok, "the grace period is over")
+}
+
+func TestStartupGraceEndsOnceReady(t *testing.T) {
+	grace := newStartupGrace(time.Minute)
+	grace.Ready()
+	_, ok := grace.RetryAfter()
+	assert.False(t, ok)
+}
+
+func TestStartupGraceDisabled(t *testing.T) {
+	var grace *StartupGrace
+	assert.Nil(t, newStartupGrace(0))
+	grace.Ready()
+	_, ok := grace.RetryAfter()
+	assert.False(t, ok)
+}
+
+func TestStartupGraceMustNotBeNegative(t *testing.T) {
+	rawYAML := `
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     startupGrace: -5s
+`
+	_, err := ParseIngress(MustReadIngress(rawYAML))
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package ingress
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// warmupTimeout is how long a warmup request may take.
+const warmupTimeout = time.Minute
+
+// warmUp requests the rule's warmupPath from its origin in the background, if warmupOnStart is
+// set. The response is discarded, only its status is logged.
+func (r *Rule) warmUp(wg *sync.WaitGroup, log *zerolog.Logger, shutdownC <-chan struct{}) {
+	service, ok := r.Service.(HTTPOriginProxy)
+	if !ok || !r.Config.WarmupOnStart {
+		return
+	}
+	path := r.Config.WarmupPath
+	ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://warmup"+path, nil)
+	if err != nil {
+		cancel()
+		log.Warn().Err(err).Msgf("Unable to warm up the origin of ingress rule %d", r.Index)
+		return
+	}
+	// Without a hostname to ask for, the origin gets its own address as the Host.
+	req.Host = ""
+	if r.Hostname != "" && !strings.Contains(r.Hostname, "*") {
+		req.Host = r.Hostname
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		go func() {
+			select {
+			case <-shutdownC:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		resp, err := service.RoundTrip(req)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Unable to warm up the origin of ingress rule %d with %s", r.Index, path)
+			return
+		}
+		_ = resp.Body.Close()
+		log.Info().Msgf("Warmed up the origin of ingress rule %d with %s, which answered %s", r.Index, path, resp.Status)
+	}()
+}
@@ -0,0 +1,68 @@
+package ingress
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmupOnStart(t *testing.T) {
+	type request struct{ host, path string }
+	requests := make(chan request, 10)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- request{host: r.Host, path: r.URL.RequestURI()}
+	}))
+	defer origin.Close()
+
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+ - hostname: app.example.com
+   service: ` + origin.URL + `
+   originRequest:
+     warmupPath: /warmup?cache=all
+     warmupOnStart: true
+ - hostname: cold.example.com
+   service: ` + origin.URL + `
+   originRequest:
+     warmupPath: /warmup
+ - service: http_status:404
+`))
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	var wg sync.WaitGroup
+	shutdownC := make(chan struct{})
+	require.NoError(t, ing.StartOrigins(&wg, &log, shutdownC, make(chan error)))
+
+	select {
+	case req := <-requests:
+		assert.Equal(t, request{host: "app.example.com", path: "/warmup?cache=all"}, req)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the origin didn't get the warmup request")
+	}
+	close(shutdownC)
+	wg.Wait()
+	// Only the rule with warmupOnStart warms up its origin.
+	assert.Empty(t, requests)
+}
+
+func TestWarmupInvalid(t *testing.T) {
+	for _, originRequest := range []string{
+		"warmupPath: warmup",
+		"warmupPath: /warmup#top",
+		"warmupOnStart: true",
+	} {
+		_, err := ParseIngress(MustReadIngress(`
+ingress:
+ - service: http://localhost:8000
+   originRequest:
+     ` + originRequest + `
+`))
+		assert.Error(t, err, originRequest)
+	}
+}
@@ -0,0 +1,46 @@
+package ingress
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// WellKnownFiles are served by cloudflared for well-known paths, instead of the origin. Its
+// methods are safe to call on a nil WellKnownFiles, which serves nothing.
+type WellKnownFiles struct {
+	files map[string][]byte
+}
+
+func newWellKnownFiles(c config.WellKnownConfig) (*WellKnownFiles, error) {
+	paths := map[string]string{
+		"/robots.txt":               c.Robots,
+		"/.well-known/security.txt": c.SecurityTxt,
+	}
+	files := make(map[string][]byte)
+	for urlPath, filename := range paths {
+		if filename == "" {
+			continue
+		}
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error reading the file to serve for %s", urlPath)
+		}
+		files[urlPath] = content
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	return &WellKnownFiles{files: files}, nil
+}
+
+// File returns the content to serve for the request path, if it's a configured well-known path.
+func (w *WellKnownFiles) File(path string) ([]byte, bool) {
+	if w == nil {
+		return nil, false
+	}
+	content, ok := w.files[path]
+	return content, ok
+}
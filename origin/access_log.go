@@ -0,0 +1,156 @@
+package origin
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// The formats of access log lines, see --access-log-format.
+const (
+	AccessLogFormatJSON     = "json"
+	AccessLogFormatCLF      = "clf"
+	AccessLogFormatCombined = "combined"
+)
+
+// clfTimeFormat is the time format of the Common Log Format, like Apache's %t.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// ValidateAccessLogFormat checks that format is one of the access log formats. The empty
+// format is JSON.
+func ValidateAccessLogFormat(format string) error {
+	switch format {
+	case "", AccessLogFormatJSON, AccessLogFormatCLF, AccessLogFormatCombined:
+		return nil
+	}
+	return fmt.Errorf("unknown access log format %q, it must be %s, %s or %s", format, AccessLogFormatJSON, AccessLogFormatCLF, AccessLogFormatCombined)
+}
+
+// accessLogResponseWriter remembers the request as the eyeball sent it, and the status and size
+// of the response, for the access and dead letter logs and the SLO metrics.
+type accessLogResponseWriter struct {
+	connection.ResponseWriter
+	method string
+	host   string
+	path   string
+	// The rest of the request, for the Common and Combined Log Formats.
+	target    string
+	proto     string
+	clientIP  string
+	referer   string
+	userAgent string
+
+	status int
+	bytes  int64
+}
+
+func newAccessLogResponseWriter(w connection.ResponseWriter, req *http.Request) *accessLogResponseWriter {
+	return &accessLogResponseWriter{
+		ResponseWriter: w,
+		method:         req.Method,
+		host:           req.Host,
+		path:           req.URL.Path,
+		target:         req.URL.RequestURI(),
+		proto:          req.Proto,
+		clientIP:       req.Header.Get("Cf-Connecting-Ip"),
+		referer:        req.Referer(),
+		userAgent:      req.UserAgent(),
+	}
+}
+
+func (w *accessLogResponseWriter) WriteRespHeaders(status int, header http.Header) error {
+	w.status = status
+	return w.ResponseWriter.WriteRespHeaders(status, header)
+}
+
+// responseStatus is the status that the eyeball got, which is 502 for requests that failed
+// before a response was written.
+func (w *accessLogResponseWriter) responseStatus() int {
+	if w.status == 0 {
+		return http.StatusBadGateway
+	}
+	return w.status
+}
+
+func (w *accessLogResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// writeAccessLog appends a line about the request to the access log of its hostname. Requests
+// that failed before a response was written are logged with 502, which the eyeball gets instead.
+func (p *proxy) writeAccessLog(accessLogs *ingress.AccessLogs, w *accessLogResponseWriter, start time.Time, fields logFields) {
+	status := w.responseStatus()
+	var line bytes.Buffer
+	switch p.accessLogFormat {
+	case AccessLogFormatCLF, AccessLogFormatCombined:
+		line.WriteString(w.commonLogLine(start, status, p.accessLogFormat == AccessLogFormatCombined))
+	default:
+		lineLog := zerolog.New(&line)
+		lineLog.Log().
+			Time("time", start).
+			Str(LogFieldCFRay, fields.cfRay).
+			Str("method", w.method).
+			Str("host", w.host).
+			Str("path", w.path).
+			Interface(LogFieldRule, fields.rule).
+			EmbedObject(rolloutConfig(fields.config)).
+			Int("status", status).
+			Int64("bytes", w.bytes).
+			Dur("duration", time.Since(start)).
+			Send()
+	}
+	if err := accessLogs.Write(w.host, line.Bytes()); err != nil {
+		p.log.Error().Err(err).Msgf("Unable to write the access log %s", accessLogs.Filename(w.host))
+	}
+}
+
+// commonLogLine formats the request like Apache's "%h %l %u %t \"%r\" %>s %b", and with
+// combined, also "\"%{Referer}i\" \"%{User-agent}i\"".
+func (w *accessLogResponseWriter) commonLogLine(start time.Time, status int, combined bool) string {
+	size := "-"
+	if w.bytes > 0 {
+		size = strconv.FormatInt(w.bytes, 10)
+	}
+	line := fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %s",
+		clfField(w.clientIP), start.Format(clfTimeFormat), clfEscape(w.method), clfEscape(w.target), clfEscape(w.proto), status, size)
+	if combined {
+		line += fmt.Sprintf(" \"%s\" \"%s\"", clfEscape(clfField(w.referer)), clfEscape(clfField(w.userAgent)))
+	}
+	return line + "\n"
+}
+
+// clfField returns - for the missing values of log fields.
+func clfField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// clfEscape escapes quotes, backslashes and control characters, so that a quoted field can't
+// end early or break the line.
+func clfEscape(value string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '"' || c == '\\':
+			escaped.WriteByte('\\')
+			escaped.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&escaped, "\\x%02x", c)
+		default:
+			escaped.WriteByte(c)
+		}
+	}
+	return escaped.String()
+}
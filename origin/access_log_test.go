@@ -0,0 +1,157 @@
+package origin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyAccessLogFilePerHostname(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer origin.Close()
+
+	dir := t.TempDir()
+	accessLogFile := filepath.Join(dir, "{hostname}", "access.log")
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Service: origin.URL, OriginRequest: config.OriginRequestConfig{AccessLogFile: &accessLogFile}},
+		},
+	})
+	require.NoError(t, err)
+	defer ing.Rules[0].AccessLogs.Close()
+	proxy := newTestProxy(t, ing)
+
+	for _, url := range []string{"http://a.example.com/one", "http://b.example.com/missing", "http://a.example.com:8443/two"} {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		require.NoError(t, proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP))
+	}
+
+	readEntries := func(hostname string) []map[string]interface{} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, hostname, "access.log"))
+		require.NoError(t, err)
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+	a := readEntries("a.example.com")
+	require.Len(t, a, 2)
+	assert.Equal(t, "/one", a[0]["path"])
+	assert.Equal(t, "/two", a[1]["path"])
+	assert.Equal(t, float64(http.StatusOK), a[0]["status"])
+	assert.Equal(t, float64(len("hello")), a[0]["bytes"])
+
+	b := readEntries("b.example.com")
+	require.Len(t, b, 1)
+	assert.Equal(t, "/missing", b[0]["path"])
+	assert.Equal(t, "b.example.com", b[0]["host"])
+	assert.Equal(t, float64(http.StatusNotFound), b[0]["status"])
+}
+
+func TestProxyAccessLogCommonFormats(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		format      string
+		path        string
+		expectLine  string
+		requestFunc func(req *http.Request)
+	}{
+		{
+			format:     AccessLogFormatCLF,
+			path:       "/docs?page=2",
+			expectLine: `203.0.113.7 - - [TIME] "GET /docs?page=2 HTTP/1.1" 200 5`,
+		},
+		{
+			format:     AccessLogFormatCLF,
+			path:       "/empty",
+			expectLine: `203.0.113.7 - - [TIME] "GET /empty HTTP/1.1" 204 -`,
+		},
+		{
+			format:     AccessLogFormatCombined,
+			path:       "/docs",
+			expectLine: `203.0.113.7 - - [TIME] "GET /docs HTTP/1.1" 200 5 "https://www.example.com/" "curl/7.68.0 \"quoted\""`,
+			requestFunc: func(req *http.Request) {
+				req.Header.Set("Referer", "https://www.example.com/")
+				req.Header.Set("User-Agent", `curl/7.68.0 "quoted"`)
+			},
+		},
+		{
+			format:     AccessLogFormatCombined,
+			path:       "/docs",
+			expectLine: `203.0.113.7 - - [TIME] "GET /docs HTTP/1.1" 200 5 "-" "-"`,
+		},
+	}
+	for _, test := range tests {
+		accessLogFile := filepath.Join(t.TempDir(), "access.log")
+		ing, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{Service: origin.URL, OriginRequest: config.OriginRequestConfig{AccessLogFile: &accessLogFile}},
+			},
+		})
+		require.NoError(t, err)
+		log := zerolog.Nop()
+		var wg sync.WaitGroup
+		shutdownC := make(chan struct{})
+		require.NoError(t, ing.StartOrigins(&wg, &log, shutdownC, make(chan error)))
+		proxy := NewOriginProxy(ing, unusedWarpRoutingService, testTags, false, nil, test.format, &log)
+
+		req, err := http.NewRequest(http.MethodGet, "http://app.example.com"+test.path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Cf-Connecting-Ip", "203.0.113.7")
+		if test.requestFunc != nil {
+			test.requestFunc(req)
+		}
+		require.NoError(t, proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP))
+		ing.Rules[0].AccessLogs.Close()
+		close(shutdownC)
+
+		content, err := ioutil.ReadFile(accessLogFile)
+		require.NoError(t, err)
+		line := strings.TrimSuffix(string(content), "\n")
+		require.NotContains(t, line, "\n")
+		// The time looks like Apache's, e.g. [10/Oct/2000:13:55:36 -0700].
+		timeStart, timeEnd := strings.Index(line, "["), strings.Index(line, "]")
+		require.True(t, timeStart > 0 && timeEnd > timeStart, line)
+		_, err = time.Parse(clfTimeFormat, line[timeStart+1:timeEnd])
+		assert.NoError(t, err, line)
+		assert.Equal(t, test.expectLine, line[:timeStart+1]+"TIME"+line[timeEnd:], test.format)
+	}
+}
+
+func TestValidateAccessLogFormat(t *testing.T) {
+	for _, format := range []string{"", AccessLogFormatJSON, AccessLogFormatCLF, AccessLogFormatCombined} {
+		assert.NoError(t, ValidateAccessLogFormat(format))
+	}
+	assert.Error(t, ValidateAccessLogFormat("apache"))
+}
@@ -0,0 +1,73 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyAdaptiveConcurrency(t *testing.T) {
+	var latency int64
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(atomic.LoadInt64(&latency)))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	adaptive := true
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "api.example.com", Service: origin.URL},
+			{Service: "http_status:404"},
+		},
+		OriginRequest: config.OriginRequestConfig{AdaptiveConcurrency: &adaptive},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	// proxyFor keeps clients busy sending requests for the given time, and counts the requests
+	// that were shed.
+	proxyFor := func(clients int, duration time.Duration) (shed int64) {
+		stop := time.Now().Add(duration)
+		var requests sync.WaitGroup
+		for i := 0; i < clients; i++ {
+			requests.Add(1)
+			go func() {
+				defer requests.Done()
+				for time.Now().Before(stop) {
+					req, err := http.NewRequest(http.MethodGet, "http://api.example.com", nil)
+					require.NoError(t, err)
+					responseWriter := newMockHTTPRespWriter()
+					require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+					if responseWriter.Code == http.StatusServiceUnavailable {
+						atomic.AddInt64(&shed, 1)
+						time.Sleep(time.Millisecond)
+					}
+				}
+			}()
+		}
+		requests.Wait()
+		return shed
+	}
+
+	atomic.StoreInt64(&latency, int64(time.Millisecond))
+	proxyFor(16, 500*time.Millisecond)
+	fastLimit := ing.Rules[0].Concurrency.Limit()
+
+	atomic.StoreInt64(&latency, int64(50*time.Millisecond))
+	shed := proxyFor(2*fastLimit, time.Second)
+	slowLimit := ing.Rules[0].Concurrency.Limit()
+	assert.Less(t, slowLimit, fastLimit, "the limit should shrink once the origin slows down")
+	assert.Greater(t, shed, int64(0), "requests beyond the limit should be shed")
+}
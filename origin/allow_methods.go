@@ -0,0 +1,15 @@
+package origin
+
+// isMethodAllowed reports whether a rule with allowMethods proxies requests with method. Rules
+// without allowMethods proxy every method.
+func isMethodAllowed(method string, allowMethods []string) bool {
+	if len(allowMethods) == 0 {
+		return true
+	}
+	for _, allowed := range allowMethods {
+		if method == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyAllowMethods(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method))
+	}))
+	defer origin.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname:      "api.example.com",
+				Service:       origin.URL,
+				OriginRequest: config.OriginRequestConfig{AllowMethods: []string{http.MethodGet, http.MethodPost}},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		url          string
+		method       string
+		expectStatus int
+	}{
+		{url: "http://api.example.com/items", method: http.MethodGet, expectStatus: http.StatusOK},
+		{url: "http://api.example.com/items", method: http.MethodPost, expectStatus: http.StatusOK},
+		{url: "http://api.example.com/items/1", method: http.MethodDelete, expectStatus: http.StatusMethodNotAllowed},
+		{url: "http://www.example.com/items/1", method: http.MethodDelete, expectStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, test.expectStatus, responseWriter.Code, "%s %s", test.method, test.url)
+		if test.expectStatus == http.StatusOK {
+			assert.Equal(t, test.method, responseWriter.Body.String())
+		} else {
+			assert.Equal(t, "GET, POST", responseWriter.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestParseAllowMethods(t *testing.T) {
+	for _, allowMethods := range [][]string{{"get"}, {"GET POST"}, {"GET", "GET"}} {
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{Service: "http://localhost:8000", OriginRequest: config.OriginRequestConfig{AllowMethods: allowMethods}},
+			},
+		})
+		assert.Error(t, err, "%v", allowMethods)
+	}
+}
@@ -0,0 +1,83 @@
+package origin
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	auditLogDirPermMode  = 0744
+	auditLogFilePermMode = 0600
+)
+
+// The reasons recorded for routing decisions.
+const (
+	auditRouted                = "routed"
+	auditLoopDetected          = "loop detected"
+	auditAbsoluteFormRejected  = "absolute-form target rejected"
+	auditClientCertMissing     = "client certificate required"
+	auditTLSMissing            = "TLS required"
+	auditRefererNotAllowed     = "referer not allowed"
+	auditMaxWebsocketsExceeded = "maxWebsockets reached"
+	auditMethodNotAllowed      = "method not allowed"
+)
+
+// AuditLog records every routing decision as a JSON line in a file of its own, independent of
+// the regular logs. The file is rotated when it reaches its maximum size, and rotated files are
+// kept. Its methods are safe to call on a nil AuditLog, which records nothing.
+type AuditLog struct {
+	log    zerolog.Logger
+	writer *lumberjack.Logger
+}
+
+// NewAuditLog appends to the audit log at path, rotating it when it reaches maxSizeMB
+// megabytes.
+func NewAuditLog(path string, maxSizeMB int) (*AuditLog, error) {
+	if maxSizeMB <= 0 {
+		return nil, errors.Errorf("the audit log's maximum size must be at least 1 megabyte, got %d", maxSizeMB)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), auditLogDirPermMode); err != nil {
+		return nil, errors.Wrap(err, "unable to create the audit log's directory")
+	}
+	// lumberjack only opens the file when it's first written, so check now that it can be.
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, auditLogFilePermMode)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open the audit log")
+	}
+	_ = file.Close()
+	writer := &lumberjack.Logger{Filename: path, MaxSize: maxSizeMB}
+	return &AuditLog{
+		log:    zerolog.New(writer).With().Timestamp().Logger(),
+		writer: writer,
+	}, nil
+}
+
+// record logs that the request was sent to service by rule, of the given configuration, or
+// rejected, for the given reason.
+func (a *AuditLog) record(req *http.Request, cfRay string, rule interface{}, config string, service string, reason string) {
+	if a == nil {
+		return
+	}
+	a.log.Log().
+		Str(LogFieldCFRay, cfRay).
+		Str("host", req.Host).
+		Str("path", req.URL.Path).
+		Interface(LogFieldRule, rule).
+		EmbedObject(rolloutConfig(config)).
+		Str(LogFieldOriginService, service).
+		Str("decision", reason).
+		Send()
+}
+
+// Close closes the current audit log file.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.writer.Close()
+}
@@ -0,0 +1,153 @@
+package origin
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestAuditLogRecordsDecisions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "routing.log")
+	auditLog, err := NewAuditLog(path, 1)
+	require.NoError(t, err)
+	defer auditLog.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname: "images.example.com",
+				Service:  "http_status:200",
+				Referer:  &config.IngressReferer{Allow: []string{"https://site.example.com"}},
+			},
+			{Service: "http_status:404"},
+		},
+	})
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	var wg sync.WaitGroup
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&wg, &log, shutdownC, make(chan error)))
+	proxy := NewOriginProxy(ing, unusedWarpRoutingService, testTags, false, auditLog, "", &log)
+
+	for _, url := range []string{"http://images.example.com/cat.png", "http://www.example.com/"} {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		require.NoError(t, proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP))
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, "images.example.com", records[0]["host"])
+	assert.Equal(t, float64(0), records[0][LogFieldRule])
+	assert.Equal(t, "HTTP 200", records[0][LogFieldOriginService])
+	assert.Equal(t, auditRefererNotAllowed, records[0]["decision"])
+	assert.NotEmpty(t, records[0]["time"])
+	assert.Equal(t, "www.example.com", records[1]["host"])
+	assert.Equal(t, float64(1), records[1][LogFieldRule])
+	assert.Equal(t, "HTTP 404", records[1][LogFieldOriginService])
+	assert.Equal(t, auditRouted, records[1]["decision"])
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "routing.log")
+	auditLog, err := NewAuditLog(path, 1)
+	require.NoError(t, err)
+	defer auditLog.Close()
+
+	req, err := http.NewRequest(http.MethodGet, "http://www.example.com/"+strings.Repeat("a", 1000), nil)
+	require.NoError(t, err)
+	record := func() {
+		auditLog.record(req, "ray", 0, "", "http://localhost:8080", auditRouted)
+	}
+	record()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	// Just short of 1 megabyte.
+	for i := int64(1); i < (1<<20)/info.Size(); i++ {
+		record()
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "the audit log must not be rotated before reaching its maximum size")
+
+	record()
+	files, err = filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2, "the audit log must be rotated at its maximum size")
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(1<<20))
+}
+
+func TestNewAuditLogInvalidMaxSize(t *testing.T) {
+	_, err := NewAuditLog(filepath.Join(t.TempDir(), "routing.log"), 0)
+	assert.Error(t, err)
+}
+
+func TestProxyReportsRulesByFileOrder(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("api"))
+	}))
+	defer origin.Close()
+	path := filepath.Join(t.TempDir(), "routing.log")
+	auditLog, err := NewAuditLog(path, 1)
+	require.NoError(t, err)
+	defer auditLog.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "www.example.com", Service: "http_status:200"},
+			{Hostname: "blog.example.com", Service: "http_status:200"},
+			{Hostname: "api.example.com", Service: origin.URL, Priority: 10},
+			{Service: "http_status:404"},
+		},
+	})
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	var wg sync.WaitGroup
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&wg, &log, shutdownC, make(chan error)))
+	proxy := NewOriginProxy(ing, unusedWarpRoutingService, testTags, false, auditLog, "", &log)
+
+	// The api rule is matched first, but it's the third rule in the file.
+	responseBytes := counterValue(t, ruleResponseBytes.WithLabelValues("2", ingress.ConfigCurrent))
+	req, err := http.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+	require.NoError(t, err)
+	responseWriter := newMockHTTPRespWriter()
+	require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+	require.Equal(t, http.StatusOK, responseWriter.Code)
+	assert.Equal(t, responseBytes+3, counterValue(t, ruleResponseBytes.WithLabelValues("2", ingress.ConfigCurrent)))
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &record))
+	assert.Equal(t, float64(2), record[LogFieldRule])
+}
@@ -0,0 +1,80 @@
+package origin
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyWebsocketSkipsBodyMatch(t *testing.T) {
+	// Each origin reports the connections it accepts, and keeps them open until the eyeball
+	// closes its side.
+	listen := func(name string, accepted chan<- string) net.Listener {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				accepted <- name
+				go func() {
+					_, _ = io.Copy(ioutil.Discard, conn)
+					conn.Close()
+				}()
+			}
+		}()
+		return listener
+	}
+	accepted := make(chan string, 2)
+	matched := listen("bodyMatch", accepted)
+	defer matched.Close()
+	fallback := listen("fallback", accepted)
+	defer fallback.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname:  "ws.example.com",
+				BodyMatch: &config.IngressBodyMatch{JSONPath: "$.event", Equals: "push"},
+				Service:   "tcp://" + matched.Addr().String(),
+			},
+			{Service: "tcp://" + fallback.Addr().String()},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	// The eyeball doesn't send anything before the origin does, so reading the body to match
+	// the first rule would block the websocket.
+	reader, eyeball := io.Pipe()
+	req, err := http.NewRequest(http.MethodGet, "http://ws.example.com/", reader)
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- proxy.Proxy(newWSRespWriter(ioutil.Discard), req, connection.TypeWebsocket)
+	}()
+	select {
+	case origin := <-accepted:
+		require.Equal(t, "fallback", origin)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the websocket wasn't routed")
+	}
+	eyeball.Close()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the websocket didn't finish")
+	}
+}
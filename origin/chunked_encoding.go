@@ -0,0 +1,61 @@
+package origin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// isChunked returns whether the origin framed the response body with chunked encoding.
+func isChunked(resp *http.Response) bool {
+	return len(resp.TransferEncoding) > 0 && resp.TransferEncoding[0] == "chunked"
+}
+
+// isChunkedFramingError returns whether err, from reading a chunked body, means that the origin
+// sent malformed or truncated chunks, rather than that the request was cancelled.
+func isChunkedFramingError(err error) bool {
+	return err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// bufferChunkedBody reads the whole chunked body of resp, so that malformed chunks are found
+// before any of the response is sent. resp then has the length of the body.
+func bufferChunkedBody(resp *http.Response) error {
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp.TransferEncoding = nil
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// chunkedBodyReader remembers why reading a chunked body failed, to tell malformed chunks
+// apart from failures to write the response to the eyeball.
+type chunkedBodyReader struct {
+	io.ReadCloser
+	err error
+}
+
+func (r *chunkedBodyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// malformed returns the error of a chunked body whose chunks were malformed, if copyErr came
+// from reading it.
+func (r *chunkedBodyReader) malformed(copyErr error) error {
+	if r == nil || copyErr == nil || copyErr != r.err || !isChunkedFramingError(r.err) {
+		return nil
+	}
+	return r.err
+}
@@ -0,0 +1,98 @@
+package origin
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// startChunkedOrigin answers /malformed with chunks whose framing is broken after the first
+// one, and any other path with well formed chunks.
+func startChunkedOrigin(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+				chunks := "5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n"
+				if req.URL.Path == "/malformed" {
+					chunks = "5\r\nhello\r\nzz\r\n world\r\n0\r\n\r\n"
+				}
+				_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n" + chunks))
+			}()
+		}
+	}()
+	return "http://" + listener.Addr().String()
+}
+
+func TestProxyMalformedChunkedEncoding(t *testing.T) {
+	origin := startChunkedOrigin(t)
+	strictChunked := true
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "strict.example.com", Service: origin, OriginRequest: config.OriginRequestConfig{StrictChunked: &strictChunked}},
+			{Service: origin},
+		},
+	})
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+	log := zerolog.New(&logs)
+	var wg sync.WaitGroup
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&wg, &log, shutdownC, make(chan error)))
+	proxy := NewOriginProxy(ing, unusedWarpRoutingService, testTags, false, nil, "", &log)
+
+	proxyURL := func(url string) (*mockHTTPRespWriter, error) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		return responseWriter, proxy.Proxy(responseWriter, req, connection.TypeHTTP)
+	}
+
+	responseWriter, err := proxyURL("http://strict.example.com/malformed")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, responseWriter.Code)
+	assert.Empty(t, responseWriter.Body.String())
+	assert.Contains(t, logs.String(), "malformed chunked encoding")
+
+	responseWriter, err = proxyURL("http://strict.example.com/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, responseWriter.Code)
+	assert.Equal(t, "hello world", responseWriter.Body.String())
+	assert.Equal(t, "11", responseWriter.Header().Get("Content-Length"))
+
+	// Without strictChunked, the first chunk is already sent, so the response is aborted.
+	logs.Reset()
+	responseWriter, err = proxyURL("http://www.example.com/malformed")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusOK, responseWriter.Code)
+	assert.Equal(t, "hello", responseWriter.Body.String())
+	assert.Contains(t, logs.String(), "malformed chunked encoding")
+
+	responseWriter, err = proxyURL("http://www.example.com/")
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", responseWriter.Body.String())
+}
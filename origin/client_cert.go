@@ -0,0 +1,18 @@
+package origin
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CertPresentedHeader is set by Cloudflare to "true" when the eyeball presented a client
+// certificate, if the "Add TLS client auth headers" managed transform is enabled.
+const CertPresentedHeader = "Cf-Cert-Presented"
+
+// hasClientCert checks if the eyeball presented a client certificate, which only the edge can
+// tell: the TLS state of requests is the one of cloudflared's connection to the edge, whose
+// peer certificates are the edge's. Without the managed transform, the header is missing and
+// requests are rejected.
+func hasClientCert(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get(CertPresentedHeader), "true")
+}
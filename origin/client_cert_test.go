@@ -0,0 +1,79 @@
+package origin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyRequireClientCert(t *testing.T) {
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "admin.example.com", Service: "http_status:200", RequireClientCert: true},
+			{Service: "http_status:200"},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		name         string
+		url          string
+		certHeader   string
+		tls          *tls.ConnectionState
+		expectStatus int
+	}{
+		{name: "cert presented", url: "http://admin.example.com", certHeader: "true", expectStatus: http.StatusOK},
+		{name: "TLS peer cert without header", url: "http://admin.example.com", tls: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}, expectStatus: http.StatusForbidden},
+		{name: "no cert", url: "http://admin.example.com", certHeader: "false", expectStatus: http.StatusForbidden},
+		{name: "no header", url: "http://admin.example.com", expectStatus: http.StatusForbidden},
+		{name: "rule without requireClientCert", url: "http://www.example.com", expectStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+			if test.certHeader != "" {
+				req.Header.Set(CertPresentedHeader, test.certHeader)
+			}
+			req.TLS = test.tls
+			responseWriter := newMockHTTPRespWriter()
+			require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+			assert.Equal(t, test.expectStatus, responseWriter.Code)
+		})
+	}
+}
+
+func TestProxyRequireClientCertOverHTTP2(t *testing.T) {
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "admin.example.com", Service: "http_status:200", RequireClientCert: true},
+			{Service: "http_status:200"},
+		},
+	})
+	require.NoError(t, err)
+	edge := newTLSEdge(t, newTestProxy(t, ing))
+
+	// The requests have the TLS state of the connection to the edge, with the edge's
+	// certificate, but only the edge's header says whether the eyeball presented one.
+	for certHeader, expectStatus := range map[string]int{"": http.StatusForbidden, "true": http.StatusOK} {
+		req, err := http.NewRequest(http.MethodGet, "https://admin.example.com/", nil)
+		require.NoError(t, err)
+		if certHeader != "" {
+			req.Header.Set(CertPresentedHeader, certHeader)
+		}
+		resp, err := edge.RoundTrip(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, expectStatus, resp.StatusCode, certHeader)
+	}
+}
@@ -0,0 +1,62 @@
+package origin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// clientRequestTimer cancels a request if the eyeball doesn't finish sending its body in time.
+// Its methods are safe to call on a nil clientRequestTimer, which never expires.
+type clientRequestTimer struct {
+	timer   *time.Timer
+	expired int32
+}
+
+// startClientRequestTimer returns req with a context that is canceled, and a body that is
+// closed, if the body isn't read to the end within timeout. Requests without a body can't be
+// slow to send.
+func startClientRequestTimer(req *http.Request, timeout time.Duration) (*http.Request, *clientRequestTimer) {
+	if timeout <= 0 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	body := req.Body
+	t := clientRequestTimer{}
+	t.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&t.expired, 1)
+		cancel()
+		// Unblocks a read that waits for the rest of the body.
+		_ = body.Close()
+	})
+	req = req.WithContext(ctx)
+	req.Body = &timedBody{ReadCloser: body, timer: &t}
+	return req, &t
+}
+
+// stop keeps the timer from expiring, e.g. because the origin already responded.
+func (t *clientRequestTimer) stop() {
+	if t != nil {
+		t.timer.Stop()
+	}
+}
+
+func (t *clientRequestTimer) isExpired() bool {
+	return t != nil && atomic.LoadInt32(&t.expired) == 1
+}
+
+// timedBody stops its timer once the whole body was read.
+type timedBody struct {
+	io.ReadCloser
+	timer *clientRequestTimer
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.timer.stop()
+	}
+	return n, err
+}
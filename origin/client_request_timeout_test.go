@@ -0,0 +1,73 @@
+package origin
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyClientRequestTimeout(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer origin.Close()
+
+	timeout := 50 * time.Millisecond
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: origin.URL}},
+		OriginRequest: config.OriginRequestConfig{
+			ClientRequestTimeout: &timeout,
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	t.Run("slow client", func(t *testing.T) {
+		// The client sends the start of the body, then stalls.
+		bodyReader, bodyWriter := io.Pipe()
+		defer bodyWriter.Close()
+		go func() {
+			_, _ = bodyWriter.Write([]byte("partial"))
+		}()
+		req, err := http.NewRequest(http.MethodPost, "http://www.example.com", bodyReader)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusRequestTimeout, responseWriter.Code)
+	})
+
+	t.Run("fast client", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "http://www.example.com", strings.NewReader("complete"))
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.Equal(t, "complete", responseWriter.Body.String())
+	})
+}
+
+func TestParseClientRequestTimeout(t *testing.T) {
+	timeout := -time.Second
+	_, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http_status:404"}},
+		OriginRequest: config.OriginRequestConfig{
+			ClientRequestTimeout: &timeout,
+		},
+	})
+	assert.Error(t, err)
+}
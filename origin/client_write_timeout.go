@@ -0,0 +1,62 @@
+package origin
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+var errClientWriteTimeout = fmt.Errorf("the eyeball stopped reading the response: %w", connection.ErrResponseAborted)
+
+// clientWriteTimeout calls abort and gives up on a write to the eyeball that blocks for longer
+// than timeout. Once that happened, every later write fails. Writes must not be concurrent.
+type clientWriteTimeout struct {
+	w       io.Writer
+	timeout time.Duration
+	abort   func()
+	expired bool
+	// buf holds the data of the write in flight, which may outlive the Write call that gave up
+	// on it.
+	buf []byte
+}
+
+// newClientWriteTimeout returns w unchanged if timeout isn't positive.
+func newClientWriteTimeout(w io.Writer, timeout time.Duration, abort func()) io.Writer {
+	if timeout <= 0 {
+		return w
+	}
+	return &clientWriteTimeout{w: w, timeout: timeout, abort: abort}
+}
+
+type writeResult struct {
+	n   int
+	err error
+}
+
+func (c *clientWriteTimeout) Write(p []byte) (int, error) {
+	if c.expired {
+		return 0, errClientWriteTimeout
+	}
+	// The write runs on its own goroutine so that it can be given up on, and it writes a copy of
+	// p, since the caller may reuse p once this returns.
+	c.buf = append(c.buf[:0], p...)
+	buf := c.buf
+	written := make(chan writeResult, 1)
+	go func() {
+		n, err := c.w.Write(buf)
+		written <- writeResult{n: n, err: err}
+	}()
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case result := <-written:
+		return result.n, result.err
+	case <-timer.C:
+		// The blocked write keeps buf, so it's never reused.
+		c.expired = true
+		c.abort()
+		return 0, errClientWriteTimeout
+	}
+}
@@ -0,0 +1,135 @@
+package origin
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// stalledRespWriter is an eyeball that never reads the response body, until it's unblocked.
+type stalledRespWriter struct {
+	*mockHTTPRespWriter
+	unblock chan struct{}
+}
+
+func (w *stalledRespWriter) Write(p []byte) (int, error) {
+	<-w.unblock
+	return len(p), nil
+}
+
+func TestProxyClientWriteTimeout(t *testing.T) {
+	originReleased := make(chan struct{})
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(originReleased)
+		// Stream the response until the proxy stops reading it.
+		chunk := make([]byte, 32*1024)
+		for {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer origin.Close()
+
+	timeout := 50 * time.Millisecond
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: origin.URL}},
+		OriginRequest: config.OriginRequestConfig{
+			ClientWriteTimeout: &timeout,
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	require.NoError(t, err)
+	responseWriter := &stalledRespWriter{mockHTTPRespWriter: newMockHTTPRespWriter(), unblock: make(chan struct{})}
+	proxied := make(chan error)
+	go func() {
+		proxied <- proxy.Proxy(responseWriter, req, connection.TypeHTTP)
+	}()
+
+	defer close(responseWriter.unblock)
+
+	select {
+	case <-originReleased:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the origin connection wasn't released")
+	}
+	// The proxy gives up on the eyeball's write, which is still blocked, so the connection
+	// resets the stream.
+	select {
+	case err := <-proxied:
+		assert.True(t, errors.Is(err, connection.ErrResponseAborted), err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the response wasn't aborted")
+	}
+	assert.Equal(t, http.StatusOK, responseWriter.Code)
+}
+
+func TestProxyClientWriteTimeoutOverHTTP2(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := make([]byte, 32*1024)
+		for {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer origin.Close()
+
+	timeout := 50 * time.Millisecond
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: origin.URL}},
+		OriginRequest: config.OriginRequestConfig{
+			ClientWriteTimeout: &timeout,
+		},
+	})
+	require.NoError(t, err)
+	edge := newTLSEdge(t, newTestProxy(t, ing))
+
+	req, err := http.NewRequest(http.MethodGet, "https://www.example.com/", nil)
+	require.NoError(t, err)
+	resp, err := edge.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The edge doesn't read the body until the stream's window is full and the write to it
+	// timed out. Then the stream is reset rather than ended, so the body isn't taken for whole.
+	time.Sleep(10 * timeout)
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(ioutil.Discard, resp.Body)
+		copied <- err
+	}()
+	select {
+	case err := <-copied:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream wasn't reset")
+	}
+}
+
+func TestParseClientWriteTimeout(t *testing.T) {
+	timeout := -time.Second
+	_, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http_status:404"}},
+		OriginRequest: config.OriginRequestConfig{
+			ClientWriteTimeout: &timeout,
+		},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+package origin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// rewriteCookieDomains replaces the Domain attribute of the Set-Cookie headers in header that
+// are scoped to rewrite.From with rewrite.To. Other cookies pass through unchanged.
+func rewriteCookieDomains(header http.Header, rewrite config.CookieDomainRewrite) {
+	cookies := header["Set-Cookie"]
+	for i, cookie := range cookies {
+		cookies[i] = rewriteCookieDomain(cookie, rewrite)
+	}
+}
+
+func rewriteCookieDomain(cookie string, rewrite config.CookieDomainRewrite) string {
+	attributes := strings.Split(cookie, ";")
+	// The first part is the cookie's name and value.
+	for i, attribute := range attributes[1:] {
+		name, value := attribute, ""
+		if eq := strings.Index(attribute, "="); eq >= 0 {
+			name, value = attribute[:eq], attribute[eq+1:]
+		}
+		if !strings.EqualFold(strings.TrimSpace(name), "Domain") {
+			continue
+		}
+		// A leading dot is ignored, see RFC 6265 section 5.2.3.
+		domain := strings.TrimPrefix(strings.TrimSpace(value), ".")
+		if strings.EqualFold(domain, strings.TrimPrefix(rewrite.From, ".")) {
+			attributes[i+1] = " Domain=" + strings.TrimPrefix(rewrite.To, ".")
+		}
+	}
+	return strings.Join(attributes, ";")
+}
@@ -0,0 +1,100 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestRewriteCookieDomain(t *testing.T) {
+	rewrite := config.CookieDomainRewrite{From: "internal.local", To: "public.example.com"}
+	tests := []struct {
+		cookie   string
+		expected string
+	}{
+		{
+			cookie:   "session=abc; Path=/; Domain=internal.local; HttpOnly",
+			expected: "session=abc; Path=/; Domain=public.example.com; HttpOnly",
+		},
+		{
+			cookie:   "session=abc; domain=.Internal.Local",
+			expected: "session=abc; Domain=public.example.com",
+		},
+		{
+			cookie:   "session=abc; Domain=other.local; Secure",
+			expected: "session=abc; Domain=other.local; Secure",
+		},
+		{
+			cookie:   "session=abc; Domain=sub.internal.local",
+			expected: "session=abc; Domain=sub.internal.local",
+		},
+		{
+			cookie:   "session=Domain=internal.local",
+			expected: "session=Domain=internal.local",
+		},
+		{
+			cookie:   "session=abc; Path=/",
+			expected: "session=abc; Path=/",
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, rewriteCookieDomain(test.cookie, rewrite), test.cookie)
+	}
+}
+
+func TestProxyRewriteCookieDomain(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "session=abc; Domain=internal.local; Path=/")
+		w.Header().Add("Set-Cookie", "tracking=xyz; Domain=cdn.example.net")
+	}))
+	defer origin.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Service: origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					RewriteCookieDomain: &config.CookieDomainRewrite{From: "internal.local", To: "public.example.com"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	req, err := http.NewRequest(http.MethodGet, "http://public.example.com", nil)
+	require.NoError(t, err)
+	responseWriter := newMockHTTPRespWriter()
+	require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+	assert.Equal(t, []string{
+		"session=abc; Domain=public.example.com; Path=/",
+		"tracking=xyz; Domain=cdn.example.net",
+	}, responseWriter.Header()["Set-Cookie"])
+}
+
+func TestParseRewriteCookieDomainInvalid(t *testing.T) {
+	for _, rewrite := range []config.CookieDomainRewrite{
+		{From: "internal.local"},
+		{To: "public.example.com"},
+		{From: "internal.local:8080", To: "public.example.com"},
+		{From: "internal.local", To: "https://public.example.com"},
+	} {
+		rewrite := rewrite
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{
+					Service:       "http_status:404",
+					OriginRequest: config.OriginRequestConfig{RewriteCookieDomain: &rewrite},
+				},
+			},
+		})
+		assert.Error(t, err, rewrite)
+	}
+}
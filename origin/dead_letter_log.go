@@ -0,0 +1,50 @@
+package origin
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// maxDeadLetterError is how much of an error is kept in the dead letter log. Errors can quote
+// parts of the response, which the log is not meant to hold.
+const maxDeadLetterError = 512
+
+// writeDeadLetter appends a line about the request to the rule's dead letter log, if it failed
+// with err or the eyeball got a 5xx status.
+func (p *proxy) writeDeadLetter(deadLetterLog *ingress.DeadLetterLog, w *accessLogResponseWriter, start time.Time, fields logFields, err error) {
+	status := w.responseStatus()
+	if err == nil && status < 500 {
+		return
+	}
+	var line bytes.Buffer
+	lineLog := zerolog.New(&line)
+	event := lineLog.Log().
+		Time("time", start).
+		Str(LogFieldCFRay, fields.cfRay).
+		Str("method", w.method).
+		Str("host", w.host).
+		Str("path", w.path).
+		Interface(LogFieldRule, fields.rule).
+		EmbedObject(rolloutConfig(fields.config)).
+		Int("status", status)
+	if err != nil {
+		event = event.Str("error", truncateError(err.Error(), maxDeadLetterError))
+	}
+	event.Send()
+	if err := deadLetterLog.Write(line.Bytes()); err != nil {
+		p.log.Error().Err(err).Msgf("Unable to write the dead letter log %s", deadLetterLog.Filename())
+	}
+}
+
+// truncateError cuts message to at most max bytes, without splitting a UTF-8 character.
+func truncateError(message string, max int) string {
+	if len(message) <= max {
+		return message
+	}
+	return strings.ToValidUTF8(message[:max], "") + "..."
+}
@@ -0,0 +1,77 @@
+package origin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyDeadLetterLog(t *testing.T) {
+	// Nothing listens on this address once the listener is closed.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	down := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	deadLetterLog := filepath.Join(t.TempDir(), "failed.log")
+	originRequest := config.OriginRequestConfig{DeadLetterLog: &deadLetterLog}
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "ok.example.com", Service: "http_status:200", OriginRequest: originRequest},
+			{Hostname: "unavailable.example.com", Service: "http_status:503", OriginRequest: originRequest},
+			{Service: down, OriginRequest: originRequest},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	for _, url := range []string{
+		"http://ok.example.com/fine",
+		"http://unavailable.example.com/busy?token=secret",
+		"http://down.example.com/unreachable",
+	} {
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader("password=secret"))
+		require.NoError(t, err)
+		_ = proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP)
+	}
+	for _, rule := range ing.Rules {
+		require.NoError(t, rule.DeadLetterLog.Close())
+	}
+
+	content, err := ioutil.ReadFile(deadLetterLog)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "secret")
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+
+	var unavailable, unreachable map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &unavailable))
+	assert.Equal(t, "POST", unavailable["method"])
+	assert.Equal(t, "/busy", unavailable["path"])
+	assert.Equal(t, float64(http.StatusServiceUnavailable), unavailable["status"])
+	assert.NotContains(t, unavailable, "error")
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &unreachable))
+	assert.Equal(t, "/unreachable", unreachable["path"])
+	assert.Equal(t, float64(http.StatusBadGateway), unreachable["status"])
+	assert.Contains(t, unreachable["error"], "Unable to reach the origin service")
+}
+
+func TestTruncateError(t *testing.T) {
+	assert.Equal(t, "short", truncateError("short", 10))
+	assert.Equal(t, "0123456789...", truncateError("0123456789abc", 10))
+	// The é is two bytes, which aren't split.
+	assert.Equal(t, "abc...", truncateError("abcé", 4))
+}
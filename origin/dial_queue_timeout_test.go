@@ -0,0 +1,87 @@
+package origin
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyDialQueueTimeout(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	maxDials := 1
+	queueTimeout := 100 * time.Millisecond
+	originRequest := config.OriginRequestConfig{
+		MaxConcurrentDials: &maxDials,
+		DialQueueTimeout:   &queueTimeout,
+	}
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "slow.example.com", Service: origin.URL},
+			{Service: origin.URL},
+		},
+		OriginRequest: originRequest,
+	})
+	require.NoError(t, err)
+	delayedDial := func(delay time.Duration) func(ctx context.Context, network, address string) (net.Conn, error) {
+		return func(ctx context.Context, network, address string) (net.Conn, error) {
+			time.Sleep(delay)
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		}
+	}
+	// Dials to the first rule's origin hold the only slot for longer than the queue timeout,
+	// those of the second rule release it well within.
+	ing.Rules[0].DialContext = delayedDial(400 * time.Millisecond)
+	ing.Rules[1].DialContext = delayedDial(20 * time.Millisecond)
+
+	proxy := newTestProxy(t, ing)
+
+	proxyConcurrently := func(url string) []int {
+		statuses := make([]int, 2)
+		var requests sync.WaitGroup
+		for i := range statuses {
+			requests.Add(1)
+			go func(i int) {
+				defer requests.Done()
+				req, err := http.NewRequest(http.MethodGet, url, nil)
+				require.NoError(t, err)
+				responseWriter := newMockHTTPRespWriter()
+				require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+				statuses[i] = responseWriter.Code
+			}(i)
+			// Let the first request take the slot.
+			time.Sleep(5 * time.Millisecond)
+		}
+		requests.Wait()
+		return statuses
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusServiceUnavailable}, proxyConcurrently("http://slow.example.com"))
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, proxyConcurrently("http://www.example.com"))
+}
+
+func TestParseDialQueueTimeout(t *testing.T) {
+	timeout := -time.Second
+	_, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http://localhost:8080"}},
+		OriginRequest: config.OriginRequestConfig{
+			DialQueueTimeout: &timeout,
+		},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package origin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// errorPageHeaders describe the body that an error page replaces, so they aren't sent with it.
+var errorPageHeaders = []string{"Content-Encoding", "Content-Length", "Content-Range", "ETag", "Last-Modified", "Transfer-Encoding"}
+
+// errorPageResponseWriter answers with the rule's error page instead of the body of responses
+// with its statuses, whether they came from the origin or from cloudflared.
+type errorPageResponseWriter struct {
+	connection.ResponseWriter
+	pages   *ingress.ErrorPages
+	data    ingress.ErrorPageData
+	head    bool
+	log     *zerolog.Logger
+	written bool
+	// replaced is set once an error page was written, and the actual body is discarded.
+	replaced bool
+}
+
+func newErrorPageResponseWriter(w connection.ResponseWriter, req *http.Request, pages *ingress.ErrorPages, cfRay string, log *zerolog.Logger) *errorPageResponseWriter {
+	return &errorPageResponseWriter{
+		ResponseWriter: w,
+		pages:          pages,
+		data:           ingress.ErrorPageData{Host: req.Host, RequestID: cfRay},
+		head:           req.Method == http.MethodHead,
+		log:            log,
+	}
+}
+
+func (w *errorPageResponseWriter) WriteRespHeaders(status int, header http.Header) error {
+	w.written = true
+	data := w.data
+	data.Status, data.StatusText = status, http.StatusText(status)
+	page, ok, err := w.pages.Render(status, data)
+	if err != nil {
+		w.log.Error().Err(err).Str(LogFieldCFRay, w.data.RequestID).Msgf("Unable to render the error page for %d", status)
+	}
+	if !ok || err != nil {
+		return w.ResponseWriter.WriteRespHeaders(status, header)
+	}
+	w.replaced = true
+	pageHeader := header.Clone()
+	for _, name := range errorPageHeaders {
+		pageHeader.Del(name)
+	}
+	pageHeader.Set("Content-Type", "text/html; charset=utf-8")
+	pageHeader.Set("Content-Length", strconv.Itoa(len(page)))
+	if err := w.ResponseWriter.WriteRespHeaders(status, pageHeader); err != nil {
+		return err
+	}
+	if w.head {
+		return nil
+	}
+	_, err = w.ResponseWriter.Write(page)
+	return err
+}
+
+func (w *errorPageResponseWriter) Write(p []byte) (int, error) {
+	if w.replaced {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// writeGatewayErrorPage answers a request that failed before any response was written with the
+// error page for 502, which the eyeball would get anyway. It reports whether there's one.
+func (w *errorPageResponseWriter) writeGatewayErrorPage() bool {
+	if w.written {
+		return false
+	}
+	data := w.data
+	data.Status, data.StatusText = http.StatusBadGateway, http.StatusText(http.StatusBadGateway)
+	if _, ok, err := w.pages.Render(http.StatusBadGateway, data); !ok || err != nil {
+		return false
+	}
+	return w.WriteRespHeaders(http.StatusBadGateway, http.Header{}) == nil
+}
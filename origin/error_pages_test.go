@@ -0,0 +1,95 @@
+package origin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyErrorPages(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.Header().Set("ETag", `"404"`)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("origin's 404"))
+		case "/busy":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("origin's 503"))
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("origin's 500"))
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer origin.Close()
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unreachable.Close()
+
+	dir := t.TempDir()
+	writePage := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0600))
+		return filename
+	}
+	errorPages := map[int]string{
+		404: writePage("404.html", "<p>Nothing at {{.Host}}, request {{.RequestID}}</p>"),
+		502: writePage("502.html", "<p>{{.Host}} is down ({{.Status}})</p>"),
+		503: writePage("503.html", "<p>{{.StatusText}}, request {{.RequestID}}</p>"),
+	}
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "down.example.com", Service: unreachable.URL},
+			{Service: origin.URL},
+		},
+		OriginRequest: config.OriginRequestConfig{ErrorPages: errorPages},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		method       string
+		url          string
+		expectStatus int
+		expectBody   string
+		expectHeader http.Header
+	}{
+		{url: "http://www.example.com/", expectStatus: http.StatusOK, expectBody: "ok"},
+		{url: "http://www.example.com/missing", expectStatus: http.StatusNotFound, expectBody: "<p>Nothing at www.example.com, request 1234-LHR</p>"},
+		{url: "http://www.example.com/busy", expectStatus: http.StatusServiceUnavailable, expectBody: "<p>Service Unavailable, request 1234-LHR</p>", expectHeader: http.Header{"Retry-After": {"30"}}},
+		{url: "http://www.example.com/broken", expectStatus: http.StatusInternalServerError, expectBody: "origin's 500"},
+		{url: "http://down.example.com/", expectStatus: http.StatusBadGateway, expectBody: "<p>down.example.com is down (502)</p>"},
+		{method: http.MethodHead, url: "http://www.example.com/missing", expectStatus: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		method := test.method
+		if method == "" {
+			method = http.MethodGet
+		}
+		req, err := http.NewRequest(method, test.url, nil)
+		require.NoError(t, err)
+		req.Header.Set("Cf-Ray", "1234-LHR")
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP), test.url)
+		assert.Equal(t, test.expectStatus, responseWriter.Code, test.url)
+		assert.Equal(t, test.expectBody, responseWriter.Body.String(), test.url)
+		if test.expectStatus != http.StatusOK && test.expectStatus != http.StatusInternalServerError {
+			assert.Equal(t, "text/html; charset=utf-8", responseWriter.Header().Get("Content-Type"), test.url)
+			assert.Empty(t, responseWriter.Header().Get("ETag"), test.url)
+		}
+		for name, values := range test.expectHeader {
+			assert.Equal(t, values, responseWriter.Header().Values(name), test.url)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package origin
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// protocolPseudoHeader is how the edge passes the :protocol pseudo-header of an extended
+// CONNECT request on. See https://tools.ietf.org/html/rfc8441#section-4
+const protocolPseudoHeader = ":protocol"
+
+var errExtendedConnectNotAllowed = errors.New("extended CONNECT requests aren't allowed for this hostname")
+
+// isExtendedConnect returns whether req is a CONNECT request that bootstraps another protocol,
+// such as WebTransport, over the request's stream.
+func isExtendedConnect(req *http.Request) bool {
+	return req.Method == http.MethodConnect && req.Header.Get(protocolPseudoHeader) != ""
+}
@@ -0,0 +1,59 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyAllowExtendedConnect(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method))
+	}))
+	defer origin.Close()
+
+	allowExtendedConnect := false
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname:      "rejected.example.com",
+				Service:       origin.URL,
+				OriginRequest: config.OriginRequestConfig{AllowExtendedConnect: &allowExtendedConnect},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		url          string
+		method       string
+		protocol     string
+		expectStatus int
+	}{
+		{url: "http://rejected.example.com/session", method: http.MethodConnect, protocol: "webtransport", expectStatus: http.StatusBadRequest},
+		{url: "http://rejected.example.com/session", method: http.MethodConnect, expectStatus: http.StatusOK},
+		{url: "http://www.example.com/session", method: http.MethodConnect, protocol: "webtransport", expectStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		require.NoError(t, err)
+		if test.protocol != "" {
+			req.Header.Add(protocolPseudoHeader, test.protocol)
+		}
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, test.expectStatus, responseWriter.Code, "%s %s %s", test.method, test.url, test.protocol)
+		if test.expectStatus == http.StatusOK {
+			assert.Equal(t, test.method, responseWriter.Body.String())
+		}
+	}
+}
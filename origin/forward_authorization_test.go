@@ -0,0 +1,52 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyForwardAuthorization(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer origin.Close()
+
+	forwardAuthorization := false
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname:      "stripped.example.com",
+				Service:       origin.URL,
+				OriginRequest: config.OriginRequestConfig{ForwardAuthorization: &forwardAuthorization},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		url                 string
+		expectAuthorization string
+	}{
+		{url: "http://stripped.example.com", expectAuthorization: ""},
+		{url: "http://www.example.com", expectAuthorization: "Bearer secret"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.Equal(t, test.expectAuthorization, responseWriter.Body.String(), test.url)
+	}
+}
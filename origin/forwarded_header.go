@@ -0,0 +1,50 @@
+package origin
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// addForwardedHeader appends the element of a Forwarded header (RFC 7239) that describes the
+// eyeball's request: its scheme, from X-Forwarded-Proto like redirectHostLocation, the host it
+// asked for, and its IP from Cf-Connecting-Ip. Elements of proxies in front of cloudflared
+// are kept, so this one is always the last.
+func addForwardedHeader(req *http.Request) {
+	proto := "https"
+	if strings.EqualFold(req.Header.Get("X-Forwarded-Proto"), "http") {
+		proto = "http"
+	}
+	pairs := []string{"proto=" + proto}
+	if req.Host != "" {
+		pairs = append(pairs, "host="+forwardedValue(req.Host))
+	}
+	if ip := net.ParseIP(req.Header.Get("Cf-Connecting-Ip")); ip != nil {
+		node := ip.String()
+		if ip.To4() == nil {
+			node = "[" + node + "]"
+		}
+		pairs = append(pairs, "for="+forwardedValue(node))
+	}
+	element := strings.Join(pairs, ";")
+	if existing := req.Header.Values("Forwarded"); len(existing) > 0 {
+		element = strings.Join(existing, ", ") + ", " + element
+	}
+	req.Header.Set("Forwarded", element)
+}
+
+// forwardedValue quotes value unless it's a token, like IPv4 addresses and hosts without a
+// port. IPv6 addresses and ports need quotes because of their colons.
+func forwardedValue(value string) string {
+	for _, c := range value {
+		if !isTokenChar(c) {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+		}
+	}
+	return value
+}
+
+// isTokenChar reports whether c may appear in a token of RFC 7230.
+func isTokenChar(c rune) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || strings.ContainsRune("!#$%&'*+-.^_`|~", c)
+}
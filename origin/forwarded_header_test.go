@@ -0,0 +1,107 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestAddForwardedHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		header   http.Header
+		expected string
+	}{
+		{
+			name:     "IPv4",
+			host:     "www.example.com",
+			header:   http.Header{"Cf-Connecting-Ip": {"203.0.113.7"}},
+			expected: "proto=https;host=www.example.com;for=203.0.113.7",
+		},
+		{
+			name:     "IPv6 over http",
+			host:     "www.example.com:8080",
+			header:   http.Header{"Cf-Connecting-Ip": {"2001:db8::1"}, "X-Forwarded-Proto": {"http"}},
+			expected: `proto=http;host="www.example.com:8080";for="[2001:db8::1]"`,
+		},
+		{
+			name:     "without eyeball IP",
+			host:     "www.example.com",
+			header:   http.Header{"Cf-Connecting-Ip": {"unknown"}},
+			expected: "proto=https;host=www.example.com",
+		},
+		{
+			name:     "after other proxies",
+			host:     "www.example.com",
+			header:   http.Header{"Cf-Connecting-Ip": {"203.0.113.7"}, "Forwarded": {"for=192.0.2.60", "for=198.51.100.17"}},
+			expected: "for=192.0.2.60, for=198.51.100.17, proto=https;host=www.example.com;for=203.0.113.7",
+		},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, "http://"+test.host+"/", nil)
+		require.NoError(t, err)
+		req.Header = test.header
+		addForwardedHeader(req)
+		assert.Equal(t, []string{test.expected}, req.Header.Values("Forwarded"), test.name)
+	}
+}
+
+func TestProxyForwardedHeader(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Origin-Host", r.Host)
+		w.Header().Set("Origin-Forwarded", r.Header.Get("Forwarded"))
+		w.Header().Set("Origin-X-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("Origin-X-Forwarded-Proto", r.Header.Get("X-Forwarded-Proto"))
+	}))
+	defer origin.Close()
+
+	forwardedHeader := true
+	httpHostHeader := "app.internal"
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname: "forwarded.example.com",
+				Service:  origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					ForwardedHeader: &forwardedHeader,
+					HTTPHostHeader:  &httpHostHeader,
+				},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	proxyRequest := func(host string) http.Header {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+		require.NoError(t, err)
+		req.Header.Set("Cf-Connecting-Ip", "203.0.113.7")
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.Header.Set("X-Forwarded-Proto", "https")
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		require.Equal(t, http.StatusOK, responseWriter.Code)
+		return responseWriter.Header()
+	}
+
+	// The host is the one the eyeball asked for, not the httpHostHeader sent to the origin, and
+	// the X-Forwarded-* headers are passed on as they are.
+	header := proxyRequest("forwarded.example.com")
+	assert.Equal(t, "app.internal", header.Get("Origin-Host"))
+	assert.Equal(t, "proto=https;host=forwarded.example.com;for=203.0.113.7", header.Get("Origin-Forwarded"))
+	assert.Equal(t, "203.0.113.7", header.Get("Origin-X-Forwarded-For"))
+	assert.Equal(t, "https", header.Get("Origin-X-Forwarded-Proto"))
+
+	header = proxyRequest("other.example.com")
+	assert.Empty(t, header.Get("Origin-Forwarded"))
+	assert.Equal(t, "203.0.113.7", header.Get("Origin-X-Forwarded-For"))
+}
@@ -0,0 +1,89 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyTooFewHealthyServices(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Groups: map[string][]config.WeightedService{
+			"api": {{Service: up.URL}, {Service: down.URL}},
+		},
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname:     "api.example.com",
+				Group:        "api",
+				LoadBalancer: &config.IngressLoadBalancer{MinHealthy: 2},
+			},
+			{Service: "http_status:404"},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	// Services are picked at random, so the down one fails one of the first requests, and every
+	// request after that is answered with 503.
+	failed := false
+	for i := 0; i < 50 && !failed; i++ {
+		req, err := http.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+		require.NoError(t, err)
+		failed = proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP) != nil
+	}
+	require.True(t, failed)
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusServiceUnavailable, responseWriter.Code)
+	}
+}
+
+func TestProxySelectTimeout(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Groups: map[string][]config.WeightedService{
+			"api": {{Service: down.URL}},
+		},
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname:     "api.example.com",
+				Group:        "api",
+				LoadBalancer: &config.IngressLoadBalancer{SelectTimeout: 100 * time.Millisecond},
+			},
+			{Service: "http_status:404"},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	// The first request fails the only service, so the next one waits for it in vain.
+	req, err := http.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+	require.NoError(t, err)
+	require.Error(t, proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP))
+
+	req, err = http.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+	require.NoError(t, err)
+	responseWriter := newMockHTTPRespWriter()
+	start := time.Now()
+	require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+	assert.Equal(t, http.StatusServiceUnavailable, responseWriter.Code)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+}
@@ -0,0 +1,68 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyHealthResponsePath(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("origin " + r.URL.Path))
+	}))
+	defer origin.Close()
+
+	healthPath := "/cf-health"
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Service: origin.URL, OriginRequest: config.OriginRequestConfig{HealthResponsePath: &healthPath}},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		method       string
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{method: http.MethodGet, path: "/cf-health", expectStatus: http.StatusOK, expectBody: "OK\n"},
+		{method: http.MethodHead, path: "/cf-health", expectStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/cf-health/", expectStatus: http.StatusServiceUnavailable, expectBody: "origin /cf-health/"},
+		{method: http.MethodGet, path: "/index.html", expectStatus: http.StatusServiceUnavailable, expectBody: "origin /index.html"},
+		{method: http.MethodPost, path: "/cf-health", expectStatus: http.StatusServiceUnavailable, expectBody: "origin /cf-health"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, "http://example.com"+test.path, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, test.expectStatus, responseWriter.Code, test.method+" "+test.path)
+		assert.Equal(t, test.expectBody, responseWriter.Body.String(), test.method+" "+test.path)
+	}
+}
+
+func TestParseHealthResponsePathInvalid(t *testing.T) {
+	for _, healthPath := range []string{"cf-health", "/cf-health?x=1", "/cf-health#top"} {
+		healthPath := healthPath
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{
+					Service:       "http_status:404",
+					OriginRequest: config.OriginRequestConfig{HealthResponsePath: &healthPath},
+				},
+			},
+		})
+		assert.Error(t, err, healthPath)
+	}
+}
@@ -0,0 +1,57 @@
+package origin
+
+import (
+	"net/http"
+	"strings"
+)
+
+// hopByHopHeaders only apply to a single connection, so they aren't proxied. See
+// https://tools.ietf.org/html/rfc7230#section-6.1
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection", // non-standard, but still sent by some clients
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopByHopHeaders removes the hop-by-hop headers, and the headers named by the
+// Connection header. If keepConnection is set, the Connection header and the headers it names
+// are kept.
+func removeHopByHopHeaders(header http.Header, keepConnection bool) {
+	if !keepConnection {
+		for _, value := range header.Values("Connection") {
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					header.Del(name)
+				}
+			}
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		switch {
+		case name == "Connection" && keepConnection:
+		case name == "Te" && hasToken(header.Values("Te"), "trailers"):
+			// The origin may send trailers, which the eyeball says it accepts.
+			header.Set("Te", "trailers")
+		default:
+			header.Del(name)
+		}
+	}
+}
+
+func hasToken(values []string, token string) bool {
+	for _, value := range values {
+		for _, t := range strings.Split(value, ",") {
+			// Parameters like "deflate;q=0.5" don't apply to trailers.
+			if strings.EqualFold(strings.TrimSpace(strings.SplitN(t, ";", 2)[0]), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
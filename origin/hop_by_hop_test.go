@@ -0,0 +1,104 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestRemoveHopByHopHeaders(t *testing.T) {
+	newHeader := func() http.Header {
+		return http.Header{
+			"Connection":          {"close, X-Session"},
+			"X-Session":           {"abc"},
+			"Keep-Alive":          {"timeout=5"},
+			"Proxy-Authorization": {"Basic Zm9vOmJhcg=="},
+			"Te":                  {"gzip, trailers"},
+			"Upgrade":             {"h2c"},
+			"Accept":              {"*/*"},
+		}
+	}
+
+	header := newHeader()
+	removeHopByHopHeaders(header, false)
+	assert.Equal(t, http.Header{"Accept": {"*/*"}, "Te": {"trailers"}}, header)
+
+	header = newHeader()
+	removeHopByHopHeaders(header, true)
+	assert.Equal(t, http.Header{
+		"Connection": {"close, X-Session"},
+		"X-Session":  {"abc"},
+		"Accept":     {"*/*"},
+		"Te":         {"trailers"},
+	}, header)
+
+	header = http.Header{"Te": {"deflate;q=0.5"}}
+	removeHopByHopHeaders(header, false)
+	assert.Empty(t, header)
+}
+
+func TestProxyRemovesHopByHopHeaders(t *testing.T) {
+	var received http.Header
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.Header().Set("Proxy-Authenticate", "Basic")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		name                 string
+		keepConnectionHeader bool
+		assumeHTTP10         bool
+		expectConnection     []string
+		expectSession        string
+	}{
+		{name: "default", expectConnection: []string{"keep-alive"}},
+		{name: "keepConnectionHeader", keepConnectionHeader: true, expectConnection: []string{"X-Session"}, expectSession: "abc"},
+		{name: "assumeHTTP10", assumeHTTP10: true, expectConnection: []string{"close"}},
+		{name: "assumeHTTP10 and keepConnectionHeader", keepConnectionHeader: true, assumeHTTP10: true, expectConnection: []string{"X-Session", "close"}, expectSession: "abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ing, err := ingress.ParseIngress(&config.Configuration{
+				Ingress: []config.UnvalidatedIngressRule{
+					{
+						Service: origin.URL,
+						OriginRequest: config.OriginRequestConfig{
+							KeepConnectionHeader: &test.keepConnectionHeader,
+							AssumeHTTP10:         &test.assumeHTTP10,
+						},
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			proxy := newTestProxy(t, ing)
+
+			req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+			require.NoError(t, err)
+			req.Header.Set("Connection", "X-Session")
+			req.Header.Set("X-Session", "abc")
+			req.Header.Set("Keep-Alive", "timeout=30")
+			req.Header.Set("Proxy-Authorization", "Basic Zm9vOmJhcg==")
+			responseWriter := newMockHTTPRespWriter()
+			require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+			assert.Equal(t, http.StatusOK, responseWriter.Code)
+
+			assert.Equal(t, test.expectConnection, received.Values("Connection"))
+			assert.Equal(t, test.expectSession, received.Get("X-Session"))
+			assert.Empty(t, received.Get("Keep-Alive"))
+			assert.Empty(t, received.Get("Proxy-Authorization"))
+			assert.Empty(t, responseWriter.Header().Get("Keep-Alive"))
+			assert.Empty(t, responseWriter.Header().Get("Proxy-Authenticate"))
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package origin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyIdempotencyKey(t *testing.T) {
+	var originRequests int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&originRequests, 1)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, "charge %d", n)
+	}))
+	defer origin.Close()
+
+	header := "Idempotency-Key"
+	window := 5 * time.Minute
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Service: origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					IdempotencyHeader: &header,
+					IdempotencyWindow: &window,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	send := func(key string) *mockHTTPRespWriter {
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/charges", nil)
+		require.NoError(t, err)
+		req.Header.Set(header, key)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		return responseWriter
+	}
+
+	first := send("key-1")
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, "charge 1", first.Body.String())
+	assert.Empty(t, first.Header().Get(ingress.IdempotentReplayedHeader))
+
+	duplicate := send("key-1")
+	assert.Equal(t, http.StatusCreated, duplicate.Code)
+	assert.Equal(t, "charge 1", duplicate.Body.String())
+	assert.Equal(t, "true", duplicate.Header().Get(ingress.IdempotentReplayedHeader))
+
+	other := send("key-2")
+	assert.Equal(t, "charge 2", other.Body.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&originRequests))
+}
+
+func TestProxyIdempotencyCoalesceOnly(t *testing.T) {
+	var originRequests int32
+	release := make(chan struct{})
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&originRequests, 1)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, "charge %d", n)
+	}))
+	defer origin.Close()
+
+	header := "Idempotency-Key"
+	coalesceOnly := true
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Service: origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					IdempotencyHeader:       &header,
+					IdempotencyCoalesceOnly: &coalesceOnly,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	send := func() *mockHTTPRespWriter {
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/charges", nil)
+		require.NoError(t, err)
+		req.Header.Set(header, "key-1")
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		return responseWriter
+	}
+
+	const concurrent = 5
+	responses := make([]*mockHTTPRespWriter, concurrent)
+	var requests sync.WaitGroup
+	for i := range responses {
+		requests.Add(1)
+		go func(i int) {
+			defer requests.Done()
+			responses[i] = send()
+		}(i)
+	}
+	// Let every request reach the origin, or wait for the first one.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	requests.Wait()
+
+	for _, response := range responses {
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.Equal(t, "charge 1", response.Body.String())
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&originRequests))
+
+	// The response isn't kept once every request in flight got it.
+	assert.Equal(t, "charge 2", send().Body.String())
+}
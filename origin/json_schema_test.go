@@ -0,0 +1,53 @@
+package origin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyJSONSchema(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer origin.Close()
+
+	schema := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, ioutil.WriteFile(schema, []byte(`{"type": "object", "required": ["id"], "properties": {"id": {"type": "integer"}}}`), 0600))
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Service: origin.URL, OriginRequest: config.OriginRequestConfig{JSONSchema: &schema}},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		body         string
+		expectStatus int
+		expectBody   string
+	}{
+		{body: `{"id": 42}`, expectStatus: http.StatusOK, expectBody: `{"id": 42}`},
+		{body: `{"id": "42"}`, expectStatus: http.StatusBadRequest, expectBody: "request body doesn't match the JSON schema: $.id should be of type integer"},
+		{body: `{}`, expectStatus: http.StatusBadRequest, expectBody: `request body doesn't match the JSON schema: $ is missing the required property "id"`},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodPost, "http://api.example.com/items", strings.NewReader(test.body))
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, test.expectStatus, responseWriter.Code, test.body)
+		assert.Equal(t, test.expectBody, responseWriter.Body.String(), test.body)
+	}
+}
@@ -0,0 +1,26 @@
+package origin
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ConnectorLoopHeader lists the connectors that already proxied a request. A connector that
+// finds itself in the list is proxying its own request again, e.g. because an ingress rule's
+// service points back at a hostname routed through the same tunnel.
+const ConnectorLoopHeader = "Cf-Cloudflared-Connector"
+
+// detectLoop checks if the connector with the given ID already proxied req, and otherwise adds
+// the ID to its ConnectorLoopHeader. Every connector adds itself, so loops through several
+// replicas of a tunnel are caught once they reach a replica that was already passed.
+func detectLoop(req *http.Request, connectorID string) bool {
+	for _, value := range req.Header.Values(ConnectorLoopHeader) {
+		for _, id := range strings.Split(value, ",") {
+			if strings.TrimSpace(id) == connectorID {
+				return true
+			}
+		}
+	}
+	req.Header.Add(ConnectorLoopHeader, connectorID)
+	return false
+}
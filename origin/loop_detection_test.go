@@ -0,0 +1,62 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyDetectsLoop(t *testing.T) {
+	seenHeaders := make(chan http.Header, 2)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenHeaders <- r.Header.Clone()
+	}))
+	defer origin.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: origin.URL}},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+	replica := newTestProxy(t, ing)
+
+	proxyWithHeader := func(p connection.OriginProxy, header http.Header) int {
+		req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		require.NoError(t, err)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, p.Proxy(responseWriter, req, connection.TypeHTTP))
+		return responseWriter.Code
+	}
+
+	// The origin routes the request back through the tunnel, to this connector.
+	assert.Equal(t, http.StatusOK, proxyWithHeader(proxy, nil))
+	looped := <-seenHeaders
+	require.Len(t, looped.Values(ConnectorLoopHeader), 1)
+	assert.Equal(t, http.StatusLoopDetected, proxyWithHeader(proxy, looped))
+
+	// A replica of the tunnel adds itself, and the loop is caught once it comes back to either.
+	assert.Equal(t, http.StatusOK, proxyWithHeader(replica, looped))
+	loopedTwice := <-seenHeaders
+	assert.Len(t, loopedTwice.Values(ConnectorLoopHeader), 2)
+	assert.Equal(t, http.StatusLoopDetected, proxyWithHeader(proxy, loopedTwice))
+	assert.Equal(t, http.StatusLoopDetected, proxyWithHeader(replica, loopedTwice))
+}
+
+func TestDetectLoopCommaSeparated(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	require.NoError(t, err)
+	req.Header.Set(ConnectorLoopHeader, "a, b")
+	assert.True(t, detectLoop(req, "b"))
+	assert.False(t, detectLoop(req, "c"))
+	assert.Equal(t, []string{"a, b", "c"}, req.Header.Values(ConnectorLoopHeader))
+}
@@ -0,0 +1,105 @@
+package origin
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyMaxWebsockets(t *testing.T) {
+	// The origin keeps every connection open until the eyeball closes it.
+	originListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer originListener.Close()
+	accepted := make(chan struct{}, 10)
+	go func() {
+		for {
+			conn, err := originListener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go func() {
+				_, _ = io.Copy(ioutil.Discard, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	maxWebsockets := 2
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Service: "tcp://" + originListener.Addr().String(),
+				OriginRequest: config.OriginRequestConfig{
+					MaxWebsockets: &maxWebsockets,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	type session struct {
+		eyeball *io.PipeWriter
+		writer  *wsRespWriter
+		done    chan error
+	}
+	open := func() *session {
+		reader, eyeball := io.Pipe()
+		req, err := http.NewRequest(http.MethodGet, "http://example.com", reader)
+		require.NoError(t, err)
+		s := &session{eyeball: eyeball, writer: newWSRespWriter(ioutil.Discard), done: make(chan error, 1)}
+		go func() {
+			s.done <- proxy.Proxy(s.writer, req, connection.TypeWebsocket)
+		}()
+		return s
+	}
+	waitAccepted := func() {
+		select {
+		case <-accepted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("origin didn't receive a connection")
+		}
+	}
+	waitDone := func(s *session) {
+		select {
+		case err := <-s.done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("session didn't finish")
+		}
+	}
+
+	first := open()
+	waitAccepted()
+	second := open()
+	waitAccepted()
+
+	rejected := open()
+	waitDone(rejected)
+	assert.Equal(t, http.StatusServiceUnavailable, rejected.writer.code)
+
+	// Closing a session frees capacity for a new one.
+	first.eyeball.Close()
+	waitDone(first)
+	third := open()
+	waitAccepted()
+
+	second.eyeball.Close()
+	third.eyeball.Close()
+	waitDone(second)
+	waitDone(third)
+	assert.Equal(t, http.StatusSwitchingProtocols, third.writer.code)
+}
@@ -1,9 +1,14 @@
 package origin
 
 import (
+	"net/http"
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
 )
 
 // Metrics uses connection.MetricsNamespace(aka cloudflared) as namespace and connection.TunnelSubsystem
@@ -43,6 +48,61 @@ var (
 			Help:      "Count of error proxying to origin",
 		},
 	)
+	ruleRequestBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: "rule",
+			Name:      "request_bytes_total",
+			Help:      "Count of request body bytes read from the eyeball, by ingress rule",
+		},
+		[]string{"rule", "config"},
+	)
+	ruleResponseBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: "rule",
+			Name:      "response_bytes_total",
+			Help:      "Count of response body bytes written to the eyeball, by ingress rule",
+		},
+		[]string{"rule", "config"},
+	)
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: connection.TunnelSubsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Time to proxy HTTP requests to the origin, by ingress rule. Sampled traces are linked as exemplars",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"rule", "config"},
+	)
+	sloRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: connection.TunnelSubsystem,
+			Name:      "slo_requests",
+			Help:      "Count of requests to ingress rules with sloGoodStatuses, by rule",
+		},
+		[]string{"rule", "config"},
+	)
+	sloGoodRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: connection.TunnelSubsystem,
+			Name:      "slo_good_requests",
+			Help:      "Count of requests to ingress rules with sloGoodStatuses that got a good status, by rule",
+		},
+		[]string{"rule", "config"},
+	)
+	sloGoodRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: connection.TunnelSubsystem,
+			Name:      "slo_good_ratio",
+			Help:      "Ratio of good requests to all the requests of ingress rules with sloGoodStatuses, by rule",
+		},
+		[]string{"rule", "config"},
+	)
 	haConnections = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Namespace: connection.MetricsNamespace,
@@ -59,6 +119,12 @@ func init() {
 		concurrentRequests,
 		responseByCode,
 		requestErrors,
+		ruleRequestBytes,
+		ruleResponseBytes,
+		requestDuration,
+		sloRequests,
+		sloGoodRequests,
+		sloGoodRatio,
 		haConnections,
 	)
 }
@@ -71,3 +137,29 @@ func incrementRequests() {
 func decrementConcurrentRequests() {
 	concurrentRequests.Dec()
 }
+
+// observeRequestDuration records how long req took to proxy for the rule. If req is part of a
+// sampled trace, the observation keeps its trace ID as an exemplar, so a slow bucket leads to a
+// trace that explains it.
+func observeRequestDuration(ruleNum int, config string, req *http.Request, duration time.Duration) {
+	observer := requestDuration.WithLabelValues(strconv.Itoa(ruleNum), config)
+	if traceID := sampledTraceID(req.Header.Get(traceparentHeader)); traceID != "" {
+		if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+			exemplarObserver.ObserveWithExemplar(duration.Seconds(), prometheus.Labels{"trace_id": traceID})
+			return
+		}
+	}
+	observer.Observe(duration.Seconds())
+}
+
+// observeSLO counts the request towards the rule's SLO. Requests that failed before a response
+// was written count with 502, which the eyeball gets instead.
+func observeSLO(ruleNum int, config string, slo *ingress.SLO, w *accessLogResponseWriter) {
+	rule := strconv.Itoa(ruleNum)
+	good, ratio := slo.Record(w.responseStatus())
+	sloRequests.WithLabelValues(rule, config).Inc()
+	if good {
+		sloGoodRequests.WithLabelValues(rule, config).Inc()
+	}
+	sloGoodRatio.WithLabelValues(rule, config).Set(ratio)
+}
@@ -0,0 +1,144 @@
+package origin
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// durationExemplars returns the trace IDs of the exemplars in the rule's duration histogram.
+func durationExemplars(t *testing.T, rule string) []string {
+	var metric dto.Metric
+	require.NoError(t, requestDuration.WithLabelValues(rule, ingress.ConfigCurrent).(prometheus.Metric).Write(&metric))
+	var traceIDs []string
+	for _, bucket := range metric.GetHistogram().GetBucket() {
+		for _, label := range bucket.GetExemplar().GetLabel() {
+			if label.GetName() == "trace_id" {
+				traceIDs = append(traceIDs, label.GetValue())
+			}
+		}
+	}
+	return traceIDs
+}
+
+func TestObserveRequestDurationExemplar(t *testing.T) {
+	tests := []struct {
+		name          string
+		traceparent   string
+		expectTraceID string
+	}{
+		{name: "sampled", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expectTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "not sampled", traceparent: "00-5bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+		{name: "invalid", traceparent: "00-6bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "no trace"},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Rule numbers that no proxy test uses, so every case starts without exemplars.
+			ruleNum := 1000 + i
+			req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+			require.NoError(t, err)
+			if test.traceparent != "" {
+				req.Header.Set(traceparentHeader, test.traceparent)
+			}
+			observeRequestDuration(ruleNum, ingress.ConfigCurrent, req, 30*time.Millisecond)
+
+			var metric dto.Metric
+			require.NoError(t, requestDuration.WithLabelValues(strconv.Itoa(ruleNum), ingress.ConfigCurrent).(prometheus.Metric).Write(&metric))
+			assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+			if test.expectTraceID == "" {
+				assert.Empty(t, durationExemplars(t, strconv.Itoa(ruleNum)))
+			} else {
+				assert.Equal(t, []string{test.expectTraceID}, durationExemplars(t, strconv.Itoa(ruleNum)))
+			}
+		})
+	}
+}
+
+func TestProxyRequestDurationExemplar(t *testing.T) {
+	received := make(chan string, 1)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(traceparentHeader)
+	}))
+	defer origin.Close()
+
+	traceContext := true
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Service: origin.URL, OriginRequest: config.OriginRequestConfig{TraceContext: &traceContext}},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	require.NoError(t, err)
+	require.NoError(t, proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP))
+	traceID := sampledTraceID(<-received)
+	require.NotEmpty(t, traceID)
+	assert.Contains(t, durationExemplars(t, "0"), traceID)
+}
+
+func TestProxySLOMetrics(t *testing.T) {
+	// Nothing listens on this address once the listener is closed.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	down := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	slo := config.OriginRequestConfig{SLOGoodStatuses: []string{"200-399"}}
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "ok.example.com", Service: "http_status:200", OriginRequest: slo},
+			{Hostname: "unavailable.example.com", Service: "http_status:503", OriginRequest: slo},
+			{Hostname: "down.example.com", Service: down, OriginRequest: slo},
+			{Service: "http_status:404"},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	for _, host := range []string{"ok.example.com", "ok.example.com", "ok.example.com", "unavailable.example.com", "down.example.com", "www.example.com"} {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+		require.NoError(t, err)
+		_ = proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP)
+	}
+
+	counter := func(vec *prometheus.CounterVec, rule string) float64 {
+		var metric dto.Metric
+		require.NoError(t, vec.WithLabelValues(rule, ingress.ConfigCurrent).Write(&metric))
+		return metric.GetCounter().GetValue()
+	}
+	ratio := func(rule string) float64 {
+		var metric dto.Metric
+		require.NoError(t, sloGoodRatio.WithLabelValues(rule, ingress.ConfigCurrent).Write(&metric))
+		return metric.GetGauge().GetValue()
+	}
+	assert.Equal(t, float64(3), counter(sloRequests, "0"))
+	assert.Equal(t, float64(3), counter(sloGoodRequests, "0"))
+	assert.Equal(t, float64(1), ratio("0"))
+
+	assert.Equal(t, float64(1), counter(sloRequests, "1"))
+	assert.Equal(t, float64(0), counter(sloGoodRequests, "1"))
+	assert.Equal(t, float64(0), ratio("1"))
+
+	// Origins that can't be reached answer 502, which isn't good.
+	assert.Equal(t, float64(1), counter(sloRequests, "2"))
+	assert.Equal(t, float64(0), counter(sloGoodRequests, "2"))
+
+	// Rules without sloGoodStatuses aren't counted.
+	assert.Equal(t, float64(0), counter(sloRequests, "3"))
+}
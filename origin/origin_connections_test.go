@@ -0,0 +1,58 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyShedsBeyondMaxOriginConnections(t *testing.T) {
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	originA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+	defer originA.Close()
+	originB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer originB.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "a.example.com", Service: originA.URL},
+			{Service: originB.URL},
+		},
+	})
+	require.NoError(t, err)
+	ing.LimitOriginConnections(1)
+	proxy := newTestProxy(t, ing)
+
+	proxyTo := func(host string) int {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		return responseWriter.Code
+	}
+
+	// The connection to a.example.com takes the only slot while its request is in flight.
+	inFlight := make(chan int, 1)
+	go func() {
+		inFlight <- proxyTo("a.example.com")
+	}()
+	<-entered
+	assert.Equal(t, http.StatusServiceUnavailable, proxyTo("b.example.com"))
+	close(release)
+	assert.Equal(t, http.StatusOK, <-inFlight)
+
+	// Once it's idle, it's closed to let b.example.com connect, and the other way around.
+	assert.Equal(t, http.StatusOK, proxyTo("b.example.com"))
+	assert.Equal(t, http.StatusOK, proxyTo("a.example.com"))
+}
@@ -8,10 +8,13 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 
+	"github.com/cloudflare/cloudflared/config"
 	"github.com/cloudflare/cloudflared/connection"
 	"github.com/cloudflare/cloudflared/ingress"
 	tunnelpogs "github.com/cloudflare/cloudflared/tunnelrpc/pogs"
@@ -22,28 +25,45 @@ const (
 	LogFieldCFRay         = "cfRay"
 	LogFieldRule          = "ingressRule"
 	LogFieldOriginService = "originService"
+	LogFieldPathTemplate  = "pathTemplate"
+	LogFieldConfig        = "config"
 )
 
 type proxy struct {
 	ingressRules ingress.Ingress
 	warpRouting  *ingress.WarpRoutingService
 	tags         []tunnelpogs.Tag
-	log          *zerolog.Logger
-	bufferPool   *bufferPool
+	// logRouting logs the rule and service of every request at info level.
+	logRouting bool
+	auditLog   *AuditLog
+	// accessLogFormat is the format of the lines written to accessLogFile, see
+	// ValidateAccessLogFormat.
+	accessLogFormat string
+	// connectorID identifies this connector in ConnectorLoopHeader.
+	connectorID string
+	log         *zerolog.Logger
+	bufferPool  *bufferPool
 }
 
 func NewOriginProxy(
 	ingressRules ingress.Ingress,
 	warpRouting *ingress.WarpRoutingService,
 	tags []tunnelpogs.Tag,
+	logRouting bool,
+	auditLog *AuditLog,
+	accessLogFormat string,
 	log *zerolog.Logger) connection.OriginProxy {
 
 	return &proxy{
-		ingressRules: ingressRules,
-		warpRouting:  warpRouting,
-		tags:         tags,
-		log:          log,
-		bufferPool:   newBufferPool(512 * 1024),
+		ingressRules:    ingressRules,
+		warpRouting:     warpRouting,
+		tags:            tags,
+		logRouting:      logRouting,
+		auditLog:        auditLog,
+		accessLogFormat: accessLogFormat,
+		connectorID:     uuid.New().String(),
+		log:             log,
+		bufferPool:      newBufferPool(512 * 1024),
 	}
 }
 
@@ -70,25 +90,117 @@ func (p *proxy) Proxy(w connection.ResponseWriter, req *http.Request, sourceConn
 			lbProbe: lbProbe,
 			rule:    ingress.ServiceWarpRouting,
 		}
-		if err := p.proxyStreamRequest(serveCtx, w, req, p.warpRouting.Proxy, logFields); err != nil {
+		p.auditLog.record(req, cfRay, ingress.ServiceWarpRouting, "", ingress.ServiceWarpRouting, auditRouted)
+		if err := p.proxyStreamRequest(serveCtx, w, req, p.warpRouting.Proxy, nil, logFields); err != nil {
 			p.logRequestError(err, cfRay, "", ingress.ServiceWarpRouting)
 			return err
 		}
 		return nil
 	}
 
-	rule, ruleNum := p.ingressRules.FindMatchingRule(req.Host, req.URL.Path)
+	if detectLoop(req, p.connectorID) {
+		p.log.Error().Str(LogFieldCFRay, cfRay).Msg("Rejected a request that this connector already proxied. An ingress rule's service probably routes back through the tunnel")
+		p.auditLog.record(req, cfRay, nil, "", "", auditLoopDetected)
+		return w.WriteRespHeaders(http.StatusLoopDetected, http.Header{})
+	}
+
+	// While a configuration is rolled out, it routes a share of the requests.
+	ingressRules := p.ingressRules.ForRequest()
+	if err := ingressRules.NormalizeRequestTarget(req); err != nil {
+		p.log.Debug().Str(LogFieldCFRay, cfRay).Msgf("Rejected a request with the absolute-form target %s", req.RequestURI)
+		p.auditLog.record(req, cfRay, nil, "", "", auditAbsoluteFormRejected)
+		return writeBadRequest(w, err)
+	}
+
+	var (
+		rule    *ingress.Rule
+		ruleNum int
+	)
+	if sourceConnectionType == connection.TypeHTTP {
+		rule, ruleNum = ingressRules.FindMatchingRuleForRequest(req)
+	} else {
+		rule, ruleNum = ingressRules.FindMatchingRuleForStream(req)
+	}
+	defer ingressRules.OpenStream(req, ruleNum)()
 	logFields := logFields{
-		cfRay:   cfRay,
-		lbProbe: lbProbe,
-		rule:    ruleNum,
+		cfRay:        cfRay,
+		lbProbe:      lbProbe,
+		rule:         rule.Index,
+		config:       ingressRules.ConfigName(),
+		pathTemplate: rule.PathTemplate,
 	}
 	p.logRequest(req, logFields)
+	p.logRoute(req, rule, logFields)
+
+	// Before the rule's checks, so that their responses echo the request ID too.
+	if header := rule.Config.RequestIDHeader; header != "" {
+		w = newRequestIDResponseWriter(w, header, ensureRequestID(req, header))
+	}
+
+	if rule.RequireClientCert && !hasClientCert(req) {
+		p.log.Debug().Str(LogFieldCFRay, cfRay).Msgf("Rejected request without a client certificate for ingress rule %d", rule.Index)
+		p.auditLog.record(req, cfRay, rule.Index, logFields.config, rule.Service.String(), auditClientCertMissing)
+		return w.WriteRespHeaders(http.StatusForbidden, http.Header{})
+	}
+	if rule.RequireTLS && !isEyeballTLS(req) {
+		p.log.Debug().Str(LogFieldCFRay, cfRay).Msgf("Rejected plaintext request for ingress rule %d", rule.Index)
+		p.auditLog.record(req, cfRay, rule.Index, logFields.config, rule.Service.String(), auditTLSMissing)
+		return writeUpgradeRequired(w, req)
+	}
+	if !rule.Referer.Allows(req) {
+		p.log.Debug().Str(LogFieldCFRay, cfRay).Msgf("Rejected request referred by %q for ingress rule %d", req.Header.Get("Referer"), rule.Index)
+		p.auditLog.record(req, cfRay, rule.Index, logFields.config, rule.Service.String(), auditRefererNotAllowed)
+		return w.WriteRespHeaders(http.StatusForbidden, http.Header{})
+	}
+	if !isMethodAllowed(req.Method, rule.Config.AllowMethods) {
+		p.log.Debug().Str(LogFieldCFRay, cfRay).Msgf("Rejected %s request, which ingress rule %d doesn't allow", req.Method, rule.Index)
+		p.auditLog.record(req, cfRay, rule.Index, logFields.config, rule.Service.String(), auditMethodNotAllowed)
+		return w.WriteRespHeaders(http.StatusMethodNotAllowed, http.Header{"Allow": {strings.Join(rule.Config.AllowMethods, ", ")}})
+	}
+	if rule.Config.TraceContext {
+		ensureTraceContext(req)
+	}
+	if !rule.Config.ForwardAuthorization {
+		req.Header.Del("Authorization")
+	}
+
+	req.Body, w = countRuleBytes(rule.Index, logFields.config, req.Body, w)
 
 	if sourceConnectionType == connection.TypeHTTP {
-		if err := p.proxyHTTPRequest(w, req, rule, logFields); err != nil {
-			rule, srv := ruleField(p.ingressRules, ruleNum)
+		p.auditLog.record(req, cfRay, rule.Index, logFields.config, rule.Service.String(), auditRouted)
+		start := time.Now()
+		var logged *accessLogResponseWriter
+		if rule.AccessLogs != nil || rule.SLO != nil || rule.DeadLetterLog != nil {
+			logged = newAccessLogResponseWriter(w, req)
+			w = logged
+		}
+		if rule.AccessLogs != nil {
+			defer p.writeAccessLog(rule.AccessLogs, logged, start, logFields)
+		}
+		if rule.SLO != nil {
+			defer observeSLO(rule.Index, logFields.config, rule.SLO, logged)
+		}
+		var paged *errorPageResponseWriter
+		if rule.ErrorPages != nil {
+			paged = newErrorPageResponseWriter(w, req, rule.ErrorPages, cfRay, p.log)
+			w = paged
+		}
+		err := p.proxyHTTPRequest(w, req, rule, logFields)
+		if rule.DeadLetterLog != nil {
+			p.writeDeadLetter(rule.DeadLetterLog, logged, start, logFields, err)
+		}
+		observeRequestDuration(rule.Index, logFields.config, req, time.Since(start))
+		ingressRules.RecordRequest(ruleNum, err)
+		if errors.As(err, &respondedError{}) {
+			requestErrors.Inc()
+			return nil
+		}
+		if err != nil {
+			rule, srv := ruleField(ingressRules, ruleNum)
 			p.logRequestError(err, cfRay, rule, srv)
+			if paged != nil && paged.writeGatewayErrorPage() {
+				return nil
+			}
 			return err
 		}
 		return nil
@@ -100,23 +212,93 @@ func (p *proxy) Proxy(w connection.ResponseWriter, req *http.Request, sourceConn
 		return fmt.Errorf("Not a connection-oriented service")
 	}
 
-	if err := p.proxyStreamRequest(serveCtx, w, req, connectionProxy, logFields); err != nil {
-		rule, srv := ruleField(p.ingressRules, ruleNum)
+	if !rule.Websockets.TryAcquire() {
+		p.log.Warn().Str(LogFieldCFRay, cfRay).Msgf("Rejected websocket for ingress rule %d, which already has maxWebsockets (%d) sessions open", rule.Index, rule.Config.MaxWebsockets)
+		p.auditLog.record(req, cfRay, rule.Index, logFields.config, rule.Service.String(), auditMaxWebsocketsExceeded)
+		return w.WriteRespHeaders(http.StatusServiceUnavailable, http.Header{})
+	}
+	defer rule.Websockets.Release()
+	p.auditLog.record(req, cfRay, rule.Index, logFields.config, rule.Service.String(), auditRouted)
+
+	if rule.Config.RewritePath != "" {
+		rewritePath(req, rule.Config.RewritePath)
+	}
+	normalizeQuery(req.URL, rule.Config.DropQueryParams, rule.Config.SortQueryParams)
+	err := p.proxyStreamRequest(serveCtx, w, req, connectionProxy, rule.Bandwidth, logFields)
+	ingressRules.RecordRequest(ruleNum, err)
+	if errors.Is(err, ingress.ErrTooManyOriginConnections) {
+		p.log.Warn().Str(LogFieldCFRay, cfRay).Msg("Shed the stream, --max-total-origin-connections connections to origins are open")
+		return w.WriteRespHeaders(http.StatusServiceUnavailable, http.Header{})
+	}
+	if err != nil {
+		rule, srv := ruleField(ingressRules, ruleNum)
 		p.logRequestError(err, cfRay, rule, srv)
 		return err
 	}
 	return nil
 }
 
+// rewritePath replaces the path of req, keeping its query.
+func rewritePath(req *http.Request, path string) {
+	req.URL.Path = path
+	req.URL.RawPath = ""
+}
+
 func ruleField(ing ingress.Ingress, ruleNum int) (ruleID string, srv string) {
-	srv = ing.Rules[ruleNum].Service.String()
+	rule := ing.Rules[ruleNum]
+	srv = rule.Service.String()
 	if ing.IsSingleRule() {
 		return "", srv
 	}
-	return fmt.Sprintf("%d", ruleNum), srv
+	return fmt.Sprintf("%d", rule.Index), srv
 }
 
 func (p *proxy) proxyHTTPRequest(w connection.ResponseWriter, req *http.Request, rule *ingress.Rule, fields logFields) error {
+	req, timing := startServerTiming(req, rule.Config.ServerTiming || rule.Config.SlowRequestThreshold > 0)
+	defer p.logSlowRequest(req, timing, rule.Config.SlowRequestThreshold, fields)
+	if rule.Config.StrictRequestFraming {
+		if err := checkRequestFraming(req); err != nil {
+			p.log.Warn().Err(err).Str(LogFieldCFRay, fields.cfRay).Msg("Rejected request that could be used for HTTP request smuggling")
+			return writeBadRequest(w, err)
+		}
+	}
+
+	if isExtendedConnect(req) {
+		if !rule.Config.AllowExtendedConnect {
+			p.log.Debug().Str(LogFieldCFRay, fields.cfRay).Msgf("Rejected extended CONNECT request for protocol %s", req.Header.Get(protocolPseudoHeader))
+			return writeBadRequest(w, errExtendedConnectNotAllowed)
+		}
+		// HTTP/1.1 has no pseudo-headers, and the origin transport rejects them.
+		req.Header.Del(protocolPseudoHeader)
+	}
+
+	if location, ok := redirectHostLocation(req, rule.Config.RedirectHost); ok {
+		return writeHostRedirect(w, location, rule.Config.RedirectHost.Status)
+	}
+	if path := rule.Config.HealthResponsePath; path != "" && req.URL.Path == path && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		return writeWellKnownFile(w, req, healthResponseBody)
+	}
+	if content, ok := rule.WellKnown.File(req.URL.Path); ok && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		return writeWellKnownFile(w, req, content)
+	}
+	if content, ok, err := rule.ACMEChallenges.File(req.URL.Path); ok && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		return writeACMEChallenge(w, req, content, err)
+	}
+
+	if err := rule.JSONSchema.ValidateRequest(req); err != nil {
+		p.log.Debug().Err(err).Str(LogFieldCFRay, fields.cfRay).Msgf("Rejected request whose body doesn't match the JSON schema %s", rule.JSONSchema)
+		if err == ingress.ErrJSONBodyTooLarge {
+			return w.WriteRespHeaders(http.StatusRequestEntityTooLarge, http.Header{})
+		}
+		return writeBadRequest(w, err)
+	}
+
+	if rule.Config.RewritePath != "" {
+		rewritePath(req, rule.Config.RewritePath)
+	}
+	normalizeTrailingSlash(req.URL, rule.Config.TrailingSlash)
+	normalizeQuery(req.URL, rule.Config.DropQueryParams, rule.Config.SortQueryParams)
+
 	// Support for WSGI Servers by switching transfer encoding from chunked to gzip/deflate
 	if rule.Config.DisableChunkedEncoding {
 		req.TransferEncoding = []string{"gzip", "deflate"}
@@ -126,8 +308,22 @@ func (p *proxy) proxyHTTPRequest(w connection.ResponseWriter, req *http.Request,
 		}
 	}
 
-	// Request origin to keep connection alive to improve performance
-	req.Header.Set("Connection", "keep-alive")
+	if rule.Config.ForwardedHeader {
+		addForwardedHeader(req)
+	}
+	removeHopByHopHeaders(req.Header, rule.Config.KeepConnectionHeader)
+	// With keepConnectionHeader and assumeHTTP10, the transport adds close to the eyeball's header.
+	if !rule.Config.KeepConnectionHeader {
+		if rule.Config.AssumeHTTP10 {
+			// The connection isn't reused, so the origin may close it to end the response.
+			req.Header.Set("Connection", "close")
+		} else {
+			// Request origin to keep connection alive to improve performance
+			req.Header.Set("Connection", "keep-alive")
+		}
+	}
+
+	req.Body = rule.Bandwidth.LimitUpstreamBody(req.Body)
 
 	httpService, ok := rule.Service.(ingress.HTTPOriginProxy)
 	if !ok {
@@ -135,30 +331,214 @@ func (p *proxy) proxyHTTPRequest(w connection.ResponseWriter, req *http.Request,
 		return fmt.Errorf("Not a http service")
 	}
 
-	resp, err := httpService.RoundTrip(req)
+	cached, pending := rule.Idempotency.Claim(req)
+	if cached != nil {
+		p.log.Debug().Msgf("CF-RAY: %s Replaying response to a request with the same idempotency key", fields.cfRay)
+		return writeCachedResponse(w, cached)
+	}
+	// Lets a retry reach the origin, unless the response was fully proxied.
+	defer pending.Abandon()
+
+	limited, ok := rule.Concurrency.TryAcquire()
+	if !ok {
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msgf("Shed the request, because the origin already has %d requests in flight", rule.Concurrency.Limit())
+		return writeErrorStatus(w, http.StatusServiceUnavailable, errors.Errorf("Shed the request, because the origin already has %d requests in flight", rule.Concurrency.Limit()))
+	}
+	defer limited.Release()
+
+	synthesizedHead := rule.Config.SynthesizeHead && req.Method == http.MethodHead
+	if synthesizedHead {
+		req.Method = http.MethodGet
+	}
+	req, clientTimer := startClientRequestTimer(req, rule.Config.ClientRequestTimeout)
+	req, responseTimer := rule.ResponseTimeouts.Start(req)
+	req, adaptiveTimer := rule.AdaptiveTimeout.Start(req)
+	req, deadline := startRequestDeadline(req, rule.Config.Deadline)
+	defer deadline.stop()
+	resp, err := roundTripFollowingRedirects(httpService, req, rule.Config.FollowRedirects)
+	limited.Responded()
+	clientTimer.stop()
+	responseTimer.Stop()
+	adaptiveTimer.Stop()
+	if err != nil && clientTimer.isExpired() {
+		p.log.Debug().Str(LogFieldCFRay, fields.cfRay).Msgf("The eyeball didn't send the whole request within %s", rule.Config.ClientRequestTimeout)
+		return w.WriteRespHeaders(http.StatusRequestTimeout, http.Header{})
+	}
+	if err != nil && responseTimer.Expired() {
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msgf("The origin didn't respond to a %s request within %s", req.Method, responseTimer.Timeout())
+		return writeErrorStatus(w, http.StatusGatewayTimeout, errors.Wrapf(err, "The origin didn't respond to a %s request within %s", req.Method, responseTimer.Timeout()))
+	}
+	if err != nil && adaptiveTimer.Expired() {
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msgf("The origin didn't respond within its adaptive timeout of %s", adaptiveTimer.Timeout())
+		return writeErrorStatus(w, http.StatusGatewayTimeout, errors.Wrapf(err, "The origin didn't respond within its adaptive timeout of %s", adaptiveTimer.Timeout()))
+	}
+	if phase, expired := deadline.expiredPhase(); err != nil && expired {
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msgf("The request exceeded its deadline of %s while %s", rule.Config.Deadline, phase)
+		return writeErrorStatus(w, http.StatusGatewayTimeout, errors.Wrapf(err, "The request exceeded its deadline of %s while %s", rule.Config.Deadline, phase))
+	}
+	if errors.Is(err, ingress.ErrTooFewHealthyServices) {
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msg("Fewer of the group's services than loadBalancer.minHealthy are healthy")
+		return writeErrorStatus(w, http.StatusServiceUnavailable, err)
+	}
+	if errors.Is(err, ingress.ErrSelectTimeout) {
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msg("None of the group's services became healthy within loadBalancer.selectTimeout")
+		return writeErrorStatus(w, http.StatusServiceUnavailable, err)
+	}
+	if errors.Is(err, ingress.ErrTooManyOriginConnections) {
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msg("Shed the request, --max-total-origin-connections connections to origins are open")
+		return writeErrorStatus(w, http.StatusServiceUnavailable, err)
+	}
+	if errors.Is(err, ingress.ErrDialQueueTimeout) {
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msgf("No connection to the origin could be started within dialQueueTimeout (%s)", rule.Config.DialQueueTimeout)
+		return writeErrorStatus(w, http.StatusServiceUnavailable, err)
+	}
+	if isResponseHeaderTooLarge(err) {
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msgf("The origin's response headers exceeded maxResponseHeaderBytes (%d)", rule.Config.MaxResponseHeaderBytes)
+		return writeErrorStatus(w, http.StatusBadGateway, err)
+	}
+	if retryAfter, ok := rule.StartupGrace.RetryAfter(); err != nil && ok {
+		p.log.Debug().Err(err).Str(LogFieldCFRay, fields.cfRay).Msg("The origin isn't reachable yet, asking the eyeball to retry")
+		return writeRetryAfter(w, retryAfter, errors.Wrap(err, "The origin isn't reachable yet"))
+	}
 	if err != nil {
 		return errors.Wrap(err, "Unable to reach the origin service. The service may be down or it may not be responding to traffic from cloudflared")
 	}
 	defer resp.Body.Close()
+	if !rule.Config.AllowUnrequestedUpgrade && isUnrequestedUpgrade(req, resp) {
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msgf("The origin switched protocols to %q, which the request didn't ask for", resp.Header.Get("Upgrade"))
+		return writeErrorStatus(w, http.StatusBadGateway, errors.Errorf("The origin switched protocols to %q, which the request didn't ask for", resp.Header.Get("Upgrade")))
+	}
+	deadline.readingBody()
+	rule.StartupGrace.Ready()
+
+	var chunkedBody *chunkedBodyReader
+	if isChunked(resp) && !connection.IsServerSentEvent(resp.Header) {
+		if rule.Config.StrictChunked {
+			if err := bufferChunkedBody(resp); isChunkedFramingError(err) {
+				p.log.Warn().Err(err).Str(LogFieldCFRay, fields.cfRay).Msg("The origin's response has malformed chunked encoding")
+				return writeErrorStatus(w, http.StatusBadGateway, errors.Wrap(err, "The origin's response has malformed chunked encoding"))
+			} else if err != nil {
+				return errors.Wrap(err, "Error reading the origin's chunked response")
+			}
+		} else {
+			chunkedBody = &chunkedBodyReader{ReadCloser: resp.Body}
+			resp.Body = chunkedBody
+		}
+	}
 
+	if err := rule.ResponseRewrite.Rewrite(resp); err != nil {
+		return err
+	}
+	removeHopByHopHeaders(resp.Header, false)
+	if rule.Config.RewriteLocation != (config.LocationRewrite{}) {
+		rewriteLocation(resp, rule.Config.RewriteLocation)
+	}
+	if rule.Config.ReportTo != (config.ReportTo{}) {
+		addReportTo(resp.Header, rule.Config.ReportTo)
+	}
+	if rule.Config.RewriteCookieDomain != (config.CookieDomainRewrite{}) {
+		rewriteCookieDomains(resp.Header, rule.Config.RewriteCookieDomain)
+	}
+	if rule.Config.ForceSecureCookies || rule.Config.CookieSameSite != "" {
+		secureCookies(resp.Header, rule.Config.ForceSecureCookies, rule.Config.CookieSameSite)
+	}
+	if rule.Config.SetCookieSameSite != "" {
+		setCookieSameSite(resp.Header, rule.Config.SetCookieSameSite)
+	}
+
+	if rule.Config.ServerTiming {
+		timing.addHeader(resp.Header)
+	}
 	err = w.WriteRespHeaders(resp.StatusCode, resp.Header)
 	if err != nil {
 		return errors.Wrap(err, "Error writing response header")
 	}
+	if synthesizedHead {
+		// The eyeball asked for the headers only.
+		p.logOriginResponse(resp, fields)
+		return nil
+	}
+	clientWriter := newClientWriteTimeout(w, rule.Config.ClientWriteTimeout, func() {
+		p.log.Debug().Str(LogFieldCFRay, fields.cfRay).Msgf("The eyeball didn't read the response for %s, aborting it", rule.Config.ClientWriteTimeout)
+		// Unblocks reading the rest of the response and releases the origin connection.
+		_ = resp.Body.Close()
+	})
+	eyeballWriter := pending.Record(resp.StatusCode, resp.Header, rule.Bandwidth.LimitDownstream(clientWriter))
+	var copyErr error
 	if connection.IsServerSentEvent(resp.Header) {
 		p.log.Debug().Msg("Detected Server-Side Events from Origin")
-		p.writeEventStream(w, resp.Body)
+		copyErr = p.writeEventStream(eyeballWriter, resp.Body)
 	} else {
 		// Use CopyBuffer, because Copy only allocates a 32KiB buffer, and cross-stream
 		// compression generates dictionary on first write
 		buf := p.bufferPool.Get()
 		defer p.bufferPool.Put(buf)
-		_, _ = io.CopyBuffer(w, resp.Body, buf)
+		if _, copyErr = io.CopyBuffer(eyeballWriter, resp.Body, buf); copyErr == nil {
+			pending.Complete()
+		} else if malformed := chunkedBody.malformed(copyErr); malformed != nil {
+			// The response already started, so it's aborted rather than ended early, which
+			// the eyeball would take for the whole body.
+			p.log.Warn().Err(malformed).Str(LogFieldCFRay, fields.cfRay).Msg("The origin's response has malformed chunked encoding, aborting it. Set strictChunked to answer 502 instead")
+			return errors.Wrap(malformed, "Error reading the origin's chunked response")
+		}
+	}
+	if errors.Is(copyErr, errClientWriteTimeout) {
+		// The eyeball's stream is reset, since the write to it is still blocked.
+		return errors.Wrap(copyErr, "Error writing the response to the eyeball")
+	}
+	if phase, expired := deadline.expiredPhase(); expired {
+		// The response already started, so the eyeball gets a truncated body.
+		p.log.Warn().Str(LogFieldCFRay, fields.cfRay).Msgf("The request exceeded its deadline of %s while %s", rule.Config.Deadline, phase)
 	}
 	p.logOriginResponse(resp, fields)
 	return nil
 }
 
+// respondedError is an error that proxyHTTPRequest already responded to the eyeball with an
+// error status for. It counts against the rule, but isn't returned to the connection.
+type respondedError struct {
+	err error
+}
+
+func (e respondedError) Error() string {
+	return e.err.Error()
+}
+
+func (e respondedError) Unwrap() error {
+	return e.err
+}
+
+// writeErrorStatus responds with status because of err, which the proxy records.
+func writeErrorStatus(w connection.ResponseWriter, status int, err error) error {
+	return writeErrorResponse(w, status, http.Header{}, err)
+}
+
+func writeErrorResponse(w connection.ResponseWriter, status int, header http.Header, err error) error {
+	if writeErr := w.WriteRespHeaders(status, header); writeErr != nil {
+		return writeErr
+	}
+	return respondedError{err: err}
+}
+
+// writeRetryAfter answers with 503 and a Retry-After header, rounded up to whole seconds,
+// because of err.
+func writeRetryAfter(w connection.ResponseWriter, retryAfter time.Duration, err error) error {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	header := http.Header{}
+	header.Set("Retry-After", strconv.Itoa(seconds))
+	return writeErrorResponse(w, http.StatusServiceUnavailable, header, err)
+}
+
+func writeCachedResponse(w connection.ResponseWriter, cached *ingress.CachedResponse) error {
+	header := cached.Header.Clone()
+	header.Set(ingress.IdempotentReplayedHeader, "true")
+	if err := w.WriteRespHeaders(cached.Status, header); err != nil {
+		return errors.Wrap(err, "Error writing response header")
+	}
+	_, _ = w.Write(cached.Body)
+	return nil
+}
+
 // proxyStreamRequest first establish a connection with origin, then it writes the status code and headers, and finally it streams data between
 // eyeball and origin.
 func (p *proxy) proxyStreamRequest(
@@ -166,6 +546,7 @@ func (p *proxy) proxyStreamRequest(
 	w connection.ResponseWriter,
 	req *http.Request,
 	connectionProxy ingress.StreamBasedOriginProxy,
+	bandwidth *ingress.BandwidthLimiter,
 	fields logFields,
 ) error {
 	originConn, resp, err := connectionProxy.EstablishConnection(req)
@@ -190,8 +571,8 @@ func (p *proxy) proxyStreamRequest(
 	}()
 
 	eyeballStream := &bidirectionalStream{
-		writer: w,
-		reader: req.Body,
+		writer: bandwidth.LimitDownstream(w),
+		reader: bandwidth.LimitUpstream(req.Body),
 	}
 	originConn.Stream(serveCtx, eyeballStream, p.log)
 	p.logOriginResponse(resp, fields)
@@ -211,14 +592,18 @@ func (wr *bidirectionalStream) Write(p []byte) (n int, err error) {
 	return wr.writer.Write(p)
 }
 
-func (p *proxy) writeEventStream(w connection.ResponseWriter, respBody io.ReadCloser) {
+// writeEventStream copies the events of respBody to w until either fails, and returns the
+// error of writing to w, if that's what failed.
+func (p *proxy) writeEventStream(w io.Writer, respBody io.ReadCloser) error {
 	reader := bufio.NewReader(respBody)
 	for {
 		line, err := reader.ReadBytes('\n')
 		if err != nil {
-			break
+			return nil
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
 		}
-		_, _ = w.Write(line)
 	}
 }
 
@@ -232,6 +617,39 @@ type logFields struct {
 	cfRay   string
 	lbProbe bool
 	rule    interface{}
+	// config is the configuration of the rule, which tells both apart while one is rolled out.
+	config string
+	// pathTemplate labels the request instead of its exact path, if the rule has one.
+	pathTemplate string
+}
+
+// rolloutConfig logs the configuration of a rule if it's being rolled out. Without it, the rule
+// is from the current configuration.
+type rolloutConfig string
+
+func (c rolloutConfig) MarshalZerologObject(e *zerolog.Event) {
+	if c == ingress.ConfigRollout {
+		e.Str(LogFieldConfig, string(c))
+	}
+}
+
+// logRoute logs one line per request with the rule it matched, if routing logs are enabled.
+func (p *proxy) logRoute(r *http.Request, rule *ingress.Rule, fields logFields) {
+	if !p.logRouting {
+		return
+	}
+	path := r.URL.Path
+	if fields.pathTemplate != "" {
+		path = fields.pathTemplate
+	}
+	p.log.Info().
+		Str(LogFieldCFRay, fields.cfRay).
+		Interface(LogFieldRule, fields.rule).
+		EmbedObject(rolloutConfig(fields.config)).
+		Str(LogFieldOriginService, rule.Service.String()).
+		Str("host", r.Host).
+		Str("path", path).
+		Msg("Routed request")
 }
 
 func (p *proxy) logRequest(r *http.Request, fields logFields) {
@@ -242,13 +660,17 @@ func (p *proxy) logRequest(r *http.Request, fields logFields) {
 	} else {
 		p.log.Debug().Msgf("All requests should have a CF-RAY header. Please open a support ticket with Cloudflare. %s %s %s ", r.Method, r.URL, r.Proto)
 	}
-	p.log.Debug().
+	log := p.log.Debug().
 		Str("CF-RAY", fields.cfRay).
 		Str("Header", fmt.Sprintf("%+v", r.Header)).
 		Str("host", r.Host).
 		Str("path", r.URL.Path).
 		Interface("rule", fields.rule).
-		Msg("Inbound request")
+		EmbedObject(rolloutConfig(fields.config))
+	if fields.pathTemplate != "" {
+		log = log.Str(LogFieldPathTemplate, fields.pathTemplate)
+	}
+	log.Msg("Inbound request")
 
 	if contentLen := r.ContentLength; contentLen == -1 {
 		p.log.Debug().Msgf("CF-RAY: %s Request Content length unknown", fields.cfRay)
@@ -266,6 +688,9 @@ func (p *proxy) logOriginResponse(resp *http.Response, fields logFields) {
 	} else {
 		p.log.Debug().Msgf("Status: %s served by ingress %v", resp.Status, fields.rule)
 	}
+	if fields.pathTemplate != "" {
+		p.log.Debug().Str(LogFieldPathTemplate, fields.pathTemplate).Msgf("CF-RAY: %s Status: %s", fields.cfRay, resp.Status)
+	}
 	p.log.Debug().Msgf("CF-RAY: %s Response Headers %+v", fields.cfRay, resp.Header)
 
 	if contentLen := resp.ContentLength; contentLen == -1 {
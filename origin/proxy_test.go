@@ -56,6 +56,16 @@ func (w *mockHTTPRespWriter) Read(data []byte) (int, error) {
 	return 0, fmt.Errorf("mockHTTPRespWriter doesn't implement io.Reader")
 }
 
+// newTestProxy starts the origins of ing and returns a proxy for its rules. The origins are
+// shut down when the test ends.
+func newTestProxy(t *testing.T, ing ingress.Ingress) connection.OriginProxy {
+	log := zerolog.Nop()
+	shutdownC := make(chan struct{})
+	t.Cleanup(func() { close(shutdownC) })
+	require.NoError(t, ing.StartOrigins(&sync.WaitGroup{}, &log, shutdownC, make(chan error)))
+	return NewOriginProxy(ing, unusedWarpRoutingService, testTags, false, nil, "", &log)
+}
+
 type mockWSRespWriter struct {
 	*mockHTTPRespWriter
 	writeNotification chan []byte
@@ -130,7 +140,7 @@ func TestProxySingleOrigin(t *testing.T) {
 	errC := make(chan error)
 	require.NoError(t, ingressRule.StartOrigins(&wg, &log, ctx.Done(), errC))
 
-	proxy := NewOriginProxy(ingressRule, unusedWarpRoutingService, testTags, &log)
+	proxy := NewOriginProxy(ingressRule, unusedWarpRoutingService, testTags, false, nil, "", &log)
 	t.Run("testProxyHTTP", testProxyHTTP(proxy))
 	t.Run("testProxyWebsocket", testProxyWebsocket(proxy))
 	t.Run("testProxySSE", testProxySSE(proxy))
@@ -286,7 +296,7 @@ func TestProxyMultipleOrigins(t *testing.T) {
 	var wg sync.WaitGroup
 	require.NoError(t, ingress.StartOrigins(&wg, &log, ctx.Done(), errC))
 
-	proxy := NewOriginProxy(ingress, unusedWarpRoutingService, testTags, &log)
+	proxy := NewOriginProxy(ingress, unusedWarpRoutingService, testTags, false, nil, "", &log)
 
 	tests := []struct {
 		url            string
@@ -364,7 +374,7 @@ func TestProxyError(t *testing.T) {
 
 	log := zerolog.Nop()
 
-	proxy := NewOriginProxy(ingress, unusedWarpRoutingService, testTags, &log)
+	proxy := NewOriginProxy(ingress, unusedWarpRoutingService, testTags, false, nil, "", &log)
 
 	responseWriter := newMockHTTPRespWriter()
 	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
@@ -601,7 +611,7 @@ func TestConnections(t *testing.T) {
 			var wg sync.WaitGroup
 			errC := make(chan error)
 			ingressRule.StartOrigins(&wg, logger, ctx.Done(), errC)
-			proxy := NewOriginProxy(ingressRule, test.args.warpRoutingService, testTags, logger)
+			proxy := NewOriginProxy(ingressRule, test.args.warpRoutingService, testTags, false, nil, "", logger)
 
 			req, err := http.NewRequest(
 				http.MethodGet,
@@ -870,3 +880,66 @@ func runEchoWSService(t *testing.T, l net.Listener) {
 		require.NoError(t, err)
 	}()
 }
+
+func TestProxyLogsPathTemplate(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{PathTemplate: "/users/{id}/posts", Service: origin.URL},
+			{Service: "http_status:404"},
+		},
+	})
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+	log := zerolog.New(&logs).Level(zerolog.DebugLevel)
+	var wg sync.WaitGroup
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&wg, &log, shutdownC, make(chan error)))
+	proxy := NewOriginProxy(ing, unusedWarpRoutingService, testTags, false, nil, "", &log)
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/users/42/posts", nil)
+	require.NoError(t, err)
+	responseWriter := newMockHTTPRespWriter()
+	require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+	assert.Equal(t, http.StatusOK, responseWriter.Code)
+	assert.Contains(t, logs.String(), `"pathTemplate":"/users/{id}/posts"`)
+}
+
+func TestProxyLogRouting(t *testing.T) {
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "api.example.com", Service: "http_status:200"},
+			{Hostname: "www.example.com", Service: "http_status:201"},
+			{Service: "http_status:404"},
+		},
+	})
+	require.NoError(t, err)
+
+	for _, logRouting := range []bool{true, false} {
+		var logs bytes.Buffer
+		log := zerolog.New(&logs).Level(zerolog.InfoLevel)
+		var wg sync.WaitGroup
+		shutdownC := make(chan struct{})
+		require.NoError(t, ing.StartOrigins(&wg, &log, shutdownC, make(chan error)))
+		proxy := NewOriginProxy(ing, unusedWarpRoutingService, testTags, logRouting, nil, "", &log)
+
+		req, err := http.NewRequest(http.MethodGet, "http://www.example.com/index.html", nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusCreated, responseWriter.Code)
+		close(shutdownC)
+
+		if logRouting {
+			assert.Contains(t, logs.String(), `"ingressRule":1,"originService":"HTTP 201","host":"www.example.com","path":"/index.html","message":"Routed request"`)
+		} else {
+			assert.NotContains(t, logs.String(), "Routed request")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package origin
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// normalizeQuery removes the query parameters named in drop from u, and sorts the others by
+// name if sortParams is set. Parameters with the same name keep their order, and every
+// parameter keeps its encoding.
+func normalizeQuery(u *url.URL, drop []string, sortParams bool) {
+	if u.RawQuery == "" || (len(drop) == 0 && !sortParams) {
+		return
+	}
+	type param struct {
+		name string
+		raw  string
+	}
+	var params []param
+	for _, raw := range strings.Split(u.RawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		name := raw
+		if i := strings.IndexByte(raw, '='); i >= 0 {
+			name = raw[:i]
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if isDropped(name, drop) {
+			continue
+		}
+		params = append(params, param{name: name, raw: raw})
+	}
+	if sortParams {
+		sort.SliceStable(params, func(i, j int) bool { return params[i].name < params[j].name })
+	}
+	raws := make([]string, len(params))
+	for i, p := range params {
+		raws[i] = p.raw
+	}
+	u.RawQuery = strings.Join(raws, "&")
+}
+
+func isDropped(name string, drop []string) bool {
+	for _, dropped := range drop {
+		if name == dropped {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	drop := []string{"utm_source", "utm_medium"}
+	tests := []struct {
+		rawQuery    string
+		drop        []string
+		sortParams  bool
+		expectQuery string
+	}{
+		{rawQuery: "b=2&utm_source=mail&a=1&utm_medium=email", drop: drop, expectQuery: "b=2&a=1"},
+		{rawQuery: "b=2&utm_source=mail&a=1&utm_medium=email", drop: drop, sortParams: true, expectQuery: "a=1&b=2"},
+		{rawQuery: "b=2&a=3&a=1&c", sortParams: true, expectQuery: "a=3&a=1&b=2&c"},
+		{rawQuery: "q=a+b%20c&utm%5Fsource=x", drop: drop, expectQuery: "q=a+b%20c"},
+		{rawQuery: "utm_source=mail", drop: drop, expectQuery: ""},
+		{rawQuery: "b=2&&a=1", expectQuery: "b=2&&a=1"},
+		{rawQuery: "", drop: drop, sortParams: true, expectQuery: ""},
+	}
+	for _, test := range tests {
+		u := &url.URL{Path: "/", RawQuery: test.rawQuery}
+		normalizeQuery(u, test.drop, test.sortParams)
+		assert.Equal(t, test.expectQuery, u.RawQuery, test.rawQuery)
+	}
+}
+
+func TestProxyNormalizesQuery(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer origin.Close()
+
+	sortParams := true
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname: "sorted.example.com",
+				Service:  origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					SortQueryParams: &sortParams,
+					DropQueryParams: []string{"utm_source", "utm_medium"},
+				},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		url         string
+		expectQuery string
+	}{
+		{url: "http://sorted.example.com/?z=1&utm_source=mail&b=2&utm_medium=email&a=3", expectQuery: "a=3&b=2&z=1"},
+		{url: "http://www.example.com/?z=1&utm_source=mail&a=3", expectQuery: "z=1&utm_source=mail&a=3"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, test.expectQuery, responseWriter.Body.String(), test.url)
+	}
+}
+
+func TestParseDropQueryParams(t *testing.T) {
+	for _, invalid := range [][]string{{""}, {"utm_source", "utm_source"}} {
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress:       []config.UnvalidatedIngressRule{{Service: "http://localhost:8080"}},
+			OriginRequest: config.OriginRequestConfig{DropQueryParams: invalid},
+		})
+		assert.Error(t, err, "%v", invalid)
+	}
+}
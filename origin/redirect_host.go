@@ -0,0 +1,37 @@
+package origin
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+// redirectHostLocation returns where redirect sends req, if req is for its from host. The
+// eyeball's scheme comes from X-Forwarded-Proto, which Cloudflare sets, and defaults to https.
+func redirectHostLocation(req *http.Request, redirect config.HostRedirect) (string, bool) {
+	if redirect.From == "" {
+		return "", false
+	}
+	host := req.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	if !strings.EqualFold(host, redirect.From) {
+		return "", false
+	}
+	scheme := "https"
+	if strings.EqualFold(req.Header.Get("X-Forwarded-Proto"), "http") {
+		scheme = "http"
+	}
+	return scheme + "://" + redirect.To + req.URL.RequestURI(), true
+}
+
+func writeHostRedirect(w connection.ResponseWriter, location string, status int) error {
+	if status == 0 {
+		status = http.StatusPermanentRedirect
+	}
+	return w.WriteRespHeaders(status, http.Header{"Location": []string{location}})
+}
@@ -0,0 +1,83 @@
+package origin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyRedirectHost(t *testing.T) {
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http_status:200"}},
+		OriginRequest: config.OriginRequestConfig{
+			RedirectHost: &config.HostRedirect{From: "example.com", To: "www.example.com"},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		name           string
+		url            string
+		forwardedProto string
+		expectStatus   int
+		expectLocation string
+	}{
+		{
+			name:           "from host",
+			url:            "http://example.com/blog/post?page=2&sort=new",
+			expectStatus:   http.StatusPermanentRedirect,
+			expectLocation: "https://www.example.com/blog/post?page=2&sort=new",
+		},
+		{
+			name:           "from host over http",
+			url:            "http://EXAMPLE.com:80/",
+			forwardedProto: "http",
+			expectStatus:   http.StatusPermanentRedirect,
+			expectLocation: "http://www.example.com/",
+		},
+		{
+			name:         "to host",
+			url:          "http://www.example.com/blog/post",
+			expectStatus: http.StatusOK,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+			if test.forwardedProto != "" {
+				req.Header.Set("X-Forwarded-Proto", test.forwardedProto)
+			}
+			responseWriter := newMockHTTPRespWriter()
+			require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+			assert.Equal(t, test.expectStatus, responseWriter.Code)
+			assert.Equal(t, test.expectLocation, responseWriter.Header().Get("Location"))
+		})
+	}
+}
+
+func TestParseRedirectHostInvalid(t *testing.T) {
+	for _, redirect := range []config.HostRedirect{
+		{From: "example.com"},
+		{From: "example.com", To: "https://www.example.com"},
+		{From: "example.com", To: "EXAMPLE.com"},
+		{From: "example.com", To: "www.example.com", Status: 200},
+	} {
+		redirect := redirect
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{{Service: "http_status:200"}},
+			OriginRequest: config.OriginRequestConfig{
+				RedirectHost: &redirect,
+			},
+		})
+		assert.Error(t, err, "%+v", redirect)
+	}
+}
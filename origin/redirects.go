@@ -0,0 +1,67 @@
+package origin
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// roundTripFollowingRedirects sends the request to the origin and follows up to maxRedirects
+// redirects to the same origin, returning the first response that isn't followed. Redirects to
+// other hosts, and redirects of requests whose body can't be replayed, are returned to the client.
+func roundTripFollowingRedirects(httpService ingress.HTTPOriginProxy, req *http.Request, maxRedirects int) (*http.Response, error) {
+	visited := make(map[string]bool)
+	for redirects := 0; ; redirects++ {
+		resp, err := httpService.RoundTrip(req)
+		// The origin service rewrote the URL to point to it, so redirects are compared to that.
+		visited[req.URL.String()] = true
+		if err != nil || redirects >= maxRedirects {
+			return resp, err
+		}
+		next := redirectRequest(req, resp)
+		if next == nil {
+			return resp, nil
+		}
+		// Drain the body so the connection to the origin can be reused.
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if visited[next.URL.String()] {
+			return nil, fmt.Errorf("redirect loop detected: origin redirected back to %s", next.URL)
+		}
+		req = next
+	}
+}
+
+// redirectRequest returns the request which follows the redirect in resp, or nil if the redirect
+// shouldn't be followed by cloudflared.
+func redirectRequest(req *http.Request, resp *http.Response) *http.Request {
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil
+	}
+	// Only requests without a body can be replayed.
+	if req.ContentLength != 0 {
+		return nil
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return nil
+	}
+	// The request URL already points to the origin, so a location with a different host is
+	// not the origin anymore.
+	if location.Host != req.URL.Host || location.Scheme != req.URL.Scheme {
+		return nil
+	}
+
+	next := req.Clone(req.Context())
+	next.URL = location
+	if resp.StatusCode == http.StatusSeeOther && next.Method != http.MethodHead {
+		next.Method = http.MethodGet
+	}
+	return next
+}
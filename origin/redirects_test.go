@@ -0,0 +1,133 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyFollowRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/start", http.RedirectHandler("/middle", http.StatusFound))
+	mux.Handle("/middle", http.RedirectHandler("/end", http.StatusTemporaryRedirect))
+	mux.HandleFunc("/end", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("end"))
+	})
+	mux.Handle("/loop-a", http.RedirectHandler("/loop-b", http.StatusFound))
+	mux.Handle("/loop-b", http.RedirectHandler("/loop-a", http.StatusFound))
+	mux.Handle("/external", http.RedirectHandler("https://example.com/", http.StatusFound))
+	origin := httptest.NewServer(mux)
+	defer origin.Close()
+
+	tests := []struct {
+		name            string
+		followRedirects int
+		path            string
+		expectedStatus  int
+		expectedBody    string
+		expectErr       bool
+	}{
+		{
+			name:           "Redirects are passed through by default",
+			path:           "/start",
+			expectedStatus: http.StatusFound,
+		},
+		{
+			name:            "Chain within the limit is followed",
+			followRedirects: 3,
+			path:            "/start",
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "end",
+		},
+		{
+			name:            "Chain beyond the limit returns the last redirect",
+			followRedirects: 1,
+			path:            "/start",
+			expectedStatus:  http.StatusTemporaryRedirect,
+		},
+		{
+			name:            "Loop is aborted",
+			followRedirects: 10,
+			path:            "/loop-a",
+			expectErr:       true,
+		},
+		{
+			name:            "Redirect to another host is passed through",
+			followRedirects: 3,
+			path:            "/external",
+			expectedStatus:  http.StatusFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			followRedirects := test.followRedirects
+			ing, err := ingress.ParseIngress(&config.Configuration{
+				Ingress: []config.UnvalidatedIngressRule{
+					{
+						Service: origin.URL,
+						OriginRequest: config.OriginRequestConfig{
+							FollowRedirects: &followRedirects,
+						},
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			proxy := newTestProxy(t, ing)
+
+			req, err := http.NewRequest(http.MethodGet, "http://example.com"+test.path, nil)
+			require.NoError(t, err)
+			responseWriter := newMockHTTPRespWriter()
+			err = proxy.Proxy(responseWriter, req, connection.TypeHTTP)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedStatus, responseWriter.Code)
+			if test.expectedBody != "" {
+				assert.Equal(t, test.expectedBody, responseWriter.Body.String())
+			}
+		})
+	}
+}
+
+func TestProxyDetectsRedirectToItself(t *testing.T) {
+	var requests int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+	}))
+	defer origin.Close()
+
+	followRedirects := 10
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Service: origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					FollowRedirects: &followRedirects,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/self", nil)
+	require.NoError(t, err)
+	err = proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "redirect loop detected")
+	// The redirect goes back to the URL that was just requested from the origin.
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
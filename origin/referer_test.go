@@ -0,0 +1,53 @@
+package origin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyReferer(t *testing.T) {
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname: "images.example.com",
+				Service:  "http_status:200",
+				Referer:  &config.IngressReferer{Allow: []string{"https://site.example.com"}},
+			},
+			{Service: "http_status:200"},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		name         string
+		url          string
+		referer      string
+		expectStatus int
+	}{
+		{name: "allowed referer", url: "http://images.example.com/cat.png", referer: "https://site.example.com/posts/1", expectStatus: http.StatusOK},
+		{name: "other site", url: "http://images.example.com/cat.png", referer: "https://hotlinker.example.net/", expectStatus: http.StatusForbidden},
+		{name: "no referer", url: "http://images.example.com/cat.png", expectStatus: http.StatusForbidden},
+		{name: "rule without referer", url: "http://www.example.com", referer: "https://hotlinker.example.net/", expectStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+			if test.referer != "" {
+				req.Header.Set("Referer", test.referer)
+			}
+			responseWriter := newMockHTTPRespWriter()
+			require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+			assert.Equal(t, test.expectStatus, responseWriter.Code)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package origin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// reportToGroup is the JSON of a Report-To header, see
+// https://www.w3.org/TR/2018/WD-reporting-1-20180925/#header
+type reportToGroup struct {
+	Group     string             `json:"group"`
+	MaxAge    int                `json:"max_age"`
+	Endpoints []reportToEndpoint `json:"endpoints"`
+}
+
+type reportToEndpoint struct {
+	URL string `json:"url"`
+}
+
+// addReportTo adds a Report-To header with the default endpoint group, unless the origin
+// already sent one.
+func addReportTo(header http.Header, reportTo config.ReportTo) {
+	if header.Get("Report-To") != "" {
+		return
+	}
+	group, err := json.Marshal(reportToGroup{
+		Group:     "default",
+		MaxAge:    reportTo.MaxAge,
+		Endpoints: []reportToEndpoint{{URL: reportTo.Endpoint}},
+	})
+	if err != nil {
+		return
+	}
+	header.Set("Report-To", string(group))
+}
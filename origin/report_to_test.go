@@ -0,0 +1,73 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyReportTo(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/own" {
+			w.Header().Set("Report-To", `{"group":"csp","max_age":60,"endpoints":[{"url":"https://csp.example.com"}]}`)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname: "public.example.com",
+				Service:  origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					ReportTo: &config.ReportTo{Endpoint: "https://reports.example.com", MaxAge: 86400},
+				},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		url            string
+		expectReportTo string
+	}{
+		{url: "http://public.example.com/", expectReportTo: `{"group":"default","max_age":86400,"endpoints":[{"url":"https://reports.example.com"}]}`},
+		{url: "http://public.example.com/own", expectReportTo: `{"group":"csp","max_age":60,"endpoints":[{"url":"https://csp.example.com"}]}`},
+		{url: "http://www.example.com/", expectReportTo: ""},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.Equal(t, test.expectReportTo, responseWriter.Header().Get("Report-To"), test.url)
+	}
+}
+
+func TestParseReportTo(t *testing.T) {
+	for _, invalid := range []config.ReportTo{
+		{Endpoint: "https://reports.example.com"},
+		{Endpoint: "https://reports.example.com", MaxAge: -1},
+		{Endpoint: "http://reports.example.com", MaxAge: 86400},
+		{Endpoint: "reports.example.com", MaxAge: 86400},
+		{MaxAge: 86400},
+	} {
+		reportTo := invalid
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress:       []config.UnvalidatedIngressRule{{Service: "http://localhost:8080"}},
+			OriginRequest: config.OriginRequestConfig{ReportTo: &reportTo},
+		})
+		assert.Error(t, err, "%+v", invalid)
+	}
+}
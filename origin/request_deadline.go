@@ -0,0 +1,85 @@
+package origin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// requestPhase is the part of proxying a request to its origin that is in progress.
+type requestPhase int32
+
+const (
+	phaseConnect requestPhase = iota
+	phaseResponse
+	phaseBody
+)
+
+func (p requestPhase) String() string {
+	switch p {
+	case phaseConnect:
+		return "connecting to the origin"
+	case phaseResponse:
+		return "waiting for the origin's response"
+	default:
+		return "reading the origin's response body"
+	}
+}
+
+// requestDeadline cancels a request that doesn't connect, get its response and read the
+// response body within one budget, and remembers the phase that was in progress when it
+// expired. Its methods are safe to call on a nil requestDeadline, which never expires.
+type requestDeadline struct {
+	budget  time.Duration
+	timer   *time.Timer
+	phase   int32
+	expired int32
+}
+
+// startRequestDeadline returns req with a context that is canceled after budget, and that
+// tracks when the request is connected to the origin.
+func startRequestDeadline(req *http.Request, budget time.Duration) (*http.Request, *requestDeadline) {
+	if budget <= 0 {
+		return req, nil
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	d := requestDeadline{budget: budget}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			d.enter(phaseResponse)
+		},
+	}
+	d.timer = time.AfterFunc(budget, func() {
+		atomic.StoreInt32(&d.expired, 1)
+		cancel()
+	})
+	return req.WithContext(httptrace.WithClientTrace(ctx, trace)), &d
+}
+
+func (d *requestDeadline) enter(phase requestPhase) {
+	if d != nil {
+		atomic.StoreInt32(&d.phase, int32(phase))
+	}
+}
+
+// readingBody records that the origin responded, and its body is being read.
+func (d *requestDeadline) readingBody() {
+	d.enter(phaseBody)
+}
+
+// stop keeps the deadline from expiring, e.g. because the whole response was proxied.
+func (d *requestDeadline) stop() {
+	if d != nil {
+		d.timer.Stop()
+	}
+}
+
+// expiredPhase returns the phase that used up the budget, if it's gone.
+func (d *requestDeadline) expiredPhase() (requestPhase, bool) {
+	if d == nil || atomic.LoadInt32(&d.expired) == 0 {
+		return 0, false
+	}
+	return requestPhase(atomic.LoadInt32(&d.phase)), true
+}
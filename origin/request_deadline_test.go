@@ -0,0 +1,100 @@
+package origin
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	deadline := 100 * time.Millisecond
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "unreachable.example.com", Service: origin.URL},
+			{Service: origin.URL},
+		},
+		OriginRequest: config.OriginRequestConfig{
+			Deadline: &deadline,
+		},
+	})
+	require.NoError(t, err)
+	// Connecting to this origin never finishes.
+	ing.Rules[0].DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-release:
+			return nil, net.ErrClosed
+		}
+	}
+	var logs bytes.Buffer
+	log := zerolog.New(&logs)
+	var wg sync.WaitGroup
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&wg, &log, shutdownC, make(chan error)))
+	proxy := NewOriginProxy(ing, unusedWarpRoutingService, testTags, false, nil, "", &log)
+
+	tests := []struct {
+		name         string
+		url          string
+		expectStatus int
+		expectPhase  string
+	}{
+		{name: "slow connect", url: "http://unreachable.example.com", expectStatus: http.StatusGatewayTimeout, expectPhase: phaseConnect.String()},
+		{name: "slow response", url: "http://www.example.com/slow", expectStatus: http.StatusGatewayTimeout, expectPhase: phaseResponse.String()},
+		{name: "fast response", url: "http://www.example.com/fast", expectStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logs.Reset()
+			req, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+			responseWriter := newMockHTTPRespWriter()
+			require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+			assert.Equal(t, test.expectStatus, responseWriter.Code)
+			if test.expectPhase != "" {
+				assert.Contains(t, logs.String(), "exceeded its deadline of 100ms while "+test.expectPhase)
+			} else {
+				assert.NotContains(t, logs.String(), "deadline")
+			}
+		})
+	}
+}
+
+func TestParseDeadline(t *testing.T) {
+	deadline := -time.Second
+	_, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http://localhost:8080"}},
+		OriginRequest: config.OriginRequestConfig{
+			Deadline: &deadline,
+		},
+	})
+	assert.Error(t, err)
+}
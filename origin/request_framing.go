@@ -0,0 +1,48 @@
+package origin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+var errContentLengthAndTransferEncoding = errors.New("request has both Content-Length and Transfer-Encoding headers")
+
+// checkRequestFraming rejects requests whose body length could be read differently by
+// cloudflared and by the origin, which is what HTTP request smuggling exploits. See
+// https://tools.ietf.org/html/rfc7230#section-3.3.3
+func checkRequestFraming(req *http.Request) error {
+	contentLengths := req.Header.Values("Content-Length")
+	if len(contentLengths) > 0 && len(req.Header.Values("Transfer-Encoding")) > 0 {
+		return errContentLengthAndTransferEncoding
+	}
+	contentLength := int64(-1)
+	for _, header := range contentLengths {
+		// A header may hold a comma-separated list, e.g. "Content-Length: 42, 42".
+		for _, value := range strings.Split(header, ",") {
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil || n < 0 {
+				return fmt.Errorf("request has an invalid Content-Length %q", value)
+			}
+			if contentLength >= 0 && n != contentLength {
+				return fmt.Errorf("request has conflicting Content-Lengths %d and %d", contentLength, n)
+			}
+			contentLength = n
+		}
+	}
+	return nil
+}
+
+func writeBadRequest(w connection.ResponseWriter, err error) error {
+	if err := w.WriteRespHeaders(http.StatusBadRequest, http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}}); err != nil {
+		return errors.Wrap(err, "Error writing response header")
+	}
+	_, _ = io.WriteString(w, err.Error())
+	return nil
+}
@@ -0,0 +1,97 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestCheckRequestFraming(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  http.Header
+		wantErr bool
+	}{
+		{name: "No body headers", header: http.Header{}},
+		{name: "Content-Length", header: http.Header{"Content-Length": {"42"}}},
+		{name: "Transfer-Encoding", header: http.Header{"Transfer-Encoding": {"chunked"}}},
+		{name: "Repeated identical Content-Length", header: http.Header{"Content-Length": {"42", "42"}}},
+		{name: "Identical Content-Length list", header: http.Header{"Content-Length": {"42, 42"}}},
+		{
+			name:    "Content-Length and Transfer-Encoding",
+			header:  http.Header{"Content-Length": {"42"}, "Transfer-Encoding": {"chunked"}},
+			wantErr: true,
+		},
+		{name: "Conflicting Content-Length", header: http.Header{"Content-Length": {"42", "7"}}, wantErr: true},
+		{name: "Conflicting Content-Length list", header: http.Header{"Content-Length": {"42, 7"}}, wantErr: true},
+		{name: "Negative Content-Length", header: http.Header{"Content-Length": {"-1"}}, wantErr: true},
+		{name: "Non-numeric Content-Length", header: http.Header{"Content-Length": {"0x2a"}}, wantErr: true},
+	}
+	for _, test := range tests {
+		req := &http.Request{Header: test.header}
+		err := checkRequestFraming(req)
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+		}
+	}
+}
+
+func TestProxyStrictRequestFraming(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("origin"))
+	}))
+	defer origin.Close()
+
+	newProxy := func(t *testing.T, strictRequestFraming *bool) connection.OriginProxy {
+		ing, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{
+					Service: origin.URL,
+					OriginRequest: config.OriginRequestConfig{
+						StrictRequestFraming: strictRequestFraming,
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+		return newTestProxy(t, ing)
+	}
+	smugglingRequest := func(t *testing.T) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader("0\r\n\r\nGET /admin HTTP/1.1\r\n\r\n"))
+		require.NoError(t, err)
+		req.Header.Set("Content-Length", "27")
+		req.Header.Set("Transfer-Encoding", "chunked")
+		return req
+	}
+
+	proxy := newProxy(t, nil)
+
+	responseWriter := newMockHTTPRespWriter()
+	require.NoError(t, proxy.Proxy(responseWriter, smugglingRequest(t), connection.TypeHTTP))
+	assert.Equal(t, http.StatusBadRequest, responseWriter.Code)
+	assert.NotEqual(t, "origin", responseWriter.Body.String())
+
+	req, err := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader("body"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Length", "4")
+	responseWriter = newMockHTTPRespWriter()
+	require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+	assert.Equal(t, http.StatusOK, responseWriter.Code)
+	assert.Equal(t, "origin", responseWriter.Body.String())
+
+	disabled := false
+	lenientProxy := newProxy(t, &disabled)
+	responseWriter = newMockHTTPRespWriter()
+	require.NoError(t, lenientProxy.Proxy(responseWriter, smugglingRequest(t), connection.TypeHTTP))
+	assert.Equal(t, http.StatusOK, responseWriter.Code)
+}
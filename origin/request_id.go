@@ -0,0 +1,40 @@
+package origin
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+// ensureRequestID returns the ID in the header of req, after setting it to a new random one
+// if the eyeball didn't send any.
+func ensureRequestID(req *http.Request, header string) string {
+	if id := req.Header.Get(header); id != "" {
+		return id
+	}
+	id := uuid.New().String()
+	req.Header.Set(header, id)
+	return id
+}
+
+// requestIDResponseWriter echoes the ID of the request in its response, including the ones
+// that cloudflared answers itself.
+type requestIDResponseWriter struct {
+	connection.ResponseWriter
+	header string
+	id     string
+}
+
+func newRequestIDResponseWriter(w connection.ResponseWriter, header, id string) *requestIDResponseWriter {
+	return &requestIDResponseWriter{ResponseWriter: w, header: header, id: id}
+}
+
+func (w *requestIDResponseWriter) WriteRespHeaders(status int, header http.Header) error {
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(w.header, w.id)
+	return w.ResponseWriter.WriteRespHeaders(status, header)
+}
@@ -0,0 +1,96 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyRequestIDHeader(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Origin-Trace-Id", r.Header.Get("X-Trace-Id"))
+	}))
+	defer origin.Close()
+
+	requestIDHeader := "X-Trace-Id"
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname:      "traced.example.com",
+				Service:       origin.URL,
+				OriginRequest: config.OriginRequestConfig{RequestIDHeader: &requestIDHeader},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	proxyRequest := func(host, requestID string) http.Header {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+		require.NoError(t, err)
+		if requestID != "" {
+			req.Header.Set("X-Trace-Id", requestID)
+		}
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		require.Equal(t, http.StatusOK, responseWriter.Code)
+		return responseWriter.Header()
+	}
+
+	// The eyeball's ID is propagated to the origin and echoed in the response.
+	header := proxyRequest("traced.example.com", "trace-1234")
+	assert.Equal(t, "trace-1234", header.Get("Origin-Trace-Id"))
+	assert.Equal(t, "trace-1234", header.Get("X-Trace-Id"))
+
+	// Without one, the origin and the eyeball get the same new ID.
+	header = proxyRequest("traced.example.com", "")
+	generated := header.Get("X-Trace-Id")
+	_, err = uuid.Parse(generated)
+	assert.NoError(t, err)
+	assert.Equal(t, generated, header.Get("Origin-Trace-Id"))
+	assert.NotEqual(t, generated, proxyRequest("traced.example.com", "").Get("X-Trace-Id"))
+
+	// Rules without requestIdHeader leave the header alone.
+	header = proxyRequest("other.example.com", "")
+	assert.Empty(t, header.Get("Origin-Trace-Id"))
+	assert.Empty(t, header.Get("X-Trace-Id"))
+}
+
+func TestProxyRequestIDHeaderOnRejection(t *testing.T) {
+	requestIDHeader := "X-Request-Id"
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http_status:200"}},
+		OriginRequest: config.OriginRequestConfig{
+			RequestIDHeader: &requestIDHeader,
+			AllowMethods:    []string{http.MethodGet},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	req, err := http.NewRequest(http.MethodPost, "http://www.example.com/", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Request-Id", "req-42")
+	responseWriter := newMockHTTPRespWriter()
+	require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+	assert.Equal(t, http.StatusMethodNotAllowed, responseWriter.Code)
+	assert.Equal(t, "req-42", responseWriter.Header().Get("X-Request-Id"))
+}
+
+func TestParseRequestIDHeaderInvalid(t *testing.T) {
+	requestIDHeader := "X Trace Id"
+	_, err := ingress.ParseIngress(&config.Configuration{
+		Ingress:       []config.UnvalidatedIngressRule{{Service: "http_status:200"}},
+		OriginRequest: config.OriginRequestConfig{RequestIDHeader: &requestIDHeader},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package origin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyAbsoluteFormTarget(t *testing.T) {
+	for _, reject := range []bool{false, true} {
+		ing, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{Hostname: "api.example.com", Service: "http_status:204"},
+				{Service: "http_status:404"},
+			},
+			RejectAbsoluteForm: reject,
+		})
+		require.NoError(t, err)
+		proxy := newTestProxy(t, ing)
+
+		req, err := http.NewRequest(http.MethodGet, "http://api.example.com/path", nil)
+		require.NoError(t, err)
+		req.Host = "www.example.com"
+		req.RequestURI = "http://api.example.com/path"
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		if reject {
+			assert.Equal(t, http.StatusBadRequest, responseWriter.Code)
+		} else {
+			assert.Equal(t, http.StatusNoContent, responseWriter.Code)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package origin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+// isEyeballTLS checks if the eyeball connected to Cloudflare over TLS. Only the edge knows: the
+// TLS state of requests is the one of cloudflared's connection to the edge. The scheme comes
+// from Cf-Visitor, like {"scheme":"https"}, or else X-Forwarded-Proto. Requests with neither
+// are taken for plaintext.
+func isEyeballTLS(req *http.Request) bool {
+	if visitor := req.Header.Get("Cf-Visitor"); visitor != "" {
+		var parsed struct {
+			Scheme string `json:"scheme"`
+		}
+		return json.Unmarshal([]byte(visitor), &parsed) == nil && strings.EqualFold(parsed.Scheme, "https")
+	}
+	return strings.EqualFold(req.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// writeUpgradeRequired answers a plaintext request with 426, asking the eyeball to repeat it
+// over TLS at the https URL in Location.
+func writeUpgradeRequired(w connection.ResponseWriter, req *http.Request) error {
+	header := http.Header{}
+	header.Set("Upgrade", "TLS/1.2, HTTP/1.1")
+	header.Set("Location", "https://"+req.Host+req.URL.RequestURI())
+	return w.WriteRespHeaders(http.StatusUpgradeRequired, header)
+}
@@ -0,0 +1,100 @@
+package origin
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyRequireTLS(t *testing.T) {
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "secure.example.com", Service: "http_status:200", RequireTLS: true},
+			{Service: "http_status:200"},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, ing.Rules[0].RequireTLS)
+
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		name           string
+		url            string
+		header         http.Header
+		tls            *tls.ConnectionState
+		expectStatus   int
+		expectLocation string
+	}{
+		{name: "Cf-Visitor https", url: "http://secure.example.com/", header: http.Header{"Cf-Visitor": {`{"scheme":"https"}`}}, expectStatus: http.StatusOK},
+		{name: "X-Forwarded-Proto https", url: "http://secure.example.com/", header: http.Header{"X-Forwarded-Proto": {"https"}}, expectStatus: http.StatusOK},
+		{
+			name:           "Cf-Visitor http",
+			url:            "http://secure.example.com/login?next=%2F",
+			header:         http.Header{"Cf-Visitor": {`{"scheme":"http"}`}, "X-Forwarded-Proto": {"https"}},
+			expectStatus:   http.StatusUpgradeRequired,
+			expectLocation: "https://secure.example.com/login?next=%2F",
+		},
+		{
+			name:           "X-Forwarded-Proto http",
+			url:            "http://secure.example.com/",
+			header:         http.Header{"X-Forwarded-Proto": {"http"}},
+			expectStatus:   http.StatusUpgradeRequired,
+			expectLocation: "https://secure.example.com/",
+		},
+		{
+			name:           "TLS to cloudflared without edge headers",
+			url:            "http://secure.example.com/",
+			tls:            &tls.ConnectionState{},
+			expectStatus:   http.StatusUpgradeRequired,
+			expectLocation: "https://secure.example.com/",
+		},
+		{name: "rule without requireTLS", url: "http://www.example.com/", expectStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+			for name, values := range test.header {
+				req.Header[name] = values
+			}
+			req.TLS = test.tls
+			responseWriter := newMockHTTPRespWriter()
+			require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+			assert.Equal(t, test.expectStatus, responseWriter.Code)
+			assert.Equal(t, test.expectLocation, responseWriter.Header().Get("Location"))
+			if test.expectStatus == http.StatusUpgradeRequired {
+				assert.Equal(t, "TLS/1.2, HTTP/1.1", responseWriter.Header().Get("Upgrade"))
+			}
+		})
+	}
+}
+
+func TestProxyRequireTLSOverHTTP2(t *testing.T) {
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "secure.example.com", Service: "http_status:200", RequireTLS: true},
+			{Service: "http_status:200"},
+		},
+	})
+	require.NoError(t, err)
+	edge := newTLSEdge(t, newTestProxy(t, ing))
+
+	// The connection to the edge is TLS either way, the eyeball's scheme is what counts.
+	for scheme, expectStatus := range map[string]int{"http": http.StatusUpgradeRequired, "https": http.StatusOK} {
+		req, err := http.NewRequest(http.MethodGet, "https://secure.example.com/", nil)
+		require.NoError(t, err)
+		req.Header.Set("Cf-Visitor", `{"scheme":"`+scheme+`"}`)
+		resp, err := edge.RoundTrip(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, expectStatus, resp.StatusCode, scheme)
+	}
+}
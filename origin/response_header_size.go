@@ -0,0 +1,9 @@
+package origin
+
+import "strings"
+
+// isResponseHeaderTooLarge returns whether err is from an origin whose response headers
+// exceeded maxResponseHeaderBytes. net/http doesn't export this error, only its message.
+func isResponseHeaderTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "server response headers exceeded")
+}
@@ -0,0 +1,61 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyMaxResponseHeaderBytes(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oversized" {
+			w.Header().Set("X-Padding", strings.Repeat("a", 8192))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	var maxResponseHeaderBytes int64 = 4096
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: origin.URL}},
+		OriginRequest: config.OriginRequestConfig{
+			MaxResponseHeaderBytes: &maxResponseHeaderBytes,
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		path         string
+		expectStatus int
+	}{
+		{path: "/oversized", expectStatus: http.StatusBadGateway},
+		{path: "/normal", expectStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com"+test.path, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, test.expectStatus, responseWriter.Code, test.path)
+	}
+}
+
+func TestParseMaxResponseHeaderBytes(t *testing.T) {
+	var maxResponseHeaderBytes int64 = -1
+	_, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http://localhost:8080"}},
+		OriginRequest: config.OriginRequestConfig{
+			MaxResponseHeaderBytes: &maxResponseHeaderBytes,
+		},
+	})
+	assert.Error(t, err)
+}
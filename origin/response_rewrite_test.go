@@ -0,0 +1,57 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyResponseRewrite(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<a href="http://internal/next">next</a>`))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write([]byte("http://internal"))
+	})
+	origin := httptest.NewServer(mux)
+	defer origin.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Service: origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					ResponseRewrite: []config.ResponseRewriteRule{{Match: "http://internal", Replace: "https://public.example.com"}},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	get := func(path string) *mockHTTPRespWriter {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		return responseWriter
+	}
+
+	page := get("/page")
+	expected := `<a href="https://public.example.com/next">next</a>`
+	assert.Equal(t, expected, page.Body.String())
+	assert.Equal(t, strconv.Itoa(len(expected)), page.Header().Get("Content-Length"))
+	assert.Equal(t, "http://internal", get("/image").Body.String())
+}
@@ -0,0 +1,75 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyResponseTimeoutByMethod(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer origin.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: origin.URL}},
+		OriginRequest: config.OriginRequestConfig{
+			ResponseTimeoutByMethod: map[string]time.Duration{
+				http.MethodGet:  10 * time.Millisecond,
+				http.MethodPost: time.Minute,
+			},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	for method, expectStatus := range map[string]int{
+		http.MethodGet:  http.StatusGatewayTimeout,
+		http.MethodPost: http.StatusOK,
+	} {
+		req, err := http.NewRequest(method, "http://www.example.com", nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, expectStatus, responseWriter.Code, method)
+	}
+}
+
+func TestProxyRecordsResponseTimeoutsAsErrors(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer origin.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: origin.URL}},
+		OriginRequest: config.OriginRequestConfig{
+			ResponseTimeoutByMethod: map[string]time.Duration{http.MethodGet: 10 * time.Millisecond},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	require.NoError(t, err)
+	responseWriter := newMockHTTPRespWriter()
+	require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+	assert.Equal(t, http.StatusGatewayTimeout, responseWriter.Code)
+
+	status := ing.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, float64(0), status[0].SuccessRate)
+	assert.Contains(t, status[0].LastError, "The origin didn't respond to a GET request within 10ms")
+}
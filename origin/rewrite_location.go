@@ -0,0 +1,38 @@
+package origin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// rewriteLocation replaces the prefix rewrite.From of the Location header of a redirect with
+// rewrite.To. The scheme and host are compared case-insensitively, the path exactly, and the
+// prefix must end at a path segment. Other Locations pass through unchanged.
+func rewriteLocation(resp *http.Response, rewrite config.LocationRewrite) {
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return
+	}
+	location := resp.Header.Get("Location")
+	from := strings.TrimSuffix(rewrite.From, "/")
+	if len(location) < len(from) {
+		return
+	}
+	// The scheme and host end at the first slash after "://".
+	schemeHostLen := len(from)
+	if i := strings.Index(from, "://"); i >= 0 {
+		if j := strings.Index(from[i+3:], "/"); j >= 0 {
+			schemeHostLen = i + 3 + j
+		}
+	}
+	if !strings.EqualFold(location[:schemeHostLen], from[:schemeHostLen]) || location[schemeHostLen:len(from)] != from[schemeHostLen:] {
+		return
+	}
+	rest := location[len(from):]
+	if rest != "" && !strings.ContainsAny(rest[:1], "/?#") {
+		// e.g. http://internal:8080/app shouldn't match http://internal:8080/application.
+		return
+	}
+	resp.Header.Set("Location", strings.TrimSuffix(rewrite.To, "/")+rest)
+}
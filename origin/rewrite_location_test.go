@@ -0,0 +1,102 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestRewriteLocation(t *testing.T) {
+	rewrite := config.LocationRewrite{From: "http://internal:8080", To: "https://public.example.com"}
+	tests := []struct {
+		status         int
+		location       string
+		expectLocation string
+	}{
+		{status: http.StatusFound, location: "http://internal:8080/login?next=%2F", expectLocation: "https://public.example.com/login?next=%2F"},
+		{status: http.StatusMovedPermanently, location: "http://internal:8080", expectLocation: "https://public.example.com"},
+		{status: http.StatusFound, location: "HTTP://Internal:8080/a", expectLocation: "https://public.example.com/a"},
+		{status: http.StatusFound, location: "http://internal:80801/a", expectLocation: "http://internal:80801/a"},
+		{status: http.StatusFound, location: "http://other:8080/a", expectLocation: "http://other:8080/a"},
+		{status: http.StatusFound, location: "/relative", expectLocation: "/relative"},
+		{status: http.StatusCreated, location: "http://internal:8080/items/1", expectLocation: "http://internal:8080/items/1"},
+		{status: http.StatusFound, location: "", expectLocation: ""},
+	}
+	for _, test := range tests {
+		resp := &http.Response{StatusCode: test.status, Header: http.Header{}}
+		if test.location != "" {
+			resp.Header.Set("Location", test.location)
+		}
+		rewriteLocation(resp, rewrite)
+		assert.Equal(t, test.expectLocation, resp.Header.Get("Location"), test.location)
+	}
+
+	withPath := config.LocationRewrite{From: "http://internal:8080/app/", To: "https://public.example.com/"}
+	resp := &http.Response{StatusCode: http.StatusFound, Header: http.Header{"Location": {"http://internal:8080/app/home"}}}
+	rewriteLocation(resp, withPath)
+	assert.Equal(t, "https://public.example.com/home", resp.Header.Get("Location"))
+	resp.Header.Set("Location", "http://internal:8080/application")
+	rewriteLocation(resp, withPath)
+	assert.Equal(t, "http://internal:8080/application", resp.Header.Get("Location"))
+}
+
+func TestProxyRewriteLocation(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://internal:8080"+r.URL.Path+"/", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname: "public.example.com",
+				Service:  origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					RewriteLocation: &config.LocationRewrite{From: "http://internal:8080", To: "https://public.example.com"},
+				},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		url            string
+		expectLocation string
+	}{
+		{url: "http://public.example.com/docs", expectLocation: "https://public.example.com/docs/"},
+		{url: "http://www.example.com/docs", expectLocation: "http://internal:8080/docs/"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusFound, responseWriter.Code)
+		assert.Equal(t, test.expectLocation, responseWriter.Header().Get("Location"), test.url)
+	}
+}
+
+func TestParseRewriteLocation(t *testing.T) {
+	for _, invalid := range []config.LocationRewrite{
+		{From: "internal:8080", To: "https://public.example.com"},
+		{From: "http://internal:8080"},
+		{From: "http://internal:8080", To: "https://public.example.com/?a=b"},
+		{From: "ftp://internal", To: "https://public.example.com"},
+	} {
+		rewrite := invalid
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress:       []config.UnvalidatedIngressRule{{Service: "http://localhost:8080"}},
+			OriginRequest: config.OriginRequestConfig{RewriteLocation: &rewrite},
+		})
+		assert.Error(t, err, "%+v", invalid)
+	}
+}
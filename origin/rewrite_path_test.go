@@ -0,0 +1,66 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyRewritePath(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.RequestURI()))
+	}))
+	defer origin.Close()
+
+	rewritePath := "/fixed/endpoint"
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname:      "api.example.com",
+				Service:       origin.URL,
+				OriginRequest: config.OriginRequestConfig{RewritePath: &rewritePath},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		url        string
+		expectPath string
+	}{
+		{url: "http://api.example.com/anything?x=1", expectPath: "/fixed/endpoint?x=1"},
+		{url: "http://api.example.com/a%2Fb", expectPath: "/fixed/endpoint"},
+		{url: "http://www.example.com/anything?x=1", expectPath: "/anything?x=1"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, test.expectPath, responseWriter.Body.String(), test.url)
+	}
+}
+
+func TestParseRewritePathInvalid(t *testing.T) {
+	for _, rewritePath := range []string{"fixed/endpoint", "/fixed?x=1", "/fixed#top"} {
+		rewritePath := rewritePath
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{
+					Service:       "http_status:404",
+					OriginRequest: config.OriginRequestConfig{RewritePath: &rewritePath},
+				},
+			},
+		})
+		assert.Error(t, err, rewritePath)
+	}
+}
@@ -0,0 +1,39 @@
+package origin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyRollOut(t *testing.T) {
+	current, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http_status:200"}},
+	})
+	require.NoError(t, err)
+	next, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http_status:202"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, current.RollOut(next, 25, 7))
+
+	proxy := newTestProxy(t, current)
+
+	const requests = 4000
+	statuses := map[int]int{}
+	for i := 0; i < requests; i++ {
+		req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		statuses[responseWriter.Code]++
+	}
+	assert.Equal(t, requests, statuses[http.StatusOK]+statuses[http.StatusAccepted])
+	assert.InDelta(t, 25, float64(statuses[http.StatusAccepted])*100/requests, 3)
+}
@@ -0,0 +1,43 @@
+package origin
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+// countRuleBytes counts the bytes of the request body that are read, and of the response body
+// that are written, towards the rule's byte counters.
+func countRuleBytes(ruleNum int, config string, body io.ReadCloser, w connection.ResponseWriter) (io.ReadCloser, connection.ResponseWriter) {
+	rule := strconv.Itoa(ruleNum)
+	if body != nil && body != http.NoBody {
+		body = &countingReader{ReadCloser: body, counter: ruleRequestBytes.WithLabelValues(rule, config)}
+	}
+	return body, &countingResponseWriter{ResponseWriter: w, counter: ruleResponseBytes.WithLabelValues(rule, config)}
+}
+
+type countingReader struct {
+	io.ReadCloser
+	counter prometheus.Counter
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.counter.Add(float64(n))
+	return n, err
+}
+
+type countingResponseWriter struct {
+	connection.ResponseWriter
+	counter prometheus.Counter
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.counter.Add(float64(n))
+	return n, err
+}
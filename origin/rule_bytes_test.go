@@ -0,0 +1,61 @@
+package origin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	var metric dto.Metric
+	require.NoError(t, counter.Write(&metric))
+	return metric.GetCounter().GetValue()
+}
+
+func TestProxyRuleBytes(t *testing.T) {
+	const responseBody = "a response of 24 bytes.\n"
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		_, _ = w.Write([]byte(responseBody))
+	}))
+	defer origin.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "counted.example.com", Service: origin.URL},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	requestBytes0 := counterValue(t, ruleRequestBytes.WithLabelValues("0", ingress.ConfigCurrent))
+	responseBytes0 := counterValue(t, ruleResponseBytes.WithLabelValues("0", ingress.ConfigCurrent))
+	requestBytes1 := counterValue(t, ruleRequestBytes.WithLabelValues("1", ingress.ConfigCurrent))
+	responseBytes1 := counterValue(t, ruleResponseBytes.WithLabelValues("1", ingress.ConfigCurrent))
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodPost, "http://counted.example.com/upload", strings.NewReader("a request of 27 bytes body."))
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		require.Equal(t, http.StatusOK, responseWriter.Code)
+		require.Equal(t, responseBody, responseWriter.Body.String())
+	}
+
+	assert.Equal(t, requestBytes0+2*27, counterValue(t, ruleRequestBytes.WithLabelValues("0", ingress.ConfigCurrent)))
+	assert.Equal(t, responseBytes0+2*24, counterValue(t, ruleResponseBytes.WithLabelValues("0", ingress.ConfigCurrent)))
+	assert.Equal(t, requestBytes1, counterValue(t, ruleRequestBytes.WithLabelValues("1", ingress.ConfigCurrent)))
+	assert.Equal(t, responseBytes1, counterValue(t, ruleResponseBytes.WithLabelValues("1", ingress.ConfigCurrent)))
+}
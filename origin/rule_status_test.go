@@ -0,0 +1,54 @@
+package origin
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyRecordsRuleStatus(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	// Nothing listens on this address once the listener is closed.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	down := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "healthy.example.com", Service: healthy.URL},
+			{Service: down},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	for _, host := range []string{"healthy.example.com", "down.example.com"} {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+		require.NoError(t, err)
+		_ = proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP)
+	}
+
+	status := ing.Status()
+	require.Len(t, status, 2)
+	assert.Equal(t, 1, status[0].Requests)
+	assert.Equal(t, float64(1), status[0].SuccessRate)
+	assert.Empty(t, status[0].LastError)
+
+	assert.Equal(t, 1, status[1].Requests)
+	assert.Equal(t, float64(0), status[1].SuccessRate)
+	assert.Contains(t, status[1].LastError, "Unable to reach the origin service")
+	assert.NotNil(t, status[1].LastErrorAt)
+}
@@ -0,0 +1,67 @@
+package origin
+
+import (
+	"net/http"
+	"strings"
+)
+
+// secureCookies adds the Secure attribute if addSecure is set, and the SameSite attribute if
+// sameSite is set, to the Set-Cookie headers in header that don't have them yet.
+func secureCookies(header http.Header, addSecure bool, sameSite string) {
+	cookies := header["Set-Cookie"]
+	for i, cookie := range cookies {
+		hasSecure, hasSameSite := false, false
+		// The first part is the cookie's name and value.
+		for _, attribute := range strings.Split(cookie, ";")[1:] {
+			switch cookieAttributeName(attribute) {
+			case "secure":
+				hasSecure = true
+			case "samesite":
+				hasSameSite = true
+			}
+		}
+		if addSecure && !hasSecure {
+			cookie += "; Secure"
+		}
+		if sameSite != "" && !hasSameSite {
+			cookie += "; SameSite=" + sameSite
+		}
+		cookies[i] = cookie
+	}
+}
+
+// setCookieSameSite sets the SameSite attribute of the Set-Cookie headers in header to
+// sameSite, replacing the one they have. Cookies with SameSite=None also get the Secure
+// attribute, because browsers reject them without it.
+func setCookieSameSite(header http.Header, sameSite string) {
+	cookies := header["Set-Cookie"]
+	for i, cookie := range cookies {
+		parts := strings.Split(cookie, ";")
+		// The first part is the cookie's name and value.
+		kept := []string{parts[0]}
+		hasSecure := false
+		for _, attribute := range parts[1:] {
+			switch cookieAttributeName(attribute) {
+			case "samesite":
+				continue
+			case "secure":
+				hasSecure = true
+			}
+			kept = append(kept, attribute)
+		}
+		cookie = strings.Join(kept, ";")
+		if sameSite == "None" && !hasSecure {
+			cookie += "; Secure"
+		}
+		cookies[i] = cookie + "; SameSite=" + sameSite
+	}
+}
+
+// cookieAttributeName returns the lower case name of a Set-Cookie attribute like "Path=/".
+func cookieAttributeName(attribute string) string {
+	name := attribute
+	if eq := strings.Index(attribute, "="); eq >= 0 {
+		name = attribute[:eq]
+	}
+	return strings.ToLower(strings.TrimSpace(name))
+}
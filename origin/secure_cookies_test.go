@@ -0,0 +1,167 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestSecureCookies(t *testing.T) {
+	tests := []struct {
+		name      string
+		addSecure bool
+		sameSite  string
+		cookie    string
+		expected  string
+	}{
+		{name: "adds Secure", addSecure: true, cookie: "session=abc; Path=/", expected: "session=abc; Path=/; Secure"},
+		{name: "keeps Secure", addSecure: true, cookie: "session=abc; secure", expected: "session=abc; secure"},
+		{name: "adds both", addSecure: true, sameSite: "Lax", cookie: "session=abc", expected: "session=abc; Secure; SameSite=Lax"},
+		{name: "keeps SameSite", addSecure: true, sameSite: "Lax", cookie: "session=abc; SameSite=Strict", expected: "session=abc; SameSite=Strict; Secure"},
+		{name: "SameSite only", sameSite: "Strict", cookie: "session=abc", expected: "session=abc; SameSite=Strict"},
+		{name: "value isn't an attribute", addSecure: true, cookie: "secure=true", expected: "secure=true; Secure"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{"Set-Cookie": {test.cookie}}
+			secureCookies(header, test.addSecure, test.sameSite)
+			assert.Equal(t, []string{test.expected}, header["Set-Cookie"])
+		})
+	}
+}
+
+func TestProxyForceSecureCookies(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+	}))
+	defer origin.Close()
+
+	forceSecureCookies, sameSite := true, "Lax"
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname: "secure.example.com",
+				Service:  origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					ForceSecureCookies: &forceSecureCookies,
+					CookieSameSite:     &sameSite,
+				},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	for host, expectCookie := range map[string]string{
+		"secure.example.com": "session=abc; Path=/; Secure; SameSite=Lax",
+		"www.example.com":    "session=abc; Path=/",
+	} {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, []string{expectCookie}, responseWriter.Header()["Set-Cookie"], host)
+	}
+}
+
+func TestParseCookieSameSite(t *testing.T) {
+	parse := func(forceSecureCookies bool, sameSite string) error {
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{
+					Service: "http_status:404",
+					OriginRequest: config.OriginRequestConfig{
+						ForceSecureCookies: &forceSecureCookies,
+						CookieSameSite:     &sameSite,
+					},
+				},
+			},
+		})
+		return err
+	}
+	assert.NoError(t, parse(true, "None"))
+	assert.NoError(t, parse(false, "Strict"))
+	assert.Error(t, parse(false, "None"))
+	assert.Error(t, parse(true, "lax"))
+}
+
+func TestSetCookieSameSite(t *testing.T) {
+	tests := []struct {
+		name     string
+		sameSite string
+		cookie   string
+		expected string
+	}{
+		{name: "adds SameSite", sameSite: "Lax", cookie: "session=abc; Path=/", expected: "session=abc; Path=/; SameSite=Lax"},
+		{name: "replaces SameSite", sameSite: "Strict", cookie: "session=abc; samesite=none; HttpOnly", expected: "session=abc; HttpOnly; SameSite=Strict"},
+		{name: "None adds Secure", sameSite: "None", cookie: "session=abc; SameSite=Lax", expected: "session=abc; Secure; SameSite=None"},
+		{name: "None keeps Secure", sameSite: "None", cookie: "session=abc; Secure", expected: "session=abc; Secure; SameSite=None"},
+		{name: "value isn't an attribute", sameSite: "Lax", cookie: "samesite=yes", expected: "samesite=yes; SameSite=Lax"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{"Set-Cookie": {test.cookie}}
+			setCookieSameSite(header, test.sameSite)
+			assert.Equal(t, []string{test.expected}, header["Set-Cookie"])
+		})
+	}
+}
+
+func TestProxySetCookieSameSite(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", SameSite: http.SameSiteStrictMode})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+	}))
+	defer origin.Close()
+
+	lax, none := "Lax", "None"
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "lax.example.com", Service: origin.URL, OriginRequest: config.OriginRequestConfig{SetCookieSameSite: &lax}},
+			{Service: origin.URL, OriginRequest: config.OriginRequestConfig{SetCookieSameSite: &none}},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	for host, expectCookies := range map[string][]string{
+		"lax.example.com": {"session=abc; Path=/; SameSite=Lax", "theme=dark; SameSite=Lax"},
+		"www.example.com": {"session=abc; Path=/; Secure; SameSite=None", "theme=dark; Secure; SameSite=None"},
+	} {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, expectCookies, responseWriter.Header()["Set-Cookie"], host)
+	}
+}
+
+func TestParseSetCookieSameSite(t *testing.T) {
+	parse := func(setSameSite, sameSite string) error {
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{
+					Service: "http_status:404",
+					OriginRequest: config.OriginRequestConfig{
+						SetCookieSameSite: &setSameSite,
+						CookieSameSite:    &sameSite,
+					},
+				},
+			},
+		})
+		return err
+	}
+	assert.NoError(t, parse("None", ""))
+	assert.NoError(t, parse("Strict", ""))
+	assert.Error(t, parse("lax", ""))
+	// cookieSameSite would have no effect.
+	assert.Error(t, parse("Lax", "Strict"))
+}
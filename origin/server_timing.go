@@ -0,0 +1,74 @@
+package origin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const serverTimingHeader = "Server-Timing"
+
+// serverTiming measures how long the phases of proxying a request took, for a Server-Timing
+// header. Its methods are safe to call on a nil serverTiming, which measures nothing.
+type serverTiming struct {
+	start time.Time
+
+	lock         sync.Mutex
+	connectStart time.Time
+	dial         time.Duration
+	ttfb         time.Duration
+}
+
+// startServerTiming returns req with a context that tracks when the request connects to the
+// origin and gets its response.
+func startServerTiming(req *http.Request, enabled bool) (*http.Request, *serverTiming) {
+	if !enabled {
+		return req, nil
+	}
+	t := serverTiming{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(string, string) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(string, string, error) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.dial = time.Now().Sub(t.connectStart)
+		},
+		GotFirstResponseByte: func() {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.ttfb = time.Now().Sub(t.start)
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), &t
+}
+
+// addHeader adds the measured phases to the response header, after those of the origin. A
+// request sent over a connection that was already open has no dial time.
+func (t *serverTiming) addHeader(header http.Header) {
+	if t == nil {
+		return
+	}
+	dial, ttfb, total := t.phases()
+	header.Add(serverTimingHeader, fmt.Sprintf("dial;dur=%s, ttfb;dur=%s, total;dur=%s", milliseconds(dial), milliseconds(ttfb), milliseconds(total)))
+}
+
+// phases returns how long dialing the origin and getting its first response byte took, and how
+// long the request has taken so far.
+func (t *serverTiming) phases() (dial, ttfb, total time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.dial, t.ttfb, time.Now().Sub(t.start)
+}
+
+// milliseconds formats d like Server-Timing durations.
+func milliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,64 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+var serverTimingFormat = regexp.MustCompile(`^dial;dur=(\d+\.\d{3}), ttfb;dur=(\d+\.\d{3}), total;dur=(\d+\.\d{3})$`)
+
+func TestProxyServerTiming(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(serverTimingHeader, "db;dur=1")
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	serverTiming := true
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "timed.example.com", Service: origin.URL, OriginRequest: config.OriginRequestConfig{ServerTiming: &serverTiming}},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	send := func(url string) []string {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		return responseWriter.Header().Values(serverTimingHeader)
+	}
+
+	timings := send("http://timed.example.com")
+	require.Len(t, timings, 2)
+	assert.Equal(t, "db;dur=1", timings[0])
+	metrics := serverTimingFormat.FindStringSubmatch(timings[1])
+	require.NotNil(t, metrics, timings[1])
+	durations := make([]float64, 3)
+	for i := range durations {
+		durations[i], err = strconv.ParseFloat(metrics[i+1], 64)
+		require.NoError(t, err)
+	}
+	dial, ttfb, total := durations[0], durations[1], durations[2]
+	assert.GreaterOrEqual(t, ttfb, 10.0, "the origin took at least 10ms to respond")
+	assert.LessOrEqual(t, dial, ttfb)
+	assert.LessOrEqual(t, ttfb, total)
+
+	assert.Equal(t, []string{"db;dur=1"}, send("http://www.example.com"))
+}
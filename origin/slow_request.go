@@ -0,0 +1,28 @@
+package origin
+
+import (
+	"net/http"
+	"time"
+)
+
+// logSlowRequest warns about a request that took longer than the rule's slowRequestThreshold,
+// whatever the log level of other requests, with where the time went.
+func (p *proxy) logSlowRequest(req *http.Request, timing *serverTiming, threshold time.Duration, fields logFields) {
+	if threshold <= 0 || timing == nil {
+		return
+	}
+	dial, ttfb, total := timing.phases()
+	if total <= threshold {
+		return
+	}
+	p.log.Warn().
+		Str(LogFieldCFRay, fields.cfRay).
+		Str("method", req.Method).
+		Str("path", req.URL.Path).
+		Interface("rule", fields.rule).
+		EmbedObject(rolloutConfig(fields.config)).
+		Dur("dial", dial).
+		Dur("ttfb", ttfb).
+		Dur("total", total).
+		Msgf("Slow request took %s, longer than slowRequestThreshold (%s)", total, threshold)
+}
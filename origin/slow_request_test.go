@@ -0,0 +1,66 @@
+package origin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxySlowRequestThreshold(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer origin.Close()
+
+	threshold := 50 * time.Millisecond
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Service: origin.URL, OriginRequest: config.OriginRequestConfig{SlowRequestThreshold: &threshold}},
+		},
+	})
+	require.NoError(t, err)
+	var logs bytes.Buffer
+	log := zerolog.New(&logs).Level(zerolog.WarnLevel)
+	var wg sync.WaitGroup
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(&wg, &log, shutdownC, make(chan error)))
+	proxy := NewOriginProxy(ing, unusedWarpRoutingService, testTags, false, nil, "", &log)
+
+	proxyPath := func(path string) {
+		req, err := http.NewRequest(http.MethodGet, "http://www.example.com"+path, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+	}
+
+	proxyPath("/fast")
+	assert.Empty(t, logs.String())
+
+	proxyPath("/slow")
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 1)
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Contains(t, entry["message"], "Slow request took")
+	assert.Equal(t, "/slow", entry["path"])
+	assert.GreaterOrEqual(t, entry["total"], float64(100))
+	assert.GreaterOrEqual(t, entry["ttfb"], float64(100))
+	assert.Contains(t, entry, "dial")
+}
@@ -0,0 +1,63 @@
+package origin
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyStartupGrace(t *testing.T) {
+	// Reserve an address for the origin, which isn't listening yet.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	grace := time.Minute
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http://" + addr}},
+		OriginRequest: config.OriginRequestConfig{
+			StartupGrace: &grace,
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	proxyRequest := func() (*mockHTTPRespWriter, error) {
+		req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		return responseWriter, proxy.Proxy(responseWriter, req, connection.TypeHTTP)
+	}
+
+	responseWriter, err := proxyRequest()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, responseWriter.Code)
+	assert.Equal(t, "60", responseWriter.Header().Get("Retry-After"))
+
+	listener, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	origin.Listener = listener
+	origin.Start()
+
+	responseWriter, err = proxyRequest()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, responseWriter.Code)
+
+	// Once the origin was up, failures are reported as usual.
+	origin.Close()
+	_, err = proxyRequest()
+	assert.Error(t, err)
+}
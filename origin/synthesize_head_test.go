@@ -0,0 +1,64 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxySynthesizeHead(t *testing.T) {
+	// An origin that doesn't implement HEAD.
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer origin.Close()
+
+	synthesizeHead := true
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname:      "synthesized.example.com",
+				Service:       origin.URL,
+				OriginRequest: config.OriginRequestConfig{SynthesizeHead: &synthesizeHead},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	req, err := http.NewRequest(http.MethodHead, "http://synthesized.example.com", nil)
+	require.NoError(t, err)
+	responseWriter := newMockHTTPRespWriter()
+	require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+	assert.Equal(t, http.StatusOK, responseWriter.Code)
+	assert.Equal(t, "text/plain", responseWriter.Header().Get("Content-Type"))
+	assert.Equal(t, `"v1"`, responseWriter.Header().Get("ETag"))
+	assert.Equal(t, "5", responseWriter.Header().Get("Content-Length"))
+	assert.Empty(t, responseWriter.Body.String())
+
+	req, err = http.NewRequest(http.MethodHead, "http://www.example.com", nil)
+	require.NoError(t, err)
+	responseWriter = newMockHTTPRespWriter()
+	require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+	assert.Equal(t, http.StatusMethodNotAllowed, responseWriter.Code, "other rules send HEAD requests as they are")
+
+	req, err = http.NewRequest(http.MethodGet, "http://synthesized.example.com", nil)
+	require.NoError(t, err)
+	responseWriter = newMockHTTPRespWriter()
+	require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+	assert.Equal(t, "hello", responseWriter.Body.String())
+}
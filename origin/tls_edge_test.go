@@ -0,0 +1,92 @@
+package origin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+
+	"github.com/cloudflare/cloudflared/connection"
+	tunnelpogs "github.com/cloudflare/cloudflared/tunnelrpc/pogs"
+)
+
+// newTLSEdge serves proxy over an http2 connection to a fake edge, which is TLS like the real
+// one, so requests carry the TLS state of cloudflared's connection to the edge. It returns the
+// edge's side of the connection, which sends the eyeballs' requests.
+func newTLSEdge(t *testing.T, proxy connection.OriginProxy) *http2.ClientConn {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "edge.argotunnel.com"},
+		DNSNames:     []string{"edge.argotunnel.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	edgeConn, tunnelConn := net.Pipe()
+	edgeTLS := tls.Server(edgeConn, &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{"h2"},
+	})
+	tunnelTLS := tls.Client(tunnelConn, &tls.Config{
+		ServerName:         "edge.argotunnel.com",
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"h2"},
+	})
+	handshakeErr := make(chan error, 1)
+	go func() { handshakeErr <- edgeTLS.Handshake() }()
+	require.NoError(t, tunnelTLS.Handshake())
+	require.NoError(t, <-handshakeErr)
+
+	log := zerolog.Nop()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-served
+	})
+	tunnel := connection.NewHTTP2Connection(
+		tunnelTLS,
+		&connection.Config{OriginProxy: proxy},
+		&connection.NamedTunnelConfig{},
+		&tunnelpogs.ConnectionOptions{},
+		connection.NewObserver(&log, &log, false),
+		0,
+		nil,
+		nil,
+	)
+	go func() {
+		defer close(served)
+		_ = tunnel.Serve(ctx)
+	}()
+
+	edge, err := (&http2.Transport{}).NewClientConn(edgeTLS)
+	require.NoError(t, err)
+	return edge
+}
+
+// edgeResponseHeader returns the origin's headers of a response that came through the edge.
+func edgeResponseHeader(t *testing.T, resp *http.Response) http.Header {
+	serialized, err := connection.DeserializeHeaders(resp.Header.Get(connection.CanonicalResponseUserHeaders))
+	require.NoError(t, err)
+	header := http.Header{}
+	for _, h := range serialized {
+		header.Add(h.Name, h.Value)
+	}
+	return header
+}
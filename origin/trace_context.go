@@ -0,0 +1,67 @@
+package origin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+const (
+	traceparentHeader = "Traceparent"
+	tracestateHeader  = "Tracestate"
+)
+
+// traceparentFormat matches the traceparent headers that can be propagated, see
+// https://www.w3.org/TR/trace-context/#traceparent-header. Future versions may append fields.
+var traceparentFormat = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})(-.*)?$`)
+
+// ensureTraceContext leaves a valid traceparent header as it is, so the origin's spans join the
+// eyeball's trace. Otherwise it starts a new trace.
+func ensureTraceContext(req *http.Request) {
+	if isValidTraceparent(req.Header.Get(traceparentHeader)) {
+		return
+	}
+	// tracestate only means something along with the traceparent it came with.
+	req.Header.Del(tracestateHeader)
+	req.Header.Del(traceparentHeader)
+	if traceparent := newTraceparent(); traceparent != "" {
+		req.Header.Set(traceparentHeader, traceparent)
+	}
+}
+
+func isValidTraceparent(value string) bool {
+	match := traceparentFormat.FindStringSubmatch(value)
+	if match == nil {
+		return false
+	}
+	version, traceID, parentID := match[1], match[2], match[3]
+	if version == "ff" || (version == "00" && match[5] != "") {
+		return false
+	}
+	return traceID != "00000000000000000000000000000000" && parentID != "0000000000000000"
+}
+
+// newTraceparent returns the traceparent of a new sampled trace with random IDs.
+func newTraceparent() string {
+	ids := make([]byte, 16+8)
+	if _, err := rand.Read(ids); err != nil {
+		// crypto/rand doesn't fail on supported platforms, and the request is better off
+		// without trace context than with a predictable one.
+		return ""
+	}
+	return "00-" + hex.EncodeToString(ids[:16]) + "-" + hex.EncodeToString(ids[16:]) + "-01"
+}
+
+// sampledTraceID returns the trace ID of a valid traceparent whose sampled flag is set, or "".
+func sampledTraceID(traceparent string) string {
+	if !isValidTraceparent(traceparent) {
+		return ""
+	}
+	match := traceparentFormat.FindStringSubmatch(traceparent)
+	flags, err := hex.DecodeString(match[4])
+	if err != nil || flags[0]&0x01 == 0 {
+		return ""
+	}
+	return match[2]
+}
@@ -0,0 +1,85 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestEnsureTraceContext(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name             string
+		traceparent      string
+		tracestate       string
+		expectPropagated bool
+	}{
+		{name: "absent"},
+		{name: "valid", traceparent: traceparent, tracestate: "congo=t61rcWkgMzE", expectPropagated: true},
+		{name: "future version with more fields", traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", expectPropagated: true},
+		{name: "uppercase", traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01", tracestate: "congo=t61rcWkgMzE"},
+		{name: "zero trace id", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "invalid version", traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "version 00 with more fields", traceparent: traceparent + "-extra"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+			require.NoError(t, err)
+			if test.traceparent != "" {
+				req.Header.Set(traceparentHeader, test.traceparent)
+			}
+			if test.tracestate != "" {
+				req.Header.Set(tracestateHeader, test.tracestate)
+			}
+			ensureTraceContext(req)
+
+			got := req.Header.Get(traceparentHeader)
+			assert.True(t, isValidTraceparent(got), got)
+			if test.expectPropagated {
+				assert.Equal(t, test.traceparent, got)
+				assert.Equal(t, test.tracestate, req.Header.Get(tracestateHeader))
+			} else {
+				assert.NotEqual(t, test.traceparent, got)
+				assert.Regexp(t, `^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`, got)
+				assert.Empty(t, req.Header.Get(tracestateHeader))
+			}
+		})
+	}
+}
+
+func TestProxyTraceContext(t *testing.T) {
+	received := make(chan string, 1)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(traceparentHeader)
+	}))
+	defer origin.Close()
+
+	for _, traceContext := range []bool{true, false} {
+		ing, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{Service: origin.URL, OriginRequest: config.OriginRequestConfig{TraceContext: &traceContext}},
+			},
+		})
+		require.NoError(t, err)
+
+		proxy := newTestProxy(t, ing)
+
+		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+		require.NoError(t, proxy.Proxy(newMockHTTPRespWriter(), req, connection.TypeHTTP))
+
+		if traceContext {
+			assert.True(t, isValidTraceparent(<-received))
+		} else {
+			assert.Empty(t, <-received)
+		}
+	}
+}
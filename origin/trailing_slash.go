@@ -0,0 +1,35 @@
+package origin
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// normalizeTrailingSlash adds or strips the trailing slash of u's path, as trailingSlash says.
+// The root path keeps its slash.
+func normalizeTrailingSlash(u *url.URL, trailingSlash string) {
+	switch trailingSlash {
+	case ingress.TrailingSlashAdd:
+		if u.Path != "" && !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+			if u.RawPath != "" {
+				u.RawPath += "/"
+			}
+		}
+	case ingress.TrailingSlashStrip:
+		if len(u.Path) > 1 && strings.HasSuffix(u.Path, "/") {
+			u.Path = strings.TrimRight(u.Path, "/")
+			if u.Path == "" {
+				u.Path = "/"
+			}
+			if u.RawPath != "" {
+				u.RawPath = strings.TrimRight(u.RawPath, "/")
+				if u.RawPath == "" {
+					u.RawPath = "/"
+				}
+			}
+		}
+	}
+}
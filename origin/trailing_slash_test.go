@@ -0,0 +1,89 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestNormalizeTrailingSlash(t *testing.T) {
+	tests := []struct {
+		trailingSlash string
+		path          string
+		expectPath    string
+	}{
+		{trailingSlash: ingress.TrailingSlashAdd, path: "/a", expectPath: "/a/"},
+		{trailingSlash: ingress.TrailingSlashAdd, path: "/a/", expectPath: "/a/"},
+		{trailingSlash: ingress.TrailingSlashAdd, path: "/", expectPath: "/"},
+		{trailingSlash: ingress.TrailingSlashAdd, path: "/a%2Fb", expectPath: "/a%2Fb/"},
+		{trailingSlash: ingress.TrailingSlashStrip, path: "/a/", expectPath: "/a"},
+		{trailingSlash: ingress.TrailingSlashStrip, path: "/a", expectPath: "/a"},
+		{trailingSlash: ingress.TrailingSlashStrip, path: "/", expectPath: "/"},
+		{trailingSlash: ingress.TrailingSlashStrip, path: "/a%2Fb/", expectPath: "/a%2Fb"},
+		{trailingSlash: ingress.TrailingSlashPreserve, path: "/a", expectPath: "/a"},
+		{trailingSlash: ingress.TrailingSlashPreserve, path: "/a/", expectPath: "/a/"},
+		{trailingSlash: "", path: "/a/", expectPath: "/a/"},
+	}
+	for _, test := range tests {
+		u, err := url.Parse("http://example.com" + test.path + "?q=1")
+		require.NoError(t, err)
+		normalizeTrailingSlash(u, test.trailingSlash)
+		assert.Equal(t, test.expectPath, u.EscapedPath(), "%s %s", test.trailingSlash, test.path)
+		assert.Equal(t, "q=1", u.RawQuery)
+	}
+}
+
+func TestProxyTrailingSlash(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer origin.Close()
+
+	add, strip := ingress.TrailingSlashAdd, ingress.TrailingSlashStrip
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "add.example.com", Service: origin.URL, OriginRequest: config.OriginRequestConfig{TrailingSlash: &add}},
+			{Hostname: "strip.example.com", Service: origin.URL, OriginRequest: config.OriginRequestConfig{TrailingSlash: &strip}},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		url        string
+		expectPath string
+	}{
+		{url: "http://add.example.com/a", expectPath: "/a/"},
+		{url: "http://strip.example.com/a/", expectPath: "/a"},
+		{url: "http://www.example.com/a/", expectPath: "/a/"},
+		{url: "http://www.example.com/a", expectPath: "/a"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.Equal(t, test.expectPath, responseWriter.Body.String(), test.url)
+	}
+}
+
+func TestParseTrailingSlash(t *testing.T) {
+	trailingSlash := "remove"
+	_, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: "http://localhost:8080"}},
+		OriginRequest: config.OriginRequestConfig{
+			TrailingSlash: &trailingSlash,
+		},
+	})
+	assert.Error(t, err)
+}
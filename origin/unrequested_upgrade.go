@@ -0,0 +1,17 @@
+package origin
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// isUnrequestedUpgrade reports whether the origin answered 101 Switching Protocols to a request
+// that didn't ask to upgrade, e.g. because its Upgrade header was removed as a hop-by-hop
+// header. Tunneling the connection would hand the eyeball a protocol it never negotiated.
+func isUnrequestedUpgrade(req *http.Request, resp *http.Response) bool {
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return false
+	}
+	return req.Header.Get("Upgrade") == "" || !httpguts.HeaderValuesContainsToken(req.Header["Connection"], "upgrade")
+}
@@ -0,0 +1,75 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyUnrequestedUpgrade(t *testing.T) {
+	// The origin switches protocols whether or not it was asked to.
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: custom\r\nConnection: Upgrade\r\n\r\ntunneled")
+		_ = buf.Flush()
+	}))
+	defer origin.Close()
+
+	allow := true
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Hostname:      "allowed.example.com",
+				Service:       origin.URL,
+				OriginRequest: config.OriginRequestConfig{AllowUnrequestedUpgrade: &allow},
+			},
+			{Service: origin.URL},
+		},
+	})
+	require.NoError(t, err)
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		url          string
+		expectStatus int
+	}{
+		{url: "http://www.example.com/", expectStatus: http.StatusBadGateway},
+		{url: "http://allowed.example.com/", expectStatus: http.StatusSwitchingProtocols},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, test.expectStatus, responseWriter.Code, test.url)
+		if test.expectStatus == http.StatusBadGateway {
+			assert.Empty(t, responseWriter.Body.String())
+		}
+	}
+	// The rejected upgrade counts against the rule.
+	status := ing.Status()
+	assert.Equal(t, float64(1), status[0].SuccessRate)
+	assert.Equal(t, float64(0), status[1].SuccessRate)
+	assert.Contains(t, status[1].LastError, "The origin switched protocols")
+}
+
+func TestIsUnrequestedUpgrade(t *testing.T) {
+	upgrade := &http.Response{StatusCode: http.StatusSwitchingProtocols}
+	req, err := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	require.NoError(t, err)
+	assert.True(t, isUnrequestedUpgrade(req, upgrade))
+
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "custom")
+	assert.False(t, isUnrequestedUpgrade(req, upgrade))
+	assert.False(t, isUnrequestedUpgrade(req, &http.Response{StatusCode: http.StatusOK}))
+}
@@ -0,0 +1,41 @@
+package origin
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+// healthResponseBody is what cloudflared answers requests to healthResponsePath with.
+var healthResponseBody = []byte("OK\n")
+
+// writeWellKnownFile serves a file configured with wellKnown, instead of asking the origin.
+func writeWellKnownFile(w connection.ResponseWriter, req *http.Request, content []byte) error {
+	header := http.Header{
+		"Content-Type":   []string{"text/plain; charset=utf-8"},
+		"Content-Length": []string{strconv.Itoa(len(content))},
+	}
+	if err := w.WriteRespHeaders(http.StatusOK, header); err != nil {
+		return errors.Wrap(err, "Error writing response header")
+	}
+	if req.Method != http.MethodHead {
+		_, _ = w.Write(content)
+	}
+	return nil
+}
+
+// writeACMEChallenge serves a challenge response from the acmeChallenge directory, or 404 if
+// there is none for the token.
+func writeACMEChallenge(w connection.ResponseWriter, req *http.Request, content []byte, err error) error {
+	if os.IsNotExist(err) {
+		return w.WriteRespHeaders(http.StatusNotFound, http.Header{})
+	}
+	if err != nil {
+		return errors.Wrap(err, "Error reading the ACME challenge response")
+	}
+	return writeWellKnownFile(w, req, content)
+}
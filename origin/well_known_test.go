@@ -0,0 +1,137 @@
+package origin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestProxyWellKnownFiles(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("origin " + r.URL.Path))
+	}))
+	defer origin.Close()
+
+	dir := t.TempDir()
+	robots := filepath.Join(dir, "robots.txt")
+	require.NoError(t, ioutil.WriteFile(robots, []byte("User-agent: *\nDisallow: /\n"), 0600))
+	securityTxt := filepath.Join(dir, "security.txt")
+	require.NoError(t, ioutil.WriteFile(securityTxt, []byte("Contact: mailto:security@example.com\n"), 0600))
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Service: origin.URL,
+				OriginRequest: config.OriginRequestConfig{
+					WellKnown: &config.WellKnownConfig{Robots: robots, SecurityTxt: securityTxt},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		method     string
+		path       string
+		expectBody string
+	}{
+		{method: http.MethodGet, path: "/robots.txt", expectBody: "User-agent: *\nDisallow: /\n"},
+		{method: http.MethodGet, path: "/.well-known/security.txt", expectBody: "Contact: mailto:security@example.com\n"},
+		{method: http.MethodHead, path: "/robots.txt", expectBody: ""},
+		{method: http.MethodGet, path: "/index.html", expectBody: "origin /index.html"},
+		{method: http.MethodPost, path: "/robots.txt", expectBody: "origin /robots.txt"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, "http://example.com"+test.path, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, http.StatusOK, responseWriter.Code, test.path)
+		assert.Equal(t, test.expectBody, responseWriter.Body.String(), test.method+" "+test.path)
+	}
+}
+
+func TestParseWellKnownMissingFile(t *testing.T) {
+	_, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Service: "http_status:404",
+				OriginRequest: config.OriginRequestConfig{
+					WellKnown: &config.WellKnownConfig{Robots: filepath.Join(t.TempDir(), "missing.txt")},
+				},
+			},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestProxyACMEChallenge(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("origin " + r.URL.Path))
+	}))
+	defer origin.Close()
+
+	webroot := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(webroot, "LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0"), []byte("LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0.key-thumbprint"), 0600))
+	// A file next to the challenge directory, which must not be reachable from it.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(filepath.Dir(webroot), "secret"), []byte("secret"), 0600))
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		Ingress: []config.UnvalidatedIngressRule{{Service: origin.URL}},
+		OriginRequest: config.OriginRequestConfig{
+			ACMEChallenge: &webroot,
+		},
+	})
+	require.NoError(t, err)
+
+	proxy := newTestProxy(t, ing)
+
+	tests := []struct {
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{path: "/.well-known/acme-challenge/LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0", expectStatus: http.StatusOK, expectBody: "LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0.key-thumbprint"},
+		{path: "/.well-known/acme-challenge/unknown-token", expectStatus: http.StatusNotFound},
+		{path: "/.well-known/acme-challenge/..%2Fsecret", expectStatus: http.StatusNotFound},
+		{path: "/.well-known/security.txt", expectStatus: http.StatusOK, expectBody: "origin /.well-known/security.txt"},
+		{path: "/index.html", expectStatus: http.StatusOK, expectBody: "origin /index.html"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com"+test.path, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.Proxy(responseWriter, req, connection.TypeHTTP))
+		assert.Equal(t, test.expectStatus, responseWriter.Code, test.path)
+		assert.Equal(t, test.expectBody, responseWriter.Body.String(), test.path)
+	}
+}
+
+func TestParseACMEChallengeNotADirectory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+	missing := filepath.Join(t.TempDir(), "missing")
+	for _, dir := range []string{file, missing} {
+		dir := dir
+		_, err := ingress.ParseIngress(&config.Configuration{
+			Ingress: []config.UnvalidatedIngressRule{
+				{
+					Service:       "http_status:404",
+					OriginRequest: config.OriginRequestConfig{ACMEChallenge: &dir},
+				},
+			},
+		})
+		assert.Error(t, err, dir)
+	}
+}
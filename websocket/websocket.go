@@ -28,12 +28,26 @@ func NewResponseHeader(req *http.Request) http.Header {
 	return header
 }
 
+// BufferSizes sets the sizes of the buffers that StreamWithBuffers copies data through. A zero
+// size uses io.Copy, which may copy without a buffer at all, e.g. between TCP connections.
+type BufferSizes struct {
+	// Read is the size of the buffer for data read from the origin.
+	Read int
+	// Write is the size of the buffer for data written to the origin.
+	Write int
+}
+
 // Stream copies copy data to & from provided io.ReadWriters.
 func Stream(tunnelConn, originConn io.ReadWriter, log *zerolog.Logger) {
+	StreamWithBuffers(tunnelConn, originConn, BufferSizes{}, log)
+}
+
+// StreamWithBuffers is like Stream, but copies data through buffers of the given sizes.
+func StreamWithBuffers(tunnelConn, originConn io.ReadWriter, sizes BufferSizes, log *zerolog.Logger) {
 	proxyDone := make(chan struct{}, 2)
 
 	go func() {
-		_, err := copyData(tunnelConn, originConn, "origin->tunnel")
+		_, err := copyData(tunnelConn, originConn, "origin->tunnel", sizes.Read)
 		if err != nil {
 			log.Debug().Msgf("origin to tunnel copy: %v", err)
 		}
@@ -41,7 +55,7 @@ func Stream(tunnelConn, originConn io.ReadWriter, log *zerolog.Logger) {
 	}()
 
 	go func() {
-		_, err := copyData(originConn, tunnelConn, "tunnel->origin")
+		_, err := copyData(originConn, tunnelConn, "tunnel->origin", sizes.Write)
 		if err != nil {
 			log.Debug().Msgf("tunnel to origin copy: %v", err)
 		}
@@ -55,12 +69,15 @@ func Stream(tunnelConn, originConn io.ReadWriter, log *zerolog.Logger) {
 // when set to true, enables logging of content copied to/from origin and tunnel
 const debugCopy = false
 
-func copyData(dst io.Writer, src io.Reader, dir string) (written int64, err error) {
+func copyData(dst io.Writer, src io.Reader, dir string, bufferSize int) (written int64, err error) {
 	if debugCopy {
 		// copyBuffer is based on stdio Copy implementation but shows copied data
 		copyBuffer := func(dst io.Writer, src io.Reader, dir string) (written int64, err error) {
 			var buf []byte
 			size := 32 * 1024
+			if bufferSize > 0 {
+				size = bufferSize
+			}
 			buf = make([]byte, size)
 			for {
 				t := time.Now()
@@ -94,11 +111,22 @@ func copyData(dst io.Writer, src io.Reader, dir string) (written int64, err erro
 			return written, err
 		}
 		return copyBuffer(dst, src, dir)
+	} else if bufferSize > 0 {
+		// Hide ReadFrom and WriteTo, which io.CopyBuffer would use instead of the buffer.
+		return io.CopyBuffer(writerOnly{dst}, readerOnly{src}, make([]byte, bufferSize))
 	} else {
 		return io.Copy(dst, src)
 	}
 }
 
+type readerOnly struct {
+	io.Reader
+}
+
+type writerOnly struct {
+	io.Writer
+}
+
 // from RFC-6455
 var keyGUID = []byte("258EAFA5-E914-47DA-95CA-C5AB0DC85B11")
 
@@ -1,9 +1,19 @@
 package websocket
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -16,3 +26,109 @@ const (
 func TestGenerateAcceptKey(t *testing.T) {
 	assert.Equal(t, testSecWebsocketAccept, generateAcceptKey(testSecWebsocketKey))
 }
+
+// readSizeRecorder records the largest buffer it was asked to read into.
+type readSizeRecorder struct {
+	io.Reader
+	lock    sync.Mutex
+	maxRead int
+}
+
+func (r *readSizeRecorder) Read(p []byte) (int, error) {
+	r.lock.Lock()
+	if len(p) > r.maxRead {
+		r.maxRead = len(p)
+	}
+	r.lock.Unlock()
+	return r.Reader.Read(p)
+}
+
+func (r *readSizeRecorder) largestRead() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.maxRead
+}
+
+func TestCopyDataBufferSize(t *testing.T) {
+	data := bytes.Repeat([]byte("cloudflared"), 100000)
+	for _, size := range []int{1024, 64 * 1024} {
+		src := &readSizeRecorder{Reader: bytes.NewReader(data)}
+		var dst bytes.Buffer
+		written, err := copyData(&dst, src, "test", size)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(data)), written)
+		assert.Equal(t, data, dst.Bytes())
+		assert.Equal(t, size, src.largestRead())
+	}
+}
+
+func TestStreamWithBuffers(t *testing.T) {
+	log := zerolog.Nop()
+	fromOrigin := &readSizeRecorder{Reader: strings.NewReader("response")}
+	fromTunnel := &readSizeRecorder{Reader: strings.NewReader("request")}
+	var toOrigin, toTunnel lockedBuffer
+	tunnelConn := &readWriter{Reader: fromTunnel, Writer: &toTunnel}
+	originConn := &readWriter{Reader: fromOrigin, Writer: &toOrigin}
+
+	StreamWithBuffers(tunnelConn, originConn, BufferSizes{Read: 4096, Write: 2048}, &log)
+
+	// Stream returns once either direction is done, so wait for both.
+	require.Eventually(t, func() bool {
+		return toTunnel.String() == "response" && toOrigin.String() == "request"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 4096, fromOrigin.largestRead())
+	assert.Equal(t, 2048, fromTunnel.largestRead())
+}
+
+type readWriter struct {
+	io.Reader
+	io.Writer
+}
+
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+// BenchmarkCopyDataBufferSize copies from a loopback TCP connection, like a TCP origin, with
+// different buffer sizes.
+func BenchmarkCopyDataBufferSize(b *testing.B) {
+	const payloadSize = 16 << 20
+	payload := make([]byte, payloadSize)
+	for _, size := range []int{4 * 1024, 32 * 1024, 64 * 1024, 256 * 1024} {
+		b.Run(fmt.Sprintf("%dKiB", size/1024), func(b *testing.B) {
+			b.SetBytes(payloadSize)
+			for i := 0; i < b.N; i++ {
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				require.NoError(b, err)
+				go func() {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					_, _ = conn.Write(payload)
+					_ = conn.Close()
+				}()
+				conn, err := net.Dial("tcp", listener.Addr().String())
+				require.NoError(b, err)
+				written, err := copyData(ioutil.Discard, conn, "origin->tunnel", size)
+				require.NoError(b, err)
+				require.Equal(b, int64(payloadSize), written)
+				_ = conn.Close()
+				_ = listener.Close()
+			}
+		})
+	}
+}